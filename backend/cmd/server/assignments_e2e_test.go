@@ -7,6 +7,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -17,7 +18,14 @@ import (
 
 	"firebase.google.com/go/v4/auth"
 	"github.com/bxcodec/faker/v3"
-	"github.com/google/go-github/v39/github"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	gitHttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/storage/memory"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -29,7 +37,7 @@ import (
 func TestAssignments(t *testing.T) {
 	t.Run("/events", func(t *testing.T) {
 		t.Run("insert assignment event", func(t *testing.T) {
-			var candidateRepo *github.Repository
+			var candidateRepo string
 			var candidate userQueryData
 
 			tr := test.NewRunner(t)
@@ -39,6 +47,7 @@ func TestAssignments(t *testing.T) {
 			fbRecruiter := createRecruiterFirebaseUser(tr)
 			trBusinessWithUser, deleteB := rawGraphlClient.CreateRecruiterAndBusiness(t, fbRecruiter)
 			tr.AddCleanupStep(deleteB)
+			setBusinessVCSProvider(tr, trBusinessWithUser, "local")
 
 			// insert assignment which triggers events
 			assignmentInsertData := insertAssignment(tr, trBusinessWithUser)
@@ -49,13 +58,13 @@ func TestAssignments(t *testing.T) {
 			vad := assertAssignmentUpdated(tr, cRec, assignmentInsertData, trBusinessWithUser)
 			assertCandidateClaims(tr, trBusinessWithUser, cRec, vad)
 			t.Run("insert assignment_events event", func(t *testing.T) {
-				candidate = updateInsertedCandidateWithGithubUsername(tr, assignmentInsertData)
+				candidate = fetchCandidate(tr, assignmentInsertData)
 				now := time.Now()
 				updateAssignmentWithTimeChosen(tr, assignmentInsertData, now.Format("15:04"), now.AddDate(0, 0, 4).Format("2006-01-02"))
 				insertAssignmentEvent(tr, assignmentInsertData, candidate, "scheduled")
 
 				assignmentDetails := waitForAssignmentDetails(tr, assignmentInsertData)
-				candidateRepo = assertGithubRepoCreated(tr, assignmentDetails, trBusinessWithUser)
+				candidateRepo = assertLocalRepoCreated(tr, assignmentDetails, candidate)
 				assertEventScheduled(tr, assignmentDetails)
 			})
 
@@ -71,7 +80,7 @@ func TestAssignments(t *testing.T) {
 				t.Run("init", func(t *testing.T) {
 					step := "init"
 					sendStepPayload(t, step, fullAssignment)
-					assertHasCommits(t, candidateRepo.GetOwner().GetLogin(), candidateRepo.GetName())
+					assertHasCommits(t, candidateRepo, candidate)
 					assertAssignmentEvent(t, fullAssignment.ID, candidate.ID, "inprogress")
 				})
 
@@ -82,13 +91,19 @@ func TestAssignments(t *testing.T) {
 				})
 
 				t.Run("cleanup", func(t *testing.T) {
-					addReviewer(t, fullAssignment.ID)
+					reviewer := addReviewer(t, fullAssignment.ID)
 					step := "cleanup"
 					sendStepPayload(t, step, fullAssignment)
-					assertGithubRepoCleaned(t, candidateRepo, 0)
+					assertLocalRepoCleaned(t, candidateRepo, candidate, reviewer)
 					assertCandidateMissedEmail(t, fullAssignment)
 					assertRecruiterMissedEmail(t, fullAssignment)
 					assertAssignmentEvent(t, fullAssignment.ID, candidate.ID, "missed")
+					assertStepCompleted(t, fullAssignment.ID, step)
+				})
+
+				t.Run("duplicate cleanup", func(t *testing.T) {
+					sendStepPayload(t, "cleanup", fullAssignment)
+					assertAssignmentEvent(t, fullAssignment.ID, candidate.ID, "missed")
 				})
 			})
 		})
@@ -113,39 +128,18 @@ func assertCandidateMissedEmail(t *testing.T, fullAssignment assignment.WithTest
 	assert.Contains(t, specialChar.ReplaceAllString(emails.Items[0].Content.Body, ""), "you missed the deadline to submit your assignment")
 }
 
-func assertGithubRepoCleaned(t *testing.T, r *github.Repository, count int) {
-	if count > 5 {
-		t.Errorf("could not assert that github repo had correct invitees after clean stage")
-		return
-	}
-
-	owner := r.GetOwner().GetLogin()
-	repoName := r.GetName()
-
-	invites, _, err := githubClient.Repositories.ListInvitations(context.Background(), owner, repoName, nil)
-	require.NoError(t, err)
-
-	var invitees = make(map[string]struct{})
-	for _, u := range invites {
-		invitees[u.Invitee.GetLogin()] = struct{}{}
-	}
-
-	if _, ok := invitees[testReviewerGithubUsername]; !ok {
-		assertGithubRepoCleaned(t, r, count+1)
-		return
-	}
+func assertLocalRepoCleaned(t *testing.T, repoURL string, candidate userQueryData, reviewer string) {
+	_, err := listRepo(repoURL, candidate.Email)
+	assert.ErrorIs(t, err, transport.ErrRepositoryNotFound, "candidate can still access repo %s after clean stage", repoURL)
 
-	if _, ok := invitees[testUserGithubUsername]; ok {
-		assertGithubRepoCleaned(t, r, count+1)
-		return
-	}
+	_, err = listRepo(repoURL, reviewer)
+	assert.NoError(t, err, "reviewer cannot access repo %s after clean stage", repoURL)
 }
 
 var addReviewerMu = `
 mutation MyMutation(
 	$assignment_id: Int!
 	$auth_id: String!
-	$github_username: String!
 	$email: String!
 ) {
 	insert_assignment_users(
@@ -154,7 +148,6 @@ mutation MyMutation(
 			user: {
 				data: {
 					auth_id: $auth_id
-					github_username: $github_username
 					email: $email
 				}
 			}
@@ -166,14 +159,17 @@ mutation MyMutation(
 
 `
 
-func addReviewer(t *testing.T, id int) {
+// addReviewer adds a new reviewer to the assignment, returning their email.
+func addReviewer(t *testing.T, id int) string {
+	email := strings.ToLower(faker.Email())
 	_, err := rawGraphlClient.Do(addReviewerMu, map[string]interface{}{
-		"assignment_id":   id,
-		"email":           faker.Email(),
-		"auth_id":         faker.UUIDHyphenated(),
-		"github_username": testReviewerGithubUsername,
+		"assignment_id": id,
+		"email":         email,
+		"auth_id":       faker.UUIDHyphenated(),
 	}, nil)
 	require.NoError(t, err)
+
+	return email
 }
 
 var assignmentEventQuery = `
@@ -213,6 +209,31 @@ func assertAssignmentEvent(t *testing.T, assignmentID int, userID int, eventType
 	require.Len(t, d.Data, 1)
 }
 
+var assignmentStepQuery = `
+query ($assignment_id: Int!, $step: String!) {
+	assignment_steps(where: {assignment_id: {_eq: $assignment_id}, step: {_eq: $step}}) {
+		completed_at
+	}
+}
+`
+
+type assignmentStepQueryData struct {
+	Data []struct {
+		CompletedAt *time.Time `json:"completed_at"`
+	} `json:"assignment_steps"`
+}
+
+func assertStepCompleted(t *testing.T, assignmentID int, step string) {
+	var d assignmentStepQueryData
+	_, err := rawGraphlClient.Do(assignmentStepQuery, map[string]interface{}{
+		"assignment_id": assignmentID,
+		"step":          step,
+	}, &d)
+	require.NoError(t, err)
+	require.Len(t, d.Data, 1)
+	assert.NotNil(t, d.Data[0].CompletedAt)
+}
+
 var specialChar = regexp.MustCompile(`[=\r\n]`)
 
 func assertFinishEmailSent(t *testing.T, fullAssignment assignment.WithTestDetails) {
@@ -267,64 +288,69 @@ func assertEventScheduled(tr *test.Runner, details assignmentTestDetailsData) {
 	// todo check hasura event scheduled
 }
 
-func assertGithubRepoCreated(tr *test.Runner, details assignmentTestDetailsData, user test.InsertUserWithBusinessMuData) *github.Repository {
-	r, _, err := githubClient.Repositories.Get(
-		context.Background(),
-		githubTestOwner,
-		strings.ToLower(fmt.Sprintf("%s-%s-test-%d", testUserGithubUsername, user.Insert.Name, details.AssignmentsByPK.ID)),
-	)
-	require.NoError(tr.T, err)
+// localURL returns the url that the test reaches a local repo at, as repo urls are based on the BACKEND_URL
+// that hasura calls the backend at.
+func localURL(repoURL string) string {
+	return "http://localhost:8000/git/" + strings.TrimPrefix(repoURL, localRepos+"/")
+}
 
-	owner := r.GetOwner().GetLogin()
-	repoName := r.GetName()
-	tr.AddCleanupStep(func() error {
-		_, err := githubClient.Repositories.Delete(context.Background(), owner, repoName)
-		return err
+// listRepo lists the refs of a local repo over http as the user with email, authenticating with the password
+// the local provider issues them.
+func listRepo(repoURL, email string) ([]*plumbing.Reference, error) {
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{localURL(repoURL)},
 	})
 
-	invites, _, err := githubClient.Repositories.ListInvitations(context.Background(), owner, repoName, nil)
-	require.NoError(tr.T, err)
-
-	var found bool
-	for _, u := range invites {
-		if u.Invitee.GetLogin() == testUserGithubUsername {
-			found = true
-			break
-		}
-	}
-
-	assert.True(tr.T, found, "could not find test user %s as invitee on generated repo %+v", testUserGithubUsername, invites)
-	return r
+	return remote.List(&git.ListOptions{
+		Auth: &gitHttp.BasicAuth{Username: email, Password: localClient.Password(email)},
+	})
 }
 
-func assertHasCommits(t *testing.T, owner string, repoName string) {
-	var commits []*github.RepositoryCommit
-	var err error
-	for i := 0; i < 3; i++ {
-		commits, _, err = githubClient.Repositories.ListCommits(context.Background(), owner, repoName, nil)
-		if err == nil {
-			break
-		}
+func assertLocalRepoCreated(tr *test.Runner, details assignmentTestDetailsData, candidate userQueryData) string {
+	repoURL := details.AssignmentsByPK.GithubRepoURL
+	require.True(tr.T, strings.HasPrefix(repoURL, localRepos+"/"), "repo %s is not a local repo", repoURL)
 
-		time.Sleep(time.Second)
+	// the repo is empty until the test is uploaded, but the candidate can already access it.
+	_, err := listRepo(repoURL, candidate.Email)
+	if !errors.Is(err, transport.ErrEmptyRemoteRepository) {
+		assert.NoError(tr.T, err, "candidate %s cannot access generated repo %s", candidate.Email, repoURL)
 	}
 
+	return repoURL
+}
+
+func assertHasCommits(t *testing.T, repoURL string, candidate userQueryData) {
+	r, err := git.Clone(memory.NewStorage(), memfs.New(), &git.CloneOptions{
+		URL:  localURL(repoURL),
+		Auth: &gitHttp.BasicAuth{Username: candidate.Email, Password: localClient.Password(candidate.Email)},
+	})
 	require.NoError(t, err)
-	require.Len(t, commits, 1)
 
-	c := commits[0].GetCommit()
-	assert.Equal(t, "start test", c.GetMessage())
+	commits, err := r.Log(&git.LogOptions{})
+	require.NoError(t, err)
 
-	tree, _, err := githubClient.Git.GetTree(context.Background(), owner, repoName, c.GetTree().GetSHA(), true)
-	assert.NoError(t, err)
+	var messages []string
+	var filenames []string
+	err = commits.ForEach(func(c *object.Commit) error {
+		messages = append(messages, c.Message)
+		if len(filenames) > 0 {
+			return nil
+		}
 
-	filenames := make([]string, 0, 2)
-	for _, e := range tree.Entries {
-		if e != nil {
-			filenames = append(filenames, e.GetPath())
+		files, err := c.Files()
+		if err != nil {
+			return err
 		}
-	}
 
+		return files.ForEach(func(f *object.File) error {
+			filenames = append(filenames, f.Name)
+			return nil
+		})
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"start test"}, messages)
 	assert.Contains(t, filenames, "test/index.txt")
 	assert.Contains(t, filenames, "echo.txt")
 }
@@ -406,23 +432,34 @@ func insertAssignmentEvent(tr *test.Runner, res insertAssignmentMuData, candidat
 	require.NoError(tr.T, err)
 }
 
-var updateUserWithGithubUsername = `
-mutation ($email: String!, $github_username: String!) {
-  update_users(where: {email: {_eq: $email}}, _set: {github_username: $github_username}) {
-    returning {
-      id
-      email
-      auth_id
-    }
+var fetchCandidateQuery = `
+query ($email: String!) {
+  users(where: {email: {_eq: $email}}) {
+    id
+    email
+    auth_id
   }
 }
+`
 
+type candidateQueryData struct {
+	Users []userQueryData `json:"users"`
+}
+
+var updateBusinessVCSProviderMu = `
+mutation ($id: Int!, $vcs_provider: String!) {
+  update_businesses_by_pk(pk_columns: {id: $id}, _set: {vcs_provider: $vcs_provider}) {
+    id
+  }
+}
 `
 
-type userUpdateData struct {
-	UpdateUsers struct {
-		Returning []userQueryData `json:"returning"`
-	} `json:"update_users"`
+func setBusinessVCSProvider(tr *test.Runner, b test.InsertUserWithBusinessMuData, provider string) {
+	_, err := rawGraphlClient.Do(updateBusinessVCSProviderMu, map[string]interface{}{
+		"id":           b.Insert.ID,
+		"vcs_provider": provider,
+	}, nil)
+	require.NoError(tr.T, err)
 }
 
 type userQueryData struct {
@@ -431,17 +468,16 @@ type userQueryData struct {
 	Email  string `json:"email"`
 }
 
-func updateInsertedCandidateWithGithubUsername(tr *test.Runner, a insertAssignmentMuData) userQueryData {
-	var d userUpdateData
+func fetchCandidate(tr *test.Runner, a insertAssignmentMuData) userQueryData {
+	var d candidateQueryData
 
-	_, err := rawGraphlClient.Do(updateUserWithGithubUsername, map[string]interface{}{
-		"email":           strings.ToLower(a.Insert.CandidateEmail),
-		"github_username": strings.ToLower(testUserGithubUsername),
+	_, err := rawGraphlClient.Do(fetchCandidateQuery, map[string]interface{}{
+		"email": strings.ToLower(a.Insert.CandidateEmail),
 	}, &d)
 	require.NoError(tr.T, err)
-	require.Len(tr.T, d.UpdateUsers.Returning, 1)
+	require.Len(tr.T, d.Users, 1)
 
-	return d.UpdateUsers.Returning[0]
+	return d.Users[0]
 }
 
 func assertCandidateClaims(tr *test.Runner, trBusinessWithUser test.InsertUserWithBusinessMuData, cRec *auth.UserRecord, vad validateAssignmentQueryData) bool {
@@ -616,7 +652,7 @@ type insertAssignmentVars struct {
 	BusinessID     int    `json:"business_id" faker:"-"`
 	Email          string `json:"email" faker:"-"`
 	Name           string `json:"name" faker:"name"`
-	ChooseUntil    string `json:"choose_until" faker:"date"`
+	ChooseUntil    string `json:"choose_until" faker:"-"`
 	TimeLimit      int    `json:"time_limit" faker:"oneof: 14400, 28800, 129000"`
 	TestGithubRepo string `json:"test_github_repo" faker:"-"`
 	TestName       string `json:"test_name" faker:"username"`
@@ -626,10 +662,12 @@ type insertAssignmentVars struct {
 
 func insertAssignment(tr *test.Runner, trBusinessWithUser test.InsertUserWithBusinessMuData) insertAssignmentMuData {
 	candidateEmail := faker.Email()
+	// choose_until must fall after the day the candidate picks, 4 days ahead, for scheduling to accept it.
 	v := insertAssignmentVars{
 		RecruiterID:    trBusinessWithUser.Insert.Creator.ID,
 		BusinessID:     trBusinessWithUser.Insert.ID,
 		Email:          candidateEmail,
+		ChooseUntil:    time.Now().AddDate(0, 0, 7).Format("2006-01-02"),
 		TestGithubRepo: testRepo,
 	}
 
@@ -12,6 +12,8 @@ import (
 	firebase "firebase.google.com/go/v4"
 	firebaseAuth "firebase.google.com/go/v4/auth"
 	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
 	"go.uber.org/zap"
 	"google.golang.org/api/option"
 
@@ -70,6 +72,24 @@ func newLogger(config options.Config) *zap.SugaredLogger {
 	return logger
 }
 
+func newSchedulerClient(config options.Config) (assignment.SchedulerClient, *sqlx.DB) {
+	if config.SchedulerDriver != "postgres" {
+		return scheduler.NewHasuraAssignmentScheduler(
+			config.HasuraURL,
+			config.HasuraToken,
+			config.AccessToken,
+			config.BackendURL,
+		), nil
+	}
+
+	db, err := sqlx.Connect("postgres", config.DatabaseURL)
+	if err != nil {
+		log.Fatalf("could not connect to scheduler database %s", err)
+	}
+
+	return scheduler.NewPostgresAssignmentScheduler(db), db
+}
+
 func run() {
 	config, err := options.ConfigFromEnv()
 	if err != nil {
@@ -80,23 +100,56 @@ func run() {
 
 	hasuraClient := graphql.NewHasuraClient(config.HasuraURL+"/v1/graphql", config.HasuraToken)
 
+	var webhookURL string
+	if config.GithubWebhookSecret != "" {
+		webhookURL = config.BackendURL + "/github/webhook"
+	}
+
 	githubClient, err := vcs.NewGithubClient(vcs.GithubInterviewerConfig{
-		AccessToken: config.GithubInterviewerAccessToken,
-		Username:    config.GithubInterviewerUsername,
-		Email:       config.GithubInterviewerEmail,
+		AccessToken:   config.GithubInterviewerAccessToken,
+		Username:      config.GithubInterviewerUsername,
+		Email:         config.GithubInterviewerEmail,
+		WebhookURL:    webhookURL,
+		WebhookSecret: config.GithubWebhookSecret,
 	}, config.GithubPrivateKeyLocation, config.GithubAppID)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	vcsClient := vcs.Router{Github: githubClient}
+	templateCreator := vcs.GithubTemplateCreator{Github: githubClient}
+	if config.GitlabURL != "" {
+		vcsClient.Gitlab = vcs.NewGitlabClient(vcs.GitlabConfig{
+			URL:         config.GitlabURL,
+			AccessToken: config.GitlabAccessToken,
+			Username:    config.GitlabUsername,
+			Email:       config.GitlabEmail,
+			NamespaceID: config.GitlabNamespaceID,
+		})
+	}
+
+	if config.BitbucketWorkspace != "" {
+		vcsClient.Bitbucket = vcs.NewBitbucketClient(vcs.BitbucketConfig{
+			Username:    config.BitbucketUsername,
+			AppPassword: config.BitbucketAppPassword,
+			Email:       config.BitbucketEmail,
+			Workspace:   config.BitbucketWorkspace,
+		})
+	}
+
+	if config.LocalGitRoot != "" {
+		vcsClient.Local = vcs.NewLocalGitClient(vcs.LocalConfig{
+			Root:     config.LocalGitRoot,
+			URL:      config.BackendURL + "/git",
+			Secret:   config.LocalGitSecret,
+			Username: config.GithubInterviewerUsername,
+			Email:    config.GithubInterviewerEmail,
+		})
+	}
+
 	mailer := newMailer(config)
 
-	scheduleClient := scheduler.NewHasuraAssignmentScheduler(
-		config.HasuraURL,
-		config.HasuraToken,
-		config.AccessToken,
-		config.BackendURL,
-	)
+	scheduleClient, schedulerDB := newSchedulerClient(config)
 
 	uCreator := user.AuthCreator{
 		Auth: auth.FirebaseClient{
@@ -106,6 +159,40 @@ func run() {
 		Repo: hasuraClient,
 	}
 
+	runner := assignment.Runner{
+		Uploader:          vcsClient,
+		Cleaner:           vcsClient,
+		SubmissionChecker: vcsClient,
+		ReviewerCollector: hasuraClient,
+		EventCreator:      hasuraClient,
+		CommitRecorder:    hasuraClient,
+		EventRecorder:     hasuraClient,
+		Mailer:            mailer,
+		Logger:            logger,
+		SchedulerClient:   scheduleClient,
+		Ledger:            hasuraClient,
+		Time:              time.Now,
+
+		StageRecorder:          hasuraClient,
+		StageSubmissionChecker: vcsClient,
+
+		CollaboratorAdder: vcsClient,
+
+		Fetcher: hasuraClient,
+
+		StartDelay:       time.Minute * 5,
+		WarningBeforeEnd: time.Minute * 10,
+	}
+
+	retrier := assignment.RetryRunner{
+		Runner:      runner,
+		DeadLetters: hasuraClient,
+		Fetcher:     hasuraClient,
+		MaxAttempts: 5,
+		BaseDelay:   time.Minute,
+		MaxDelay:    time.Hour,
+	}
+
 	ah := eventsHttp.AssignmentHandler{
 		Inviter: assignment.Inviter{
 			BusinessRepo:   hasuraClient,
@@ -114,51 +201,115 @@ func run() {
 			UserCreator:    uCreator,
 			CandidatesURL:  config.CandidatesURL,
 		},
-		Logger: logger,
-		Runner: assignment.Runner{
-			Uploader:          githubClient,
-			Cleaner:           githubClient,
-			SubmissionChecker: githubClient,
-			ReviewerCollector: hasuraClient,
-			EventCreator:      hasuraClient,
-			Mailer:            mailer,
-			Logger:            logger,
-			SchedulerClient:   scheduleClient,
-			Time:              time.Now,
-			StartDelay:        time.Minute * 5,
-			WarningBeforeEnd:  time.Minute * 10,
-		},
+		Logger:   logger,
+		Runner:   retrier,
+		Replayer: retrier,
 		Scheduler: assignment.Scheduler{
 			Fetcher:         hasuraClient,
 			SchedulerClient: scheduleClient,
-			VCSCreator:      githubClient,
+			VCSCreator:      vcsClient,
+			TemplateCreator: templateCreator,
 			Updater:         hasuraClient,
+			Ledger:          hasuraClient,
+			Mailer:          mailer,
+			Events:          hasuraClient,
+			Logger:          logger,
+			Time:            time.Now,
+		},
+		Expirer: assignment.Expirer{
+			Fetcher:      hasuraClient,
+			EventCreator: hasuraClient,
+			Mailer:       mailer,
+			Logger:       logger,
+			Time:         time.Now,
+		},
+		Reconciler: assignment.Reconciler{
+			Fetcher: hasuraClient,
+			Runner:  retrier,
+			Time:    time.Now,
+			Grace:   time.Minute * 15,
 		},
 	}
 
+	workerCtx, stopWorker := context.WithCancel(context.Background())
+	defer stopWorker()
+	if schedulerDB != nil {
+		worker := scheduler.PostgresWorker{
+			DB:       schedulerDB,
+			Runner:   retrier,
+			Logger:   logger,
+			Interval: time.Second * time.Duration(config.SchedulerPollInterval),
+			Lease:    time.Minute * 5,
+		}
+
+		go worker.Run(workerCtx)
+	}
+
+	submitter := assignment.Submitter{
+		Finder:  hasuraClient,
+		Fetcher: hasuraClient,
+		Runner:  retrier,
+	}
+
+	gwh := eventsHttp.GithubHandler{
+		Secret:    config.GithubWebhookSecret,
+		Submitter: submitter,
+		Logger:    logger,
+	}
+
 	rh := eventsHttp.ReviewerHandler{
 		Logger: logger,
 		Assigner: assignmentuser.Assigner{
 			ReviewerRepository: hasuraClient,
-			VCSClient:          githubClient,
+			VCSClient:          vcsClient,
 			Mailer:             mailer,
 			APPURL:             config.AppURL,
 		},
 	}
 
+	th := eventsHttp.TestHandler{
+		Checker: assignment.Preflight{
+			Fetcher:   hasuraClient,
+			Validator: vcsClient,
+			Recorder:  hasuraClient,
+			Time:      time.Now,
+			MaxFiles:  5000,
+			MaxSize:   100 << 20,
+		},
+		Logger: logger,
+	}
+
 	collector, err := vcs.NewGithubRepoCollector(config.GithubPrivateKeyLocation, config.GithubAppID)
 	if err != nil {
 		log.Fatalf("could not init github repository collector %s", err)
 	}
 
+	var gitlabCollector core.RepoCollector
+	if vcsClient.Gitlab != nil {
+		gitlabCollector = vcsClient.Gitlab
+	}
+
+	var bitbucketCollector core.WorkspaceCollector
+	if vcsClient.Bitbucket != nil {
+		bitbucketCollector = vcsClient.Bitbucket
+	}
+
+	var localCollector core.RepoCollector
+	if vcsClient.Local != nil {
+		localCollector = vcsClient.Local
+	}
+
 	gh, err := api.NewGraphQLQueryHandler(
 		config.HasuraURL+"/v1/graphql",
 		&auth.FirebaseVerifier{
 			ProjectID: config.FirebaseProjectID,
 		},
 		&api.RepositoryResolver{
-			HasuraURL: config.HasuraURL + "/v1/graphql",
-			Collector: collector,
+			HasuraURL:          config.HasuraURL + "/v1/graphql",
+			Collector:          collector,
+			GitlabCollector:    gitlabCollector,
+			BitbucketCollector: bitbucketCollector,
+			LocalCollector:     localCollector,
 		},
 		&api.UserResolver{
 			Inviter: user.Inviter{
@@ -169,6 +320,38 @@ func run() {
 			},
 			Logger: logger,
 		},
+		&api.AssignmentResolver{
+			HasuraURL: config.HasuraURL + "/v1/graphql",
+			Extender: assignment.Extender{
+				Fetcher:          hasuraClient,
+				SchedulerClient:  scheduleClient,
+				Ledger:           hasuraClient,
+				EventRecorder:    hasuraClient,
+				StageRecorder:    hasuraClient,
+				Mailer:           mailer,
+				WarningBeforeEnd: runner.WarningBeforeEnd,
+			},
+			Starter: assignment.Starter{
+				Fetcher:         hasuraClient,
+				VCSCreator:      vcsClient,
+				TemplateCreator: templateCreator,
+				Updater:         hasuraClient,
+				SchedulerClient: scheduleClient,
+				Ledger:          hasuraClient,
+				Time:            time.Now,
+			},
+			Submitter: submitter,
+			Simulator: assignment.Simulator{
+				Fetcher:          hasuraClient,
+				StartDelay:       runner.StartDelay,
+				WarningBeforeEnd: runner.WarningBeforeEnd,
+				Time:             time.Now,
+			},
+			Fetcher:      hasuraClient,
+			Credentialer: vcsClient,
+			Logger:       logger,
+			Time:         time.Now,
+		},
 	)
 	if err != nil {
 		log.Fatalf("could not init graphql api handler %s", err)
@@ -189,13 +372,28 @@ func run() {
 	a.Use(httputil.RequireAccessTokenMiddleware(config.AccessToken))
 	a.Methods(http.MethodPost).Path("/events").HandlerFunc(ah.EventHandler)
 	a.Methods(http.MethodPost).Path("/process").HandlerFunc(ah.ProcessHandler)
+	a.Methods(http.MethodPost).Path("/expire").HandlerFunc(ah.ExpireHandler)
+	a.Methods(http.MethodPost).Path("/reconcile").HandlerFunc(ah.ReconcileHandler)
+	a.Methods(http.MethodPost).Path("/dead-letters/{id:[0-9]+}/replay").HandlerFunc(ah.ReplayHandler)
 
 	re := r.PathPrefix("/reviewers").Subrouter()
 	a.Use(httputil.RequireAccessTokenMiddleware(config.AccessToken))
 	re.Methods(http.MethodPost).Path("/events").HandlerFunc(rh.EventsHandler)
 
+	te := r.PathPrefix("/tests").Subrouter()
+	te.Use(httputil.RequireAccessTokenMiddleware(config.AccessToken))
+	te.Methods(http.MethodPost).Path("/events").HandlerFunc(th.EventsHandler)
+
 	r.Methods(http.MethodPost).Path("/graphql").Handler(gh)
 
+	if config.GithubWebhookSecret != "" {
+		r.Methods(http.MethodPost).Path("/github/webhook").HandlerFunc(gwh.WebhookHandler)
+	}
+
+	if vcsClient.Local != nil {
+		r.PathPrefix("/git/").Handler(vcsClient.Local.Handler())
+	}
+
 	srv := &http.Server{
 		Addr:         "0.0.0.0:8000",
 		WriteTimeout: time.Second * 15,
@@ -218,6 +416,7 @@ func run() {
 	ctx, cancel := context.WithTimeout(context.Background(), wait)
 	defer cancel()
 
+	stopWorker()
 	srv.Shutdown(ctx)
 	log.Println("shutting down")
 	os.Exit(0)
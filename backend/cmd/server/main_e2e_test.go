@@ -10,29 +10,33 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
 	"syscall"
 	"testing"
 	"time"
 
 	firebase "firebase.google.com/go/v4"
 	firebaseAuth "firebase.google.com/go/v4/auth"
-	"github.com/google/go-github/v39/github"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
-	"golang.org/x/oauth2"
 	"google.golang.org/api/option"
 
+	"github.com/testrelay/testrelay/backend/internal/options"
 	"github.com/testrelay/testrelay/backend/internal/store/graphql"
 	"github.com/testrelay/testrelay/backend/internal/test"
+	"github.com/testrelay/testrelay/backend/internal/vcs"
 )
 
 var (
-	testUserGithubUsername     = "testrelaycandidate"
-	testReviewerGithubUsername = "testrelayrecruiter"
-	githubTestOwner            = "testrelay-interviewer"
-	testRepo                   = "https://github.com/testrelayrecruiter/a-test-repository.git"
+	// testRepo is the local test repo that assignments are uploaded from, see initLocalGit.
+	testRepo string
+	// localRepos is the base url of local repos, which the test reaches at localhost, see localURL.
+	localRepos string
 
-	githubClient    *github.Client
+	localGitRoot    string
+	localClient     *vcs.LocalGitClient
 	rawGraphlClient test.GraphQLClient
 	hasuraClient    *graphql.HasuraClient
 	firebaseClient  *firebaseAuth.Client
@@ -45,7 +49,7 @@ func TestMain(m *testing.M) {
 	}
 
 	initGraphqlClients()
-	initGithubClient()
+	initLocalGit()
 	initFirebaseAuth()
 
 	go run()
@@ -67,6 +71,7 @@ func TestMain(m *testing.M) {
 	code := m.Run()
 
 	// You can't defer this because os.Exit doesn't care for defer
+	os.RemoveAll(localGitRoot)
 	os.Exit(code)
 }
 
@@ -88,13 +93,65 @@ func initFirebaseAuth() {
 	firebaseClient = a
 }
 
-func initGithubClient() {
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: os.Getenv("GITHUB_ACCESS_TOKEN")},
-	)
+// initLocalGit hosts the assignment repos of the e2e tests in a temporary directory with the local provider,
+// committing the test repo that assignments are uploaded from.
+func initLocalGit() {
+	root, err := os.MkdirTemp("", "testrelay-e2e")
+	if err != nil {
+		log.Fatalf("could not create local git root %s", err)
+	}
+
+	localGitRoot = root
+	os.Setenv("LOCAL_GIT_ROOT", root)
+	if os.Getenv("LOCAL_GIT_SECRET") == "" {
+		os.Setenv("LOCAL_GIT_SECRET", "e2e-secret")
+	}
+
+	config, err := options.ConfigFromEnv()
+	if err != nil {
+		log.Fatalf("could not load config %s", err)
+	}
+
+	localRepos = config.BackendURL + "/git"
+	testRepo = localRepos + "/a-test-repository"
+	localClient = vcs.NewLocalGitClient(vcs.LocalConfig{
+		Root:   root,
+		URL:    localRepos,
+		Secret: config.LocalGitSecret,
+	})
+
+	dir := filepath.Join(root, "a-test-repository")
+	r, err := git.PlainInit(dir, false)
+	if err != nil {
+		log.Fatalf("could not init test repo %s", err)
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		log.Fatalf("could not open test repo worktree %s", err)
+	}
 
-	tc := oauth2.NewClient(context.Background(), ts)
-	githubClient = github.NewClient(tc)
+	for name, content := range map[string]string{"test/index.txt": "# Test", "echo.txt": "echo"} {
+		err = os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755)
+		if err == nil {
+			err = os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		}
+		if err == nil {
+			_, err = w.Add(name)
+		}
+		if err != nil {
+			log.Fatalf("could not add %s to test repo %s", name, err)
+		}
+	}
+
+	_, err = w.Commit("test", &git.CommitOptions{Author: &object.Signature{
+		Name:  "testrelay",
+		Email: "interviewer@testrelay.io",
+		When:  time.Now(),
+	}})
+	if err != nil {
+		log.Fatalf("could not commit test repo %s", err)
+	}
 }
 
 func initGraphqlClients() {
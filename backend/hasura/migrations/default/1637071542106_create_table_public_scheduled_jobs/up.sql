@@ -0,0 +1,16 @@
+CREATE TABLE "public"."scheduled_jobs" (
+    "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+    "assignment_id" integer NOT NULL,
+    "step" character varying NOT NULL,
+    "payload" jsonb NOT NULL DEFAULT jsonb_build_object(),
+    "schedule_at" timestamp with time zone NOT NULL,
+    "status" character varying NOT NULL DEFAULT 'pending',
+    "attempts" integer NOT NULL DEFAULT 0,
+    "locked_until" timestamp with time zone,
+    "last_error" text,
+    "created_at" timestamp with time zone NOT NULL DEFAULT now(),
+    "completed_at" timestamp with time zone,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX "scheduled_jobs_pending_schedule_at_idx" ON "public"."scheduled_jobs" ("schedule_at") WHERE "status" = 'pending';
+COMMENT ON TABLE "public"."scheduled_jobs" IS E'assignment steps scheduled by the built in postgres scheduler';
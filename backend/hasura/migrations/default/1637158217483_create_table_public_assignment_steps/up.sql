@@ -0,0 +1,20 @@
+CREATE TABLE "public"."assignment_steps" (
+    "id" serial NOT NULL,
+    "assignment_id" integer NOT NULL,
+    "step" character varying NOT NULL,
+    "event_id" character varying NOT NULL DEFAULT '',
+    "schedule_at" timestamp with time zone,
+    "actions" jsonb NOT NULL DEFAULT jsonb_build_object(),
+    "completed_at" timestamp with time zone,
+    "created_at" timestamp with time zone NOT NULL DEFAULT now(),
+    "updated_at" timestamp with time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY ("id"),
+    FOREIGN KEY ("assignment_id") REFERENCES "public"."assignments"("id") ON UPDATE CASCADE ON DELETE CASCADE,
+    UNIQUE ("assignment_id", "step")
+);
+COMMENT ON TABLE "public"."assignment_steps" IS E'ledger of the progress of each assignment step';
+CREATE TRIGGER "set_public_assignment_steps_updated_at"
+BEFORE UPDATE ON "public"."assignment_steps"
+FOR EACH ROW
+EXECUTE PROCEDURE "public"."set_current_timestamp_updated_at"();
+COMMENT ON TRIGGER "set_public_assignment_steps_updated_at" ON "public"."assignment_steps" IS 'trigger to set value of column "updated_at" to current timestamp on row update';
@@ -0,0 +1 @@
+INSERT INTO public.assignment_status (value) VALUES ('extended') ON CONFLICT DO NOTHING;
@@ -0,0 +1,2 @@
+ALTER TABLE "public"."tests" ADD COLUMN "reminders" jsonb NOT NULL DEFAULT jsonb_build_array();
+COMMENT ON COLUMN "public"."tests"."reminders" IS E'Extra candidate reminders, e.g. [{"before": "start", "minutes": 1440}]';
@@ -0,0 +1 @@
+DELETE FROM public.assignment_status WHERE value = 'expired';
@@ -0,0 +1 @@
+INSERT INTO public.assignment_status (value) VALUES ('expired') ON CONFLICT DO NOTHING;
@@ -0,0 +1 @@
+ALTER TABLE "public"."assignment_steps" DROP COLUMN "attempts";
@@ -0,0 +1,2 @@
+ALTER TABLE "public"."assignment_steps" ADD COLUMN "attempts" integer NOT NULL DEFAULT 0;
+COMMENT ON COLUMN "public"."assignment_steps"."attempts" IS E'number of times the step has failed';
@@ -0,0 +1,13 @@
+CREATE TABLE "public"."dead_letters" (
+    "id" serial NOT NULL,
+    "assignment_id" integer NOT NULL,
+    "step" character varying NOT NULL,
+    "payload" jsonb NOT NULL,
+    "error" text NOT NULL,
+    "attempts" integer NOT NULL,
+    "replayed_at" timestamp with time zone,
+    "created_at" timestamp with time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY ("id"),
+    FOREIGN KEY ("assignment_id") REFERENCES "public"."assignments"("id") ON UPDATE CASCADE ON DELETE CASCADE
+);
+COMMENT ON TABLE "public"."dead_letters" IS E'assignment steps that failed permanently or ran out of retries';
@@ -0,0 +1,2 @@
+ALTER TABLE "public"."assignments" DROP CONSTRAINT "assignments_mode_check";
+ALTER TABLE "public"."assignments" DROP COLUMN "mode";
@@ -0,0 +1,3 @@
+ALTER TABLE "public"."assignments" ADD COLUMN "mode" character varying NOT NULL DEFAULT 'scheduled';
+ALTER TABLE "public"."assignments" ADD CONSTRAINT "assignments_mode_check" CHECK (mode IN ('scheduled', 'on_demand'));
+COMMENT ON COLUMN "public"."assignments"."mode" IS E'scheduled assignments start at the chosen day and time, on_demand assignments start when the candidate asks';
@@ -0,0 +1,20 @@
+CREATE TABLE "public"."test_stages" (
+    "id" serial NOT NULL,
+    "test_id" integer NOT NULL,
+    "position" integer NOT NULL,
+    "name" character varying NOT NULL DEFAULT '',
+    "github_repo" character varying,
+    "time_limit" integer NOT NULL,
+    "created_at" timestamp with time zone NOT NULL DEFAULT now(),
+    "updated_at" timestamp with time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY ("id"),
+    FOREIGN KEY ("test_id") REFERENCES "public"."tests"("id") ON UPDATE CASCADE ON DELETE CASCADE,
+    UNIQUE ("test_id", "position"),
+    CHECK (time_limit > 0)
+);
+COMMENT ON TABLE "public"."test_stages" IS E'ordered parts of a staged test, each with its own source repo and time limit';
+CREATE TRIGGER "set_public_test_stages_updated_at"
+BEFORE UPDATE ON "public"."test_stages"
+FOR EACH ROW
+EXECUTE PROCEDURE "public"."set_current_timestamp_updated_at"();
+COMMENT ON TRIGGER "set_public_test_stages_updated_at" ON "public"."test_stages" IS 'trigger to set value of column "updated_at" to current timestamp on row update';
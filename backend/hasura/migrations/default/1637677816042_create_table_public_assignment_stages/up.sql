@@ -0,0 +1,22 @@
+CREATE TABLE "public"."assignment_stages" (
+    "id" serial NOT NULL,
+    "assignment_id" integer NOT NULL,
+    "position" integer NOT NULL,
+    "status" character varying NOT NULL DEFAULT 'inprogress',
+    "branch" character varying NOT NULL DEFAULT '',
+    "started_at" timestamp with time zone NOT NULL,
+    "deadline" timestamp with time zone NOT NULL,
+    "finished_at" timestamp with time zone,
+    "created_at" timestamp with time zone NOT NULL DEFAULT now(),
+    "updated_at" timestamp with time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY ("id"),
+    FOREIGN KEY ("assignment_id") REFERENCES "public"."assignments"("id") ON UPDATE CASCADE ON DELETE CASCADE,
+    UNIQUE ("assignment_id", "position"),
+    CHECK (status IN ('inprogress', 'submitted', 'missed'))
+);
+COMMENT ON TABLE "public"."assignment_stages" IS E'state of each stage of a staged assignment';
+CREATE TRIGGER "set_public_assignment_stages_updated_at"
+BEFORE UPDATE ON "public"."assignment_stages"
+FOR EACH ROW
+EXECUTE PROCEDURE "public"."set_current_timestamp_updated_at"();
+COMMENT ON TRIGGER "set_public_assignment_stages_updated_at" ON "public"."assignment_stages" IS 'trigger to set value of column "updated_at" to current timestamp on row update';
@@ -0,0 +1,2 @@
+ALTER TABLE "public"."businesses" ADD COLUMN "timezone" character varying NOT NULL DEFAULT 'UTC';
+COMMENT ON COLUMN "public"."businesses"."timezone" IS E'IANA timezone that business availability and blackouts are given in';
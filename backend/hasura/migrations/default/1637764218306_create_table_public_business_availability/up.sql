@@ -0,0 +1,19 @@
+CREATE TABLE "public"."business_availability" (
+    "id" serial NOT NULL,
+    "business_id" integer NOT NULL,
+    "weekday" integer NOT NULL,
+    "start_time" time without time zone NOT NULL,
+    "end_time" time without time zone NOT NULL,
+    "created_at" timestamp with time zone NOT NULL DEFAULT now(),
+    "updated_at" timestamp with time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY ("id"),
+    FOREIGN KEY ("business_id") REFERENCES "public"."businesses"("id") ON UPDATE CASCADE ON DELETE CASCADE,
+    CHECK (weekday >= 0 AND weekday <= 6),
+    CHECK (end_time = '00:00:00' OR end_time > start_time)
+);
+COMMENT ON TABLE "public"."business_availability" IS E'weekly windows a business is available for candidates to start a test, weekday 0 is sunday and an end_time of midnight is the end of the day';
+CREATE TRIGGER "set_public_business_availability_updated_at"
+BEFORE UPDATE ON "public"."business_availability"
+FOR EACH ROW
+EXECUTE PROCEDURE "public"."set_current_timestamp_updated_at"();
+COMMENT ON TRIGGER "set_public_business_availability_updated_at" ON "public"."business_availability" IS 'trigger to set value of column "updated_at" to current timestamp on row update';
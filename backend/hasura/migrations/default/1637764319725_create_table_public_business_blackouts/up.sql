@@ -0,0 +1,17 @@
+CREATE TABLE "public"."business_blackouts" (
+    "id" serial NOT NULL,
+    "business_id" integer NOT NULL,
+    "day" date NOT NULL,
+    "reason" character varying NOT NULL DEFAULT '',
+    "created_at" timestamp with time zone NOT NULL DEFAULT now(),
+    "updated_at" timestamp with time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY ("id"),
+    FOREIGN KEY ("business_id") REFERENCES "public"."businesses"("id") ON UPDATE CASCADE ON DELETE CASCADE,
+    UNIQUE ("business_id", "day")
+);
+COMMENT ON TABLE "public"."business_blackouts" IS E'days a business is unavailable for candidates to start a test, e.g. holidays';
+CREATE TRIGGER "set_public_business_blackouts_updated_at"
+BEFORE UPDATE ON "public"."business_blackouts"
+FOR EACH ROW
+EXECUTE PROCEDURE "public"."set_current_timestamp_updated_at"();
+COMMENT ON TRIGGER "set_public_business_blackouts_updated_at" ON "public"."business_blackouts" IS 'trigger to set value of column "updated_at" to current timestamp on row update';
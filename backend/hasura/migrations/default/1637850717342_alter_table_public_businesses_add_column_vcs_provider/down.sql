@@ -0,0 +1,4 @@
+ALTER TABLE "public"."users" DROP COLUMN "gitlab_username";
+ALTER TABLE "public"."businesses" DROP COLUMN "gitlab_group_id";
+ALTER TABLE "public"."businesses" DROP CONSTRAINT "businesses_vcs_provider_check";
+ALTER TABLE "public"."businesses" DROP COLUMN "vcs_provider";
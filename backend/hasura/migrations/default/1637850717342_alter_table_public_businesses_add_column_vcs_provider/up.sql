@@ -0,0 +1,6 @@
+ALTER TABLE "public"."businesses" ADD COLUMN "vcs_provider" character varying NOT NULL DEFAULT 'github';
+ALTER TABLE "public"."businesses" ADD CONSTRAINT "businesses_vcs_provider_check" CHECK (vcs_provider IN ('github', 'gitlab'));
+COMMENT ON COLUMN "public"."businesses"."vcs_provider" IS E'Provider that assignment repos are created on, one of github|gitlab';
+ALTER TABLE "public"."businesses" ADD COLUMN "gitlab_group_id" character varying;
+COMMENT ON COLUMN "public"."businesses"."gitlab_group_id" IS E'Gitlab group that holds the business test projects';
+ALTER TABLE "public"."users" ADD COLUMN "gitlab_username" character varying;
@@ -0,0 +1,5 @@
+ALTER TABLE "public"."users" DROP COLUMN "bitbucket_account_id";
+ALTER TABLE "public"."businesses" DROP COLUMN "bitbucket_workspace";
+ALTER TABLE "public"."businesses" DROP CONSTRAINT "businesses_vcs_provider_check";
+ALTER TABLE "public"."businesses" ADD CONSTRAINT "businesses_vcs_provider_check" CHECK (vcs_provider IN ('github', 'gitlab'));
+COMMENT ON COLUMN "public"."businesses"."vcs_provider" IS E'Provider that assignment repos are created on, one of github|gitlab';
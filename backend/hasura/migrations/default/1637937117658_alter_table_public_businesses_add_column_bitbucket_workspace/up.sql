@@ -0,0 +1,6 @@
+ALTER TABLE "public"."businesses" DROP CONSTRAINT "businesses_vcs_provider_check";
+ALTER TABLE "public"."businesses" ADD CONSTRAINT "businesses_vcs_provider_check" CHECK (vcs_provider IN ('github', 'gitlab', 'bitbucket'));
+COMMENT ON COLUMN "public"."businesses"."vcs_provider" IS E'Provider that assignment repos are created on, one of github|gitlab|bitbucket';
+ALTER TABLE "public"."businesses" ADD COLUMN "bitbucket_workspace" character varying;
+COMMENT ON COLUMN "public"."businesses"."bitbucket_workspace" IS E'Bitbucket workspace that holds the business test repos';
+ALTER TABLE "public"."users" ADD COLUMN "bitbucket_account_id" character varying;
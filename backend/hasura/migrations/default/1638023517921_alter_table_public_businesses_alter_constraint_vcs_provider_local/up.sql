@@ -0,0 +1,3 @@
+ALTER TABLE "public"."businesses" DROP CONSTRAINT "businesses_vcs_provider_check";
+ALTER TABLE "public"."businesses" ADD CONSTRAINT "businesses_vcs_provider_check" CHECK (vcs_provider IN ('github', 'gitlab', 'bitbucket', 'local'));
+COMMENT ON COLUMN "public"."businesses"."vcs_provider" IS E'Provider that assignment repos are created on, one of github|gitlab|bitbucket|local';
@@ -0,0 +1,4 @@
+ALTER TABLE "public"."assignment_stages" DROP COLUMN "commit_sha";
+ALTER TABLE "public"."assignments" DROP COLUMN "test_commit_sha";
+ALTER TABLE "public"."test_stages" DROP COLUMN "github_ref";
+ALTER TABLE "public"."tests" DROP COLUMN "github_ref";
@@ -0,0 +1,8 @@
+ALTER TABLE "public"."tests" ADD COLUMN "github_ref" character varying;
+COMMENT ON COLUMN "public"."tests"."github_ref" IS E'Branch, tag or commit of github_repo that is uploaded, defaults to the default branch';
+ALTER TABLE "public"."test_stages" ADD COLUMN "github_ref" character varying;
+COMMENT ON COLUMN "public"."test_stages"."github_ref" IS E'Branch, tag or commit of the stage repo that is uploaded';
+ALTER TABLE "public"."assignments" ADD COLUMN "test_commit_sha" character varying;
+COMMENT ON COLUMN "public"."assignments"."test_commit_sha" IS E'Commit of the test repo that was uploaded to the assignment repo';
+ALTER TABLE "public"."assignment_stages" ADD COLUMN "commit_sha" character varying NOT NULL DEFAULT '';
+COMMENT ON COLUMN "public"."assignment_stages"."commit_sha" IS E'Commit of the stage repo that was uploaded to the stage branch';
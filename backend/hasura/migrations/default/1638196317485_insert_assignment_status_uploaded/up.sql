@@ -0,0 +1 @@
+INSERT INTO public.assignment_status (value) VALUES ('uploaded') ON CONFLICT DO NOTHING;
@@ -0,0 +1,2 @@
+ALTER TABLE "public"."tests" ADD COLUMN "template_glob" character varying;
+COMMENT ON COLUMN "public"."tests"."template_glob" IS E'Gitignore style pattern of test files rendered with the assignment details on upload';
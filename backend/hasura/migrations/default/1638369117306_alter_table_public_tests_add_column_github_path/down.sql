@@ -0,0 +1,2 @@
+ALTER TABLE "public"."test_stages" DROP COLUMN "github_path";
+ALTER TABLE "public"."tests" DROP COLUMN "github_path";
@@ -0,0 +1,4 @@
+ALTER TABLE "public"."tests" ADD COLUMN "github_path" character varying;
+COMMENT ON COLUMN "public"."tests"."github_path" IS E'Directory of github_repo uploaded as the root of the assignment repo, defaults to the repo root';
+ALTER TABLE "public"."test_stages" ADD COLUMN "github_path" character varying;
+COMMENT ON COLUMN "public"."test_stages"."github_path" IS E'Directory of the stage repo uploaded to the stage branch';
@@ -0,0 +1 @@
+ALTER TABLE "public"."tests" DROP COLUMN "validation";
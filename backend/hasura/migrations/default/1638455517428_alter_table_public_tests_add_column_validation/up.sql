@@ -0,0 +1,2 @@
+ALTER TABLE "public"."tests" ADD COLUMN "validation" jsonb;
+COMMENT ON COLUMN "public"."tests"."validation" IS E'Preflight report of the test source repos, null until the test has been checked';
@@ -0,0 +1,3 @@
+ALTER TABLE "public"."tests" DROP COLUMN "template_owner";
+ALTER TABLE "public"."tests" DROP CONSTRAINT "tests_upload_mode_check";
+ALTER TABLE "public"."tests" DROP COLUMN "upload_mode";
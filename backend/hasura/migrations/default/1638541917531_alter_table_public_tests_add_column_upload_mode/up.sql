@@ -0,0 +1,5 @@
+ALTER TABLE "public"."tests" ADD COLUMN "upload_mode" text NOT NULL DEFAULT 'push';
+ALTER TABLE "public"."tests" ADD CONSTRAINT "tests_upload_mode_check" CHECK (upload_mode IN ('push', 'template'));
+COMMENT ON COLUMN "public"."tests"."upload_mode" IS E'How assignment repos are filled with the test, push uploads the test repo and template generates the repo from the test repo';
+ALTER TABLE "public"."tests" ADD COLUMN "template_owner" varchar;
+COMMENT ON COLUMN "public"."tests"."template_owner" IS E'Github org that repos generated from the template are created in, the interviewer account if null';
@@ -0,0 +1 @@
+ALTER TABLE "public"."businesses" DROP COLUMN "github_org";
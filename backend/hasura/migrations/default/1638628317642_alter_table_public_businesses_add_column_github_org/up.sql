@@ -0,0 +1,2 @@
+ALTER TABLE "public"."businesses" ADD COLUMN "github_org" varchar;
+COMMENT ON COLUMN "public"."businesses"."github_org" IS E'Github org that assignment repos are created in through the github installation, the interviewer account if null';
@@ -0,0 +1 @@
+ALTER TABLE "public"."scheduled_jobs" ADD COLUMN "attempts" integer NOT NULL DEFAULT 0;
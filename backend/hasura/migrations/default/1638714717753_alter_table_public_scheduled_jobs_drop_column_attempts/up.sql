@@ -0,0 +1 @@
+ALTER TABLE "public"."scheduled_jobs" DROP COLUMN "attempts";
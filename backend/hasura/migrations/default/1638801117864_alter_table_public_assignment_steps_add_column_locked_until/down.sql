@@ -0,0 +1 @@
+ALTER TABLE "public"."assignment_steps" DROP COLUMN "locked_until";
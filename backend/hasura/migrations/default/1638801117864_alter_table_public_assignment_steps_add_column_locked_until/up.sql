@@ -0,0 +1,2 @@
+ALTER TABLE "public"."assignment_steps" ADD COLUMN "locked_until" timestamp with time zone;
+COMMENT ON COLUMN "public"."assignment_steps"."locked_until" IS E'time the claim of the run of the step in progress expires';
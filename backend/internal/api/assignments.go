@@ -0,0 +1,720 @@
+package api
+
+//go:generate mockgen -destination mocks/assignments.go -package mocks . Extender,Starter,Submitter,Simulator,Credentialer,Fetcher
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/graphql-go/graphql"
+	hGraph "github.com/hasura/go-graphql-client"
+	"go.uber.org/zap"
+
+	"github.com/testrelay/testrelay/backend/internal/core/assignment"
+	"github.com/testrelay/testrelay/backend/internal/core/user"
+	"github.com/testrelay/testrelay/backend/internal/httputil"
+	intTime "github.com/testrelay/testrelay/backend/internal/time"
+)
+
+type Extender interface {
+	Extend(assignmentID, minutes, userID int) (assignment.Extension, error)
+}
+
+type Starter interface {
+	Start(assignmentID, userID int, timezone string) (assignment.Start, error)
+}
+
+// Submitter finishes an in progress assignment on behalf of its candidate once they have submitted their work.
+type Submitter interface {
+	SubmitAssignment(assignmentID, userID int) error
+}
+
+type Simulator interface {
+	Simulate(input assignment.SimulationInput) ([]assignment.TimelineEntry, error)
+}
+
+// Fetcher fetches an assignment along with the details of its test and business.
+type Fetcher interface {
+	GetAssignment(id int) (assignment.WithTestDetails, error)
+}
+
+// Credentialer issues the password that users clone an assignment repo with, for vcs providers that host repos
+// themselves rather than through each user's own account.
+type Credentialer interface {
+	Password(repoURL, username string) (string, bool)
+}
+
+// Hasura roles that the resolvers query assignments as. Recruiters use the default user role, while candidates
+// must ask for the candidate role as the user role only sees the assignments of the user's businesses.
+const (
+	roleUser      = "user"
+	roleCandidate = "candidate"
+)
+
+// AssignmentResolver implements a Resolver interface, declaring methods needed to resolve assignment mutations.
+type AssignmentResolver struct {
+	HasuraURL string
+	Extender  Extender
+	Starter   Starter
+	Submitter Submitter
+	Simulator Simulator
+	Fetcher   Fetcher
+	// Credentialer is nil when no provider issues its own credentials.
+	Credentialer Credentialer
+	Logger       *zap.SugaredLogger
+	Time         assignment.Time
+}
+
+// Fields returns the mutations defined for the assignment graphql object.
+func (a AssignmentResolver) Fields() (graphql.Fields, graphql.Fields) {
+	extensionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "AssignmentExtension",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"minutes": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"deadline": &graphql.Field{
+				Type: graphql.String,
+			},
+		},
+	})
+
+	startType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "AssignmentStart",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"started_at": &graphql.Field{
+				Type: graphql.String,
+			},
+			"deadline": &graphql.Field{
+				Type: graphql.String,
+			},
+		},
+	})
+
+	submissionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "AssignmentSubmission",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: graphql.Int,
+			},
+		},
+	})
+
+	scheduleType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ScheduleValidation",
+		Fields: graphql.Fields{
+			"start_at": &graphql.Field{
+				Type: graphql.String,
+			},
+			"send_notification_at": &graphql.Field{
+				Type: graphql.String,
+			},
+		},
+	})
+
+	slotType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "AvailableSlot",
+		Fields: graphql.Fields{
+			"start": &graphql.Field{
+				Type: graphql.String,
+			},
+			"end": &graphql.Field{
+				Type: graphql.String,
+			},
+		},
+	})
+
+	timelineType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "AssignmentTimelineEntry",
+		Fields: graphql.Fields{
+			"step": &graphql.Field{
+				Type: graphql.String,
+			},
+			"emails": &graphql.Field{
+				Type: graphql.NewList(graphql.String),
+			},
+			"at_utc": &graphql.Field{
+				Type: graphql.String,
+			},
+			"at_local": &graphql.Field{
+				Type: graphql.String,
+			},
+			"skipped": &graphql.Field{
+				Type: graphql.Boolean,
+			},
+		},
+	})
+
+	credentialsType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "RepoCredentials",
+		Fields: graphql.Fields{
+			"url": &graphql.Field{
+				Type: graphql.String,
+			},
+			"username": &graphql.Field{
+				Type: graphql.String,
+			},
+			"password": &graphql.Field{
+				Type: graphql.String,
+			},
+		},
+	})
+
+	queries := graphql.Fields{
+		"validateSchedule": &graphql.Field{
+			Type:        scheduleType,
+			Description: "Validate a candidate's chosen day, time and timezone for an assignment",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+				"day": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+				"time": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+				"timezone": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: a.ValidateSchedule,
+		},
+		"availableSlots": &graphql.Field{
+			Type:        graphql.NewList(slotType),
+			Description: "List the periods a candidate can start an assignment, between now and the end of its choose until day",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+				"timezone": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: a.AvailableSlots,
+		},
+		"assignmentTimeline": &graphql.Field{
+			Type:        graphql.NewList(timelineType),
+			Description: "Simulate when each step and email of an assignment runs, without scheduling anything",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.Int,
+				},
+				"day": &graphql.ArgumentConfig{
+					Type: graphql.String,
+				},
+				"time": &graphql.ArgumentConfig{
+					Type: graphql.String,
+				},
+				"timezone": &graphql.ArgumentConfig{
+					Type: graphql.String,
+				},
+				"time_limit": &graphql.ArgumentConfig{
+					Type: graphql.Int,
+				},
+			},
+			Resolve: a.AssignmentTimeline,
+		},
+		"repoCredentials": &graphql.Field{
+			Type:        credentialsType,
+			Description: "Get the credentials the requesting user clones an assignment repo with, if its provider issues them",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+			},
+			Resolve: a.RepoCredentials,
+		},
+	}
+
+	return queries, graphql.Fields{
+		"extendAssignment": &graphql.Field{
+			Type:        extensionType,
+			Description: "Extend the deadline of an in progress assignment",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+				"minutes": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+			},
+			Resolve: a.ExtendAssignment,
+		},
+		"startAssignment": &graphql.Field{
+			Type:        startType,
+			Description: "Start an on demand assignment straight away",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+				"timezone": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: a.StartAssignment,
+		},
+		"submitAssignment": &graphql.Field{
+			Type:        submissionType,
+			Description: "Submit an in progress assignment early once the candidate's work has been submitted on its repo",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+			},
+			Resolve: a.SubmitAssignment,
+		},
+	}
+}
+
+type ExtendAssignmentResponse struct {
+	ID       int    `json:"id"`
+	Minutes  int    `json:"minutes"`
+	Deadline string `json:"deadline"`
+}
+
+// ExtendAssignment extends the deadline of the assignment given in the graphql params.
+// The requesting user must have access to the assignment, this is checked by fetching the
+// assignment from hasura using the user's token.
+func (a AssignmentResolver) ExtendAssignment(p graphql.ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(int)
+	minutes, _ := p.Args["minutes"].(int)
+	token := fmt.Sprintf("%s", p.Context.Value("token"))
+
+	userID, err := userPK(token)
+	if err != nil {
+		a.Logger.Errorf("could not get user pk from token %s", err)
+		return nil, fmt.Errorf("could not extend assignment %d", id)
+	}
+
+	err = a.authorize(token, roleUser, id)
+	if err != nil {
+		a.Logger.Errorf("user %d could not access assignment %d %s", userID, id, err)
+		return nil, fmt.Errorf("could not extend assignment %d", id)
+	}
+
+	e, err := a.Extender.Extend(id, minutes, userID)
+	if err != nil {
+		if errors.Is(err, assignment.ErrInvalidExtension) ||
+			errors.Is(err, assignment.ErrNotInProgress) ||
+			errors.Is(err, assignment.ErrNoPendingEnd) {
+			return nil, err
+		}
+
+		a.Logger.Errorf("could not extend assignment %d %s", id, err)
+		return nil, fmt.Errorf("could not extend assignment %d", id)
+	}
+
+	return ExtendAssignmentResponse{
+		ID:       e.AssignmentID,
+		Minutes:  e.Minutes,
+		Deadline: e.Deadline.Format(time.RFC3339),
+	}, nil
+}
+
+type StartAssignmentResponse struct {
+	ID        int    `json:"id"`
+	StartedAt string `json:"started_at"`
+	Deadline  string `json:"deadline"`
+}
+
+// StartAssignment starts the on demand assignment given in the graphql params in the candidate's timezone.
+// Only the candidate of the assignment can start it.
+func (a AssignmentResolver) StartAssignment(p graphql.ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(int)
+	tz, _ := p.Args["timezone"].(string)
+	token := fmt.Sprintf("%s", p.Context.Value("token"))
+
+	_, err := time.LoadLocation(tz)
+	if tz == "" || err != nil {
+		return nil, intTime.ValidationError{
+			Field:   "timezone",
+			Code:    intTime.CodeInvalidTimezone,
+			Message: fmt.Sprintf("%q is not a valid IANA timezone", tz),
+		}
+	}
+
+	userID, err := userPK(token)
+	if err != nil {
+		a.Logger.Errorf("could not get user pk from token %s", err)
+		return nil, fmt.Errorf("could not start assignment %d", id)
+	}
+
+	err = a.authorize(token, roleCandidate, id)
+	if err != nil {
+		a.Logger.Errorf("user %d could not access assignment %d %s", userID, id, err)
+		return nil, fmt.Errorf("could not start assignment %d", id)
+	}
+
+	s, err := a.Starter.Start(id, userID, tz)
+	if err != nil {
+		if errors.Is(err, assignment.ErrNotOnDemand) ||
+			errors.Is(err, assignment.ErrNotCandidate) ||
+			errors.Is(err, assignment.ErrAlreadyStarted) ||
+			errors.Is(err, assignment.ErrChooseUntilExpired) {
+			return nil, err
+		}
+
+		a.Logger.Errorf("could not start assignment %d %s", id, err)
+		return nil, fmt.Errorf("could not start assignment %d", id)
+	}
+
+	return StartAssignmentResponse{
+		ID:        s.AssignmentID,
+		StartedAt: s.StartedAt.Format(time.RFC3339),
+		Deadline:  s.Deadline.Format(time.RFC3339),
+	}, nil
+}
+
+type SubmitAssignmentResponse struct {
+	ID int `json:"id"`
+}
+
+// SubmitAssignment submits the assignment given in the graphql params early, checking that the candidate has
+// submitted their work on the assignment repo through its vcs provider. Only the candidate of the assignment can
+// submit it.
+func (a AssignmentResolver) SubmitAssignment(p graphql.ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(int)
+	token := fmt.Sprintf("%s", p.Context.Value("token"))
+
+	userID, err := userPK(token)
+	if err != nil {
+		a.Logger.Errorf("could not get user pk from token %s", err)
+		return nil, fmt.Errorf("could not submit assignment %d", id)
+	}
+
+	err = a.authorize(token, roleCandidate, id)
+	if err != nil {
+		a.Logger.Errorf("user %d could not access assignment %d %s", userID, id, err)
+		return nil, fmt.Errorf("could not submit assignment %d", id)
+	}
+
+	err = a.Submitter.SubmitAssignment(id, userID)
+	if err != nil {
+		if errors.Is(err, assignment.ErrNotCandidate) ||
+			errors.Is(err, assignment.ErrNotInProgress) ||
+			errors.Is(err, assignment.ErrEarlyStage) ||
+			errors.Is(err, assignment.ErrNotSubmitted) {
+			return nil, err
+		}
+
+		a.Logger.Errorf("could not submit assignment %d %s", id, err)
+		return nil, fmt.Errorf("could not submit assignment %d", id)
+	}
+
+	return SubmitAssignmentResponse{ID: id}, nil
+}
+
+type ValidateScheduleResponse struct {
+	StartAt            string `json:"start_at"`
+	SendNotificationAt string `json:"send_notification_at"`
+}
+
+// ValidateSchedule checks that the day, time and timezone in the graphql params can be used to schedule
+// the assignment, returning when the assignment would start. Invalid choices are returned as an
+// intTime.ValidationError, which includes the invalid field and a code in the graphql error extensions.
+func (a AssignmentResolver) ValidateSchedule(p graphql.ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(int)
+	token := fmt.Sprintf("%s", p.Context.Value("token"))
+
+	w, err := a.window(token, id)
+	if err != nil {
+		a.Logger.Errorf("could not fetch assignment %d to validate schedule %s", id, err)
+		return nil, fmt.Errorf("could not validate schedule for assignment %d", id)
+	}
+
+	day, _ := p.Args["day"].(string)
+	clock, _ := p.Args["time"].(string)
+	tz, _ := p.Args["timezone"].(string)
+	t, err := intTime.Validate(intTime.AssignmentChoices{
+		DayChosen:  day,
+		TimeChosen: clock,
+		Timezone:   tz,
+	}, w)
+	if err != nil {
+		var v intTime.ValidationError
+		if errors.As(err, &v) {
+			return nil, v
+		}
+
+		a.Logger.Errorf("could not validate schedule for assignment %d %s", id, err)
+		return nil, fmt.Errorf("could not validate schedule for assignment %d", id)
+	}
+
+	return ValidateScheduleResponse{
+		StartAt:            t.StartAssignmentAt,
+		SendNotificationAt: t.SendNotificationAt,
+	}, nil
+}
+
+type SlotResponse struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// AvailableSlots lists the periods between now and the end of the assignment's choose_until day that
+// the business is available for the assignment to start, in the timezone given in the graphql params.
+func (a AssignmentResolver) AvailableSlots(p graphql.ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(int)
+	tz, _ := p.Args["timezone"].(string)
+	token := fmt.Sprintf("%s", p.Context.Value("token"))
+
+	loc, err := time.LoadLocation(tz)
+	if tz == "" || err != nil {
+		return nil, intTime.ValidationError{
+			Field:   "timezone",
+			Code:    intTime.CodeInvalidTimezone,
+			Message: fmt.Sprintf("%q is not a valid IANA timezone", tz),
+		}
+	}
+
+	w, err := a.window(token, id)
+	if err != nil {
+		a.Logger.Errorf("could not fetch assignment %d to list slots %s", id, err)
+		return nil, fmt.Errorf("could not list available slots for assignment %d", id)
+	}
+
+	chooseUntil, err := time.ParseInLocation("2006-01-02", w.ChooseUntil, loc)
+	if err != nil {
+		a.Logger.Errorf("could not parse choose until %s of assignment %d %s", w.ChooseUntil, id, err)
+		return nil, fmt.Errorf("could not list available slots for assignment %d", id)
+	}
+
+	slots, err := w.Availability.Slots(w.Now.In(loc), chooseUntil.AddDate(0, 0, 1))
+	if err != nil {
+		a.Logger.Errorf("could not list available slots for assignment %d %s", id, err)
+		return nil, fmt.Errorf("could not list available slots for assignment %d", id)
+	}
+
+	res := make([]SlotResponse, len(slots))
+	for i, s := range slots {
+		res[i] = SlotResponse{
+			Start: s.Start.Format(time.RFC3339),
+			End:   s.End.Format(time.RFC3339),
+		}
+	}
+
+	return res, nil
+}
+
+type TimelineEntryResponse struct {
+	Step    string   `json:"step"`
+	Emails  []string `json:"emails"`
+	AtUTC   string   `json:"at_utc"`
+	AtLocal string   `json:"at_local"`
+	Skipped bool     `json:"skipped"`
+}
+
+// AssignmentTimeline simulates the assignment given in the graphql params, returning when each step runs
+// in both UTC and the candidate's timezone. The day, time, timezone and time limit params override those
+// of the assignment, or describe a hypothetical assignment if no id is given. The requesting user must have
+// access to the assignment if an id is given.
+func (a AssignmentResolver) AssignmentTimeline(p graphql.ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(int)
+	token := fmt.Sprintf("%s", p.Context.Value("token"))
+
+	if id != 0 {
+		err := a.authorize(token, roleUser, id)
+		if err != nil {
+			a.Logger.Errorf("could not access assignment %d to simulate %s", id, err)
+			return nil, fmt.Errorf("could not simulate assignment %d", id)
+		}
+	}
+
+	input := assignment.SimulationInput{AssignmentID: id}
+	input.DayChosen, _ = p.Args["day"].(string)
+	input.TimeChosen, _ = p.Args["time"].(string)
+	input.Timezone, _ = p.Args["timezone"].(string)
+	input.TimeLimit, _ = p.Args["time_limit"].(int)
+
+	timeline, err := a.Simulator.Simulate(input)
+	if err != nil {
+		var v intTime.ValidationError
+		if errors.As(err, &v) {
+			return nil, v
+		}
+
+		if errors.Is(err, assignment.ErrNoTimeLimit) {
+			return nil, err
+		}
+
+		a.Logger.Errorf("could not simulate assignment %d %s", id, err)
+		return nil, fmt.Errorf("could not simulate assignment %d", id)
+	}
+
+	res := make([]TimelineEntryResponse, len(timeline))
+	for i, e := range timeline {
+		res[i] = TimelineEntryResponse{
+			Step:    e.Step,
+			Emails:  e.Emails,
+			AtUTC:   e.At.UTC().Format(time.RFC3339),
+			AtLocal: e.At.Format(time.RFC3339),
+			Skipped: e.Skipped,
+		}
+	}
+
+	return res, nil
+}
+
+type RepoCredentialsResponse struct {
+	URL      string `json:"url"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// RepoCredentials returns the username and password that the requesting user clones the repo of the assignment
+// in the graphql params with. Both the candidate and the business's reviewers can access the assignment, so it
+// is fetched as a business user and then as the candidate. It returns nil if the assignment has no repo yet or
+// its provider does not issue credentials, in which case users sign in with their own account.
+func (a AssignmentResolver) RepoCredentials(p graphql.ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(int)
+	token := fmt.Sprintf("%s", p.Context.Value("token"))
+
+	email, err := userEmail(token)
+	if err != nil {
+		a.Logger.Errorf("could not get user email from token %s", err)
+		return nil, fmt.Errorf("could not get repo credentials for assignment %d", id)
+	}
+
+	repoURL, err := a.repoURL(token, roleUser, id)
+	if err != nil {
+		repoURL, err = a.repoURL(token, roleCandidate, id)
+	}
+	if err != nil {
+		a.Logger.Errorf("user %s could not access assignment %d %s", email, id, err)
+		return nil, fmt.Errorf("could not get repo credentials for assignment %d", id)
+	}
+
+	if repoURL == "" || a.Credentialer == nil {
+		return nil, nil
+	}
+
+	password, ok := a.Credentialer.Password(repoURL, email)
+	if !ok {
+		return nil, nil
+	}
+
+	return RepoCredentialsResponse{
+		URL:      repoURL,
+		Username: email,
+		Password: password,
+	}, nil
+}
+
+// repoURL fetches the repo url of the assignment from hasura using the given token and role, erroring if the
+// assignment cannot be accessed.
+func (a AssignmentResolver) repoURL(token, role string, id int) (string, error) {
+	var q struct {
+		AssignmentsByPK struct {
+			ID            hGraph.Int    `graphql:"id"`
+			GithubRepoURL hGraph.String `graphql:"github_repo_url"`
+		} `graphql:"assignments_by_pk(id: $id)"`
+	}
+
+	err := a.client(token, role).Query(context.Background(), &q, map[string]interface{}{
+		"id": hGraph.Int(id),
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not query assignment %w", err)
+	}
+
+	if int(q.AssignmentsByPK.ID) != id {
+		return "", errors.New("assignment not found")
+	}
+
+	return string(q.AssignmentsByPK.GithubRepoURL), nil
+}
+
+// window authorizes the candidate's token to access the assignment, then fetches it through the Fetcher,
+// returning the window that the assignment can be scheduled in.
+func (a AssignmentResolver) window(token string, id int) (intTime.Window, error) {
+	err := a.authorize(token, roleCandidate, id)
+	if err != nil {
+		return intTime.Window{}, err
+	}
+
+	details, err := a.Fetcher.GetAssignment(id)
+	if err != nil {
+		return intTime.Window{}, fmt.Errorf("could not fetch assignment %w", err)
+	}
+
+	return details.Window(a.Time()), nil
+}
+
+// client returns a hasura client that makes requests as the user the token belongs to, in the given role.
+func (a AssignmentResolver) client(token, role string) *hGraph.Client {
+	return hGraph.NewClient(a.HasuraURL,
+		&http.Client{
+			Transport: &httputil.BearerTransport{Token: token, Role: role},
+		},
+	)
+}
+
+// authorize errors if the assignment cannot be fetched from hasura using the given token and role.
+func (a AssignmentResolver) authorize(token, role string, id int) error {
+	var q struct {
+		AssignmentsByPK struct {
+			ID hGraph.Int `graphql:"id"`
+		} `graphql:"assignments_by_pk(id: $id)"`
+	}
+
+	err := a.client(token, role).Query(context.Background(), &q, map[string]interface{}{
+		"id": hGraph.Int(id),
+	})
+	if err != nil {
+		return fmt.Errorf("could not query assignment %w", err)
+	}
+
+	if int(q.AssignmentsByPK.ID) != id {
+		return errors.New("assignment not found")
+	}
+
+	return nil
+}
+
+// userPK returns the testrelay user pk from the custom claims of the token.
+// The token must already have been verified, see GraphQLQueryHandler.
+func userPK(token string) (int, error) {
+	var claims jwt.MapClaims
+	_, _, err := new(jwt.Parser).ParseUnverified(token, &claims)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse token %w", err)
+	}
+
+	custom, _ := claims[user.CustomClaimKey].(map[string]interface{})
+	pk, _ := custom["x-hasura-user-pk"].(string)
+
+	id, err := strconv.Atoi(pk)
+	if err != nil {
+		return 0, fmt.Errorf("invalid user pk %q %w", pk, err)
+	}
+
+	return id, nil
+}
+
+// userEmail returns the email claim of the token, which local repos identify users by.
+// The token must already have been verified, see GraphQLQueryHandler.
+func userEmail(token string) (string, error) {
+	var claims jwt.MapClaims
+	_, _, err := new(jwt.Parser).ParseUnverified(token, &claims)
+	if err != nil {
+		return "", fmt.Errorf("could not parse token %w", err)
+	}
+
+	email, _ := claims["email"].(string)
+	if email == "" {
+		return "", errors.New("token has no email")
+	}
+
+	return email, nil
+}
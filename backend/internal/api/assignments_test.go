@@ -0,0 +1,480 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/golang/mock/gomock"
+	"github.com/graphql-go/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/testrelay/testrelay/backend/internal/api"
+	"github.com/testrelay/testrelay/backend/internal/api/mocks"
+	"github.com/testrelay/testrelay/backend/internal/core/assignment"
+	"github.com/testrelay/testrelay/backend/internal/core/user"
+	intTime "github.com/testrelay/testrelay/backend/internal/time"
+)
+
+func TestAssignmentResolver(t *testing.T) {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"email": "jane@testrelay.io",
+		user.CustomClaimKey: map[string]interface{}{
+			"x-hasura-user-pk": "7",
+		},
+	}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	// hasura fakes a hasura that returns body, checking that it is queried with the token in the given role.
+	hasura := func(t *testing.T, role, body string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "Bearer "+token, r.Header.Get("Authorization"))
+			assert.Equal(t, role, r.Header.Get("X-Hasura-Role"))
+			w.Write([]byte(body))
+		}))
+	}
+
+	// found is the hasura response for an assignment the token can access.
+	found := `{"data":{"assignments_by_pk":{"id":12}}}`
+
+	// newAssignment returns an assignment that can be chosen until chooseUntil, of a business that is only
+	// available on mondays, 09:00 to 17:00 in London.
+	newAssignment := func(chooseUntil string) assignment.WithTestDetails {
+		a := assignment.WithTestDetails{ID: 12, ChooseUntil: chooseUntil}
+		a.Test.Business.Availability = intTime.Availability{
+			Timezone:  "Europe/London",
+			Openings:  []intTime.Opening{{Weekday: time.Monday, Start: "09:00:00", End: "17:00:00"}},
+			Blackouts: []string{"2021-11-22"},
+		}
+
+		return a
+	}
+
+	t.Run("ExtendAssignment", func(t *testing.T) {
+		p := graphql.ResolveParams{
+			Context: context.WithValue(context.Background(), "token", token),
+			Args: map[string]interface{}{
+				"id":      12,
+				"minutes": 30,
+			},
+		}
+
+		t.Run("should extend the assignment as the requesting user", func(t *testing.T) {
+			srv := hasura(t, "user", `{"data":{"assignments_by_pk":{"id":12}}}`)
+			defer srv.Close()
+
+			ctrl := gomock.NewController(t)
+			extender := mocks.NewMockExtender(ctrl)
+
+			r := api.AssignmentResolver{
+				HasuraURL: srv.URL,
+				Extender:  extender,
+				Logger:    zap.NewNop().Sugar(),
+			}
+
+			deadline := time.Date(2021, 11, 12, 12, 40, 0, 0, time.UTC)
+			extender.EXPECT().Extend(12, 30, 7).Return(assignment.Extension{
+				AssignmentID: 12,
+				Minutes:      30,
+				Deadline:     deadline,
+			}, nil)
+
+			actual, err := r.ExtendAssignment(p)
+			require.NoError(t, err)
+
+			assert.Equal(t, api.ExtendAssignmentResponse{
+				ID:       12,
+				Minutes:  30,
+				Deadline: "2021-11-12T12:40:00Z",
+			}, actual)
+		})
+
+		t.Run("should error if the user cannot access the assignment", func(t *testing.T) {
+			srv := hasura(t, "user", `{"data":{"assignments_by_pk":null}}`)
+			defer srv.Close()
+
+			ctrl := gomock.NewController(t)
+			extender := mocks.NewMockExtender(ctrl)
+
+			r := api.AssignmentResolver{
+				HasuraURL: srv.URL,
+				Extender:  extender,
+				Logger:    zap.NewNop().Sugar(),
+			}
+
+			_, err := r.ExtendAssignment(p)
+			assert.EqualError(t, err, "could not extend assignment 12")
+		})
+	})
+
+	t.Run("StartAssignment", func(t *testing.T) {
+		p := graphql.ResolveParams{
+			Context: context.WithValue(context.Background(), "token", token),
+			Args: map[string]interface{}{
+				"id":       12,
+				"timezone": "Europe/London",
+			},
+		}
+
+		t.Run("should start the assignment as the requesting candidate", func(t *testing.T) {
+			srv := hasura(t, "candidate", `{"data":{"assignments_by_pk":{"id":12}}}`)
+			defer srv.Close()
+
+			ctrl := gomock.NewController(t)
+			starter := mocks.NewMockStarter(ctrl)
+
+			r := api.AssignmentResolver{
+				HasuraURL: srv.URL,
+				Starter:   starter,
+				Logger:    zap.NewNop().Sugar(),
+			}
+
+			startedAt := time.Date(2021, 11, 12, 10, 30, 0, 0, time.UTC)
+			starter.EXPECT().Start(12, 7, "Europe/London").Return(assignment.Start{
+				AssignmentID: 12,
+				StartedAt:    startedAt,
+				Deadline:     startedAt.Add(time.Hour * 2),
+			}, nil)
+
+			actual, err := r.StartAssignment(p)
+			require.NoError(t, err)
+
+			assert.Equal(t, api.StartAssignmentResponse{
+				ID:        12,
+				StartedAt: "2021-11-12T10:30:00Z",
+				Deadline:  "2021-11-12T12:30:00Z",
+			}, actual)
+		})
+
+		t.Run("should return errors the candidate can act on", func(t *testing.T) {
+			srv := hasura(t, "candidate", `{"data":{"assignments_by_pk":{"id":12}}}`)
+			defer srv.Close()
+
+			ctrl := gomock.NewController(t)
+			starter := mocks.NewMockStarter(ctrl)
+
+			r := api.AssignmentResolver{
+				HasuraURL: srv.URL,
+				Starter:   starter,
+				Logger:    zap.NewNop().Sugar(),
+			}
+
+			starter.EXPECT().Start(12, 7, "Europe/London").Return(assignment.Start{}, assignment.ErrNotOnDemand)
+
+			_, err := r.StartAssignment(p)
+			assert.ErrorIs(t, err, assignment.ErrNotOnDemand)
+		})
+
+		t.Run("should reject invalid timezones", func(t *testing.T) {
+			r := api.AssignmentResolver{Logger: zap.NewNop().Sugar()}
+
+			_, err := r.StartAssignment(graphql.ResolveParams{
+				Context: p.Context,
+				Args:    map[string]interface{}{"id": 12, "timezone": "Mars/Olympus"},
+			})
+
+			var v intTime.ValidationError
+			require.ErrorAs(t, err, &v)
+			assert.Equal(t, intTime.CodeInvalidTimezone, v.Code)
+		})
+	})
+
+	t.Run("SubmitAssignment", func(t *testing.T) {
+		p := graphql.ResolveParams{
+			Context: context.WithValue(context.Background(), "token", token),
+			Args:    map[string]interface{}{"id": 12},
+		}
+
+		t.Run("should submit the assignment as the requesting candidate", func(t *testing.T) {
+			srv := hasura(t, "candidate", found)
+			defer srv.Close()
+
+			ctrl := gomock.NewController(t)
+			submitter := mocks.NewMockSubmitter(ctrl)
+
+			r := api.AssignmentResolver{
+				HasuraURL: srv.URL,
+				Submitter: submitter,
+				Logger:    zap.NewNop().Sugar(),
+			}
+
+			submitter.EXPECT().SubmitAssignment(12, 7).Return(nil)
+
+			actual, err := r.SubmitAssignment(p)
+			require.NoError(t, err)
+			assert.Equal(t, api.SubmitAssignmentResponse{ID: 12}, actual)
+		})
+
+		t.Run("should return errors the candidate can act on", func(t *testing.T) {
+			srv := hasura(t, "candidate", found)
+			defer srv.Close()
+
+			ctrl := gomock.NewController(t)
+			submitter := mocks.NewMockSubmitter(ctrl)
+
+			r := api.AssignmentResolver{
+				HasuraURL: srv.URL,
+				Submitter: submitter,
+				Logger:    zap.NewNop().Sugar(),
+			}
+
+			submitter.EXPECT().SubmitAssignment(12, 7).Return(assignment.ErrNotSubmitted)
+
+			_, err := r.SubmitAssignment(p)
+			assert.ErrorIs(t, err, assignment.ErrNotSubmitted)
+		})
+	})
+
+	t.Run("ValidateSchedule", func(t *testing.T) {
+		now := func() time.Time { return time.Date(2021, 11, 12, 10, 0, 0, 0, time.UTC) }
+		params := func(day, clock, tz string) graphql.ResolveParams {
+			return graphql.ResolveParams{
+				Context: context.WithValue(context.Background(), "token", token),
+				Args: map[string]interface{}{
+					"id":       12,
+					"day":      day,
+					"time":     clock,
+					"timezone": tz,
+				},
+			}
+		}
+
+		t.Run("should return the start time for a valid choice", func(t *testing.T) {
+			srv := hasura(t, "candidate", found)
+			defer srv.Close()
+
+			fetcher := mocks.NewMockFetcher(gomock.NewController(t))
+			fetcher.EXPECT().GetAssignment(12).Return(newAssignment("2021-11-20"), nil)
+
+			r := api.AssignmentResolver{HasuraURL: srv.URL, Fetcher: fetcher, Logger: zap.NewNop().Sugar(), Time: now}
+
+			actual, err := r.ValidateSchedule(params("2021-11-15", "09:00:00", "Europe/London"))
+			require.NoError(t, err)
+
+			assert.Equal(t, api.ValidateScheduleResponse{
+				StartAt:            "2021-11-15T09:00:00Z",
+				SendNotificationAt: "2021-11-15T08:55:00Z",
+			}, actual)
+		})
+
+		t.Run("should return validation errors with their field and code", func(t *testing.T) {
+			srv := hasura(t, "candidate", found)
+			defer srv.Close()
+
+			fetcher := mocks.NewMockFetcher(gomock.NewController(t))
+			fetcher.EXPECT().GetAssignment(12).Return(newAssignment("2021-11-20"), nil)
+
+			r := api.AssignmentResolver{HasuraURL: srv.URL, Fetcher: fetcher, Logger: zap.NewNop().Sugar(), Time: now}
+
+			_, err := r.ValidateSchedule(params("2021-11-21", "09:00:00", "Europe/London"))
+
+			v, ok := err.(intTime.ValidationError)
+			require.True(t, ok)
+			assert.Equal(t, map[string]interface{}{
+				"field": "day",
+				"code":  intTime.CodeAfterChooseUntil,
+			}, v.Extensions())
+		})
+
+		t.Run("should reject choices when the business is unavailable", func(t *testing.T) {
+			srv := hasura(t, "candidate", found)
+			defer srv.Close()
+
+			fetcher := mocks.NewMockFetcher(gomock.NewController(t))
+			fetcher.EXPECT().GetAssignment(12).Return(newAssignment("2021-11-16"), nil)
+
+			r := api.AssignmentResolver{HasuraURL: srv.URL, Fetcher: fetcher, Logger: zap.NewNop().Sugar(), Time: now}
+
+			_, err := r.ValidateSchedule(params("2021-11-15", "18:00:00", "Europe/London"))
+
+			v, ok := err.(intTime.ValidationError)
+			require.True(t, ok)
+			assert.Equal(t, intTime.CodeUnavailable, v.Code)
+		})
+	})
+
+	t.Run("AvailableSlots", func(t *testing.T) {
+		now := func() time.Time { return time.Date(2021, 11, 12, 10, 0, 0, 0, time.UTC) }
+		params := func(tz string) graphql.ResolveParams {
+			return graphql.ResolveParams{
+				Context: context.WithValue(context.Background(), "token", token),
+				Args: map[string]interface{}{
+					"id":       12,
+					"timezone": tz,
+				},
+			}
+		}
+
+		t.Run("should list the slots until choose until in the candidate's timezone", func(t *testing.T) {
+			srv := hasura(t, "candidate", found)
+			defer srv.Close()
+
+			fetcher := mocks.NewMockFetcher(gomock.NewController(t))
+			fetcher.EXPECT().GetAssignment(12).Return(newAssignment("2021-11-16"), nil)
+
+			r := api.AssignmentResolver{HasuraURL: srv.URL, Fetcher: fetcher, Logger: zap.NewNop().Sugar(), Time: now}
+
+			actual, err := r.AvailableSlots(params("Asia/Tokyo"))
+			require.NoError(t, err)
+
+			assert.Equal(t, []api.SlotResponse{
+				{Start: "2021-11-15T18:00:00+09:00", End: "2021-11-16T02:00:00+09:00"},
+			}, actual)
+		})
+
+		t.Run("should error if the candidate cannot access the assignment", func(t *testing.T) {
+			srv := hasura(t, "candidate", `{"data":{"assignments_by_pk":null}}`)
+			defer srv.Close()
+
+			r := api.AssignmentResolver{HasuraURL: srv.URL, Fetcher: mocks.NewMockFetcher(gomock.NewController(t)), Logger: zap.NewNop().Sugar(), Time: now}
+
+			_, err := r.AvailableSlots(params("Asia/Tokyo"))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "could not list available slots for assignment 12")
+		})
+
+		t.Run("should error for an unknown timezone", func(t *testing.T) {
+			r := api.AssignmentResolver{Logger: zap.NewNop().Sugar(), Time: now}
+
+			_, err := r.AvailableSlots(params("Asia/Nowhere"))
+
+			v, ok := err.(intTime.ValidationError)
+			require.True(t, ok)
+			assert.Equal(t, intTime.CodeInvalidTimezone, v.Code)
+		})
+	})
+	t.Run("AssignmentTimeline", func(t *testing.T) {
+		p := graphql.ResolveParams{
+			Context: context.WithValue(context.Background(), "token", token),
+			Args: map[string]interface{}{
+				"id":       12,
+				"timezone": "Asia/Tokyo",
+			},
+		}
+
+		t.Run("should return each step in utc and the candidate's timezone", func(t *testing.T) {
+			srv := hasura(t, "user", `{"data":{"assignments_by_pk":{"id":12}}}`)
+			defer srv.Close()
+
+			ctrl := gomock.NewController(t)
+			s := mocks.NewMockSimulator(ctrl)
+			r := api.AssignmentResolver{HasuraURL: srv.URL, Simulator: s, Logger: zap.NewNop().Sugar()}
+
+			tokyo, err := time.LoadLocation("Asia/Tokyo")
+			require.NoError(t, err)
+
+			s.EXPECT().Simulate(assignment.SimulationInput{AssignmentID: 12, Timezone: "Asia/Tokyo"}).Return([]assignment.TimelineEntry{
+				{Step: "start", Emails: []string{"warning"}, At: time.Date(2021, 11, 12, 9, 55, 0, 0, tokyo)},
+			}, nil)
+
+			actual, err := r.AssignmentTimeline(p)
+			require.NoError(t, err)
+
+			assert.Equal(t, []api.TimelineEntryResponse{{
+				Step:    "start",
+				Emails:  []string{"warning"},
+				AtUTC:   "2021-11-12T00:55:00Z",
+				AtLocal: "2021-11-12T09:55:00+09:00",
+			}}, actual)
+		})
+
+		t.Run("should error if the user cannot access the assignment", func(t *testing.T) {
+			srv := hasura(t, "user", `{"data":{"assignments_by_pk":null}}`)
+			defer srv.Close()
+
+			r := api.AssignmentResolver{HasuraURL: srv.URL, Logger: zap.NewNop().Sugar()}
+
+			_, err := r.AssignmentTimeline(p)
+			assert.EqualError(t, err, "could not simulate assignment 12")
+		})
+	})
+
+	t.Run("RepoCredentials", func(t *testing.T) {
+		p := graphql.ResolveParams{
+			Context: context.WithValue(context.Background(), "token", token),
+			Args: map[string]interface{}{
+				"id": 12,
+			},
+		}
+
+		repo := `{"data":{"assignments_by_pk":{"id":12,"github_repo_url":"https://backend.testrelay.io/git/jane-test-12.git"}}}`
+
+		t.Run("should issue credentials to a reviewer of the business", func(t *testing.T) {
+			srv := hasura(t, "user", repo)
+			defer srv.Close()
+
+			ctrl := gomock.NewController(t)
+			c := mocks.NewMockCredentialer(ctrl)
+			r := api.AssignmentResolver{HasuraURL: srv.URL, Credentialer: c, Logger: zap.NewNop().Sugar()}
+
+			c.EXPECT().Password("https://backend.testrelay.io/git/jane-test-12.git", "jane@testrelay.io").Return("s3cret", true)
+
+			actual, err := r.RepoCredentials(p)
+			require.NoError(t, err)
+
+			assert.Equal(t, api.RepoCredentialsResponse{
+				URL:      "https://backend.testrelay.io/git/jane-test-12.git",
+				Username: "jane@testrelay.io",
+				Password: "s3cret",
+			}, actual)
+		})
+
+		t.Run("should fetch the assignment as the candidate if the user is not a reviewer", func(t *testing.T) {
+			var roles []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				role := r.Header.Get("X-Hasura-Role")
+				roles = append(roles, role)
+				if role == "candidate" {
+					w.Write([]byte(repo))
+					return
+				}
+
+				w.Write([]byte(`{"data":{"assignments_by_pk":null}}`))
+			}))
+			defer srv.Close()
+
+			ctrl := gomock.NewController(t)
+			c := mocks.NewMockCredentialer(ctrl)
+			r := api.AssignmentResolver{HasuraURL: srv.URL, Credentialer: c, Logger: zap.NewNop().Sugar()}
+
+			c.EXPECT().Password("https://backend.testrelay.io/git/jane-test-12.git", "jane@testrelay.io").Return("s3cret", true)
+
+			actual, err := r.RepoCredentials(p)
+			require.NoError(t, err)
+			assert.Equal(t, "s3cret", actual.(api.RepoCredentialsResponse).Password)
+			assert.Equal(t, []string{"user", "candidate"}, roles)
+		})
+
+		t.Run("should return nothing for repos that users access with their own account", func(t *testing.T) {
+			srv := hasura(t, "user", `{"data":{"assignments_by_pk":{"id":12,"github_repo_url":"https://github.com/testrelay/jane-test-12"}}}`)
+			defer srv.Close()
+
+			ctrl := gomock.NewController(t)
+			c := mocks.NewMockCredentialer(ctrl)
+			r := api.AssignmentResolver{HasuraURL: srv.URL, Credentialer: c, Logger: zap.NewNop().Sugar()}
+
+			c.EXPECT().Password("https://github.com/testrelay/jane-test-12", "jane@testrelay.io").Return("", false)
+
+			actual, err := r.RepoCredentials(p)
+			require.NoError(t, err)
+			assert.Nil(t, actual)
+		})
+
+		t.Run("should error if the user cannot access the assignment", func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"data":{"assignments_by_pk":null}}`))
+			}))
+			defer srv.Close()
+
+			r := api.AssignmentResolver{HasuraURL: srv.URL, Logger: zap.NewNop().Sugar()}
+
+			_, err := r.RepoCredentials(p)
+			assert.EqualError(t, err, "could not get repo credentials for assignment 12")
+		})
+	})
+}
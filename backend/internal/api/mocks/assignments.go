@@ -0,0 +1,239 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/testrelay/testrelay/backend/internal/api (interfaces: Extender,Starter,Submitter,Simulator,Credentialer,Fetcher)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	assignment "github.com/testrelay/testrelay/backend/internal/core/assignment"
+)
+
+// MockExtender is a mock of Extender interface.
+type MockExtender struct {
+	ctrl     *gomock.Controller
+	recorder *MockExtenderMockRecorder
+}
+
+// MockExtenderMockRecorder is the mock recorder for MockExtender.
+type MockExtenderMockRecorder struct {
+	mock *MockExtender
+}
+
+// NewMockExtender creates a new mock instance.
+func NewMockExtender(ctrl *gomock.Controller) *MockExtender {
+	mock := &MockExtender{ctrl: ctrl}
+	mock.recorder = &MockExtenderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockExtender) EXPECT() *MockExtenderMockRecorder {
+	return m.recorder
+}
+
+// Extend mocks base method.
+func (m *MockExtender) Extend(arg0, arg1, arg2 int) (assignment.Extension, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Extend", arg0, arg1, arg2)
+	ret0, _ := ret[0].(assignment.Extension)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Extend indicates an expected call of Extend.
+func (mr *MockExtenderMockRecorder) Extend(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Extend", reflect.TypeOf((*MockExtender)(nil).Extend), arg0, arg1, arg2)
+}
+
+// MockStarter is a mock of Starter interface.
+type MockStarter struct {
+	ctrl     *gomock.Controller
+	recorder *MockStarterMockRecorder
+}
+
+// MockStarterMockRecorder is the mock recorder for MockStarter.
+type MockStarterMockRecorder struct {
+	mock *MockStarter
+}
+
+// NewMockStarter creates a new mock instance.
+func NewMockStarter(ctrl *gomock.Controller) *MockStarter {
+	mock := &MockStarter{ctrl: ctrl}
+	mock.recorder = &MockStarterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStarter) EXPECT() *MockStarterMockRecorder {
+	return m.recorder
+}
+
+// Start mocks base method.
+func (m *MockStarter) Start(arg0, arg1 int, arg2 string) (assignment.Start, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Start", arg0, arg1, arg2)
+	ret0, _ := ret[0].(assignment.Start)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Start indicates an expected call of Start.
+func (mr *MockStarterMockRecorder) Start(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Start", reflect.TypeOf((*MockStarter)(nil).Start), arg0, arg1, arg2)
+}
+
+// MockSubmitter is a mock of Submitter interface.
+type MockSubmitter struct {
+	ctrl     *gomock.Controller
+	recorder *MockSubmitterMockRecorder
+}
+
+// MockSubmitterMockRecorder is the mock recorder for MockSubmitter.
+type MockSubmitterMockRecorder struct {
+	mock *MockSubmitter
+}
+
+// NewMockSubmitter creates a new mock instance.
+func NewMockSubmitter(ctrl *gomock.Controller) *MockSubmitter {
+	mock := &MockSubmitter{ctrl: ctrl}
+	mock.recorder = &MockSubmitterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSubmitter) EXPECT() *MockSubmitterMockRecorder {
+	return m.recorder
+}
+
+// SubmitAssignment mocks base method.
+func (m *MockSubmitter) SubmitAssignment(arg0, arg1 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SubmitAssignment", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SubmitAssignment indicates an expected call of SubmitAssignment.
+func (mr *MockSubmitterMockRecorder) SubmitAssignment(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubmitAssignment", reflect.TypeOf((*MockSubmitter)(nil).SubmitAssignment), arg0, arg1)
+}
+
+// MockSimulator is a mock of Simulator interface.
+type MockSimulator struct {
+	ctrl     *gomock.Controller
+	recorder *MockSimulatorMockRecorder
+}
+
+// MockSimulatorMockRecorder is the mock recorder for MockSimulator.
+type MockSimulatorMockRecorder struct {
+	mock *MockSimulator
+}
+
+// NewMockSimulator creates a new mock instance.
+func NewMockSimulator(ctrl *gomock.Controller) *MockSimulator {
+	mock := &MockSimulator{ctrl: ctrl}
+	mock.recorder = &MockSimulatorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSimulator) EXPECT() *MockSimulatorMockRecorder {
+	return m.recorder
+}
+
+// Simulate mocks base method.
+func (m *MockSimulator) Simulate(arg0 assignment.SimulationInput) ([]assignment.TimelineEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Simulate", arg0)
+	ret0, _ := ret[0].([]assignment.TimelineEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Simulate indicates an expected call of Simulate.
+func (mr *MockSimulatorMockRecorder) Simulate(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Simulate", reflect.TypeOf((*MockSimulator)(nil).Simulate), arg0)
+}
+
+// MockCredentialer is a mock of Credentialer interface.
+type MockCredentialer struct {
+	ctrl     *gomock.Controller
+	recorder *MockCredentialerMockRecorder
+}
+
+// MockCredentialerMockRecorder is the mock recorder for MockCredentialer.
+type MockCredentialerMockRecorder struct {
+	mock *MockCredentialer
+}
+
+// NewMockCredentialer creates a new mock instance.
+func NewMockCredentialer(ctrl *gomock.Controller) *MockCredentialer {
+	mock := &MockCredentialer{ctrl: ctrl}
+	mock.recorder = &MockCredentialerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCredentialer) EXPECT() *MockCredentialerMockRecorder {
+	return m.recorder
+}
+
+// Password mocks base method.
+func (m *MockCredentialer) Password(arg0, arg1 string) (string, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Password", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// Password indicates an expected call of Password.
+func (mr *MockCredentialerMockRecorder) Password(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Password", reflect.TypeOf((*MockCredentialer)(nil).Password), arg0, arg1)
+}
+
+// MockFetcher is a mock of Fetcher interface.
+type MockFetcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockFetcherMockRecorder
+}
+
+// MockFetcherMockRecorder is the mock recorder for MockFetcher.
+type MockFetcherMockRecorder struct {
+	mock *MockFetcher
+}
+
+// NewMockFetcher creates a new mock instance.
+func NewMockFetcher(ctrl *gomock.Controller) *MockFetcher {
+	mock := &MockFetcher{ctrl: ctrl}
+	mock.recorder = &MockFetcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFetcher) EXPECT() *MockFetcherMockRecorder {
+	return m.recorder
+}
+
+// GetAssignment mocks base method.
+func (m *MockFetcher) GetAssignment(arg0 int) (assignment.WithTestDetails, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAssignment", arg0)
+	ret0, _ := ret[0].(assignment.WithTestDetails)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAssignment indicates an expected call of GetAssignment.
+func (mr *MockFetcherMockRecorder) GetAssignment(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAssignment", reflect.TypeOf((*MockFetcher)(nil).GetAssignment), arg0)
+}
@@ -19,6 +19,29 @@ import (
 type RepositoryResolver struct {
 	HasuraURL string
 	Collector core.RepoCollector
+	// GitlabCollector lists the projects of businesses that use gitlab. It is nil when gitlab is not configured.
+	GitlabCollector core.RepoCollector
+	// BitbucketCollector lists the repos of businesses that use bitbucket. It is nil when bitbucket is not
+	// configured.
+	BitbucketCollector core.WorkspaceCollector
+	// LocalCollector lists the test repos in LOCAL_GIT_ROOT for businesses that use local repos. It is nil when
+	// local repos are not configured.
+	LocalCollector core.RepoCollector
+}
+
+// workspaceCollector adapts a core.WorkspaceCollector into a core.RepoCollector for a single workspace, ignoring
+// the installation id.
+type workspaceCollector struct {
+	collector core.WorkspaceCollector
+	workspace string
+}
+
+func (w workspaceCollector) CollectRepos(int64) ([]core.Repo, error) {
+	return w.collector.CollectRepos(w.workspace)
+}
+
+func (w workspaceCollector) CollectDirs(_ int64, fullName, path string) ([]core.RepoDir, error) {
+	return w.collector.CollectDirs(w.workspace, fullName, path)
 }
 
 // Fields implements the Resolver interface returning a resolvable qraphql schema.
@@ -36,6 +59,18 @@ func (r *RepositoryResolver) Fields() (graphql.Fields, graphql.Fields) {
 		},
 	})
 
+	dirType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "RepoDir",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{
+				Type: graphql.String,
+			},
+			"path": &graphql.Field{
+				Type: graphql.String,
+			},
+		},
+	})
+
 	fields := graphql.Fields{
 		"repos": &graphql.Field{
 			Type:        graphql.NewList(repoType),
@@ -47,6 +82,23 @@ func (r *RepositoryResolver) Fields() (graphql.Fields, graphql.Fields) {
 			},
 			Resolve: r.ResolveRepos,
 		},
+		"repo_dirs": &graphql.Field{
+			Type:        graphql.NewList(dirType),
+			Description: "Get the directories at path in a business repo",
+			Args: graphql.FieldConfigArgument{
+				"business_id": &graphql.ArgumentConfig{
+					Type: graphql.Int,
+				},
+				"full_name": &graphql.ArgumentConfig{
+					Type: graphql.String,
+				},
+				"path": &graphql.ArgumentConfig{
+					Type:         graphql.String,
+					DefaultValue: "",
+				},
+			},
+			Resolve: r.ResolveRepoDirs,
+		},
 	}
 
 	return fields, nil
@@ -54,16 +106,51 @@ func (r *RepositoryResolver) Fields() (graphql.Fields, graphql.Fields) {
 
 // ResolveRepos returns a list of test repositories for the provided business_id in the graphql params.
 // It expects that a vcs app has been installed on the business and fetches the installation_id from
-// storage. ResolveRepos errors if no valid installation can be found or if fetching repositories fails.
+// storage, the gitlab_group_id for businesses that use gitlab or the bitbucket_workspace for businesses that use
+// bitbucket. Businesses that use local repos list every test repo on disk. ResolveRepos errors if no valid installation can be found or if fetching repositories fails.
 func (r *RepositoryResolver) ResolveRepos(p graphql.ResolveParams) (interface{}, error) {
 	id, ok := p.Args["business_id"].(int)
 	if !ok {
 		return []core.Repo{}, nil
 	}
 
+	collector, installationID, ok := r.collector(p, id)
+	if !ok {
+		return []core.Repo{}, nil
+	}
+
+	return collector.CollectRepos(installationID)
+}
+
+// ResolveRepoDirs returns the directories at path in the repo full_name of the business_id in the graphql params,
+// so that a test can be pointed at a subtree of a monorepo. Like ResolveRepos it returns an empty list if the
+// business has no valid installation.
+func (r *RepositoryResolver) ResolveRepoDirs(p graphql.ResolveParams) (interface{}, error) {
+	id, ok := p.Args["business_id"].(int)
+	fullName, _ := p.Args["full_name"].(string)
+	if !ok || fullName == "" {
+		return []core.RepoDir{}, nil
+	}
+
+	collector, installationID, ok := r.collector(p, id)
+	if !ok {
+		return []core.RepoDir{}, nil
+	}
+
+	path, _ := p.Args["path"].(string)
+	return collector.CollectDirs(installationID, fullName, path)
+}
+
+// collector returns the RepoCollector for the vcs provider of the business, along with the github installation
+// or gitlab group id that it should collect from. Bitbucket collectors are bound to the business's workspace, and
+// local collectors need no installation. It returns false if the business has no valid installation.
+func (r *RepositoryResolver) collector(p graphql.ResolveParams, id int) (core.RepoCollector, int64, bool) {
 	var q struct {
 		BusinessByPK struct {
 			GithubInstallationID hGraph.String `graphql:"github_installation_id"`
+			VCSProvider          hGraph.String `graphql:"vcs_provider"`
+			GitlabGroupID        hGraph.String `graphql:"gitlab_group_id"`
+			BitbucketWorkspace   hGraph.String `graphql:"bitbucket_workspace"`
 		} `graphql:"businesses_by_pk(id: $id)"`
 	}
 
@@ -78,16 +165,41 @@ func (r *RepositoryResolver) ResolveRepos(p graphql.ResolveParams) (interface{},
 	})
 	if err != nil {
 		log.Printf("failed to query hasura with id %d err %s\n", id, err)
-		return []core.Repo{}, nil
+		return nil, 0, false
+	}
+
+	switch q.BusinessByPK.VCSProvider {
+	case core.VCSProviderGitlab:
+		if r.GitlabCollector == nil || q.BusinessByPK.GitlabGroupID == "" {
+			log.Printf("returned nil gitlab group for business")
+			return nil, 0, false
+		}
+
+		groupID, _ := strconv.ParseInt(string(q.BusinessByPK.GitlabGroupID), 10, 64)
+		return r.GitlabCollector, groupID, true
+	case core.VCSProviderBitbucket:
+		if r.BitbucketCollector == nil || q.BusinessByPK.BitbucketWorkspace == "" {
+			log.Printf("returned nil bitbucket workspace for business")
+			return nil, 0, false
+		}
+
+		return workspaceCollector{collector: r.BitbucketCollector, workspace: string(q.BusinessByPK.BitbucketWorkspace)}, 0, true
+	case core.VCSProviderLocal:
+		if r.LocalCollector == nil {
+			log.Printf("returned nil local collector for business")
+			return nil, 0, false
+		}
+
+		return r.LocalCollector, 0, true
 	}
 
 	if q.BusinessByPK.GithubInstallationID == "" {
 		log.Printf("returned nil github installation for business")
-		return []core.Repo{}, nil
+		return nil, 0, false
 	}
 
 	installationID := q.BusinessByPK.GithubInstallationID
 	in, _ := strconv.ParseInt(string(installationID), 10, 64)
 
-	return r.Collector.CollectRepos(in)
+	return r.Collector, in, true
 }
@@ -0,0 +1,46 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/graphql-go/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/testrelay/testrelay/backend/internal/api"
+	"github.com/testrelay/testrelay/backend/internal/core"
+	"github.com/testrelay/testrelay/backend/internal/vcs"
+)
+
+func TestRepositoryResolver(t *testing.T) {
+	t.Run("ResolveRepos should list the local test repos of businesses that use local repos", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"data":{"businesses_by_pk":{"vcs_provider":"local"}}}`))
+		}))
+		defer srv.Close()
+
+		root := t.TempDir()
+		_, err := git.PlainInit(filepath.Join(root, "backend-test"), true)
+		require.NoError(t, err)
+
+		resolver := api.RepositoryResolver{
+			HasuraURL: srv.URL,
+			LocalCollector: vcs.NewLocalGitClient(vcs.LocalConfig{
+				Root: root,
+				URL:  "https://backend.testrelay.io/git",
+			}),
+		}
+
+		repos, err := resolver.ResolveRepos(graphql.ResolveParams{
+			Context: context.WithValue(context.Background(), "token", "token"),
+			Args:    map[string]interface{}{"business_id": 3},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []core.Repo{{FullName: "https://backend.testrelay.io/git/backend-test"}}, repos)
+	})
+}
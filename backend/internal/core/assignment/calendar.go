@@ -0,0 +1,75 @@
+package assignment
+
+//go:generate mockgen -destination mocks/calendar.go -package mocks . EventFetcher
+import (
+	"fmt"
+	"time"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+)
+
+// EventFetcher defines an interface for a type that fetches the events recorded against an assignment.
+type EventFetcher interface {
+	// Events returns the events for the assignment, oldest first.
+	Events(assignmentID int) ([]Event, error)
+}
+
+type calendarEmailData struct {
+	Assignment WithTestDetails
+	Start      string
+	End        string
+}
+
+// sendCalendar emails the candidate a calendar event for the assignment starting at start. The event has
+// the same UID each time it is sent and a sequence that increases each time the assignment is scheduled
+// or cancelled, so calendars update or remove the original event.
+func (s Scheduler) sendCalendar(assignment WithTestDetails, start time.Time, cancelled bool) error {
+	events, err := s.Events.Events(assignment.ID)
+	if err != nil {
+		return fmt.Errorf("could not fetch events for assignment %d %w", assignment.ID, err)
+	}
+
+	var sequence int
+	for _, e := range events {
+		if e.Type == "scheduled" || e.Type == "cancelled" {
+			sequence++
+		}
+	}
+
+	if sequence > 0 {
+		sequence--
+	}
+
+	end := start.Add(time.Second * time.Duration(assignment.timeLimit()))
+	config := core.MailConfig{
+		TemplateName: "scheduled",
+		Subject:      "Your " + assignment.Test.Business.Name + " technical test is scheduled",
+		From:         "candidates",
+		To:           assignment.CandidateEmail,
+		Event: &core.CalendarEvent{
+			UID:         fmt.Sprintf("testrelay-assignment-%d", assignment.ID),
+			Sequence:    sequence,
+			Cancelled:   cancelled,
+			Summary:     assignment.Test.Business.Name + " technical test",
+			Description: "Your test instructions will be uploaded to " + assignment.GithubRepoURL,
+			URL:         assignment.GithubRepoURL,
+			Start:       start,
+			End:         end,
+		},
+	}
+	if cancelled {
+		config.TemplateName = "cancelled"
+		config.Subject = "Your " + assignment.Test.Business.Name + " technical test has been cancelled"
+	}
+
+	err = s.Mailer.Send(config, calendarEmailData{
+		Assignment: assignment,
+		Start:      readableIn(start, assignment.TestTimezoneChosen),
+		End:        readableIn(end, assignment.TestTimezoneChosen),
+	})
+	if err != nil {
+		return fmt.Errorf("could not send %s email to candidate %s %w", config.TemplateName, assignment.CandidateEmail, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package assignment_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+	"github.com/testrelay/testrelay/backend/internal/core/assignment"
+	"github.com/testrelay/testrelay/backend/internal/core/assignment/mocks"
+	coreMocks "github.com/testrelay/testrelay/backend/internal/core/mocks"
+)
+
+func TestSchedulerCalendar(t *testing.T) {
+	now := time.Date(2021, 11, 12, 8, 0, 0, 0, time.UTC)
+	a := assignment.WithTestDetails{
+		ID:                 12,
+		SchedulerID:        "event-0",
+		TestDayChosen:      "2021-11-12",
+		TestTimeChosen:     "10:00:00",
+		TestTimezoneChosen: "Europe/London",
+		ChooseUntil:        "2021-11-13",
+		TimeLimit:          7200,
+		CandidateEmail:     "jane@testrelay.io",
+		GithubRepoURL:      "https://github.com/testrelay/jane.git",
+		Test:               assignment.Test{Business: assignment.Business{Name: "TestRelay"}},
+	}
+	history := []assignment.Event{
+		{Type: "sent"},
+		{Type: "scheduled"},
+		{Type: "scheduled"},
+	}
+
+	t.Run("Start should send an updated calendar event when rescheduling", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		f := mocks.NewMockFetcher(ctrl)
+		su := mocks.NewMockScheduleUpdater(ctrl)
+		sc := mocks.NewMockSchedulerClient(ctrl)
+		ledger := mocks.NewMockStepLedger(ctrl)
+		events := mocks.NewMockEventFetcher(ctrl)
+		mailer := coreMocks.NewMockMailer(ctrl)
+
+		s := assignment.Scheduler{
+			Fetcher:         f,
+			SchedulerClient: sc,
+			Updater:         su,
+			Ledger:          ledger,
+			Events:          events,
+			Mailer:          mailer,
+			Logger:          zap.NewNop().Sugar(),
+			Time:            func() time.Time { return now },
+		}
+
+		f.EXPECT().GetAssignment(12).Return(a, nil)
+		sc.EXPECT().Stop("event-0").Return(nil)
+		ledger.EXPECT().GetStep(12, "start").Return(assignment.StepRecord{EventID: "event-0"}, nil)
+		sc.EXPECT().Start(gomock.Any()).Return("event-1", nil)
+		ledger.EXPECT().SaveStep(assignment.StepRecord{AssignmentID: 12, Step: "start"}).Return(nil)
+		ledger.EXPECT().ScheduleStep(12, "start", "event-1", gomock.Any()).Return(nil)
+		su.EXPECT().UpdateAssignmentWithDetails(12, "event-1", a.GithubRepoURL).Return(nil)
+		events.EXPECT().Events(12).Return(history, nil)
+		mailer.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(func(config core.MailConfig, data interface{}) error {
+			assert.Equal(t, "scheduled", config.TemplateName)
+			assert.Equal(t, "jane@testrelay.io", config.To)
+			assert.Equal(t, &core.CalendarEvent{
+				UID:         "testrelay-assignment-12",
+				Sequence:    1,
+				Summary:     "TestRelay technical test",
+				Description: "Your test instructions will be uploaded to https://github.com/testrelay/jane.git",
+				URL:         "https://github.com/testrelay/jane.git",
+				Start:       time.Date(2021, 11, 12, 10, 0, 0, 0, time.UTC),
+				End:         time.Date(2021, 11, 12, 12, 0, 0, 0, time.UTC),
+			}, utcEvent(config.Event))
+
+			return nil
+		})
+
+		err := s.Start(12)
+		require.NoError(t, err)
+	})
+
+	t.Run("Stop should cancel the calendar event", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		f := mocks.NewMockFetcher(ctrl)
+		sc := mocks.NewMockSchedulerClient(ctrl)
+		ledger := mocks.NewMockStepLedger(ctrl)
+		events := mocks.NewMockEventFetcher(ctrl)
+		mailer := coreMocks.NewMockMailer(ctrl)
+
+		s := assignment.Scheduler{
+			Fetcher:         f,
+			SchedulerClient: sc,
+			Ledger:          ledger,
+			Events:          events,
+			Mailer:          mailer,
+			Logger:          zap.NewNop().Sugar(),
+			Time:            func() time.Time { return now },
+		}
+
+		f.EXPECT().GetAssignment(12).Return(a, nil)
+		sc.EXPECT().Stop("event-0").Return(nil)
+		ledger.EXPECT().GetStep(12, "start").Return(assignment.StepRecord{EventID: "event-0"}, nil)
+		events.EXPECT().Events(12).Return(append(history, assignment.Event{Type: "cancelled"}), nil)
+		mailer.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(func(config core.MailConfig, data interface{}) error {
+			assert.Equal(t, "cancelled", config.TemplateName)
+			assert.True(t, config.Event.Cancelled)
+			assert.Equal(t, 2, config.Event.Sequence)
+
+			return nil
+		})
+
+		err := s.Stop(12)
+		require.NoError(t, err)
+	})
+}
+
+func utcEvent(e *core.CalendarEvent) *core.CalendarEvent {
+	if e == nil {
+		return nil
+	}
+
+	u := *e
+	u.Start, u.End = u.Start.UTC(), u.End.UTC()
+	return &u
+}
@@ -1,13 +1,19 @@
 package assignment
 
 import (
+	"errors"
 	"fmt"
 	"time"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+	intTime "github.com/testrelay/testrelay/backend/internal/time"
 )
 
 type Short struct {
 	CandidateName string
 	GithubRepoUrl string
+	// VCSProvider is the provider hosting GithubRepoUrl, one of github|gitlab|bitbucket|local.
+	VCSProvider string
 }
 
 type Full struct {
@@ -67,15 +73,19 @@ type WithTestDetails struct {
 	CandidateEmail     string    `json:"candidate_email"`
 	TestTimezoneChosen string    `json:"test_timezone_chosen"`
 	SchedulerID        string    `json:"step_arn"`
+	Mode               string    `json:"mode"`
 	Candidate          Candidate `json:"candidate"`
 	Recruiter          Recruiter `json:"recruiter"`
 	Test               Test      `json:"test"`
 }
 
 type Candidate struct {
-	Email             string `json:"email"`
-	GithubUsername    string `json:"github_username"`
-	GithubAccessToken string `json:"github_access_token"`
+	Email          string `json:"email"`
+	GithubUsername string `json:"github_username"`
+	GitlabUsername string `json:"gitlab_username"`
+	// BitbucketAccountID identifies the candidate on bitbucket, which no longer accepts usernames.
+	BitbucketAccountID string `json:"bitbucket_account_id"`
+	GithubAccessToken  string `json:"github_access_token"`
 }
 
 type Recruiter struct {
@@ -83,14 +93,142 @@ type Recruiter struct {
 }
 
 type Test struct {
-	Business   Business `json:"business"`
-	Name       string   `json:"name"`
-	GithubRepo string   `json:"github_repo"`
+	Business   Business   `json:"business"`
+	Name       string     `json:"name"`
+	GithubRepo string     `json:"github_repo"`
+	Reminders  []Reminder `json:"reminders"`
+	Stages     []Stage    `json:"stages"`
+	// GithubRef is the branch, tag or commit of GithubRepo that is uploaded. It defaults to the default branch.
+	GithubRef string `json:"github_ref"`
+	// GithubPath is the directory of GithubRepo that is uploaded as the root of the assignment repo, e.g. one
+	// exercise of a monorepo. It defaults to the root of GithubRepo.
+	GithubPath string `json:"github_path"`
+	// TemplateGlob matches the test files that are rendered with the assignment's details, see core.TemplateData.
+	TemplateGlob string `json:"template_glob"`
+	// UploadMode is how assignment repos are filled with the test, one of core.UploadModePush|UploadModeTemplate.
+	UploadMode string `json:"upload_mode"`
+	// TemplateOwner is the github org that repos generated from GithubRepo are created in, see core.CreateDetails.
+	TemplateOwner string `json:"template_owner"`
 }
 
 type Business struct {
 	Name                 string `json:"name"`
 	GithubInstallationID int64  `json:"github_installation_id"`
+	// GithubOrg is the github org that assignment repos are created in through the installation. Repos are
+	// created under the interviewer account if it is empty.
+	GithubOrg string `json:"github_org"`
+	// VCSProvider is the provider assignment repos are created on, one of github|gitlab|bitbucket|local.
+	VCSProvider string `json:"vcs_provider"`
+	// GitlabGroupID is the gitlab group that holds the business's test projects.
+	GitlabGroupID int64 `json:"gitlab_group_id"`
+	// BitbucketWorkspace is the bitbucket workspace that holds the business's test repos.
+	BitbucketWorkspace string               `json:"bitbucket_workspace"`
+	Availability       intTime.Availability `json:"availability"`
+}
+
+// installationID returns the id of the github app installation, or the gitlab group, that has access
+// to the business's test repos.
+func (b Business) installationID() int64 {
+	if b.VCSProvider == core.VCSProviderGitlab {
+		return b.GitlabGroupID
+	}
+
+	return b.GithubInstallationID
+}
+
+// vcsUsername returns the candidate's username on the vcs provider of the business.
+func (a WithTestDetails) vcsUsername() string {
+	switch a.Test.Business.VCSProvider {
+	case core.VCSProviderGitlab:
+		return a.Candidate.GitlabUsername
+	case core.VCSProviderBitbucket:
+		return a.Candidate.BitbucketAccountID
+	case core.VCSProviderLocal:
+		return a.Candidate.Email
+	}
+
+	return a.Candidate.GithubUsername
+}
+
+// createDetails returns the details needed to create the assignment's repo.
+func (a WithTestDetails) createDetails() core.CreateDetails {
+	d := core.CreateDetails{
+		Provider:     a.Test.Business.VCSProvider,
+		BusinessName: a.Test.Business.Name,
+		Username:     a.vcsUsername(),
+		ID:           a.ID,
+		Org:          a.Test.Business.GithubOrg,
+	}
+	if d.Org != "" || a.Test.fromTemplate() {
+		d.InstallationID = a.Test.Business.GithubInstallationID
+	}
+
+	if a.Test.fromTemplate() {
+		d.TemplateRepo = a.Test.GithubRepo
+		d.TemplateOwner = a.Test.TemplateOwner
+	}
+
+	return d
+}
+
+// fromTemplate reports whether assignment repos of the test are generated from its github template repo rather
+// than having the test pushed to them.
+func (t Test) fromTemplate() bool {
+	return t.UploadMode == core.UploadModeTemplate
+}
+
+// templateErr returns an error if the test cannot be generated from a template, as only github repos without
+// stages can be.
+func (t Test) templateErr() error {
+	if p := t.Business.VCSProvider; p != "" && p != core.VCSProviderGithub {
+		return fmt.Errorf("cannot be generated from a template on %s", p)
+	}
+
+	if len(t.Stages) > 0 {
+		return errors.New("has stages and cannot be generated from a template")
+	}
+
+	return nil
+}
+
+// repoCreator returns template if the test of the assignment generates its repos from a template, otherwise creator.
+// It errors if the test cannot be generated from a template, see Test.templateErr.
+func (a WithTestDetails) repoCreator(creator, template core.VCSCreator) (core.VCSCreator, error) {
+	if !a.Test.fromTemplate() {
+		return creator, nil
+	}
+
+	err := a.Test.templateErr()
+	if err != nil {
+		return nil, fmt.Errorf("test %s %w", a.Test.Name, err)
+	}
+
+	if template == nil {
+		return nil, errors.New("no template creator is configured")
+	}
+
+	return template, nil
+}
+
+// Window returns the window that the assignment can be scheduled in, as of now.
+func (a WithTestDetails) Window(now time.Time) intTime.Window {
+	return intTime.Window{
+		Now:          now,
+		ChooseUntil:  a.ChooseUntil,
+		Availability: a.Test.Business.Availability,
+	}
+}
+
+// templateData returns the values test files are rendered with for an upload that ends at deadline.
+func (a WithTestDetails) templateData(deadline time.Time) core.TemplateData {
+	return core.TemplateData{
+		CandidateName:  a.CandidateName,
+		CandidateEmail: a.CandidateEmail,
+		BusinessName:   a.Test.Business.Name,
+		TestName:       a.Test.Name,
+		Deadline:       readableIn(deadline, a.TestTimezoneChosen),
+		Timezone:       a.TestTimezoneChosen,
+	}
 }
 
 type SentDetails struct {
@@ -0,0 +1,83 @@
+package assignment
+
+//go:generate mockgen -destination mocks/expire.go -package mocks . ExpiryFetcher
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+)
+
+// ExpiryFetcher defines an interface for a type that finds assignments that were sent to a candidate
+// but never scheduled before their choose_until date.
+type ExpiryFetcher interface {
+	// UnscheduledBefore returns the sent or viewed assignments with a choose_until before the given date.
+	UnscheduledBefore(date time.Time) ([]WithTestDetails, error)
+}
+
+// Expirer marks assignments that the candidate never scheduled as expired.
+type Expirer struct {
+	Fetcher      ExpiryFetcher
+	EventCreator EventCreator
+	Mailer       core.Mailer
+	Logger       *zap.SugaredLogger
+	Time         Time
+}
+
+// Sweep expires all assignments whose choose_until date has passed without the candidate choosing a time,
+// emailing both the candidate and the recruiter. The choose_until date is inclusive, so an assignment is
+// only expired once the whole day has passed in UTC. An assignment that fails to expire is logged and does
+// not stop the sweep. Sweep returns the number of assignments expired.
+func (e Expirer) Sweep() (int, error) {
+	today := e.Time().UTC().Truncate(time.Hour * 24)
+	assignments, err := e.Fetcher.UnscheduledBefore(today)
+	if err != nil {
+		return 0, fmt.Errorf("could not fetch unscheduled assignments %w", err)
+	}
+
+	var expired int
+	for _, a := range assignments {
+		err := e.expire(a)
+		if err != nil {
+			e.Logger.Error("could not expire assignment", "assignment_id", a.ID, "error", err)
+			continue
+		}
+
+		expired++
+	}
+
+	return expired, nil
+}
+
+// expire moves the assignment to the terminal expired status before sending emails, so that a failed
+// email is never retried by a later sweep.
+func (e Expirer) expire(a WithTestDetails) error {
+	err := e.EventCreator.NewAssignmentEvent(a.CandidateID, a.ID, "expired")
+	if err != nil {
+		return fmt.Errorf("could not insert event 'expired' %w", err)
+	}
+
+	err = e.Mailer.Send(core.MailConfig{
+		TemplateName: "expired",
+		Subject:      "Your invite to the " + a.Test.Business.Name + " technical test has expired",
+		From:         "candidates",
+		To:           a.CandidateEmail,
+	}, a)
+	if err != nil {
+		e.Logger.Error("could not send expired email to candidate", "assignment_id", a.ID, "error", err)
+	}
+
+	err = e.Mailer.Send(core.MailConfig{
+		TemplateName: "expired-recruiter",
+		Subject:      a.CandidateName + " did not schedule their technical test in time",
+		From:         "candidates",
+		To:           a.Recruiter.Email,
+	}, a)
+	if err != nil {
+		e.Logger.Error("could not send expired email to recruiter", "assignment_id", a.ID, "error", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package assignment_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+	"github.com/testrelay/testrelay/backend/internal/core/assignment"
+	"github.com/testrelay/testrelay/backend/internal/core/assignment/mocks"
+	coreMocks "github.com/testrelay/testrelay/backend/internal/core/mocks"
+)
+
+func TestExpirer(t *testing.T) {
+	now := time.Date(2021, 11, 12, 10, 30, 0, 0, time.UTC)
+	today := time.Date(2021, 11, 12, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Sweep", func(t *testing.T) {
+		t.Run("should expire unscheduled assignments and email the candidate and recruiter", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			fetcher := mocks.NewMockExpiryFetcher(ctrl)
+			events := mocks.NewMockEventCreator(ctrl)
+			mailer := coreMocks.NewMockMailer(ctrl)
+
+			e := assignment.Expirer{
+				Fetcher:      fetcher,
+				EventCreator: events,
+				Mailer:       mailer,
+				Logger:       zap.NewNop().Sugar(),
+				Time:         func() time.Time { return now },
+			}
+
+			a := assignment.WithTestDetails{
+				ID:             12,
+				CandidateID:    3,
+				CandidateName:  "Jane",
+				CandidateEmail: "jane@testrelay.io",
+				Recruiter:      assignment.Recruiter{Email: "recruiter@testrelay.io"},
+				Test:           assignment.Test{Business: assignment.Business{Name: "TestRelay"}},
+			}
+			fetcher.EXPECT().UnscheduledBefore(today).Return([]assignment.WithTestDetails{a}, nil)
+			events.EXPECT().NewAssignmentEvent(3, 12, "expired").Return(nil)
+			mailer.EXPECT().Send(core.MailConfig{
+				TemplateName: "expired",
+				Subject:      "Your invite to the TestRelay technical test has expired",
+				From:         "candidates",
+				To:           "jane@testrelay.io",
+			}, a).Return(nil)
+			mailer.EXPECT().Send(core.MailConfig{
+				TemplateName: "expired-recruiter",
+				Subject:      "Jane did not schedule their technical test in time",
+				From:         "candidates",
+				To:           "recruiter@testrelay.io",
+			}, a).Return(nil)
+
+			n, err := e.Sweep()
+			require.NoError(t, err)
+			assert.Equal(t, 1, n)
+		})
+
+		t.Run("should continue sweeping when an assignment fails to expire", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			fetcher := mocks.NewMockExpiryFetcher(ctrl)
+			events := mocks.NewMockEventCreator(ctrl)
+			mailer := coreMocks.NewMockMailer(ctrl)
+
+			e := assignment.Expirer{
+				Fetcher:      fetcher,
+				EventCreator: events,
+				Mailer:       mailer,
+				Logger:       zap.NewNop().Sugar(),
+				Time:         func() time.Time { return now },
+			}
+
+			fetcher.EXPECT().UnscheduledBefore(today).Return([]assignment.WithTestDetails{{ID: 12}, {ID: 13}}, nil)
+			events.EXPECT().NewAssignmentEvent(0, 12, "expired").Return(errors.New("hasura down"))
+			events.EXPECT().NewAssignmentEvent(0, 13, "expired").Return(nil)
+			mailer.EXPECT().Send(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+
+			n, err := e.Sweep()
+			require.NoError(t, err)
+			assert.Equal(t, 1, n)
+		})
+	})
+}
@@ -0,0 +1,216 @@
+package assignment
+
+//go:generate mockgen -destination mocks/extend.go -package mocks . EventRecorder
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+)
+
+var (
+	ErrInvalidExtension = errors.New("extension must be a positive number of minutes")
+	ErrNotInProgress    = errors.New("assignment is not in progress")
+	ErrNoPendingEnd     = errors.New("assignment has no pending end to extend")
+)
+
+// EventRecorder defines an interface for a type that records an assignment event
+// without changing the status of the assignment.
+type EventRecorder interface {
+	RecordAssignmentEvent(userID int, assignmentID int, eventType string, meta map[string]interface{}) error
+}
+
+// Extension holds the details of an assignment deadline extension.
+type Extension struct {
+	AssignmentID int
+	Minutes      int
+	Deadline     time.Time
+}
+
+type extensionEmailData struct {
+	Assignment WithTestDetails
+	Minutes    int
+	Deadline   string
+}
+
+// Extender moves the deadline of assignments that are in progress.
+type Extender struct {
+	Fetcher         Fetcher
+	SchedulerClient SchedulerClient
+	Ledger          StepLedger
+	EventRecorder   EventRecorder
+	StageRecorder   StageRecorder
+	Mailer          core.Mailer
+
+	WarningBeforeEnd time.Duration
+}
+
+// Extend pushes back the deadline of an in progress assignment by the given minutes.
+// The pending end step is stopped and rescheduled, or if the end step has already run the pending cleanup
+// step is rescheduled instead. Pending end reminders, and the pending start of the next stage of a staged test,
+// are moved by the same amount, along with the recorded deadline of the current stage. An extended event is recorded against the userID who gave the extension and the
+// candidate is emailed their new deadline.
+func (e Extender) Extend(assignmentID, minutes, userID int) (Extension, error) {
+	if minutes <= 0 {
+		return Extension{}, ErrInvalidExtension
+	}
+
+	assignment, err := e.Fetcher.GetAssignment(assignmentID)
+	if err != nil {
+		return Extension{}, fmt.Errorf("could not fetch assignment id %d %w", assignmentID, err)
+	}
+
+	if assignment.Status != "inprogress" {
+		return Extension{}, ErrNotInProgress
+	}
+
+	extension := time.Minute * time.Duration(minutes)
+	previous, deadline, err := e.reschedule(assignment, extension)
+	if err != nil {
+		return Extension{}, err
+	}
+
+	err = e.moveSteps(assignment, extension, deadline)
+	if err != nil {
+		return Extension{}, err
+	}
+
+	err = e.EventRecorder.RecordAssignmentEvent(userID, assignmentID, "extended", map[string]interface{}{
+		"minutes":           minutes,
+		"previous_deadline": previous.Format(time.RFC3339),
+		"deadline":          deadline.Format(time.RFC3339),
+	})
+	if err != nil {
+		return Extension{}, fmt.Errorf("could not insert event 'extended' %w", err)
+	}
+
+	err = e.Mailer.Send(core.MailConfig{
+		TemplateName: "extended",
+		Subject:      "You've been given more time for your " + assignment.Test.Business.Name + " technical test",
+		From:         "candidates",
+		To:           assignment.CandidateEmail,
+	}, extensionEmailData{
+		Assignment: assignment,
+		Minutes:    minutes,
+		Deadline:   readableIn(deadline, assignment.TestTimezoneChosen),
+	})
+	if err != nil {
+		return Extension{}, fmt.Errorf("could not send extension email to candidate %s %w", assignment.CandidateEmail, err)
+	}
+
+	return Extension{
+		AssignmentID: assignmentID,
+		Minutes:      minutes,
+		Deadline:     deadline,
+	}, nil
+}
+
+// reschedule moves the next pending end or cleanup step, returning the previous and new deadline.
+func (e Extender) reschedule(assignment WithTestDetails, extension time.Duration) (time.Time, time.Time, error) {
+	end, err := e.Ledger.GetStep(assignment.ID, "end")
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("could not get end step from ledger %w", err)
+	}
+
+	// the deadline falls WarningBeforeEnd after the end step, at the same time as the cleanup step.
+	step, offset := end, e.WarningBeforeEnd
+	if !end.Pending() {
+		step, err = e.Ledger.GetStep(assignment.ID, "cleanup")
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("could not get cleanup step from ledger %w", err)
+		}
+
+		offset = 0
+	}
+
+	if !step.Pending() {
+		return time.Time{}, time.Time{}, ErrNoPendingEnd
+	}
+
+	err = e.SchedulerClient.Stop(step.EventID)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("could not stop pending %s step %w", step.Step, err)
+	}
+
+	scheduleAt := step.ScheduleAt.Add(extension)
+	_, err = scheduleStep(e.SchedulerClient, e.Ledger, StartInput{
+		Type:       step.Step,
+		ID:         int64(assignment.ID),
+		ScheduleAt: scheduleAt.Format(time.RFC3339),
+		Data:       assignment,
+	})
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("could not reschedule %s step %w", step.Step, err)
+	}
+
+	return step.ScheduleAt.Add(offset), scheduleAt.Add(offset), nil
+}
+
+// moveSteps pushes back the pending end reminders of the assignment by the extension. For staged tests the
+// pending start of the next stage is pushed back too, so that the candidate gets the extra time on the current
+// stage and every later stage still ends at the new deadline. The current stage ends when the next stage starts,
+// or at the new deadline of the assignment if it is the last stage, and its recorded deadline is moved to match.
+func (e Extender) moveSteps(assignment WithTestDetails, extension time.Duration, deadline time.Time) error {
+	var steps []string
+	for _, r := range assignment.Test.reminders("end") {
+		steps = append(steps, r.Step())
+	}
+
+	for _, s := range assignment.Test.Stages {
+		steps = append(steps, s.Step())
+	}
+
+	current := len(assignment.Test.Stages) - 1
+	for _, step := range steps {
+		record, err := e.Ledger.GetStep(assignment.ID, step)
+		if err != nil {
+			return fmt.Errorf("could not get step %s from ledger %w", step, err)
+		}
+
+		if !record.Pending() {
+			continue
+		}
+
+		err = e.SchedulerClient.Stop(record.EventID)
+		if err != nil {
+			return fmt.Errorf("could not stop pending step %s %w", step, err)
+		}
+
+		scheduleAt := record.ScheduleAt.Add(extension)
+		_, err = scheduleStep(e.SchedulerClient, e.Ledger, StartInput{
+			Type:       step,
+			ID:         int64(assignment.ID),
+			ScheduleAt: scheduleAt.Format(time.RFC3339),
+			Data:       assignment,
+		})
+		if err != nil {
+			return fmt.Errorf("could not reschedule step %s %w", step, err)
+		}
+
+		if i, ok := assignment.Test.stage(step); ok && i > 0 {
+			current, deadline = i-1, scheduleAt
+		}
+	}
+
+	if current < 0 {
+		return nil
+	}
+
+	err := e.StageRecorder.ExtendStage(assignment.ID, assignment.Test.Stages[current].Position, deadline)
+	if err != nil {
+		return fmt.Errorf("could not extend stage %d %w", assignment.Test.Stages[current].Position, err)
+	}
+
+	return nil
+}
+
+// readableIn formats t in the timezone tz, falling back to UTC if tz is not a valid location.
+func readableIn(t time.Time, tz string) string {
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	return t.In(loc).Format("Mon 02 January 15:04 MST")
+}
@@ -0,0 +1,235 @@
+package assignment_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+	"github.com/testrelay/testrelay/backend/internal/core/assignment"
+	"github.com/testrelay/testrelay/backend/internal/core/assignment/mocks"
+	coreMocks "github.com/testrelay/testrelay/backend/internal/core/mocks"
+)
+
+func TestExtender(t *testing.T) {
+	endAt := time.Date(2021, 11, 12, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Extend", func(t *testing.T) {
+		t.Run("should reschedule the pending end step", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			fetcher := mocks.NewMockFetcher(ctrl)
+			sc := mocks.NewMockSchedulerClient(ctrl)
+			ledger := mocks.NewMockStepLedger(ctrl)
+			recorder := mocks.NewMockEventRecorder(ctrl)
+			mailer := coreMocks.NewMockMailer(ctrl)
+
+			e := assignment.Extender{
+				Fetcher:          fetcher,
+				SchedulerClient:  sc,
+				Ledger:           ledger,
+				EventRecorder:    recorder,
+				Mailer:           mailer,
+				WarningBeforeEnd: time.Minute * 10,
+			}
+
+			a := assignment.WithTestDetails{
+				ID:                 12,
+				Status:             "inprogress",
+				CandidateEmail:     "jane@testrelay.io",
+				TestTimezoneChosen: "Europe/London",
+				Test:               assignment.Test{Business: assignment.Business{Name: "TestRelay"}},
+			}
+			fetcher.EXPECT().GetAssignment(12).Return(a, nil)
+			ledger.EXPECT().GetStep(12, "end").Return(assignment.StepRecord{
+				AssignmentID: 12,
+				Step:         "end",
+				EventID:      "event-1",
+				ScheduleAt:   endAt,
+			}, nil)
+			sc.EXPECT().Stop("event-1").Return(nil)
+
+			newEndAt := endAt.Add(time.Minute * 30)
+			sc.EXPECT().Start(assignment.StartInput{
+				Type:       "end",
+				ID:         12,
+				ScheduleAt: newEndAt.Format(time.RFC3339),
+				Data:       a,
+			}).Return("event-2", nil)
+			ledger.EXPECT().ScheduleStep(12, "end", "event-2", newEndAt).Return(nil)
+
+			recorder.EXPECT().RecordAssignmentEvent(7, 12, "extended", map[string]interface{}{
+				"minutes":           30,
+				"previous_deadline": "2021-11-12T12:10:00Z",
+				"deadline":          "2021-11-12T12:40:00Z",
+			}).Return(nil)
+			mailer.EXPECT().Send(core.MailConfig{
+				TemplateName: "extended",
+				Subject:      "You've been given more time for your TestRelay technical test",
+				From:         "candidates",
+				To:           "jane@testrelay.io",
+			}, gomock.Any()).Return(nil)
+
+			ext, err := e.Extend(12, 30, 7)
+			require.NoError(t, err)
+
+			assert.Equal(t, assignment.Extension{
+				AssignmentID: 12,
+				Minutes:      30,
+				Deadline:     newEndAt.Add(time.Minute * 10),
+			}, ext)
+		})
+
+		t.Run("should reschedule cleanup once the end step has run", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			fetcher := mocks.NewMockFetcher(ctrl)
+			sc := mocks.NewMockSchedulerClient(ctrl)
+			ledger := mocks.NewMockStepLedger(ctrl)
+			recorder := mocks.NewMockEventRecorder(ctrl)
+			mailer := coreMocks.NewMockMailer(ctrl)
+
+			e := assignment.Extender{
+				Fetcher:          fetcher,
+				SchedulerClient:  sc,
+				Ledger:           ledger,
+				EventRecorder:    recorder,
+				Mailer:           mailer,
+				WarningBeforeEnd: time.Minute * 10,
+			}
+
+			a := assignment.WithTestDetails{ID: 12, Status: "inprogress"}
+			cleanupAt := endAt.Add(time.Minute * 10)
+			fetcher.EXPECT().GetAssignment(12).Return(a, nil)
+			ledger.EXPECT().GetStep(12, "end").Return(assignment.StepRecord{
+				AssignmentID: 12,
+				Step:         "end",
+				EventID:      "event-1",
+				Completed:    true,
+			}, nil)
+			ledger.EXPECT().GetStep(12, "cleanup").Return(assignment.StepRecord{
+				AssignmentID: 12,
+				Step:         "cleanup",
+				EventID:      "event-2",
+				ScheduleAt:   cleanupAt,
+			}, nil)
+			sc.EXPECT().Stop("event-2").Return(nil)
+			sc.EXPECT().Start(assignment.StartInput{
+				Type:       "cleanup",
+				ID:         12,
+				ScheduleAt: cleanupAt.Add(time.Minute * 5).Format(time.RFC3339),
+				Data:       a,
+			}).Return("event-3", nil)
+			ledger.EXPECT().ScheduleStep(12, "cleanup", "event-3", cleanupAt.Add(time.Minute*5)).Return(nil)
+			recorder.EXPECT().RecordAssignmentEvent(7, 12, "extended", gomock.Any()).Return(nil)
+			mailer.EXPECT().Send(gomock.Any(), gomock.Any()).Return(nil)
+
+			ext, err := e.Extend(12, 5, 7)
+			require.NoError(t, err)
+			assert.Equal(t, cleanupAt.Add(time.Minute*5), ext.Deadline)
+		})
+
+		t.Run("should move the pending start of the next stage of a staged test", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			fetcher := mocks.NewMockFetcher(ctrl)
+			sc := mocks.NewMockSchedulerClient(ctrl)
+			ledger := mocks.NewMockStepLedger(ctrl)
+			recorder := mocks.NewMockEventRecorder(ctrl)
+			stages := mocks.NewMockStageRecorder(ctrl)
+			mailer := coreMocks.NewMockMailer(ctrl)
+
+			e := assignment.Extender{
+				Fetcher:          fetcher,
+				SchedulerClient:  sc,
+				Ledger:           ledger,
+				EventRecorder:    recorder,
+				StageRecorder:    stages,
+				Mailer:           mailer,
+				WarningBeforeEnd: time.Minute * 10,
+			}
+
+			a := assignment.WithTestDetails{ID: 12, Status: "inprogress", Test: assignment.Test{Stages: []assignment.Stage{
+				{Position: 1, TimeLimit: 3600},
+				{Position: 2, TimeLimit: 3600},
+				{Position: 3, TimeLimit: 3600},
+			}}}
+			stage3At := endAt.Add(-time.Minute * 50)
+			fetcher.EXPECT().GetAssignment(12).Return(a, nil)
+			ledger.EXPECT().GetStep(12, "end").Return(assignment.StepRecord{
+				AssignmentID: 12,
+				Step:         "end",
+				EventID:      "event-1",
+				ScheduleAt:   endAt,
+			}, nil)
+			sc.EXPECT().Stop("event-1").Return(nil)
+			sc.EXPECT().Start(gomock.Any()).Return("event-2", nil)
+			ledger.EXPECT().ScheduleStep(12, "end", "event-2", gomock.Any()).Return(nil)
+
+			// the first two stages have already started, leaving only the third pending.
+			ledger.EXPECT().GetStep(12, "stage:1").Return(assignment.StepRecord{}, nil)
+			ledger.EXPECT().GetStep(12, "stage:2").Return(assignment.StepRecord{
+				AssignmentID: 12,
+				Step:         "stage:2",
+				EventID:      "event-3",
+				Completed:    true,
+			}, nil)
+			ledger.EXPECT().GetStep(12, "stage:3").Return(assignment.StepRecord{
+				AssignmentID: 12,
+				Step:         "stage:3",
+				EventID:      "event-4",
+				ScheduleAt:   stage3At,
+				Attempts:     1,
+			}, nil)
+			sc.EXPECT().Stop("event-4").Return(nil)
+			sc.EXPECT().Start(assignment.StartInput{
+				Type:       "stage:3",
+				ID:         12,
+				ScheduleAt: stage3At.Add(time.Minute * 30).Format(time.RFC3339),
+				Data:       a,
+			}).Return("event-5", nil)
+			ledger.EXPECT().ScheduleStep(12, "stage:3", "event-5", stage3At.Add(time.Minute*30)).Return(nil)
+			// the second stage is in progress and now ends when the third stage starts.
+			stages.EXPECT().ExtendStage(12, 2, stage3At.Add(time.Minute*30)).Return(nil)
+
+			recorder.EXPECT().RecordAssignmentEvent(7, 12, "extended", gomock.Any()).Return(nil)
+			mailer.EXPECT().Send(gomock.Any(), gomock.Any()).Return(nil)
+
+			ext, err := e.Extend(12, 30, 7)
+			require.NoError(t, err)
+			assert.Equal(t, endAt.Add(time.Minute*40), ext.Deadline)
+		})
+
+		t.Run("should error if the assignment is not in progress", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			fetcher := mocks.NewMockFetcher(ctrl)
+
+			e := assignment.Extender{Fetcher: fetcher}
+
+			fetcher.EXPECT().GetAssignment(12).Return(assignment.WithTestDetails{ID: 12, Status: "scheduled"}, nil)
+
+			_, err := e.Extend(12, 30, 7)
+			assert.ErrorIs(t, err, assignment.ErrNotInProgress)
+		})
+
+		t.Run("should error if there is no pending step to move", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			fetcher := mocks.NewMockFetcher(ctrl)
+			ledger := mocks.NewMockStepLedger(ctrl)
+
+			e := assignment.Extender{Fetcher: fetcher, Ledger: ledger}
+
+			fetcher.EXPECT().GetAssignment(12).Return(assignment.WithTestDetails{ID: 12, Status: "inprogress"}, nil)
+			ledger.EXPECT().GetStep(12, "end").Return(assignment.StepRecord{}, nil)
+			ledger.EXPECT().GetStep(12, "cleanup").Return(assignment.StepRecord{}, nil)
+
+			_, err := e.Extend(12, 30, 7)
+			assert.ErrorIs(t, err, assignment.ErrNoPendingEnd)
+		})
+
+		t.Run("should error on a non positive extension", func(t *testing.T) {
+			_, err := assignment.Extender{}.Extend(12, 0, 7)
+			assert.ErrorIs(t, err, assignment.ErrInvalidExtension)
+		})
+	})
+}
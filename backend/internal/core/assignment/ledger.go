@@ -0,0 +1,125 @@
+package assignment
+
+//go:generate mockgen -destination mocks/ledger.go -package mocks . StepLedger
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// stepClaim is how long a run of a step holds its claim on the step, see StepLedger.ClaimStep. It is well above
+// the time any step takes to run, so that a claim only expires if the run holding it has died.
+const stepClaim = time.Minute * 15
+
+// StepRecord is an entry in the step ledger. It holds the progress of a single step for an assignment.
+// A step is made up of a number of actions, e.g. upload files, send email, each of which are recorded
+// in Actions once they have succeeded.
+type StepRecord struct {
+	AssignmentID int
+	Step         string
+	// EventID is the id of the scheduled event returned by the SchedulerClient that triggers the step.
+	EventID    string
+	ScheduleAt time.Time
+	// Actions holds the completion time of each action that has run for the step.
+	Actions   map[string]time.Time
+	Completed bool
+	// Attempts is the number of times the step has failed. See RetryRunner.
+	Attempts int
+}
+
+// Pending returns whether the step has been scheduled but has not yet completed.
+func (s StepRecord) Pending() bool {
+	return s.EventID != "" && !s.Completed
+}
+
+// StepLedger defines an interface for a type that persists the progress of assignment steps.
+// GetStep must return a zero StepRecord and no error if the step has no entry.
+type StepLedger interface {
+	GetStep(assignmentID int, step string) (StepRecord, error)
+	SaveStep(record StepRecord) error
+	// ScheduleStep records the scheduled event that triggers the step, leaving the progress recorded for the
+	// step untouched.
+	ScheduleStep(assignmentID int, step string, eventID string, scheduleAt time.Time) error
+	// ClaimStep atomically claims a step that has not completed until the given time, so that only one run of
+	// the step can be in progress. It returns false if the step has completed or another run holds the claim.
+	ClaimStep(assignmentID int, step string, until time.Time) (bool, error)
+	// ReleaseStep releases the claim on the step so that it can be run again.
+	ReleaseStep(assignmentID int, step string) error
+}
+
+// stepProgress tracks the progress of a step that is running, making sure that
+// actions that have already completed in a previous run are skipped.
+type stepProgress struct {
+	ledger StepLedger
+	record StepRecord
+	now    Time
+}
+
+// do runs f if the action has not already completed, recording its completion in the ledger.
+func (p *stepProgress) do(action string, f func() error) error {
+	if _, ok := p.record.Actions[action]; ok {
+		return nil
+	}
+
+	err := f()
+	if err != nil {
+		return err
+	}
+
+	if p.record.Actions == nil {
+		p.record.Actions = make(map[string]time.Time)
+	}
+	p.record.Actions[action] = p.now()
+
+	err = p.ledger.SaveStep(p.record)
+	if err != nil {
+		return fmt.Errorf("could not record action %s for step %s %w", action, p.record.Step, err)
+	}
+
+	return nil
+}
+
+// mark records the action as completed if it has not already completed and returns the time it completed.
+// Steps mark when they first ran, so that times derived from it, e.g. deadlines, are the same when a step resumes.
+func (p *stepProgress) mark(action string) (time.Time, error) {
+	err := p.do(action, func() error { return nil })
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return p.record.Actions[action], nil
+}
+
+// decide runs f if the action has not already completed, recording the outcome f returns with the action.
+// It returns the recorded outcome, so that a step which resumes acts on the same outcome as the run that failed.
+func (p *stepProgress) decide(action string, f func() (string, error)) (string, error) {
+	for a := range p.record.Actions {
+		if strings.HasPrefix(a, action+":") {
+			return strings.TrimPrefix(a, action+":"), nil
+		}
+	}
+
+	outcome, err := f()
+	if err != nil {
+		return "", err
+	}
+
+	err = p.do(action+":"+outcome, func() error { return nil })
+	if err != nil {
+		return "", err
+	}
+
+	return outcome, nil
+}
+
+// complete marks the step as completed in the ledger.
+func (p *stepProgress) complete() error {
+	p.record.Completed = true
+
+	err := p.ledger.SaveStep(p.record)
+	if err != nil {
+		return fmt.Errorf("could not mark step %s as completed %w", p.record.Step, err)
+	}
+
+	return nil
+}
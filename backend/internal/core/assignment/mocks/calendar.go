@@ -0,0 +1,50 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/testrelay/testrelay/backend/internal/core/assignment (interfaces: EventFetcher)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	assignment "github.com/testrelay/testrelay/backend/internal/core/assignment"
+)
+
+// MockEventFetcher is a mock of EventFetcher interface.
+type MockEventFetcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockEventFetcherMockRecorder
+}
+
+// MockEventFetcherMockRecorder is the mock recorder for MockEventFetcher.
+type MockEventFetcherMockRecorder struct {
+	mock *MockEventFetcher
+}
+
+// NewMockEventFetcher creates a new mock instance.
+func NewMockEventFetcher(ctrl *gomock.Controller) *MockEventFetcher {
+	mock := &MockEventFetcher{ctrl: ctrl}
+	mock.recorder = &MockEventFetcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockEventFetcher) EXPECT() *MockEventFetcherMockRecorder {
+	return m.recorder
+}
+
+// Events mocks base method.
+func (m *MockEventFetcher) Events(arg0 int) ([]assignment.Event, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Events", arg0)
+	ret0, _ := ret[0].([]assignment.Event)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Events indicates an expected call of Events.
+func (mr *MockEventFetcherMockRecorder) Events(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Events", reflect.TypeOf((*MockEventFetcher)(nil).Events), arg0)
+}
@@ -0,0 +1,51 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/testrelay/testrelay/backend/internal/core/assignment (interfaces: ExpiryFetcher)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	assignment "github.com/testrelay/testrelay/backend/internal/core/assignment"
+)
+
+// MockExpiryFetcher is a mock of ExpiryFetcher interface.
+type MockExpiryFetcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockExpiryFetcherMockRecorder
+}
+
+// MockExpiryFetcherMockRecorder is the mock recorder for MockExpiryFetcher.
+type MockExpiryFetcherMockRecorder struct {
+	mock *MockExpiryFetcher
+}
+
+// NewMockExpiryFetcher creates a new mock instance.
+func NewMockExpiryFetcher(ctrl *gomock.Controller) *MockExpiryFetcher {
+	mock := &MockExpiryFetcher{ctrl: ctrl}
+	mock.recorder = &MockExpiryFetcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockExpiryFetcher) EXPECT() *MockExpiryFetcherMockRecorder {
+	return m.recorder
+}
+
+// UnscheduledBefore mocks base method.
+func (m *MockExpiryFetcher) UnscheduledBefore(arg0 time.Time) ([]assignment.WithTestDetails, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnscheduledBefore", arg0)
+	ret0, _ := ret[0].([]assignment.WithTestDetails)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnscheduledBefore indicates an expected call of UnscheduledBefore.
+func (mr *MockExpiryFetcherMockRecorder) UnscheduledBefore(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnscheduledBefore", reflect.TypeOf((*MockExpiryFetcher)(nil).UnscheduledBefore), arg0)
+}
@@ -0,0 +1,48 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/testrelay/testrelay/backend/internal/core/assignment (interfaces: EventRecorder)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockEventRecorder is a mock of EventRecorder interface.
+type MockEventRecorder struct {
+	ctrl     *gomock.Controller
+	recorder *MockEventRecorderMockRecorder
+}
+
+// MockEventRecorderMockRecorder is the mock recorder for MockEventRecorder.
+type MockEventRecorderMockRecorder struct {
+	mock *MockEventRecorder
+}
+
+// NewMockEventRecorder creates a new mock instance.
+func NewMockEventRecorder(ctrl *gomock.Controller) *MockEventRecorder {
+	mock := &MockEventRecorder{ctrl: ctrl}
+	mock.recorder = &MockEventRecorderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockEventRecorder) EXPECT() *MockEventRecorderMockRecorder {
+	return m.recorder
+}
+
+// RecordAssignmentEvent mocks base method.
+func (m *MockEventRecorder) RecordAssignmentEvent(arg0, arg1 int, arg2 string, arg3 map[string]interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordAssignmentEvent", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordAssignmentEvent indicates an expected call of RecordAssignmentEvent.
+func (mr *MockEventRecorderMockRecorder) RecordAssignmentEvent(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordAssignmentEvent", reflect.TypeOf((*MockEventRecorder)(nil).RecordAssignmentEvent), arg0, arg1, arg2, arg3)
+}
@@ -0,0 +1,108 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/testrelay/testrelay/backend/internal/core/assignment (interfaces: StepLedger)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	assignment "github.com/testrelay/testrelay/backend/internal/core/assignment"
+)
+
+// MockStepLedger is a mock of StepLedger interface.
+type MockStepLedger struct {
+	ctrl     *gomock.Controller
+	recorder *MockStepLedgerMockRecorder
+}
+
+// MockStepLedgerMockRecorder is the mock recorder for MockStepLedger.
+type MockStepLedgerMockRecorder struct {
+	mock *MockStepLedger
+}
+
+// NewMockStepLedger creates a new mock instance.
+func NewMockStepLedger(ctrl *gomock.Controller) *MockStepLedger {
+	mock := &MockStepLedger{ctrl: ctrl}
+	mock.recorder = &MockStepLedgerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStepLedger) EXPECT() *MockStepLedgerMockRecorder {
+	return m.recorder
+}
+
+// ClaimStep mocks base method.
+func (m *MockStepLedger) ClaimStep(arg0 int, arg1 string, arg2 time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimStep", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimStep indicates an expected call of ClaimStep.
+func (mr *MockStepLedgerMockRecorder) ClaimStep(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimStep", reflect.TypeOf((*MockStepLedger)(nil).ClaimStep), arg0, arg1, arg2)
+}
+
+// GetStep mocks base method.
+func (m *MockStepLedger) GetStep(arg0 int, arg1 string) (assignment.StepRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStep", arg0, arg1)
+	ret0, _ := ret[0].(assignment.StepRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStep indicates an expected call of GetStep.
+func (mr *MockStepLedgerMockRecorder) GetStep(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStep", reflect.TypeOf((*MockStepLedger)(nil).GetStep), arg0, arg1)
+}
+
+// ReleaseStep mocks base method.
+func (m *MockStepLedger) ReleaseStep(arg0 int, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseStep", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseStep indicates an expected call of ReleaseStep.
+func (mr *MockStepLedgerMockRecorder) ReleaseStep(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseStep", reflect.TypeOf((*MockStepLedger)(nil).ReleaseStep), arg0, arg1)
+}
+
+// SaveStep mocks base method.
+func (m *MockStepLedger) SaveStep(arg0 assignment.StepRecord) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveStep", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveStep indicates an expected call of SaveStep.
+func (mr *MockStepLedgerMockRecorder) SaveStep(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveStep", reflect.TypeOf((*MockStepLedger)(nil).SaveStep), arg0)
+}
+
+// ScheduleStep mocks base method.
+func (m *MockStepLedger) ScheduleStep(arg0 int, arg1, arg2 string, arg3 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ScheduleStep", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ScheduleStep indicates an expected call of ScheduleStep.
+func (mr *MockStepLedgerMockRecorder) ScheduleStep(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScheduleStep", reflect.TypeOf((*MockStepLedger)(nil).ScheduleStep), arg0, arg1, arg2, arg3)
+}
@@ -0,0 +1,64 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/testrelay/testrelay/backend/internal/core/assignment (interfaces: StartUpdater)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockStartUpdater is a mock of StartUpdater interface.
+type MockStartUpdater struct {
+	ctrl     *gomock.Controller
+	recorder *MockStartUpdaterMockRecorder
+}
+
+// MockStartUpdaterMockRecorder is the mock recorder for MockStartUpdater.
+type MockStartUpdaterMockRecorder struct {
+	mock *MockStartUpdater
+}
+
+// NewMockStartUpdater creates a new mock instance.
+func NewMockStartUpdater(ctrl *gomock.Controller) *MockStartUpdater {
+	mock := &MockStartUpdater{ctrl: ctrl}
+	mock.recorder = &MockStartUpdaterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStartUpdater) EXPECT() *MockStartUpdaterMockRecorder {
+	return m.recorder
+}
+
+// UpdateAssignmentStarted mocks base method.
+func (m *MockStartUpdater) UpdateAssignmentStarted(arg0 int, arg1 time.Time, arg2 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAssignmentStarted", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateAssignmentStarted indicates an expected call of UpdateAssignmentStarted.
+func (mr *MockStartUpdaterMockRecorder) UpdateAssignmentStarted(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAssignmentStarted", reflect.TypeOf((*MockStartUpdater)(nil).UpdateAssignmentStarted), arg0, arg1, arg2)
+}
+
+// UpdateAssignmentWithDetails mocks base method.
+func (m *MockStartUpdater) UpdateAssignmentWithDetails(arg0 int, arg1, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAssignmentWithDetails", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateAssignmentWithDetails indicates an expected call of UpdateAssignmentWithDetails.
+func (mr *MockStartUpdaterMockRecorder) UpdateAssignmentWithDetails(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAssignmentWithDetails", reflect.TypeOf((*MockStartUpdater)(nil).UpdateAssignmentWithDetails), arg0, arg1, arg2)
+}
@@ -0,0 +1,87 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/testrelay/testrelay/backend/internal/core/assignment (interfaces: TestFetcher,ValidationRecorder)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	assignment "github.com/testrelay/testrelay/backend/internal/core/assignment"
+)
+
+// MockTestFetcher is a mock of TestFetcher interface.
+type MockTestFetcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockTestFetcherMockRecorder
+}
+
+// MockTestFetcherMockRecorder is the mock recorder for MockTestFetcher.
+type MockTestFetcherMockRecorder struct {
+	mock *MockTestFetcher
+}
+
+// NewMockTestFetcher creates a new mock instance.
+func NewMockTestFetcher(ctrl *gomock.Controller) *MockTestFetcher {
+	mock := &MockTestFetcher{ctrl: ctrl}
+	mock.recorder = &MockTestFetcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTestFetcher) EXPECT() *MockTestFetcherMockRecorder {
+	return m.recorder
+}
+
+// GetTest mocks base method.
+func (m *MockTestFetcher) GetTest(arg0 int) (assignment.Test, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTest", arg0)
+	ret0, _ := ret[0].(assignment.Test)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTest indicates an expected call of GetTest.
+func (mr *MockTestFetcherMockRecorder) GetTest(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTest", reflect.TypeOf((*MockTestFetcher)(nil).GetTest), arg0)
+}
+
+// MockValidationRecorder is a mock of ValidationRecorder interface.
+type MockValidationRecorder struct {
+	ctrl     *gomock.Controller
+	recorder *MockValidationRecorderMockRecorder
+}
+
+// MockValidationRecorderMockRecorder is the mock recorder for MockValidationRecorder.
+type MockValidationRecorderMockRecorder struct {
+	mock *MockValidationRecorder
+}
+
+// NewMockValidationRecorder creates a new mock instance.
+func NewMockValidationRecorder(ctrl *gomock.Controller) *MockValidationRecorder {
+	mock := &MockValidationRecorder{ctrl: ctrl}
+	mock.recorder = &MockValidationRecorderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockValidationRecorder) EXPECT() *MockValidationRecorderMockRecorder {
+	return m.recorder
+}
+
+// RecordTestValidation mocks base method.
+func (m *MockValidationRecorder) RecordTestValidation(arg0 int, arg1 assignment.ValidationReport) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordTestValidation", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordTestValidation indicates an expected call of RecordTestValidation.
+func (mr *MockValidationRecorderMockRecorder) RecordTestValidation(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordTestValidation", reflect.TypeOf((*MockValidationRecorder)(nil).RecordTestValidation), arg0, arg1)
+}
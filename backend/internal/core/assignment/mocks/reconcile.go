@@ -0,0 +1,65 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/testrelay/testrelay/backend/internal/core/assignment (interfaces: ReconcileFetcher)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	assignment "github.com/testrelay/testrelay/backend/internal/core/assignment"
+)
+
+// MockReconcileFetcher is a mock of ReconcileFetcher interface.
+type MockReconcileFetcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockReconcileFetcherMockRecorder
+}
+
+// MockReconcileFetcherMockRecorder is the mock recorder for MockReconcileFetcher.
+type MockReconcileFetcherMockRecorder struct {
+	mock *MockReconcileFetcher
+}
+
+// NewMockReconcileFetcher creates a new mock instance.
+func NewMockReconcileFetcher(ctrl *gomock.Controller) *MockReconcileFetcher {
+	mock := &MockReconcileFetcher{ctrl: ctrl}
+	mock.recorder = &MockReconcileFetcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReconcileFetcher) EXPECT() *MockReconcileFetcherMockRecorder {
+	return m.recorder
+}
+
+// Events mocks base method.
+func (m *MockReconcileFetcher) Events(arg0 int) ([]assignment.Event, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Events", arg0)
+	ret0, _ := ret[0].([]assignment.Event)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Events indicates an expected call of Events.
+func (mr *MockReconcileFetcherMockRecorder) Events(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Events", reflect.TypeOf((*MockReconcileFetcher)(nil).Events), arg0)
+}
+
+// InFlight mocks base method.
+func (m *MockReconcileFetcher) InFlight() ([]assignment.WithTestDetails, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InFlight")
+	ret0, _ := ret[0].([]assignment.WithTestDetails)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InFlight indicates an expected call of InFlight.
+func (mr *MockReconcileFetcherMockRecorder) InFlight() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InFlight", reflect.TypeOf((*MockReconcileFetcher)(nil).InFlight))
+}
@@ -0,0 +1,78 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/testrelay/testrelay/backend/internal/core/assignment (interfaces: DeadLetterQueue)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	assignment "github.com/testrelay/testrelay/backend/internal/core/assignment"
+)
+
+// MockDeadLetterQueue is a mock of DeadLetterQueue interface.
+type MockDeadLetterQueue struct {
+	ctrl     *gomock.Controller
+	recorder *MockDeadLetterQueueMockRecorder
+}
+
+// MockDeadLetterQueueMockRecorder is the mock recorder for MockDeadLetterQueue.
+type MockDeadLetterQueueMockRecorder struct {
+	mock *MockDeadLetterQueue
+}
+
+// NewMockDeadLetterQueue creates a new mock instance.
+func NewMockDeadLetterQueue(ctrl *gomock.Controller) *MockDeadLetterQueue {
+	mock := &MockDeadLetterQueue{ctrl: ctrl}
+	mock.recorder = &MockDeadLetterQueueMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDeadLetterQueue) EXPECT() *MockDeadLetterQueueMockRecorder {
+	return m.recorder
+}
+
+// AddDeadLetter mocks base method.
+func (m *MockDeadLetterQueue) AddDeadLetter(arg0 assignment.DeadLetter) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddDeadLetter", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddDeadLetter indicates an expected call of AddDeadLetter.
+func (mr *MockDeadLetterQueueMockRecorder) AddDeadLetter(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddDeadLetter", reflect.TypeOf((*MockDeadLetterQueue)(nil).AddDeadLetter), arg0)
+}
+
+// GetDeadLetter mocks base method.
+func (m *MockDeadLetterQueue) GetDeadLetter(arg0 int) (assignment.DeadLetter, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeadLetter", arg0)
+	ret0, _ := ret[0].(assignment.DeadLetter)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeadLetter indicates an expected call of GetDeadLetter.
+func (mr *MockDeadLetterQueueMockRecorder) GetDeadLetter(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeadLetter", reflect.TypeOf((*MockDeadLetterQueue)(nil).GetDeadLetter), arg0)
+}
+
+// MarkReplayed mocks base method.
+func (m *MockDeadLetterQueue) MarkReplayed(arg0 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkReplayed", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkReplayed indicates an expected call of MarkReplayed.
+func (mr *MockDeadLetterQueueMockRecorder) MarkReplayed(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkReplayed", reflect.TypeOf((*MockDeadLetterQueue)(nil).MarkReplayed), arg0)
+}
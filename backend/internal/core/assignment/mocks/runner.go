@@ -0,0 +1,123 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/testrelay/testrelay/backend/internal/core/assignment (interfaces: EventCreator,ReviewerCollector,CommitRecorder)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockEventCreator is a mock of EventCreator interface.
+type MockEventCreator struct {
+	ctrl     *gomock.Controller
+	recorder *MockEventCreatorMockRecorder
+}
+
+// MockEventCreatorMockRecorder is the mock recorder for MockEventCreator.
+type MockEventCreatorMockRecorder struct {
+	mock *MockEventCreator
+}
+
+// NewMockEventCreator creates a new mock instance.
+func NewMockEventCreator(ctrl *gomock.Controller) *MockEventCreator {
+	mock := &MockEventCreator{ctrl: ctrl}
+	mock.recorder = &MockEventCreatorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockEventCreator) EXPECT() *MockEventCreatorMockRecorder {
+	return m.recorder
+}
+
+// NewAssignmentEvent mocks base method.
+func (m *MockEventCreator) NewAssignmentEvent(arg0, arg1 int, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewAssignmentEvent", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// NewAssignmentEvent indicates an expected call of NewAssignmentEvent.
+func (mr *MockEventCreatorMockRecorder) NewAssignmentEvent(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewAssignmentEvent", reflect.TypeOf((*MockEventCreator)(nil).NewAssignmentEvent), arg0, arg1, arg2)
+}
+
+// MockReviewerCollector is a mock of ReviewerCollector interface.
+type MockReviewerCollector struct {
+	ctrl     *gomock.Controller
+	recorder *MockReviewerCollectorMockRecorder
+}
+
+// MockReviewerCollectorMockRecorder is the mock recorder for MockReviewerCollector.
+type MockReviewerCollectorMockRecorder struct {
+	mock *MockReviewerCollector
+}
+
+// NewMockReviewerCollector creates a new mock instance.
+func NewMockReviewerCollector(ctrl *gomock.Controller) *MockReviewerCollector {
+	mock := &MockReviewerCollector{ctrl: ctrl}
+	mock.recorder = &MockReviewerCollectorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReviewerCollector) EXPECT() *MockReviewerCollectorMockRecorder {
+	return m.recorder
+}
+
+// Reviewers mocks base method.
+func (m *MockReviewerCollector) Reviewers(arg0 int) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reviewers", arg0)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reviewers indicates an expected call of Reviewers.
+func (mr *MockReviewerCollectorMockRecorder) Reviewers(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reviewers", reflect.TypeOf((*MockReviewerCollector)(nil).Reviewers), arg0)
+}
+
+// MockCommitRecorder is a mock of CommitRecorder interface.
+type MockCommitRecorder struct {
+	ctrl     *gomock.Controller
+	recorder *MockCommitRecorderMockRecorder
+}
+
+// MockCommitRecorderMockRecorder is the mock recorder for MockCommitRecorder.
+type MockCommitRecorderMockRecorder struct {
+	mock *MockCommitRecorder
+}
+
+// NewMockCommitRecorder creates a new mock instance.
+func NewMockCommitRecorder(ctrl *gomock.Controller) *MockCommitRecorder {
+	mock := &MockCommitRecorder{ctrl: ctrl}
+	mock.recorder = &MockCommitRecorderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCommitRecorder) EXPECT() *MockCommitRecorderMockRecorder {
+	return m.recorder
+}
+
+// RecordTestCommit mocks base method.
+func (m *MockCommitRecorder) RecordTestCommit(arg0 int, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordTestCommit", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordTestCommit indicates an expected call of RecordTestCommit.
+func (mr *MockCommitRecorderMockRecorder) RecordTestCommit(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordTestCommit", reflect.TypeOf((*MockCommitRecorder)(nil).RecordTestCommit), arg0, arg1)
+}
@@ -0,0 +1,77 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/testrelay/testrelay/backend/internal/core/assignment (interfaces: StageRecorder)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockStageRecorder is a mock of StageRecorder interface.
+type MockStageRecorder struct {
+	ctrl     *gomock.Controller
+	recorder *MockStageRecorderMockRecorder
+}
+
+// MockStageRecorderMockRecorder is the mock recorder for MockStageRecorder.
+type MockStageRecorderMockRecorder struct {
+	mock *MockStageRecorder
+}
+
+// NewMockStageRecorder creates a new mock instance.
+func NewMockStageRecorder(ctrl *gomock.Controller) *MockStageRecorder {
+	mock := &MockStageRecorder{ctrl: ctrl}
+	mock.recorder = &MockStageRecorderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStageRecorder) EXPECT() *MockStageRecorderMockRecorder {
+	return m.recorder
+}
+
+// ExtendStage mocks base method.
+func (m *MockStageRecorder) ExtendStage(arg0, arg1 int, arg2 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExtendStage", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExtendStage indicates an expected call of ExtendStage.
+func (mr *MockStageRecorderMockRecorder) ExtendStage(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExtendStage", reflect.TypeOf((*MockStageRecorder)(nil).ExtendStage), arg0, arg1, arg2)
+}
+
+// FinishStage mocks base method.
+func (m *MockStageRecorder) FinishStage(arg0, arg1 int, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FinishStage", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FinishStage indicates an expected call of FinishStage.
+func (mr *MockStageRecorderMockRecorder) FinishStage(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinishStage", reflect.TypeOf((*MockStageRecorder)(nil).FinishStage), arg0, arg1, arg2)
+}
+
+// StartStage mocks base method.
+func (m *MockStageRecorder) StartStage(arg0, arg1 int, arg2, arg3 string, arg4, arg5 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartStage", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StartStage indicates an expected call of StartStage.
+func (mr *MockStageRecorderMockRecorder) StartStage(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartStage", reflect.TypeOf((*MockStageRecorder)(nil).StartStage), arg0, arg1, arg2, arg3, arg4, arg5)
+}
@@ -0,0 +1,49 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/testrelay/testrelay/backend/internal/core/assignment (interfaces: RepoFinder)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockRepoFinder is a mock of RepoFinder interface.
+type MockRepoFinder struct {
+	ctrl     *gomock.Controller
+	recorder *MockRepoFinderMockRecorder
+}
+
+// MockRepoFinderMockRecorder is the mock recorder for MockRepoFinder.
+type MockRepoFinderMockRecorder struct {
+	mock *MockRepoFinder
+}
+
+// NewMockRepoFinder creates a new mock instance.
+func NewMockRepoFinder(ctrl *gomock.Controller) *MockRepoFinder {
+	mock := &MockRepoFinder{ctrl: ctrl}
+	mock.recorder = &MockRepoFinderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRepoFinder) EXPECT() *MockRepoFinderMockRecorder {
+	return m.recorder
+}
+
+// AssignmentIDByRepoURL mocks base method.
+func (m *MockRepoFinder) AssignmentIDByRepoURL(arg0 string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AssignmentIDByRepoURL", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AssignmentIDByRepoURL indicates an expected call of AssignmentIDByRepoURL.
+func (mr *MockRepoFinderMockRecorder) AssignmentIDByRepoURL(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AssignmentIDByRepoURL", reflect.TypeOf((*MockRepoFinder)(nil).AssignmentIDByRepoURL), arg0)
+}
@@ -0,0 +1,141 @@
+package assignment
+
+//go:generate mockgen -destination mocks/ondemand.go -package mocks . StartUpdater
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+)
+
+const (
+	// ModeScheduled assignments start at the day and time chosen by the candidate.
+	ModeScheduled = "scheduled"
+	// ModeOnDemand assignments start as soon as the candidate asks to start, see Starter.
+	ModeOnDemand = "on_demand"
+)
+
+var (
+	ErrNotOnDemand        = errors.New("assignment cannot be started on demand")
+	ErrAlreadyStarted     = errors.New("assignment has already been started")
+	ErrChooseUntilExpired = errors.New("assignment can no longer be started")
+)
+
+// StartUpdater defines an interface for a type that records an on demand start against an assignment.
+type StartUpdater interface {
+	// UpdateAssignmentStarted moves an assignment that is sent or viewed to scheduled, setting the chosen day, time
+	// and timezone to startedAt in the timezone. The update is conditional on the status of the assignment, so
+	// that only one start can win. It returns false if the assignment is no longer sent or viewed.
+	UpdateAssignmentStarted(id int, startedAt time.Time, timezone string) (bool, error)
+	UpdateAssignmentWithDetails(id int, runID string, url string) error
+}
+
+// Start holds the details of an assignment that has been started on demand.
+type Start struct {
+	AssignmentID int
+	StartedAt    time.Time
+	Deadline     time.Time
+}
+
+// Starter starts on demand assignments straight away at the request of the candidate.
+type Starter struct {
+	Fetcher    Fetcher
+	VCSCreator core.VCSCreator
+	// TemplateCreator creates the repos of tests that are generated from a github template repo.
+	TemplateCreator core.VCSCreator
+	Updater         StartUpdater
+	SchedulerClient SchedulerClient
+	Ledger          StepLedger
+	Time            Time
+}
+
+// Start schedules the init step of an on demand assignment to run straight away for the candidate with the
+// given userID, skipping the warning that scheduled assignments send before they start. The assignment is
+// recorded as started in the candidate's timezone. The end and cleanup steps are scheduled by init relative
+// to the time the assignment actually started. Start errors with ErrNotOnDemand, ErrNotCandidate,
+// ErrAlreadyStarted or ErrChooseUntilExpired if the assignment cannot be started by the candidate.
+func (s Starter) Start(assignmentID, userID int, timezone string) (Start, error) {
+	assignment, err := s.Fetcher.GetAssignment(assignmentID)
+	if err != nil {
+		return Start{}, fmt.Errorf("could not fetch assignment id %d %w", assignmentID, err)
+	}
+
+	if assignment.Mode != ModeOnDemand {
+		return Start{}, ErrNotOnDemand
+	}
+
+	if assignment.CandidateID != userID {
+		return Start{}, ErrNotCandidate
+	}
+
+	if assignment.Status != "sent" && assignment.Status != "viewed" {
+		return Start{}, ErrAlreadyStarted
+	}
+
+	loc, err := time.LoadLocation(timezone)
+	if timezone == "" || err != nil {
+		return Start{}, fmt.Errorf("could not load timezone %q %w", timezone, err)
+	}
+
+	now := s.Time().UTC()
+	chooseUntil, err := time.Parse("2006-01-02", assignment.ChooseUntil)
+	if err != nil {
+		return Start{}, fmt.Errorf("could not parse choose until %s %w", assignment.ChooseUntil, err)
+	}
+
+	if now.Truncate(time.Hour * 24).After(chooseUntil) {
+		return Start{}, ErrChooseUntilExpired
+	}
+
+	// the assignment is moved to scheduled before anything else, so that a concurrent start of the same
+	// assignment cannot create a second repo or init it twice. An init that fails to be scheduled after
+	// this point is repaired by the Reconciler.
+	startedAt := now.Truncate(time.Second)
+	ok, err := s.Updater.UpdateAssignmentStarted(assignment.ID, startedAt, timezone)
+	if err != nil {
+		return Start{}, fmt.Errorf("could not update assignment with start details %w", err)
+	}
+
+	if !ok {
+		return Start{}, ErrAlreadyStarted
+	}
+
+	assignment.Status = "scheduled"
+	assignment.TestDayChosen = startedAt.In(loc).Format("2006-01-02")
+	assignment.TestTimeChosen = startedAt.In(loc).Format("15:04:05")
+	assignment.TestTimezoneChosen = timezone
+
+	if assignment.GithubRepoURL == "" {
+		creator, err := assignment.repoCreator(s.VCSCreator, s.TemplateCreator)
+		if err != nil {
+			return Start{}, fmt.Errorf("could not generate repo for assignment %w", err)
+		}
+
+		assignment.GithubRepoURL, err = creator.CreateRepo(assignment.createDetails())
+		if err != nil {
+			return Start{}, fmt.Errorf("could not generate repo for assignment %w", err)
+		}
+	}
+
+	schedulerID, err := scheduleNewStep(s.SchedulerClient, s.Ledger, StartInput{
+		Type:       "init",
+		ID:         int64(assignment.ID),
+		ScheduleAt: startedAt.Format(time.RFC3339),
+		Data:       assignment,
+	})
+	if err != nil {
+		return Start{}, fmt.Errorf("could not schedule assignment %d to init %w", assignment.ID, err)
+	}
+
+	err = s.Updater.UpdateAssignmentWithDetails(assignment.ID, schedulerID, assignment.GithubRepoURL)
+	if err != nil {
+		return Start{}, fmt.Errorf("could not update assignment with schedule details %w", err)
+	}
+
+	return Start{
+		AssignmentID: assignment.ID,
+		StartedAt:    startedAt,
+		Deadline:     startedAt.Add(time.Second * time.Duration(assignment.timeLimit())),
+	}, nil
+}
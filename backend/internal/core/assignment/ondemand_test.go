@@ -0,0 +1,255 @@
+package assignment_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+	"github.com/testrelay/testrelay/backend/internal/core/assignment"
+	"github.com/testrelay/testrelay/backend/internal/core/assignment/mocks"
+	coreMocks "github.com/testrelay/testrelay/backend/internal/core/mocks"
+)
+
+func TestStarter(t *testing.T) {
+	now := time.Date(2021, 11, 12, 10, 30, 0, 0, time.UTC)
+	onDemand := assignment.WithTestDetails{
+		ID:          12,
+		Mode:        assignment.ModeOnDemand,
+		Status:      "viewed",
+		ChooseUntil: "2021-11-12",
+		CandidateID: 7,
+		TimeLimit:   7200,
+		Candidate:   assignment.Candidate{GithubUsername: "jane"},
+		Test: assignment.Test{
+			Business:     assignment.Business{Name: "TestRelay"},
+			GithubRepo:   "https://github.com/testrelay/test",
+			GithubRef:    "v1.0",
+			TemplateGlob: "README.md",
+		},
+	}
+
+	t.Run("Start", func(t *testing.T) {
+		t.Run("should schedule init straight away in the candidate's timezone", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			fetcher := mocks.NewMockFetcher(ctrl)
+			creator := coreMocks.NewMockVCSCreator(ctrl)
+			updater := mocks.NewMockStartUpdater(ctrl)
+			sc := mocks.NewMockSchedulerClient(ctrl)
+			ledger := mocks.NewMockStepLedger(ctrl)
+
+			s := assignment.Starter{
+				Fetcher:         fetcher,
+				VCSCreator:      creator,
+				Updater:         updater,
+				SchedulerClient: sc,
+				Ledger:          ledger,
+				Time:            func() time.Time { return now },
+			}
+
+			started := onDemand
+			started.Status = "scheduled"
+			started.GithubRepoURL = "https://github.com/testrelay/jane"
+			started.TestDayChosen = "2021-11-12"
+			started.TestTimeChosen = "05:30:00"
+			started.TestTimezoneChosen = "America/New_York"
+
+			fetcher.EXPECT().GetAssignment(12).Return(onDemand, nil)
+			gomock.InOrder(
+				updater.EXPECT().UpdateAssignmentStarted(12, now, "America/New_York").Return(true, nil),
+				creator.EXPECT().CreateRepo(core.CreateDetails{BusinessName: "TestRelay", Username: "jane", ID: 12}).Return("https://github.com/testrelay/jane", nil),
+				ledger.EXPECT().SaveStep(assignment.StepRecord{AssignmentID: 12, Step: "init"}).Return(nil),
+				sc.EXPECT().Start(assignment.StartInput{
+					Type:       "init",
+					ID:         12,
+					ScheduleAt: "2021-11-12T10:30:00Z",
+					Data:       started,
+				}).Return("event-1", nil),
+				ledger.EXPECT().ScheduleStep(12, "init", "event-1", now).Return(nil),
+				updater.EXPECT().UpdateAssignmentWithDetails(12, "event-1", "https://github.com/testrelay/jane").Return(nil),
+			)
+
+			start, err := s.Start(12, 7, "America/New_York")
+			require.NoError(t, err)
+
+			assert.Equal(t, assignment.Start{
+				AssignmentID: 12,
+				StartedAt:    now,
+				Deadline:     now.Add(time.Hour * 2),
+			}, start)
+		})
+
+		t.Run("should generate the repo from a template", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			fetcher := mocks.NewMockFetcher(ctrl)
+			templates := coreMocks.NewMockVCSCreator(ctrl)
+			updater := mocks.NewMockStartUpdater(ctrl)
+			sc := mocks.NewMockSchedulerClient(ctrl)
+			ledger := mocks.NewMockStepLedger(ctrl)
+
+			s := assignment.Starter{
+				Fetcher:         fetcher,
+				VCSCreator:      coreMocks.NewMockVCSCreator(ctrl),
+				TemplateCreator: templates,
+				Updater:         updater,
+				SchedulerClient: sc,
+				Ledger:          ledger,
+				Time:            func() time.Time { return now },
+			}
+
+			a := onDemand
+			a.Test.UploadMode = core.UploadModeTemplate
+			a.Test.TemplateOwner = "acme"
+			a.Test.Business.GithubInstallationID = 99
+
+			fetcher.EXPECT().GetAssignment(12).Return(a, nil)
+			updater.EXPECT().UpdateAssignmentStarted(12, now, "UTC").Return(true, nil)
+			templates.EXPECT().CreateRepo(core.CreateDetails{
+				BusinessName:   "TestRelay",
+				Username:       "jane",
+				ID:             12,
+				TemplateRepo:   "https://github.com/testrelay/test",
+				TemplateOwner:  "acme",
+				InstallationID: 99,
+			}).Return("https://github.com/acme/jane", nil)
+			ledger.EXPECT().SaveStep(gomock.Any()).Return(nil)
+			sc.EXPECT().Start(gomock.Any()).Return("event-1", nil)
+			ledger.EXPECT().ScheduleStep(12, "init", "event-1", now).Return(nil)
+			updater.EXPECT().UpdateAssignmentWithDetails(12, "event-1", "https://github.com/acme/jane").Return(nil)
+
+			_, err := s.Start(12, 7, "UTC")
+			require.NoError(t, err)
+		})
+
+		t.Run("should not start an assignment that a concurrent request has started", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			fetcher := mocks.NewMockFetcher(ctrl)
+			updater := mocks.NewMockStartUpdater(ctrl)
+
+			s := assignment.Starter{
+				Fetcher:    fetcher,
+				VCSCreator: coreMocks.NewMockVCSCreator(ctrl),
+				Updater:    updater,
+				Time:       func() time.Time { return now },
+			}
+
+			fetcher.EXPECT().GetAssignment(12).Return(onDemand, nil)
+			updater.EXPECT().UpdateAssignmentStarted(12, now, "UTC").Return(false, nil)
+
+			_, err := s.Start(12, 7, "UTC")
+			assert.ErrorIs(t, err, assignment.ErrAlreadyStarted)
+		})
+
+		t.Run("should error for an invalid timezone", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			fetcher := mocks.NewMockFetcher(ctrl)
+
+			s := assignment.Starter{Fetcher: fetcher, Time: func() time.Time { return now }}
+
+			fetcher.EXPECT().GetAssignment(12).Return(onDemand, nil)
+
+			_, err := s.Start(12, 7, "Mars/Olympus")
+			assert.Error(t, err)
+		})
+
+		t.Run("should create the repo in the business org through its installation", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			fetcher := mocks.NewMockFetcher(ctrl)
+			creator := coreMocks.NewMockVCSCreator(ctrl)
+
+			updater := mocks.NewMockStartUpdater(ctrl)
+
+			s := assignment.Starter{
+				Fetcher:    fetcher,
+				VCSCreator: creator,
+				Updater:    updater,
+				Time:       func() time.Time { return now },
+			}
+
+			a := onDemand
+			a.Test.Business.GithubInstallationID = 99
+			a.Test.Business.GithubOrg = "acme-hiring"
+			fetcher.EXPECT().GetAssignment(12).Return(a, nil)
+			updater.EXPECT().UpdateAssignmentStarted(12, now, "UTC").Return(true, nil)
+			creator.EXPECT().CreateRepo(core.CreateDetails{
+				BusinessName:   "TestRelay",
+				Username:       "jane",
+				ID:             12,
+				Org:            "acme-hiring",
+				InstallationID: 99,
+			}).Return("", errors.New("installation cannot create repos"))
+
+			_, err := s.Start(12, 7, "UTC")
+			assert.Error(t, err)
+		})
+
+		t.Run("should not generate repos from a template for tests with stages", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			fetcher := mocks.NewMockFetcher(ctrl)
+
+			updater := mocks.NewMockStartUpdater(ctrl)
+
+			s := assignment.Starter{
+				Fetcher:         fetcher,
+				VCSCreator:      coreMocks.NewMockVCSCreator(ctrl),
+				TemplateCreator: coreMocks.NewMockVCSCreator(ctrl),
+				Updater:         updater,
+				Time:            func() time.Time { return now },
+			}
+
+			a := onDemand
+			a.Test.UploadMode = core.UploadModeTemplate
+			a.Test.Stages = []assignment.Stage{{Position: 1}, {Position: 2}}
+			fetcher.EXPECT().GetAssignment(12).Return(a, nil)
+			updater.EXPECT().UpdateAssignmentStarted(12, now, "UTC").Return(true, nil)
+
+			_, err := s.Start(12, 7, "UTC")
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "has stages and cannot be generated from a template")
+		})
+
+		t.Run("should not start assignments that cannot be started on demand", func(t *testing.T) {
+			scheduled := onDemand
+			scheduled.Mode = assignment.ModeScheduled
+
+			started := onDemand
+			started.Status = "inprogress"
+
+			expired := onDemand
+			expired.ChooseUntil = "2021-11-11"
+
+			tests := []struct {
+				name     string
+				a        assignment.WithTestDetails
+				userID   int
+				expected error
+			}{
+				{name: "scheduled mode", a: scheduled, userID: 7, expected: assignment.ErrNotOnDemand},
+				{name: "another user", a: onDemand, userID: 8, expected: assignment.ErrNotCandidate},
+				{name: "already started", a: started, userID: 7, expected: assignment.ErrAlreadyStarted},
+				{name: "past choose until", a: expired, userID: 7, expected: assignment.ErrChooseUntilExpired},
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					ctrl := gomock.NewController(t)
+					fetcher := mocks.NewMockFetcher(ctrl)
+
+					s := assignment.Starter{
+						Fetcher: fetcher,
+						Time:    func() time.Time { return now },
+					}
+
+					fetcher.EXPECT().GetAssignment(12).Return(tt.a, nil)
+
+					_, err := s.Start(12, tt.userID, "UTC")
+					assert.ErrorIs(t, err, tt.expected)
+				})
+			}
+		})
+	})
+}
@@ -0,0 +1,170 @@
+package assignment
+
+//go:generate mockgen -destination mocks/preflight.go -package mocks . TestFetcher,ValidationRecorder
+import (
+	"fmt"
+	"time"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+)
+
+// TestFetcher defines an interface for a type that fetches a test along with its business and stages.
+type TestFetcher interface {
+	GetTest(id int) (Test, error)
+}
+
+// ValidationRecorder defines an interface for a type that stores the preflight report of a test.
+type ValidationRecorder interface {
+	RecordTestValidation(testID int, report ValidationReport) error
+}
+
+// ValidationReport is the outcome of checking that each source repo of a test can be uploaded.
+type ValidationReport struct {
+	Passed    bool           `json:"passed"`
+	CheckedAt time.Time      `json:"checked_at"`
+	Sources   []SourceReport `json:"sources"`
+	// Errors are problems with the test itself rather than one of its sources, e.g. duplicate reminders.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// SourceReport is the outcome of checking a single source repo of a test.
+type SourceReport struct {
+	// Stage is the position of the stage that is uploaded from the source, or 0 for a test without stages.
+	Stage int    `json:"stage"`
+	Repo  string `json:"repo"`
+	Ref   string `json:"ref"`
+	Path  string `json:"path"`
+	// SHA is the commit that Ref resolved to, empty if the source could not be downloaded.
+	SHA      string   `json:"sha"`
+	Files    int      `json:"files"`
+	Size     int64    `json:"size"`
+	Excluded []string `json:"excluded"`
+	Errors   []string `json:"errors"`
+}
+
+// Preflight checks the source repos of a test when it is created or changed, so that a broken repo is
+// found before an assignment fails to start.
+type Preflight struct {
+	Fetcher   TestFetcher
+	Validator core.VCSValidator
+	Recorder  ValidationRecorder
+	Time      Time
+
+	// MaxFiles and MaxSize limit the number of files, and their total size in bytes, that a source can
+	// upload. A zero limit is not checked.
+	MaxFiles int
+	MaxSize  int64
+}
+
+// Check downloads and unpacks each source of the test through the business's installation, as Runner does
+// when an assignment starts, and checks it against the limits, along with the test's reminders. The report is
+// stored on the test and returned.
+// Problems with a source or the test fail the report, Check only errors if the test cannot be fetched or the report stored.
+func (p Preflight) Check(testID int) (ValidationReport, error) {
+	t, err := p.Fetcher.GetTest(testID)
+	if err != nil {
+		return ValidationReport{}, fmt.Errorf("could not fetch test %d %w", testID, err)
+	}
+
+	report := ValidationReport{Passed: true, CheckedAt: p.Time()}
+	if err := t.remindersErr(); err != nil {
+		report.Passed = false
+		report.Errors = append(report.Errors, "test "+err.Error())
+	}
+
+	for _, s := range t.sources() {
+		s = p.check(t, s)
+		if len(s.Errors) > 0 {
+			report.Passed = false
+		}
+
+		report.Sources = append(report.Sources, s)
+	}
+
+	err = p.Recorder.RecordTestValidation(testID, report)
+	if err != nil {
+		return ValidationReport{}, fmt.Errorf("could not record validation of test %d %w", testID, err)
+	}
+
+	return report, nil
+}
+
+func (p Preflight) check(t Test, s SourceReport) SourceReport {
+	s.Excluded = []string{}
+	s.Errors = []string{}
+	if s.Repo == "" {
+		s.Errors = append(s.Errors, "no source repo is set")
+		return s
+	}
+
+	if t.fromTemplate() {
+		if err := t.templateErr(); err != nil {
+			s.Errors = append(s.Errors, "test "+err.Error())
+		}
+	}
+
+	res, err := p.Validator.Validate(core.UploadDetails{
+		TestVCSRepoURL: s.Repo,
+		Ref:            s.Ref,
+		Path:           s.Path,
+		InstallationID: t.Business.installationID(),
+		Workspace:      t.Business.BitbucketWorkspace,
+		Provider:       t.Business.VCSProvider,
+		TemplateGlob:   t.TemplateGlob,
+		TemplateData:   core.TemplateData{BusinessName: t.Business.Name, TestName: t.Name},
+	})
+	if err != nil {
+		s.Errors = append(s.Errors, err.Error())
+		return s
+	}
+
+	s.SHA = res.SHA
+	s.Files = res.Files
+	s.Size = res.Size
+	if res.Excluded != nil {
+		s.Excluded = res.Excluded
+	}
+
+	if res.Files == 0 {
+		s.Errors = append(s.Errors, "there are no files to upload")
+	}
+
+	if p.MaxFiles > 0 && res.Files > p.MaxFiles {
+		s.Errors = append(s.Errors, fmt.Sprintf("%d files exceeds the limit of %d", res.Files, p.MaxFiles))
+	}
+
+	if p.MaxSize > 0 && res.Size > p.MaxSize {
+		s.Errors = append(s.Errors, fmt.Sprintf("%d bytes exceeds the limit of %d", res.Size, p.MaxSize))
+	}
+
+	return s
+}
+
+// sources returns the repo, ref and path uploaded for each stage of the test, or for the test itself if it
+// has no stages. Stages that share a source with an earlier stage are only returned once. Repos generated from
+// a template hold the default branch of the whole test repo, so the ref and path are not returned.
+func (t Test) sources() []SourceReport {
+	if t.fromTemplate() && len(t.Stages) == 0 {
+		return []SourceReport{{Repo: t.GithubRepo}}
+	}
+
+	if len(t.Stages) == 0 {
+		return []SourceReport{{Repo: t.GithubRepo, Ref: t.GithubRef, Path: t.GithubPath}}
+	}
+
+	type key struct{ repo, ref, path string }
+	seen := make(map[key]struct{})
+
+	var sources []SourceReport
+	for _, s := range t.Stages {
+		k := key{s.repo(t), s.ref(t), s.path(t)}
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+
+		sources = append(sources, SourceReport{Stage: s.Position, Repo: k.repo, Ref: k.ref, Path: k.path})
+	}
+
+	return sources
+}
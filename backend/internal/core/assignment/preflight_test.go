@@ -0,0 +1,207 @@
+package assignment_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+	"github.com/testrelay/testrelay/backend/internal/core/assignment"
+	"github.com/testrelay/testrelay/backend/internal/core/assignment/mocks"
+	coreMocks "github.com/testrelay/testrelay/backend/internal/core/mocks"
+)
+
+func TestPreflight(t *testing.T) {
+	now := time.Date(2021, 12, 2, 10, 30, 0, 0, time.UTC)
+
+	setup := func(t *testing.T) (assignment.Preflight, *mocks.MockTestFetcher, *coreMocks.MockVCSValidator, *mocks.MockValidationRecorder) {
+		ctrl := gomock.NewController(t)
+		fetcher := mocks.NewMockTestFetcher(ctrl)
+		validator := coreMocks.NewMockVCSValidator(ctrl)
+		recorder := mocks.NewMockValidationRecorder(ctrl)
+
+		return assignment.Preflight{
+			Fetcher:   fetcher,
+			Validator: validator,
+			Recorder:  recorder,
+			Time:      func() time.Time { return now },
+			MaxFiles:  10,
+			MaxSize:   1024,
+		}, fetcher, validator, recorder
+	}
+
+	business := assignment.Business{Name: "TestRelay", GithubInstallationID: 99}
+
+	t.Run("Check", func(t *testing.T) {
+		t.Run("should pass a test whose repo can be uploaded", func(t *testing.T) {
+			p, fetcher, validator, recorder := setup(t)
+
+			fetcher.EXPECT().GetTest(12).Return(assignment.Test{
+				Business:     business,
+				Name:         "Backend",
+				GithubRepo:   "https://github.com/acme/monorepo",
+				GithubRef:    "v1.0",
+				GithubPath:   "backend",
+				TemplateGlob: "*.md",
+			}, nil)
+			validator.EXPECT().Validate(core.UploadDetails{
+				TestVCSRepoURL: "https://github.com/acme/monorepo",
+				Ref:            "v1.0",
+				Path:           "backend",
+				InstallationID: 99,
+				TemplateGlob:   "*.md",
+				TemplateData:   core.TemplateData{BusinessName: "TestRelay", TestName: "Backend"},
+			}).Return(core.ValidateResult{
+				UploadResult: core.UploadResult{SHA: "abc123", Excluded: []string{"solution.go"}},
+				Files:        3,
+				Size:         512,
+			}, nil)
+
+			expected := assignment.ValidationReport{
+				Passed:    true,
+				CheckedAt: now,
+				Sources: []assignment.SourceReport{{
+					Repo:     "https://github.com/acme/monorepo",
+					Ref:      "v1.0",
+					Path:     "backend",
+					SHA:      "abc123",
+					Files:    3,
+					Size:     512,
+					Excluded: []string{"solution.go"},
+					Errors:   []string{},
+				}},
+			}
+			recorder.EXPECT().RecordTestValidation(12, expected).Return(nil)
+
+			report, err := p.Check(12)
+			require.NoError(t, err)
+			assert.Equal(t, expected, report)
+		})
+
+		t.Run("should fail a test whose repo breaks the limits or cannot be downloaded", func(t *testing.T) {
+			p, fetcher, validator, recorder := setup(t)
+
+			fetcher.EXPECT().GetTest(12).Return(assignment.Test{
+				Business:   business,
+				GithubRepo: "https://github.com/acme/backend",
+				Stages: []assignment.Stage{
+					{Position: 1},
+					{Position: 2},
+					{Position: 3, GithubRepo: "https://github.com/acme/removed"},
+				},
+			}, nil)
+			validator.EXPECT().Validate(gomock.Any()).DoAndReturn(func(data core.UploadDetails) (core.ValidateResult, error) {
+				if data.TestVCSRepoURL == "https://github.com/acme/removed" {
+					return core.ValidateResult{}, errors.New("could not download repo contents")
+				}
+
+				return core.ValidateResult{UploadResult: core.UploadResult{SHA: "abc123"}, Files: 11, Size: 2048}, nil
+			}).Times(2)
+
+			var report assignment.ValidationReport
+			recorder.EXPECT().RecordTestValidation(12, gomock.Any()).DoAndReturn(func(id int, r assignment.ValidationReport) error {
+				report = r
+				return nil
+			})
+
+			res, err := p.Check(12)
+			require.NoError(t, err)
+			assert.Equal(t, report, res)
+			assert.False(t, res.Passed)
+
+			require.Len(t, res.Sources, 2)
+			assert.Equal(t, 1, res.Sources[0].Stage)
+			assert.Equal(t, []string{
+				"11 files exceeds the limit of 10",
+				"2048 bytes exceeds the limit of 1024",
+			}, res.Sources[0].Errors)
+			assert.Equal(t, 3, res.Sources[1].Stage)
+			assert.Equal(t, []string{"could not download repo contents"}, res.Sources[1].Errors)
+		})
+
+		t.Run("should fail a test without a repo or files", func(t *testing.T) {
+			p, fetcher, validator, recorder := setup(t)
+
+			fetcher.EXPECT().GetTest(12).Return(assignment.Test{
+				Business: business,
+				Stages: []assignment.Stage{
+					{Position: 1},
+					{Position: 2, GithubRepo: "https://github.com/acme/empty"},
+				},
+			}, nil)
+			validator.EXPECT().Validate(gomock.Any()).Return(core.ValidateResult{}, nil)
+			recorder.EXPECT().RecordTestValidation(12, gomock.Any()).Return(nil)
+
+			res, err := p.Check(12)
+			require.NoError(t, err)
+			assert.False(t, res.Passed)
+			require.Len(t, res.Sources, 2)
+			assert.Equal(t, []string{"no source repo is set"}, res.Sources[0].Errors)
+			assert.Equal(t, []string{"there are no files to upload"}, res.Sources[1].Errors)
+		})
+
+		t.Run("should check the default branch of a template test", func(t *testing.T) {
+			p, fetcher, validator, recorder := setup(t)
+
+			fetcher.EXPECT().GetTest(12).Return(assignment.Test{
+				Business:   assignment.Business{Name: "TestRelay", GithubInstallationID: 99, VCSProvider: core.VCSProviderGitlab},
+				GithubRepo: "https://github.com/acme/template",
+				GithubRef:  "v1.0",
+				GithubPath: "backend",
+				UploadMode: core.UploadModeTemplate,
+			}, nil)
+			validator.EXPECT().Validate(gomock.Any()).DoAndReturn(func(data core.UploadDetails) (core.ValidateResult, error) {
+				assert.Equal(t, "https://github.com/acme/template", data.TestVCSRepoURL)
+				assert.Equal(t, core.VCSProviderGitlab, data.Provider)
+				assert.Empty(t, data.Ref)
+				assert.Empty(t, data.Path)
+				return core.ValidateResult{Files: 1, Size: 1}, nil
+			})
+			recorder.EXPECT().RecordTestValidation(12, gomock.Any()).Return(nil)
+
+			res, err := p.Check(12)
+			require.NoError(t, err)
+			assert.False(t, res.Passed)
+			require.Len(t, res.Sources, 1)
+			assert.Equal(t, []string{"test cannot be generated from a template on gitlab"}, res.Sources[0].Errors)
+		})
+
+		t.Run("should fail a test with duplicate reminders", func(t *testing.T) {
+			p, fetcher, validator, recorder := setup(t)
+
+			fetcher.EXPECT().GetTest(12).Return(assignment.Test{
+				Business:   business,
+				GithubRepo: "https://github.com/acme/backend",
+				Reminders: []assignment.Reminder{
+					{Before: "end", Minutes: 30},
+					{Before: "start", Minutes: 30},
+					{Before: "end", Minutes: 30, Template: "custom-end"},
+				},
+			}, nil)
+			validator.EXPECT().Validate(gomock.Any()).Return(core.ValidateResult{Files: 1, Size: 1}, nil)
+			recorder.EXPECT().RecordTestValidation(12, gomock.Any()).Return(nil)
+
+			res, err := p.Check(12)
+			require.NoError(t, err)
+			assert.False(t, res.Passed)
+			assert.Equal(t, []string{"test has more than one reminder 30 minutes before the end"}, res.Errors)
+			require.Len(t, res.Sources, 1)
+			assert.Empty(t, res.Sources[0].Errors)
+		})
+
+		t.Run("should error if the report cannot be recorded", func(t *testing.T) {
+			p, fetcher, validator, recorder := setup(t)
+
+			fetcher.EXPECT().GetTest(12).Return(assignment.Test{Business: business, GithubRepo: "https://github.com/acme/backend"}, nil)
+			validator.EXPECT().Validate(gomock.Any()).Return(core.ValidateResult{Files: 1, Size: 1}, nil)
+			recorder.EXPECT().RecordTestValidation(12, gomock.Any()).Return(errors.New("hasura down"))
+
+			_, err := p.Check(12)
+			assert.Error(t, err)
+		})
+	})
+}
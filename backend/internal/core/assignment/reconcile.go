@@ -0,0 +1,163 @@
+package assignment
+
+//go:generate mockgen -destination mocks/reconcile.go -package mocks . ReconcileFetcher
+import (
+	"fmt"
+	"time"
+
+	intTime "github.com/testrelay/testrelay/backend/internal/time"
+)
+
+// Event is an entry in the event history of an assignment.
+type Event struct {
+	Type      string
+	CreatedAt time.Time
+}
+
+// ReconcileFetcher defines an interface for a type that fetches assignments that are still in flight
+// and the events recorded against them.
+type ReconcileFetcher interface {
+	// InFlight returns all scheduled or inprogress assignments.
+	InFlight() ([]WithTestDetails, error)
+	// Events returns the events for the assignment, oldest first.
+	Events(assignmentID int) ([]Event, error)
+}
+
+// Repair is a step that the Reconciler found missing for an assignment. An assignment that could not be checked,
+// e.g. because its events could not be fetched, is reported without a Step and with the reason in Error.
+type Repair struct {
+	AssignmentID int       `json:"assignment_id"`
+	Status       string    `json:"status"`
+	Step         string    `json:"step"`
+	Due          time.Time `json:"due"`
+	Applied      bool      `json:"applied"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// Reconciler finds assignments that are stuck because a scheduled step never ran, e.g. an assignment that
+// is still inprogress long after its deadline, and re-runs the missing step using the Runner, so that a repair
+// that fails with a transient error is retried like any other run.
+type Reconciler struct {
+	Fetcher ReconcileFetcher
+	Runner  RetryRunner
+	Time    Time
+	// Grace is how long a step can be overdue before it is considered missing.
+	Grace time.Duration
+}
+
+// Reconcile compares the status and chosen time of each in flight assignment with the events recorded
+// against it, returning a Repair for each assignment with an overdue init, end or cleanup step.
+// When apply is false Reconcile only reports the missing steps. When apply is true each missing step is
+// run. Errors checking or repairing an assignment are recorded on its Repair rather than stopping the remaining
+// assignments.
+func (r Reconciler) Reconcile(apply bool) ([]Repair, error) {
+	assignments, err := r.Fetcher.InFlight()
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch in flight assignments %w", err)
+	}
+
+	repairs := []Repair{}
+	for _, a := range assignments {
+		repair, ok, err := r.missing(a)
+		if err != nil {
+			repairs = append(repairs, Repair{AssignmentID: a.ID, Status: a.Status, Error: err.Error()})
+			continue
+		}
+
+		if !ok {
+			continue
+		}
+
+		if apply {
+			err := r.Runner.Run(repair.Step, RunData{Data: a})
+			if err != nil {
+				repair.Error = err.Error()
+			}
+			repair.Applied = err == nil
+		}
+
+		repairs = append(repairs, repair)
+	}
+
+	return repairs, nil
+}
+
+// missing returns the overdue step for the assignment, if any.
+func (r Reconciler) missing(a WithTestDetails) (Repair, bool, error) {
+	t, err := intTime.Parse(intTime.AssignmentChoices{
+		DayChosen:  a.TestDayChosen,
+		TimeChosen: a.TestTimeChosen,
+		Timezone:   a.TestTimezoneChosen,
+	})
+	if err != nil {
+		return Repair{}, false, fmt.Errorf("could not parse chosen time for assignment %d %w", a.ID, err)
+	}
+
+	start, err := time.Parse(time.RFC3339, t.StartAssignmentAt)
+	if err != nil {
+		return Repair{}, false, fmt.Errorf("could not parse start time for assignment %d %w", a.ID, err)
+	}
+
+	events, err := r.Fetcher.Events(a.ID)
+	if err != nil {
+		return Repair{}, false, fmt.Errorf("could not fetch events for assignment %d %w", a.ID, err)
+	}
+
+	var started *Event
+	for i, e := range events {
+		switch e.Type {
+		case "submitted", "missed":
+			return Repair{}, false, nil
+		case "inprogress":
+			started = &events[i]
+		}
+	}
+
+	now := r.Time()
+	repair := Repair{AssignmentID: a.ID, Status: a.Status}
+	if started == nil {
+		repair.Step, repair.Due = "init", start
+
+		return repair, now.After(start.Add(r.Grace)), nil
+	}
+
+	deadline, ended, err := r.deadline(a, started.CreatedAt)
+	if err != nil {
+		return Repair{}, false, err
+	}
+
+	endAt := deadline.Add(-r.Runner.Runner.WarningBeforeEnd)
+	switch {
+	case now.After(deadline.Add(r.Grace)):
+		repair.Step, repair.Due = "cleanup", deadline
+	case !ended && now.After(endAt.Add(r.Grace)):
+		repair.Step, repair.Due = "end", endAt
+	default:
+		return Repair{}, false, nil
+	}
+
+	return repair, true, nil
+}
+
+// deadline returns the deadline of an in progress assignment that started at startedAt, and whether the
+// end step has completed. Scheduled cleanup and end steps take precedence, so that extensions are respected.
+func (r Reconciler) deadline(a WithTestDetails, startedAt time.Time) (time.Time, bool, error) {
+	end, err := r.Runner.Runner.Ledger.GetStep(a.ID, "end")
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("could not get end step from ledger %w", err)
+	}
+
+	cleanup, err := r.Runner.Runner.Ledger.GetStep(a.ID, "cleanup")
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("could not get cleanup step from ledger %w", err)
+	}
+
+	switch {
+	case !cleanup.ScheduleAt.IsZero():
+		return cleanup.ScheduleAt, end.Completed, nil
+	case !end.ScheduleAt.IsZero():
+		return end.ScheduleAt.Add(r.Runner.Runner.WarningBeforeEnd), end.Completed, nil
+	default:
+		return startedAt.Add(time.Second * time.Duration(a.timeLimit())), end.Completed, nil
+	}
+}
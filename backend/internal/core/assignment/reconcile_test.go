@@ -0,0 +1,188 @@
+package assignment_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+	"github.com/testrelay/testrelay/backend/internal/core/assignment"
+	"github.com/testrelay/testrelay/backend/internal/core/assignment/mocks"
+	coreMocks "github.com/testrelay/testrelay/backend/internal/core/mocks"
+)
+
+func TestReconciler(t *testing.T) {
+	logger := zap.NewNop().Sugar()
+	start := time.Date(2021, 11, 12, 10, 0, 0, 0, time.UTC)
+	chosen := assignment.WithTestDetails{
+		TestDayChosen:      "2021-11-12",
+		TestTimeChosen:     "10:00:00",
+		TestTimezoneChosen: "UTC",
+		TimeLimit:          7200,
+	}
+
+	newAssignment := func(id int, status string) assignment.WithTestDetails {
+		a := chosen
+		a.ID = id
+		a.Status = status
+		return a
+	}
+
+	t.Run("Reconcile", func(t *testing.T) {
+		t.Run("should report missing steps without running them in dry run mode", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			fetcher := mocks.NewMockReconcileFetcher(ctrl)
+			ledger := mocks.NewMockStepLedger(ctrl)
+
+			r := assignment.Reconciler{
+				Fetcher: fetcher,
+				Runner:  assignment.RetryRunner{Runner: assignment.Runner{Ledger: ledger, WarningBeforeEnd: time.Minute * 10}},
+				Time:    func() time.Time { return start.Add(time.Hour * 3) },
+				Grace:   time.Minute * 15,
+			}
+
+			fetcher.EXPECT().InFlight().Return([]assignment.WithTestDetails{
+				newAssignment(1, "scheduled"),
+				newAssignment(2, "inprogress"),
+				newAssignment(3, "inprogress"),
+			}, nil)
+
+			fetcher.EXPECT().Events(1).Return([]assignment.Event{{Type: "scheduled", CreatedAt: start.Add(-time.Hour)}}, nil)
+
+			fetcher.EXPECT().Events(2).Return([]assignment.Event{{Type: "inprogress", CreatedAt: start}}, nil)
+			ledger.EXPECT().GetStep(2, "end").Return(assignment.StepRecord{}, nil)
+			ledger.EXPECT().GetStep(2, "cleanup").Return(assignment.StepRecord{}, nil)
+
+			// extended by 2 hours, so cleanup is not yet due.
+			fetcher.EXPECT().Events(3).Return([]assignment.Event{{Type: "inprogress", CreatedAt: start}}, nil)
+			ledger.EXPECT().GetStep(3, "end").Return(assignment.StepRecord{
+				EventID:    "event-1",
+				ScheduleAt: start.Add(time.Hour*4 - time.Minute*10),
+			}, nil)
+			ledger.EXPECT().GetStep(3, "cleanup").Return(assignment.StepRecord{}, nil)
+
+			repairs, err := r.Reconcile(false)
+			require.NoError(t, err)
+
+			assert.Equal(t, []assignment.Repair{
+				{AssignmentID: 1, Status: "scheduled", Step: "init", Due: start},
+				{AssignmentID: 2, Status: "inprogress", Step: "cleanup", Due: start.Add(time.Hour * 2)},
+			}, repairs)
+		})
+
+		t.Run("should run the missing end step in apply mode", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			fetcher := mocks.NewMockReconcileFetcher(ctrl)
+			ledger := mocks.NewMockStepLedger(ctrl)
+			sc := mocks.NewMockSchedulerClient(ctrl)
+			mailer := coreMocks.NewMockMailer(ctrl)
+
+			now := start.Add(time.Hour*2 - time.Minute*5)
+			r := assignment.Reconciler{
+				Fetcher: fetcher,
+				Runner: assignment.RetryRunner{
+					Runner: assignment.Runner{
+						SchedulerClient:  sc,
+						Mailer:           mailer,
+						Ledger:           ledger,
+						Logger:           logger,
+						Time:             func() time.Time { return now },
+						WarningBeforeEnd: time.Minute * 10,
+					},
+				},
+				Time:  func() time.Time { return now },
+				Grace: time.Minute * 2,
+			}
+
+			a := newAssignment(2, "inprogress")
+			a.CandidateEmail = "jane@testrelay.io"
+			fetcher.EXPECT().InFlight().Return([]assignment.WithTestDetails{a}, nil)
+			fetcher.EXPECT().Events(2).Return([]assignment.Event{{Type: "inprogress", CreatedAt: start}}, nil)
+			expectClaim(ledger, 2, "end")
+			ledger.EXPECT().GetStep(2, "end").Return(assignment.StepRecord{}, nil).Times(2)
+			ledger.EXPECT().GetStep(2, "cleanup").Return(assignment.StepRecord{}, nil).Times(2)
+
+			mailer.EXPECT().Send(core.MailConfig{
+				TemplateName: "end",
+				Subject:      "Your technical test is about to finish",
+				From:         "candidates",
+				To:           "jane@testrelay.io",
+			}, a).Return(nil)
+			sc.EXPECT().Start(gomock.Any()).Return("event-2", nil)
+			ledger.EXPECT().ScheduleStep(2, "cleanup", "event-2", gomock.Any()).Return(nil)
+			ledger.EXPECT().SaveStep(gomock.Any()).Return(nil).Times(2)
+
+			repairs, err := r.Reconcile(true)
+			require.NoError(t, err)
+
+			assert.Equal(t, []assignment.Repair{
+				{AssignmentID: 2, Status: "inprogress", Step: "end", Due: start.Add(time.Hour*2 - time.Minute*10), Applied: true},
+			}, repairs)
+		})
+
+		t.Run("should record assignments that could not be checked and continue", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			fetcher := mocks.NewMockReconcileFetcher(ctrl)
+			ledger := mocks.NewMockStepLedger(ctrl)
+
+			r := assignment.Reconciler{
+				Fetcher: fetcher,
+				Runner:  assignment.RetryRunner{Runner: assignment.Runner{Ledger: ledger, WarningBeforeEnd: time.Minute * 10}},
+				Time:    func() time.Time { return start.Add(time.Hour * 3) },
+				Grace:   time.Minute * 15,
+			}
+
+			bad := newAssignment(2, "scheduled")
+			bad.TestTimezoneChosen = "Mars/Olympus_Mons"
+			fetcher.EXPECT().InFlight().Return([]assignment.WithTestDetails{
+				newAssignment(1, "scheduled"),
+				bad,
+				newAssignment(3, "inprogress"),
+				newAssignment(4, "scheduled"),
+			}, nil)
+
+			fetcher.EXPECT().Events(1).Return([]assignment.Event{{Type: "scheduled", CreatedAt: start.Add(-time.Hour)}}, nil)
+			fetcher.EXPECT().Events(3).Return([]assignment.Event{{Type: "inprogress", CreatedAt: start}}, nil)
+			ledger.EXPECT().GetStep(3, "end").Return(assignment.StepRecord{}, errors.New("hasura is down"))
+			fetcher.EXPECT().Events(4).Return([]assignment.Event{{Type: "scheduled", CreatedAt: start.Add(-time.Hour)}}, nil)
+
+			repairs, err := r.Reconcile(false)
+			require.NoError(t, err)
+			require.Len(t, repairs, 4)
+
+			assert.Equal(t, assignment.Repair{AssignmentID: 1, Status: "scheduled", Step: "init", Due: start}, repairs[0])
+			assert.Equal(t, 2, repairs[1].AssignmentID)
+			assert.Empty(t, repairs[1].Step)
+			assert.Contains(t, repairs[1].Error, "could not parse chosen time for assignment 2")
+			assert.Equal(t, 3, repairs[2].AssignmentID)
+			assert.Empty(t, repairs[2].Step)
+			assert.Contains(t, repairs[2].Error, "hasura is down")
+			assert.Equal(t, assignment.Repair{AssignmentID: 4, Status: "scheduled", Step: "init", Due: start}, repairs[3])
+		})
+
+		t.Run("should skip assignments that have already finished", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			fetcher := mocks.NewMockReconcileFetcher(ctrl)
+
+			r := assignment.Reconciler{
+				Fetcher: fetcher,
+				Time:    func() time.Time { return start.Add(time.Hour * 3) },
+			}
+
+			fetcher.EXPECT().InFlight().Return([]assignment.WithTestDetails{newAssignment(1, "inprogress")}, nil)
+			fetcher.EXPECT().Events(1).Return([]assignment.Event{
+				{Type: "inprogress", CreatedAt: start},
+				{Type: "submitted", CreatedAt: start.Add(time.Hour)},
+			}, nil)
+
+			repairs, err := r.Reconcile(true)
+			require.NoError(t, err)
+			assert.Empty(t, repairs)
+		})
+	})
+}
@@ -0,0 +1,196 @@
+package assignment
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+)
+
+const reminderStepPrefix = "reminder:"
+
+// Reminder is an email sent to the candidate a number of minutes before their assignment starts or ends.
+// Reminders are configured per test and are sent in addition to the default start and end warnings.
+type Reminder struct {
+	// Before is one of start|end.
+	Before  string `json:"before"`
+	Minutes int    `json:"minutes"`
+	// Template is the name of the email template to send. It defaults to reminder-start or reminder-end.
+	Template string `json:"template,omitempty"`
+}
+
+// Step returns the name of the scheduled step that sends the reminder, e.g. reminder:start:1440. Reminders have
+// no id, so a test can only have one reminder for each before and minutes, see Test.remindersErr.
+func (r Reminder) Step() string {
+	return fmt.Sprintf("%s%s:%d", reminderStepPrefix, r.Before, r.Minutes)
+}
+
+func (r Reminder) valid() bool {
+	return (r.Before == "start" || r.Before == "end") && r.Minutes > 0
+}
+
+func (r Reminder) templateName() string {
+	if r.Template != "" {
+		return r.Template
+	}
+
+	return "reminder-" + r.Before
+}
+
+// reminders returns the valid reminders for the test that are sent before the given point, start|end.
+// Only the first of any reminders that share a step is returned.
+func (t Test) reminders(before string) []Reminder {
+	var rs []Reminder
+	seen := make(map[string]struct{})
+	for _, r := range t.Reminders {
+		if _, ok := seen[r.Step()]; ok || !r.valid() || r.Before != before {
+			continue
+		}
+		seen[r.Step()] = struct{}{}
+
+		rs = append(rs, r)
+	}
+
+	return rs
+}
+
+// remindersErr errors if the test has more than one reminder with the same step, as only the first of them
+// would be sent.
+func (t Test) remindersErr() error {
+	seen := make(map[string]struct{})
+	for _, r := range t.Reminders {
+		if _, ok := seen[r.Step()]; ok {
+			return fmt.Errorf("has more than one reminder %d minutes before the %s", r.Minutes, r.Before)
+		}
+		seen[r.Step()] = struct{}{}
+	}
+
+	return nil
+}
+
+type reminderEmailData struct {
+	Assignment WithTestDetails
+	In         string
+}
+
+// scheduleReminders schedules a reminder step for each reminder sent before the point at.
+// Reminders that would be sent before now are skipped.
+func scheduleReminders(schedule func(StartInput) error, assignment WithTestDetails, before string, at time.Time, now Time) error {
+	for _, r := range assignment.Test.reminders(before) {
+		sendAt := at.Add(-time.Minute * time.Duration(r.Minutes))
+		if !sendAt.After(now()) {
+			continue
+		}
+
+		err := schedule(StartInput{
+			Type:       r.Step(),
+			ID:         int64(assignment.ID),
+			ScheduleAt: sendAt.Format(time.RFC3339),
+			Data:       assignment,
+		})
+		if err != nil {
+			return fmt.Errorf("could not schedule reminder %s %w", r.Step(), err)
+		}
+	}
+
+	return nil
+}
+
+// stopReminders stops the pending reminder steps sent before the point, start|end. Stopped reminders are marked
+// as completed, so that stopping them again does nothing and an event that could not be stopped is skipped when
+// it runs. Rescheduling a reminder replaces its record, see scheduleNewStep.
+func stopReminders(client SchedulerClient, ledger StepLedger, assignment WithTestDetails, before string) error {
+	for _, r := range assignment.Test.reminders(before) {
+		record, err := ledger.GetStep(assignment.ID, r.Step())
+		if err != nil {
+			return fmt.Errorf("could not get step %s from ledger %w", r.Step(), err)
+		}
+
+		if !record.Pending() {
+			continue
+		}
+
+		err = client.Stop(record.EventID)
+		if err != nil {
+			return fmt.Errorf("could not stop reminder %s %w", r.Step(), err)
+		}
+
+		record.AssignmentID = assignment.ID
+		record.Step = r.Step()
+		record.Completed = true
+		err = ledger.SaveStep(record)
+		if err != nil {
+			return fmt.Errorf("could not mark reminder %s as stopped %w", r.Step(), err)
+		}
+	}
+
+	return nil
+}
+
+func (r Runner) remind(assignment WithTestDetails, step string, p *stepProgress) error {
+	var reminder Reminder
+	for _, rem := range assignment.Test.Reminders {
+		if rem.Step() == step {
+			reminder = rem
+			break
+		}
+	}
+
+	if !reminder.valid() {
+		r.Logger.Info("assignment reminder no longer exists", "step", step, "assignment_id", assignment.ID)
+		return nil
+	}
+
+	current, err := r.Fetcher.GetAssignment(assignment.ID)
+	if err != nil {
+		return fmt.Errorf("could not fetch assignment id %d %w", assignment.ID, err)
+	}
+
+	// the assignment may have been cancelled, missed or submitted since the reminder was scheduled.
+	if current.Status != "scheduled" && current.Status != "inprogress" {
+		r.Logger.Info("assignment is no longer running", "step", step, "assignment_id", assignment.ID, "status", current.Status)
+		return nil
+	}
+
+	in := readableMinutes(reminder.Minutes)
+	subject := "Your " + assignment.Test.Business.Name + " technical test starts in " + in
+	if reminder.Before == "end" {
+		subject = "Your " + assignment.Test.Business.Name + " technical test finishes in " + in
+	}
+
+	return p.do("mail", func() error {
+		err := r.Mailer.Send(core.MailConfig{
+			TemplateName: reminder.templateName(),
+			Subject:      subject,
+			From:         "candidates",
+			To:           assignment.CandidateEmail,
+		}, reminderEmailData{Assignment: assignment, In: in})
+		if err != nil {
+			return fmt.Errorf("could not send reminder %s to candidate %s %w", step, assignment.CandidateEmail, err)
+		}
+
+		return nil
+	})
+}
+
+func isReminderStep(step string) bool {
+	return strings.HasPrefix(step, reminderStepPrefix)
+}
+
+// readableMinutes formats minutes in the largest whole unit, e.g. 1440 is "1 day".
+func readableMinutes(minutes int) string {
+	n, unit := minutes, "minute"
+	switch {
+	case minutes%1440 == 0:
+		n, unit = minutes/1440, "day"
+	case minutes%60 == 0:
+		n, unit = minutes/60, "hour"
+	}
+
+	if n != 1 {
+		unit += "s"
+	}
+
+	return fmt.Sprintf("%d %s", n, unit)
+}
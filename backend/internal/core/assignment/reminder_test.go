@@ -0,0 +1,218 @@
+package assignment_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+	"github.com/testrelay/testrelay/backend/internal/core/assignment"
+	"github.com/testrelay/testrelay/backend/internal/core/assignment/mocks"
+	coreMocks "github.com/testrelay/testrelay/backend/internal/core/mocks"
+)
+
+func TestReminders(t *testing.T) {
+	logger := zap.NewNop().Sugar()
+	reminders := []assignment.Reminder{
+		{Before: "start", Minutes: 1440},
+		{Before: "start", Minutes: 60},
+		{Before: "end", Minutes: 30, Template: "custom-end"},
+		// duplicates share a step with an earlier reminder, so are never scheduled or sent.
+		{Before: "end", Minutes: 30, Template: "duplicate-end"},
+		{Before: "start", Minutes: 60, Template: "duplicate-start"},
+	}
+
+	t.Run("Scheduler.Start should schedule a step for each start reminder that is in the future", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		f := mocks.NewMockFetcher(ctrl)
+		su := mocks.NewMockScheduleUpdater(ctrl)
+		sc := mocks.NewMockSchedulerClient(ctrl)
+		ledger := mocks.NewMockStepLedger(ctrl)
+		events := mocks.NewMockEventFetcher(ctrl)
+		mailer := coreMocks.NewMockMailer(ctrl)
+
+		// 2 hours before the test starts, so the day before reminder has passed.
+		now := time.Date(2021, 11, 12, 8, 0, 0, 0, time.UTC)
+		s := assignment.Scheduler{
+			Fetcher:         f,
+			SchedulerClient: sc,
+			Updater:         su,
+			Ledger:          ledger,
+			Events:          events,
+			Mailer:          mailer,
+			Time:            func() time.Time { return now },
+		}
+
+		a := assignment.WithTestDetails{
+			ID:                 12,
+			TestDayChosen:      "2021-11-12",
+			TestTimeChosen:     "10:00:00",
+			TestTimezoneChosen: "UTC",
+			ChooseUntil:        "2021-11-13",
+			GithubRepoURL:      "https://github.com/testrelay/repo.git",
+			Test:               assignment.Test{Reminders: reminders},
+		}
+		f.EXPECT().GetAssignment(12).Return(a, nil)
+
+		sc.EXPECT().Start(gomock.Any()).DoAndReturn(func(input assignment.StartInput) (string, error) {
+			assert.Equal(t, "start", input.Type)
+			return "event-1", nil
+		})
+		sc.EXPECT().Start(assignment.StartInput{
+			Type:       "reminder:start:60",
+			ID:         12,
+			ScheduleAt: "2021-11-12T09:00:00Z",
+			Data:       a,
+		}).Return("event-2", nil)
+		// reminders of a rescheduled assignment are scheduled afresh, clearing the reminders sent before.
+		ledger.EXPECT().SaveStep(assignment.StepRecord{AssignmentID: 12, Step: "start"}).Return(nil)
+		ledger.EXPECT().ScheduleStep(12, "start", "event-1", gomock.Any()).Return(nil)
+		ledger.EXPECT().SaveStep(assignment.StepRecord{AssignmentID: 12, Step: "reminder:start:60"}).Return(nil)
+		ledger.EXPECT().ScheduleStep(12, "reminder:start:60", "event-2", time.Date(2021, 11, 12, 9, 0, 0, 0, time.UTC)).Return(nil)
+		su.EXPECT().UpdateAssignmentWithDetails(12, "event-1", a.GithubRepoURL).Return(nil)
+		events.EXPECT().Events(12).Return(nil, nil)
+		mailer.EXPECT().Send(gomock.Any(), gomock.Any()).Return(nil)
+
+		err := s.Start(12)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Runner.Run", func(t *testing.T) {
+		now := time.Date(2021, 11, 12, 10, 0, 0, 0, time.UTC)
+
+		t.Run("should send the reminder template for reminder steps", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			ledger := mocks.NewMockStepLedger(ctrl)
+			mailer := coreMocks.NewMockMailer(ctrl)
+			f := mocks.NewMockFetcher(ctrl)
+
+			r := assignment.Runner{
+				Fetcher: f,
+				Mailer:  mailer,
+				Ledger:  ledger,
+				Logger:  logger,
+				Time:    func() time.Time { return now },
+			}
+
+			a := assignment.WithTestDetails{
+				ID:             12,
+				CandidateEmail: "jane@testrelay.io",
+				Test: assignment.Test{
+					Business:  assignment.Business{Name: "TestRelay"},
+					Reminders: reminders,
+				},
+			}
+			expectClaim(ledger, 12, "reminder:end:30")
+			ledger.EXPECT().GetStep(12, "reminder:end:30").Return(assignment.StepRecord{}, nil)
+			f.EXPECT().GetAssignment(12).Return(assignment.WithTestDetails{ID: 12, Status: "inprogress"}, nil)
+			mailer.EXPECT().Send(core.MailConfig{
+				TemplateName: "custom-end",
+				Subject:      "Your TestRelay technical test finishes in 30 minutes",
+				From:         "candidates",
+				To:           "jane@testrelay.io",
+			}, gomock.Any()).Return(nil)
+			ledger.EXPECT().SaveStep(gomock.Any()).Return(nil).Times(2)
+
+			err := r.Run("reminder:end:30", assignment.RunData{Data: a})
+			assert.NoError(t, err)
+		})
+
+		t.Run("should skip reminders that have been removed from the test", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			ledger := mocks.NewMockStepLedger(ctrl)
+
+			r := assignment.Runner{
+				Ledger: ledger,
+				Logger: logger,
+				Time:   func() time.Time { return now },
+			}
+
+			expectClaim(ledger, 12, "reminder:end:45")
+			ledger.EXPECT().GetStep(12, "reminder:end:45").Return(assignment.StepRecord{}, nil)
+			ledger.EXPECT().SaveStep(gomock.Any()).Return(nil)
+
+			err := r.Run("reminder:end:45", assignment.RunData{Data: assignment.WithTestDetails{ID: 12}})
+			assert.NoError(t, err)
+		})
+
+		t.Run("should skip reminders for assignments that are no longer running", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			ledger := mocks.NewMockStepLedger(ctrl)
+			f := mocks.NewMockFetcher(ctrl)
+
+			r := assignment.Runner{
+				Fetcher: f,
+				Ledger:  ledger,
+				Logger:  logger,
+				Time:    func() time.Time { return now },
+			}
+
+			a := assignment.WithTestDetails{
+				ID:     12,
+				Status: "inprogress",
+				Test:   assignment.Test{Reminders: reminders},
+			}
+			expectClaim(ledger, 12, "reminder:end:30")
+			ledger.EXPECT().GetStep(12, "reminder:end:30").Return(assignment.StepRecord{}, nil)
+			f.EXPECT().GetAssignment(12).Return(assignment.WithTestDetails{ID: 12, Status: "submitted"}, nil)
+			ledger.EXPECT().SaveStep(gomock.Any()).DoAndReturn(func(r assignment.StepRecord) error {
+				assert.True(t, r.Completed)
+				return nil
+			})
+
+			err := r.Run("reminder:end:30", assignment.RunData{Data: a})
+			assert.NoError(t, err)
+		})
+
+		t.Run("init should schedule end reminders", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			ledger := mocks.NewMockStepLedger(ctrl)
+			sc := mocks.NewMockSchedulerClient(ctrl)
+
+			r := assignment.Runner{
+				SchedulerClient:  sc,
+				Ledger:           ledger,
+				Logger:           logger,
+				Time:             func() time.Time { return now },
+				WarningBeforeEnd: time.Minute * 10,
+			}
+
+			a := assignment.WithTestDetails{
+				ID:        12,
+				TimeLimit: 7200,
+				Test:      assignment.Test{Reminders: reminders},
+			}
+			expectClaim(ledger, 12, "init")
+			ledger.EXPECT().GetStep(12, "init").Return(assignment.StepRecord{
+				EventID: "event-1",
+				Actions: map[string]time.Time{"started": now, "upload": now, "event": now},
+			}, nil)
+			ledger.EXPECT().GetStep(12, "end").Return(assignment.StepRecord{EventID: "event-2"}, nil)
+			ledger.EXPECT().GetStep(12, "reminder:end:30").Return(assignment.StepRecord{}, nil)
+
+			sendAt := now.Add(time.Minute * 90)
+			sc.EXPECT().Start(assignment.StartInput{
+				Type:       "reminder:end:30",
+				ID:         12,
+				ScheduleAt: sendAt.Format(time.RFC3339),
+				Data:       a,
+			}).Return("event-3", nil)
+
+			gomock.InOrder(
+				ledger.EXPECT().ScheduleStep(12, "reminder:end:30", "event-3", sendAt).Return(nil),
+				ledger.EXPECT().SaveStep(gomock.Any()).DoAndReturn(func(r assignment.StepRecord) error {
+					assert.Equal(t, "init", r.Step)
+					assert.True(t, r.Completed)
+					return nil
+				}),
+			)
+
+			err := r.Run("init", assignment.RunData{Data: a, EventID: "event-1"})
+			require.NoError(t, err)
+		})
+	})
+}
@@ -0,0 +1,187 @@
+package assignment
+
+//go:generate mockgen -destination mocks/retry.go -package mocks . DeadLetterQueue
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+)
+
+// ErrAlreadyReplayed is returned when replaying a dead letter that has already been replayed.
+var ErrAlreadyReplayed = errors.New("dead letter has already been replayed")
+
+// DeadLetter is a step that failed permanently or ran out of retries.
+type DeadLetter struct {
+	ID           int
+	AssignmentID int
+	Step         string
+	Data         WithTestDetails
+	Error        string
+	Attempts     int
+	Replayed     bool
+}
+
+// DeadLetterQueue defines an interface for a type that persists dead letters.
+type DeadLetterQueue interface {
+	AddDeadLetter(letter DeadLetter) error
+	GetDeadLetter(id int) (DeadLetter, error)
+	MarkReplayed(id int) error
+}
+
+// RetryRunner wraps a Runner, retrying steps that fail with a transient error using exponential backoff.
+// Steps that fail with a permanent error, or that fail MaxAttempts times, are added to the DeadLetters queue
+// so that they can be inspected and replayed.
+type RetryRunner struct {
+	Runner      Runner
+	DeadLetters DeadLetterQueue
+	Fetcher     Fetcher
+
+	// MaxAttempts is the number of times a step can fail before it is dead lettered.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, each subsequent retry doubles the delay up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// Run runs the step using the Runner. Failures are either retried or dead lettered, so Run only returns
+// an error if the failure could not be recorded.
+func (r RetryRunner) Run(step string, data RunData) error {
+	err := r.Runner.Run(step, data)
+	if err == nil {
+		return nil
+	}
+
+	return r.fail(step, data.Data, err)
+}
+
+// fail records a failed run of the step, scheduling a retry for transient errors
+// that have not yet reached MaxAttempts and adding a dead letter otherwise.
+func (r RetryRunner) fail(step string, a WithTestDetails, runErr error) error {
+	record, err := r.Runner.Ledger.GetStep(a.ID, step)
+	if err != nil {
+		return fmt.Errorf("could not get step %s for assignment %d from ledger %s %w", step, a.ID, runErr, err)
+	}
+
+	record.AssignmentID = a.ID
+	record.Step = step
+	record.Attempts++
+
+	if core.IsTransient(runErr) && record.Attempts < r.MaxAttempts {
+		at := r.Runner.Time().Add(r.backoff(record.Attempts))
+		id, err := r.Runner.SchedulerClient.Start(StartInput{
+			Type:       step,
+			ID:         int64(a.ID),
+			ScheduleAt: at.Format(time.RFC3339),
+			Data:       a,
+		})
+		if err != nil {
+			return fmt.Errorf("could not schedule retry of step %s for assignment %d %s %w", step, a.ID, runErr, err)
+		}
+
+		record.EventID = id
+		record.ScheduleAt = at
+		err = r.Runner.Ledger.SaveStep(record)
+		if err != nil {
+			return fmt.Errorf("could not record retry of step %s for assignment %d %w", step, a.ID, err)
+		}
+
+		r.Runner.Logger.Warn(
+			"assignment step failed, retrying",
+			"step", step,
+			"assignment_id", a.ID,
+			"attempts", record.Attempts,
+			"retry_at", at,
+			"error", runErr,
+		)
+		return nil
+	}
+
+	err = r.Runner.Ledger.SaveStep(record)
+	if err != nil {
+		return fmt.Errorf("could not record failure of step %s for assignment %d %w", step, a.ID, err)
+	}
+
+	err = r.DeadLetters.AddDeadLetter(DeadLetter{
+		AssignmentID: a.ID,
+		Step:         step,
+		Data:         a,
+		Error:        runErr.Error(),
+		Attempts:     record.Attempts,
+	})
+	if err != nil {
+		return fmt.Errorf("could not dead letter step %s for assignment %d %s %w", step, a.ID, runErr, err)
+	}
+
+	r.Runner.Logger.Error(
+		"assignment step dead lettered",
+		"step", step,
+		"assignment_id", a.ID,
+		"attempts", record.Attempts,
+		"error", runErr,
+	)
+	return nil
+}
+
+// backoff returns the delay before the given retry attempt.
+func (r RetryRunner) backoff(attempt int) time.Duration {
+	delay := r.BaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= r.MaxDelay {
+			return r.MaxDelay
+		}
+	}
+
+	return delay
+}
+
+// Replay runs the step of a dead letter again with the latest assignment data and a fresh set of attempts.
+// If the step fails again it is retried or dead lettered as usual and the failure is returned.
+func (r RetryRunner) Replay(id int) error {
+	letter, err := r.DeadLetters.GetDeadLetter(id)
+	if err != nil {
+		return fmt.Errorf("could not get dead letter %d %w", id, err)
+	}
+
+	if letter.Replayed {
+		return ErrAlreadyReplayed
+	}
+
+	a, err := r.Fetcher.GetAssignment(letter.AssignmentID)
+	if err != nil {
+		return fmt.Errorf("could not fetch assignment %d %w", letter.AssignmentID, err)
+	}
+
+	record, err := r.Runner.Ledger.GetStep(a.ID, letter.Step)
+	if err != nil {
+		return fmt.Errorf("could not get step %s for assignment %d from ledger %w", letter.Step, a.ID, err)
+	}
+
+	record.AssignmentID = a.ID
+	record.Step = letter.Step
+	record.Attempts = 0
+	err = r.Runner.Ledger.SaveStep(record)
+	if err != nil {
+		return fmt.Errorf("could not reset attempts of step %s for assignment %d %w", letter.Step, a.ID, err)
+	}
+
+	runErr := r.Runner.Run(letter.Step, RunData{Data: a})
+
+	err = r.DeadLetters.MarkReplayed(id)
+	if err != nil {
+		return fmt.Errorf("could not mark dead letter %d as replayed %w", id, err)
+	}
+
+	if runErr != nil {
+		err = r.fail(letter.Step, a, runErr)
+		if err != nil {
+			return err
+		}
+
+		return fmt.Errorf("replay of dead letter %d failed %w", id, runErr)
+	}
+
+	return nil
+}
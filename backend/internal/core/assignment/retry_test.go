@@ -0,0 +1,177 @@
+package assignment_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+	"github.com/testrelay/testrelay/backend/internal/core/assignment"
+	"github.com/testrelay/testrelay/backend/internal/core/assignment/mocks"
+	coreMocks "github.com/testrelay/testrelay/backend/internal/core/mocks"
+)
+
+func TestRetryRunner(t *testing.T) {
+	logger := zap.NewNop().Sugar()
+	now := time.Date(2021, 11, 12, 10, 0, 0, 0, time.UTC)
+	a := assignment.WithTestDetails{ID: 12, CandidateEmail: "jane@testrelay.io"}
+
+	endMail := core.MailConfig{
+		TemplateName: "end",
+		Subject:      "Your technical test is about to finish",
+		From:         "candidates",
+		To:           "jane@testrelay.io",
+	}
+
+	newRetryRunner := func(ctrl *gomock.Controller) (assignment.RetryRunner, *mocks.MockStepLedger, *mocks.MockSchedulerClient, *coreMocks.MockMailer, *mocks.MockDeadLetterQueue) {
+		ledger := mocks.NewMockStepLedger(ctrl)
+		sc := mocks.NewMockSchedulerClient(ctrl)
+		mailer := coreMocks.NewMockMailer(ctrl)
+		dlq := mocks.NewMockDeadLetterQueue(ctrl)
+
+		return assignment.RetryRunner{
+			Runner: assignment.Runner{
+				Mailer:           mailer,
+				Logger:           logger,
+				SchedulerClient:  sc,
+				Ledger:           ledger,
+				Time:             func() time.Time { return now },
+				WarningBeforeEnd: time.Minute * 10,
+			},
+			DeadLetters: dlq,
+			MaxAttempts: 3,
+			BaseDelay:   time.Minute,
+			MaxDelay:    time.Minute * 3,
+		}, ledger, sc, mailer, dlq
+	}
+
+	t.Run("Run", func(t *testing.T) {
+		t.Run("should retry transient errors with exponential backoff", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r, ledger, sc, mailer, _ := newRetryRunner(ctrl)
+
+			record := assignment.StepRecord{AssignmentID: 12, Step: "end", EventID: "event-1", Attempts: 1}
+			expectClaim(ledger, 12, "end")
+			ledger.EXPECT().GetStep(12, "end").Return(record, nil).Times(2)
+			mailer.EXPECT().Send(endMail, a).Return(core.NewTransientError(errors.New("connection reset")))
+			sc.EXPECT().Start(assignment.StartInput{
+				Type:       "end",
+				ID:         12,
+				ScheduleAt: now.Add(time.Minute * 2).Format(time.RFC3339),
+				Data:       a,
+			}).Return("event-2", nil)
+			ledger.EXPECT().SaveStep(assignment.StepRecord{
+				AssignmentID: 12,
+				Step:         "end",
+				EventID:      "event-2",
+				ScheduleAt:   now.Add(time.Minute * 2),
+				Attempts:     2,
+			}).Return(nil)
+
+			err := r.Run("end", assignment.RunData{Data: a, EventID: "event-1"})
+			require.NoError(t, err)
+		})
+
+		t.Run("should dead letter permanent errors", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r, ledger, _, mailer, dlq := newRetryRunner(ctrl)
+
+			record := assignment.StepRecord{AssignmentID: 12, Step: "end", EventID: "event-1"}
+			expectClaim(ledger, 12, "end")
+			ledger.EXPECT().GetStep(12, "end").Return(record, nil).Times(2)
+			mailer.EXPECT().Send(endMail, a).Return(errors.New("template not found"))
+
+			record.Attempts = 1
+			ledger.EXPECT().SaveStep(record).Return(nil)
+			dlq.EXPECT().AddDeadLetter(assignment.DeadLetter{
+				AssignmentID: 12,
+				Step:         "end",
+				Data:         a,
+				Error:        "could not send finish email to candidate jane@testrelay.io template not found",
+				Attempts:     1,
+			}).Return(nil)
+
+			err := r.Run("end", assignment.RunData{Data: a, EventID: "event-1"})
+			require.NoError(t, err)
+		})
+
+		t.Run("should dead letter transient errors once attempts are exhausted", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r, ledger, _, mailer, dlq := newRetryRunner(ctrl)
+
+			record := assignment.StepRecord{AssignmentID: 12, Step: "end", EventID: "event-3", Attempts: 2}
+			expectClaim(ledger, 12, "end")
+			ledger.EXPECT().GetStep(12, "end").Return(record, nil).Times(2)
+			mailer.EXPECT().Send(endMail, a).Return(core.NewTransientError(errors.New("connection reset")))
+
+			record.Attempts = 3
+			ledger.EXPECT().SaveStep(record).Return(nil)
+			dlq.EXPECT().AddDeadLetter(gomock.Any()).DoAndReturn(func(letter assignment.DeadLetter) error {
+				assert.Equal(t, 3, letter.Attempts)
+				return nil
+			})
+
+			err := r.Run("end", assignment.RunData{Data: a, EventID: "event-3"})
+			require.NoError(t, err)
+		})
+
+		t.Run("should error if the failure cannot be recorded", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r, ledger, _, mailer, dlq := newRetryRunner(ctrl)
+
+			expectClaim(ledger, 12, "end")
+			ledger.EXPECT().GetStep(12, "end").Return(assignment.StepRecord{}, nil).Times(2)
+			mailer.EXPECT().Send(endMail, a).Return(errors.New("template not found"))
+			ledger.EXPECT().SaveStep(gomock.Any()).Return(nil)
+			dlq.EXPECT().AddDeadLetter(gomock.Any()).Return(errors.New("hasura down"))
+
+			err := r.Run("end", assignment.RunData{Data: a})
+			assert.Error(t, err)
+		})
+	})
+
+	t.Run("Replay", func(t *testing.T) {
+		t.Run("should rerun the step with the latest assignment and mark the dead letter replayed", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r, ledger, sc, mailer, dlq := newRetryRunner(ctrl)
+			fetcher := mocks.NewMockFetcher(ctrl)
+			r.Fetcher = fetcher
+
+			dlq.EXPECT().GetDeadLetter(4).Return(assignment.DeadLetter{ID: 4, AssignmentID: 12, Step: "end", Attempts: 3}, nil)
+			fetcher.EXPECT().GetAssignment(12).Return(a, nil)
+
+			record := assignment.StepRecord{AssignmentID: 12, Step: "end", EventID: "event-3", Attempts: 3}
+			ledger.EXPECT().GetStep(12, "end").Return(record, nil)
+			record.Attempts = 0
+			ledger.EXPECT().SaveStep(record).Return(nil)
+
+			expectClaim(ledger, 12, "end")
+			ledger.EXPECT().GetStep(12, "end").Return(record, nil)
+			mailer.EXPECT().Send(endMail, a).Return(nil)
+			ledger.EXPECT().GetStep(12, "cleanup").Return(assignment.StepRecord{}, nil)
+			sc.EXPECT().Start(gomock.Any()).Return("event-4", nil)
+			ledger.EXPECT().ScheduleStep(12, "cleanup", "event-4", gomock.Any()).Return(nil)
+			ledger.EXPECT().SaveStep(gomock.Any()).Return(nil).Times(2)
+
+			dlq.EXPECT().MarkReplayed(4).Return(nil)
+
+			err := r.Replay(4)
+			require.NoError(t, err)
+		})
+
+		t.Run("should not replay a dead letter twice", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r, _, _, _, dlq := newRetryRunner(ctrl)
+
+			dlq.EXPECT().GetDeadLetter(4).Return(assignment.DeadLetter{ID: 4, Replayed: true}, nil)
+
+			err := r.Replay(4)
+			assert.ErrorIs(t, err, assignment.ErrAlreadyReplayed)
+		})
+	})
+}
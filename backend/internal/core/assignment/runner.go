@@ -1,12 +1,15 @@
 package assignment
 
+//go:generate mockgen -destination mocks/runner.go -package mocks . EventCreator,ReviewerCollector,CommitRecorder
 import (
+	"errors"
 	"fmt"
 	"time"
 
 	"go.uber.org/zap"
 
 	"github.com/testrelay/testrelay/backend/internal/core"
+	"github.com/testrelay/testrelay/backend/internal/vcs"
 )
 
 type EventCreator interface {
@@ -16,8 +19,18 @@ type EventCreator interface {
 type ReviewerCollector interface {
 	Reviewers(assignmentID int) ([]string, error)
 }
+
+// CommitRecorder records the commit of the test repo that was uploaded to an assignment, so that the code
+// each candidate started from can be audited and reproduced.
+type CommitRecorder interface {
+	RecordTestCommit(assignmentID int, sha string) error
+}
+
 type RunData struct {
 	Data WithTestDetails `json:"data"`
+	// EventID is the id of the scheduled event that triggered the run. It can be blank
+	// if the step is run outside a scheduled event.
+	EventID string `json:"event_id"`
 }
 
 type Time func() time.Time
@@ -28,83 +41,252 @@ type Runner struct {
 	SubmissionChecker core.VCSSubmissionChecker
 	ReviewerCollector ReviewerCollector
 	EventCreator      EventCreator
+	CommitRecorder    CommitRecorder
+	EventRecorder     EventRecorder
 	Mailer            core.Mailer
 	Logger            *zap.SugaredLogger
 	SchedulerClient   SchedulerClient
+	Ledger            StepLedger
 	Time              Time
 
+	StageRecorder          StageRecorder
+	StageSubmissionChecker core.VCSStageSubmissionChecker
+
+	// CollaboratorAdder gives the candidate access to repos generated from a template when the assignment starts.
+	CollaboratorAdder core.VCSCollaboratorAdder
+
+	// Fetcher fetches the current status of an assignment before a reminder is sent, as the run data is a
+	// snapshot from when the reminder was scheduled.
+	Fetcher Fetcher
+
 	StartDelay       time.Duration
 	WarningBeforeEnd time.Duration
 }
 
+// Run runs the given step for an assignment. Progress of the step is recorded in the Ledger,
+// making Run safe to call multiple times for the same step. Steps that have already completed are
+// skipped and steps that previously failed part way through resume from the first action that failed.
+// Runs triggered by a scheduled event that has since been replaced by a newer event are also skipped.
+// Each run claims the step in the Ledger before it starts, so that concurrent deliveries of the same
+// event do not both run the step's actions.
 func (r Runner) Run(step string, data RunData) error {
 	assignment := data.Data
 
+	claimed, err := r.Ledger.ClaimStep(assignment.ID, step, r.Time().Add(stepClaim))
+	if err != nil {
+		return fmt.Errorf("could not claim step %s for assignment %d in ledger %w", step, assignment.ID, err)
+	}
+
+	if !claimed {
+		r.Logger.Info("assignment step already completed or running", "step", step, "assignment_id", assignment.ID)
+		return nil
+	}
+
+	defer func() {
+		err := r.Ledger.ReleaseStep(assignment.ID, step)
+		if err != nil {
+			r.Logger.Error("could not release assignment step", "step", step, "assignment_id", assignment.ID, "error", err)
+		}
+	}()
+
+	record, err := r.Ledger.GetStep(assignment.ID, step)
+	if err != nil {
+		return fmt.Errorf("could not get step %s for assignment %d from ledger %w", step, assignment.ID, err)
+	}
+
+	if record.Completed {
+		r.Logger.Info("assignment step already completed", "step", step, "assignment_id", assignment.ID)
+		return nil
+	}
+
+	if data.EventID != "" && record.EventID != "" && data.EventID != record.EventID {
+		r.Logger.Info(
+			"assignment step event has been superseded",
+			"step", step,
+			"assignment_id", assignment.ID,
+			"event_id", data.EventID,
+		)
+		return nil
+	}
+
+	record.AssignmentID = assignment.ID
+	record.Step = step
+	if record.EventID == "" {
+		record.EventID = data.EventID
+	}
+
+	p := &stepProgress{ledger: r.Ledger, record: record, now: r.Time}
 	switch step {
 	case "start":
-		return r.start(assignment)
+		err = r.start(assignment, p)
 	case "init":
-		return r.init(assignment)
+		err = r.init(assignment, p)
 	case "end":
-		return r.end(assignment)
+		err = r.end(assignment, p)
 	case "cleanup":
-		return r.cleanup(assignment)
+		err = r.cleanup(assignment, p)
 	default:
+		if isReminderStep(step) {
+			err = r.remind(assignment, step, p)
+			break
+		}
+
+		if isStageStep(step) {
+			err = r.stage(assignment, step, p)
+			break
+		}
+
 		r.Logger.Info("assignment step does not exist", "step", step)
+		return nil
+	}
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return p.complete()
 }
 
-func (r Runner) cleanup(assignment WithTestDetails) error {
-	reviewers, err := r.ReviewerCollector.Reviewers(assignment.ID)
+// schedule schedules a future step for the assignment recording the scheduled event in the Ledger.
+// If the step has already been scheduled schedule does nothing, so that retried steps do not
+// schedule the same step twice.
+func (r Runner) schedule(input StartInput) error {
+	record, err := r.Ledger.GetStep(int(input.ID), input.Type)
 	if err != nil {
-		return fmt.Errorf("could not get reviewers for assignemnt %d %w", assignment.ID, err)
+		return fmt.Errorf("could not get step %s from ledger %w", input.Type, err)
 	}
 
-	err = r.Cleaner.Cleanup(core.CleanDetails{
-		ID:                 int64(assignment.ID),
-		VCSRepoURL:         assignment.GithubRepoURL,
-		CandidateUsername:  assignment.Candidate.GithubUsername,
-		ReviewersUsernames: reviewers,
-	})
+	if record.EventID != "" {
+		return nil
+	}
+
+	_, err = scheduleStep(r.SchedulerClient, r.Ledger, input)
+	return err
+}
+
+// scheduleStep starts a scheduled event for the step in input and records the
+// scheduled event in the ledger, keeping any progress of the step, e.g. when a
+// pending step is moved. It returns the id of the scheduled event.
+func scheduleStep(client SchedulerClient, ledger StepLedger, input StartInput) (string, error) {
+	scheduleAt, err := time.Parse(time.RFC3339, input.ScheduleAt)
 	if err != nil {
-		return fmt.Errorf("could not cleanup github repo for assignemnt %d %w", assignment.ID, err)
+		return "", fmt.Errorf("could not parse schedule time %s %w", input.ScheduleAt, err)
 	}
 
-	ok, err := r.SubmissionChecker.IsSubmitted(assignment.GithubRepoURL, assignment.Candidate.GithubUsername)
+	id, err := client.Start(input)
 	if err != nil {
-		return fmt.Errorf("could not check github repo is submitted assignemnt %d %w", assignment.ID, err)
+		return "", err
 	}
 
-	status := "submitted"
-	if !ok {
-		status = "missed"
+	err = ledger.ScheduleStep(int(input.ID), input.Type, id, scheduleAt)
+	if err != nil {
+		return "", fmt.Errorf("could not record scheduled step %s event %s %w", input.Type, id, err)
 	}
-	err = r.EventCreator.NewAssignmentEvent(assignment.CandidateID, assignment.ID, status)
+
+	return id, nil
+}
+
+// scheduleNewStep schedules the step in input as a new occurrence of the step, clearing the progress recorded for
+// any previous occurrence, e.g. reminders sent or stopped before the assignment was rescheduled.
+func scheduleNewStep(client SchedulerClient, ledger StepLedger, input StartInput) (string, error) {
+	err := ledger.SaveStep(StepRecord{AssignmentID: int(input.ID), Step: input.Type})
 	if err != nil {
-		return fmt.Errorf("could not insert event '%s' %w", status, err)
+		return "", fmt.Errorf("could not clear previous step %s %w", input.Type, err)
 	}
 
-	err = r.sendEnd(status, assignment)
+	return scheduleStep(client, ledger, input)
+}
+
+func (r Runner) cleanup(assignment WithTestDetails, p *stepProgress) error {
+	// end reminders are still pending if the assignment finishes before its deadline, e.g. it was submitted early.
+	err := stopReminders(r.SchedulerClient, r.Ledger, assignment, "end")
+	if err != nil {
+		return fmt.Errorf("could not stop pending end reminders %w", err)
+	}
+
+	if n := len(assignment.Test.Stages); n > 0 {
+		err := r.finishStage(assignment, n-1, p)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = p.do("vcs_cleanup", func() error {
+		reviewers, err := r.ReviewerCollector.Reviewers(assignment.ID)
+		if err != nil {
+			return fmt.Errorf("could not get reviewers for assignemnt %d %w", assignment.ID, err)
+		}
+
+		err = r.Cleaner.Cleanup(core.CleanDetails{
+			ID:                 int64(assignment.ID),
+			VCSRepoURL:         assignment.GithubRepoURL,
+			CandidateUsername:  assignment.vcsUsername(),
+			ReviewersUsernames: reviewers,
+		})
+		if err != nil {
+			return fmt.Errorf("could not cleanup github repo for assignemnt %d %w", assignment.ID, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// the outcome is recorded so that a resumed cleanup sends the same emails as the run that failed.
+	status, err := p.decide("status", func() (string, error) {
+		ok, err := r.SubmissionChecker.IsSubmitted(assignment.GithubRepoURL, assignment.vcsUsername())
+		if err != nil {
+			return "", fmt.Errorf("could not check github repo is submitted assignemnt %d %w", assignment.ID, err)
+		}
+
+		if !ok {
+			return "missed", nil
+		}
+
+		return "submitted", nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = p.do("event", func() error {
+		err := r.EventCreator.NewAssignmentEvent(assignment.CandidateID, assignment.ID, status)
+		if err != nil {
+			return fmt.Errorf("could not insert event '%s' %w", status, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = r.sendEnd(status, assignment, p)
 	if err != nil {
 		return fmt.Errorf("could not send end emails %w", err)
 	}
 	return nil
 }
 
-func (r Runner) end(assignment WithTestDetails) error {
-	err := r.Mailer.Send(core.MailConfig{
-		TemplateName: "end",
-		Subject:      "Your technical test is about to finish",
-		From:         "candidates",
-		To:           assignment.CandidateEmail,
-	}, assignment)
+func (r Runner) end(assignment WithTestDetails, p *stepProgress) error {
+	err := p.do("mail", func() error {
+		err := r.Mailer.Send(core.MailConfig{
+			TemplateName: "end",
+			Subject:      "Your technical test is about to finish",
+			From:         "candidates",
+			To:           assignment.CandidateEmail,
+		}, assignment)
+		if err != nil {
+			return fmt.Errorf("could not send finish email to candidate %s %w", assignment.CandidateEmail, err)
+		}
+
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("could not send finish email to candidate %s %w", assignment.CandidateEmail, err)
+		return err
 	}
 
-	_, err = r.SchedulerClient.Start(StartInput{
+	err = r.schedule(StartInput{
 		Type:       "cleanup",
 		ID:         int64(assignment.ID),
 		ScheduleAt: r.Time().Add(r.WarningBeforeEnd).Format(time.RFC3339),
@@ -117,47 +299,136 @@ func (r Runner) end(assignment WithTestDetails) error {
 	return nil
 }
 
-func (r Runner) init(assignment WithTestDetails) error {
-	err := r.Uploader.Upload(core.UploadDetails{
-		ID:             int64(assignment.ID),
-		VCSRepoURL:     assignment.GithubRepoURL,
-		TestVCSRepoURL: assignment.Test.GithubRepo,
-		InstallationID: assignment.Test.Business.GithubInstallationID,
-	})
+// recordUpload records an uploaded event for the assignment, reporting the commit of the test repo that was
+// uploaded and the files its .testrelayignore left out of the candidate's commit. Any extra meta, e.g. the
+// stage that was uploaded, is added to the event.
+func (r Runner) recordUpload(assignmentID int, res core.UploadResult, extra map[string]interface{}) error {
+	excluded := res.Excluded
+	if excluded == nil {
+		excluded = []string{}
+	}
+
+	meta := map[string]interface{}{
+		"commit_sha": res.SHA,
+		"excluded":   excluded,
+	}
+	for k, v := range extra {
+		meta[k] = v
+	}
+
+	err := r.EventRecorder.RecordAssignmentEvent(0, assignmentID, "uploaded", meta)
+	if err != nil {
+		return fmt.Errorf("could not insert event 'uploaded' %w", err)
+	}
+
+	return nil
+}
+
+func (r Runner) init(assignment WithTestDetails, p *stepProgress) error {
+	now, err := p.mark("started")
 	if err != nil {
-		return fmt.Errorf("could not upload assignment to github %w", err)
+		return err
+	}
+
+	deadline := now.Add(time.Second * time.Duration(assignment.timeLimit()))
+
+	if len(assignment.Test.Stages) > 0 {
+		_, err = r.startStage(assignment, 0, p)
+		if err != nil {
+			return err
+		}
+	} else if assignment.Test.fromTemplate() {
+		// repos generated from a template already hold the test, so the candidate is only given access now.
+		err = p.do("collaborator", func() error {
+			err := r.CollaboratorAdder.AddCollaborator(assignment.GithubRepoURL, assignment.vcsUsername())
+			if err != nil && !errors.Is(err, vcs.ErrorAlreadyCollaborator) {
+				return fmt.Errorf("could not add candidate to assignment repo %s %w", assignment.GithubRepoURL, err)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		err = p.do("upload", func() error {
+			res, err := r.Uploader.Upload(core.UploadDetails{
+				ID:             int64(assignment.ID),
+				VCSRepoURL:     assignment.GithubRepoURL,
+				TestVCSRepoURL: assignment.Test.GithubRepo,
+				Ref:            assignment.Test.GithubRef,
+				Path:           assignment.Test.GithubPath,
+				InstallationID: assignment.Test.Business.installationID(),
+				Workspace:      assignment.Test.Business.BitbucketWorkspace,
+				TemplateGlob:   assignment.Test.TemplateGlob,
+				TemplateData:   assignment.templateData(deadline),
+			})
+			if err != nil {
+				return fmt.Errorf("could not upload assignment to github %w", err)
+			}
+
+			// recorded within the upload action so that a retried upload always records the commit it pushed.
+			err = r.CommitRecorder.RecordTestCommit(assignment.ID, res.SHA)
+			if err != nil {
+				return fmt.Errorf("could not record test commit %s %w", res.SHA, err)
+			}
+
+			return r.recordUpload(assignment.ID, res, nil)
+		})
+		if err != nil {
+			return err
+		}
 	}
 
-	err = r.EventCreator.NewAssignmentEvent(assignment.CandidateID, assignment.ID, "inprogress")
+	err = p.do("event", func() error {
+		err := r.EventCreator.NewAssignmentEvent(assignment.CandidateID, assignment.ID, "inprogress")
+		if err != nil {
+			return fmt.Errorf("could not insert event 'inprogress' %w", err)
+		}
+
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("could not insert event 'inprogress' %w", err)
+		return err
 	}
 
-	_, err = r.SchedulerClient.Start(StartInput{
+	err = r.schedule(StartInput{
 		Type:       "end",
 		ID:         int64(assignment.ID),
-		ScheduleAt: r.Time().Add(-r.WarningBeforeEnd).Add(time.Second * time.Duration(assignment.TimeLimit)).Format(time.RFC3339),
+		ScheduleAt: deadline.Add(-r.WarningBeforeEnd).Format(time.RFC3339),
 		Data:       assignment,
 	})
 	if err != nil {
 		return fmt.Errorf("could not schedule assignment to end %w", err)
 	}
 
+	err = scheduleReminders(r.schedule, assignment, "end", deadline, r.Time)
+	if err != nil {
+		return fmt.Errorf("could not schedule end reminders %w", err)
+	}
+
 	return nil
 }
 
-func (r Runner) start(assignment WithTestDetails) error {
-	err := r.Mailer.Send(core.MailConfig{
-		TemplateName: "warning",
-		Subject:      "5 minute reminder for your " + assignment.Test.Business.Name + " technical test",
-		From:         "candidates",
-		To:           assignment.CandidateEmail,
-	}, assignment)
+func (r Runner) start(assignment WithTestDetails, p *stepProgress) error {
+	err := p.do("mail", func() error {
+		err := r.Mailer.Send(core.MailConfig{
+			TemplateName: "warning",
+			Subject:      "5 minute reminder for your " + assignment.Test.Business.Name + " technical test",
+			From:         "candidates",
+			To:           assignment.CandidateEmail,
+		}, assignment)
+		if err != nil {
+			return fmt.Errorf("could not send reminder email to candidate %s %w", assignment.CandidateEmail, err)
+		}
+
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("could not send reminder email to candidate %s %w", assignment.CandidateEmail, err)
+		return err
 	}
 
-	_, err = r.SchedulerClient.Start(StartInput{
+	err = r.schedule(StartInput{
 		Type:       "init",
 		ID:         int64(assignment.ID),
 		ScheduleAt: r.Time().Add(r.StartDelay).Format(time.RFC3339),
@@ -170,20 +441,27 @@ func (r Runner) start(assignment WithTestDetails) error {
 	return nil
 }
 
-func (r Runner) sendEnd(status string, data WithTestDetails) error {
+func (r Runner) sendEnd(status string, data WithTestDetails, p *stepProgress) error {
 	subject := "Thanks for submitting your test for " + data.Test.Business.Name
 	if status != "submitted" {
 		subject = "You missed the deadline for submitting your technical test"
 	}
 
-	err := r.Mailer.Send(core.MailConfig{
-		TemplateName: status,
-		Subject:      subject,
-		From:         "candidates",
-		To:           data.CandidateEmail,
-	}, data)
+	err := p.do("mail_candidate", func() error {
+		err := r.Mailer.Send(core.MailConfig{
+			TemplateName: status,
+			Subject:      subject,
+			From:         "candidates",
+			To:           data.CandidateEmail,
+		}, data)
+		if err != nil {
+			return fmt.Errorf("could not send email to candidate %w", err)
+		}
+
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("could not send email to candidate %w", err)
+		return err
 	}
 
 	subject = data.CandidateName + " has submitted their assignment"
@@ -191,15 +469,17 @@ func (r Runner) sendEnd(status string, data WithTestDetails) error {
 		subject = data.CandidateName + " missed the deadline to submit their technical assignment"
 	}
 
-	err = r.Mailer.Send(core.MailConfig{
-		TemplateName: status + "-recruiter",
-		Subject:      subject,
-		From:         "candidates",
-		To:           data.Recruiter.Email,
-	}, data)
-	if err != nil {
-		return fmt.Errorf("could not send email to recruiter %w", err)
-	}
+	return p.do("mail_recruiter", func() error {
+		err := r.Mailer.Send(core.MailConfig{
+			TemplateName: status + "-recruiter",
+			Subject:      subject,
+			From:         "candidates",
+			To:           data.Recruiter.Email,
+		}, data)
+		if err != nil {
+			return fmt.Errorf("could not send email to recruiter %w", err)
+		}
 
-	return err
+		return nil
+	})
 }
@@ -0,0 +1,231 @@
+package assignment_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+	"github.com/testrelay/testrelay/backend/internal/core/assignment"
+	"github.com/testrelay/testrelay/backend/internal/core/assignment/mocks"
+	coreMocks "github.com/testrelay/testrelay/backend/internal/core/mocks"
+)
+
+// expectClaim expects a run of the step to claim the step in the ledger and release it once the run is done.
+func expectClaim(ledger *mocks.MockStepLedger, id int, step string) {
+	ledger.EXPECT().ClaimStep(id, step, gomock.Any()).Return(true, nil)
+	ledger.EXPECT().ReleaseStep(id, step).Return(nil)
+}
+
+func TestRunner(t *testing.T) {
+	now := time.Date(2021, 11, 12, 10, 0, 0, 0, time.UTC)
+	logger := zap.NewNop().Sugar()
+
+	t.Run("Run", func(t *testing.T) {
+		t.Run("should skip steps that have already completed", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			ledger := mocks.NewMockStepLedger(ctrl)
+
+			r := assignment.Runner{Ledger: ledger, Logger: logger, Time: func() time.Time { return now }}
+
+			expectClaim(ledger, 12, "init")
+			ledger.EXPECT().GetStep(12, "init").Return(assignment.StepRecord{
+				AssignmentID: 12,
+				Step:         "init",
+				EventID:      "event-1",
+				Completed:    true,
+			}, nil)
+
+			err := r.Run("init", assignment.RunData{Data: assignment.WithTestDetails{ID: 12}, EventID: "event-1"})
+			assert.NoError(t, err)
+		})
+
+		t.Run("should skip steps that another run has claimed", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			ledger := mocks.NewMockStepLedger(ctrl)
+
+			r := assignment.Runner{Ledger: ledger, Logger: logger, Time: func() time.Time { return now }}
+
+			ledger.EXPECT().ClaimStep(12, "init", now.Add(time.Minute*15)).Return(false, nil)
+
+			err := r.Run("init", assignment.RunData{Data: assignment.WithTestDetails{ID: 12}, EventID: "event-1"})
+			assert.NoError(t, err)
+		})
+
+		t.Run("should release the step when it fails", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			ledger := mocks.NewMockStepLedger(ctrl)
+			mailer := coreMocks.NewMockMailer(ctrl)
+
+			r := assignment.Runner{Mailer: mailer, Ledger: ledger, Logger: logger, Time: func() time.Time { return now }}
+
+			expectClaim(ledger, 12, "end")
+			ledger.EXPECT().GetStep(12, "end").Return(assignment.StepRecord{}, nil)
+			mailer.EXPECT().Send(gomock.Any(), gomock.Any()).Return(errors.New("smtp is down"))
+
+			err := r.Run("end", assignment.RunData{Data: assignment.WithTestDetails{ID: 12}})
+			assert.Error(t, err)
+		})
+
+		t.Run("should skip events that have been superseded", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			ledger := mocks.NewMockStepLedger(ctrl)
+
+			r := assignment.Runner{Ledger: ledger, Logger: logger, Time: func() time.Time { return now }}
+
+			expectClaim(ledger, 12, "end")
+			ledger.EXPECT().GetStep(12, "end").Return(assignment.StepRecord{
+				AssignmentID: 12,
+				Step:         "end",
+				EventID:      "event-2",
+			}, nil)
+
+			err := r.Run("end", assignment.RunData{Data: assignment.WithTestDetails{ID: 12}, EventID: "event-1"})
+			assert.NoError(t, err)
+		})
+
+		t.Run("should resume cleanup from the first incomplete action", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			ledger := mocks.NewMockStepLedger(ctrl)
+			checker := coreMocks.NewMockVCSSubmissionChecker(ctrl)
+			mailer := coreMocks.NewMockMailer(ctrl)
+
+			r := assignment.Runner{
+				SubmissionChecker: checker,
+				Mailer:            mailer,
+				Ledger:            ledger,
+				Logger:            logger,
+				Time:              func() time.Time { return now },
+			}
+
+			a := assignment.WithTestDetails{
+				ID:            12,
+				CandidateName: "Jane",
+				GithubRepoURL: "https://github.com/testrelay/repo.git",
+				Candidate:     assignment.Candidate{GithubUsername: "jane"},
+				Recruiter:     assignment.Recruiter{Email: "recruiter@testrelay.io"},
+			}
+			record := assignment.StepRecord{
+				AssignmentID: 12,
+				Step:         "cleanup",
+				EventID:      "event-1",
+				Actions: map[string]time.Time{
+					"vcs_cleanup":      now,
+					"status:submitted": now,
+					"event":            now,
+					"mail_candidate":   now,
+				},
+			}
+			expectClaim(ledger, 12, "cleanup")
+			ledger.EXPECT().GetStep(12, "cleanup").Return(record, nil)
+			// the submission outcome is reused from the failed run rather than checked again.
+			checker.EXPECT().IsSubmitted(gomock.Any(), gomock.Any()).Times(0)
+			mailer.EXPECT().Send(core.MailConfig{
+				TemplateName: "submitted-recruiter",
+				Subject:      "Jane has submitted their assignment",
+				From:         "candidates",
+				To:           "recruiter@testrelay.io",
+			}, a).Return(nil)
+
+			var saved []assignment.StepRecord
+			ledger.EXPECT().SaveStep(gomock.Any()).DoAndReturn(func(r assignment.StepRecord) error {
+				saved = append(saved, r)
+				return nil
+			}).Times(2)
+
+			err := r.Run("cleanup", assignment.RunData{Data: a, EventID: "event-1"})
+			require.NoError(t, err)
+
+			require.Len(t, saved, 2)
+			assert.Contains(t, saved[0].Actions, "mail_recruiter")
+			assert.False(t, saved[0].Completed)
+			assert.True(t, saved[1].Completed)
+		})
+
+		t.Run("should not schedule a step that has already been scheduled", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			ledger := mocks.NewMockStepLedger(ctrl)
+			sc := mocks.NewMockSchedulerClient(ctrl)
+
+			r := assignment.Runner{
+				SchedulerClient: sc,
+				Ledger:          ledger,
+				Logger:          logger,
+				Time:            func() time.Time { return now },
+			}
+
+			expectClaim(ledger, 12, "init")
+			ledger.EXPECT().GetStep(12, "init").Return(assignment.StepRecord{
+				AssignmentID: 12,
+				Step:         "init",
+				EventID:      "event-1",
+				Actions:      map[string]time.Time{"started": now, "upload": now, "event": now},
+			}, nil)
+			ledger.EXPECT().GetStep(12, "end").Return(assignment.StepRecord{
+				AssignmentID: 12,
+				Step:         "end",
+				EventID:      "event-2",
+			}, nil)
+			ledger.EXPECT().SaveStep(gomock.Any()).DoAndReturn(func(r assignment.StepRecord) error {
+				assert.Equal(t, "init", r.Step)
+				assert.True(t, r.Completed)
+				return nil
+			})
+
+			err := r.Run("init", assignment.RunData{Data: assignment.WithTestDetails{ID: 12}, EventID: "event-1"})
+			assert.NoError(t, err)
+		})
+
+		t.Run("should record scheduled steps in the ledger", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			ledger := mocks.NewMockStepLedger(ctrl)
+			sc := mocks.NewMockSchedulerClient(ctrl)
+			mailer := coreMocks.NewMockMailer(ctrl)
+
+			r := assignment.Runner{
+				SchedulerClient:  sc,
+				Mailer:           mailer,
+				Ledger:           ledger,
+				Logger:           logger,
+				Time:             func() time.Time { return now },
+				WarningBeforeEnd: time.Minute * 10,
+			}
+
+			a := assignment.WithTestDetails{ID: 12, CandidateEmail: "jane@testrelay.io"}
+			expectClaim(ledger, 12, "end")
+			ledger.EXPECT().GetStep(12, "end").Return(assignment.StepRecord{}, nil)
+			mailer.EXPECT().Send(gomock.Any(), a).Return(nil)
+
+			cleanupAt := now.Add(time.Minute * 10)
+			ledger.EXPECT().GetStep(12, "cleanup").Return(assignment.StepRecord{}, nil)
+			sc.EXPECT().Start(assignment.StartInput{
+				Type:       "cleanup",
+				ID:         12,
+				ScheduleAt: cleanupAt.Format(time.RFC3339),
+				Data:       a,
+			}).Return("event-3", nil)
+
+			gomock.InOrder(
+				ledger.EXPECT().SaveStep(gomock.Any()).DoAndReturn(func(r assignment.StepRecord) error {
+					assert.Equal(t, "end", r.Step)
+					assert.Contains(t, r.Actions, "mail")
+					return nil
+				}),
+				ledger.EXPECT().ScheduleStep(12, "cleanup", "event-3", cleanupAt).Return(nil),
+				ledger.EXPECT().SaveStep(gomock.Any()).DoAndReturn(func(r assignment.StepRecord) error {
+					assert.Equal(t, "end", r.Step)
+					assert.True(t, r.Completed)
+					return nil
+				}),
+			)
+
+			err := r.Run("end", assignment.RunData{Data: a})
+			assert.NoError(t, err)
+		})
+	})
+}
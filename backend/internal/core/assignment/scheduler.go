@@ -3,6 +3,9 @@ package assignment
 //go:generate mockgen -destination mocks/scheduler.go -package mocks . Fetcher,ScheduleUpdater,SchedulerClient
 import (
 	"fmt"
+	"time"
+
+	"go.uber.org/zap"
 
 	"github.com/testrelay/testrelay/backend/internal/core"
 	intTime "github.com/testrelay/testrelay/backend/internal/time"
@@ -42,25 +45,84 @@ type Scheduler struct {
 	Fetcher         Fetcher
 	SchedulerClient SchedulerClient
 	VCSCreator      core.VCSCreator
+	// TemplateCreator creates the repos of tests that are generated from a github template repo.
+	TemplateCreator core.VCSCreator
 	Updater         ScheduleUpdater
+	Ledger          StepLedger
+	Mailer          core.Mailer
+	Events          EventFetcher
+	Logger          *zap.SugaredLogger
+	Time            Time
 }
 
-// Stop terminates a previously started assignment using the assignmentID.
+// Stop terminates a previously started assignment using the assignmentID. If the candidate had chosen
+// a time the candidate is sent a cancellation for the calendar event sent by Start.
 func (s Scheduler) Stop(assignmentID int) error {
 	assignment, err := s.Fetcher.GetAssignment(assignmentID)
 	if err != nil {
 		return fmt.Errorf("could not fetch assignment id %d %w", assignmentID, err)
 	}
 
-	err = s.SchedulerClient.Stop(assignment.SchedulerID)
+	err = stopStart(s.SchedulerClient, s.Ledger, assignment)
+	if err != nil {
+		return err
+	}
+
+	err = stopReminders(s.SchedulerClient, s.Ledger, assignment, "start")
+	if err != nil {
+		return fmt.Errorf("could not stop previously scheduled reminders %w", err)
+	}
+
+	if assignment.TestDayChosen == "" {
+		return nil
+	}
+
+	t, err := intTime.Parse(intTime.AssignmentChoices{
+		DayChosen:  assignment.TestDayChosen,
+		TimeChosen: assignment.TestTimeChosen,
+		Timezone:   assignment.TestTimezoneChosen,
+	})
+	if err != nil {
+		s.Logger.Error("could not parse start of cancelled assignment", "assignment_id", assignment.ID, "error", err)
+		return nil
+	}
+
+	startAt, _ := time.Parse(time.RFC3339, t.StartAssignmentAt)
+	err = s.sendCalendar(assignment, startAt, true)
+	if err != nil {
+		s.Logger.Error("could not send cancelled email to candidate", "assignment_id", assignment.ID, "error", err)
+	}
+
+	return nil
+}
+
+// stopStart stops the pending start step of the assignment. A retry of a failed start step is scheduled as a new
+// event that only the ledger records, so the ledger's event is stopped as well as the assignment's SchedulerID.
+func stopStart(client SchedulerClient, ledger StepLedger, assignment WithTestDetails) error {
+	err := client.Stop(assignment.SchedulerID)
 	if err != nil {
 		return fmt.Errorf("could not stop previously scheduled assignment %w", err)
 	}
 
+	record, err := ledger.GetStep(assignment.ID, "start")
+	if err != nil {
+		return fmt.Errorf("could not get start step from ledger %w", err)
+	}
+
+	if record.Pending() && record.EventID != assignment.SchedulerID {
+		err = client.Stop(record.EventID)
+		if err != nil {
+			return fmt.Errorf("could not stop retry of start step %w", err)
+		}
+	}
+
 	return nil
 }
 
-// Start schedules an assignment to execute at a date in the future.
+// Start schedules an assignment to execute at a date in the future. The chosen day and time are
+// validated in the candidate's timezone against the availability of the business, see intTime.Validate.
+// Once scheduled the candidate is emailed a calendar event for the assignment, which is updated each time
+// the assignment is rescheduled.
 func (s Scheduler) Start(assignmentID int) error {
 	assignment, err := s.Fetcher.GetAssignment(assignmentID)
 	if err != nil {
@@ -68,9 +130,14 @@ func (s Scheduler) Start(assignmentID int) error {
 	}
 
 	if assignment.SchedulerID != "" {
-		err := s.SchedulerClient.Stop(assignment.SchedulerID)
+		err := stopStart(s.SchedulerClient, s.Ledger, assignment)
 		if err != nil {
-			return fmt.Errorf("could not stop previously scheduled assignment %w", err)
+			return err
+		}
+
+		err = stopReminders(s.SchedulerClient, s.Ledger, assignment, "start")
+		if err != nil {
+			return fmt.Errorf("could not stop previously scheduled reminders %w", err)
 		}
 	}
 
@@ -79,39 +146,58 @@ func (s Scheduler) Start(assignmentID int) error {
 		TimeChosen: assignment.TestTimeChosen,
 		Timezone:   assignment.TestTimezoneChosen,
 	}
-	t, err := intTime.Parse(timeInput)
+	t, err := intTime.Validate(timeInput, assignment.Window(s.Time()))
 	if err != nil {
 		return fmt.Errorf("error formatting assignment schedule time %w", err)
 	}
 
 	githubRepoURL := assignment.GithubRepoURL
 	if assignment.GithubRepoURL == "" {
-		githubRepoURL, err = s.VCSCreator.CreateRepo(
-			assignment.Test.Business.Name,
-			assignment.Candidate.GithubUsername,
-			assignment.ID,
-		)
+		creator, err := assignment.repoCreator(s.VCSCreator, s.TemplateCreator)
+		if err != nil {
+			return fmt.Errorf("could not generate repo for assignment %w", err)
+		}
+
+		githubRepoURL, err = creator.CreateRepo(assignment.createDetails())
 		if err != nil {
 			return fmt.Errorf("could not generate repo for assignment %w", err)
 		}
 	}
 
 	assignment.GithubRepoURL = githubRepoURL
-	schedulerID, err := s.SchedulerClient.Start(StartInput{
+	schedulerID, err := scheduleNewStep(s.SchedulerClient, s.Ledger, StartInput{
 		Type:       "start",
 		ID:         int64(assignment.ID),
 		ScheduleAt: t.SendNotificationAt,
-		Duration:   int(assignment.TimeLimit) - 600,
+		Duration:   assignment.timeLimit() - 600,
 		Data:       assignment,
 	})
 	if err != nil {
 		return fmt.Errorf("could not schedule assignment to start %w", err)
 	}
 
+	startAt, err := time.Parse(time.RFC3339, t.StartAssignmentAt)
+	if err != nil {
+		return fmt.Errorf("could not parse assignment start time %w", err)
+	}
+
+	err = scheduleReminders(func(input StartInput) error {
+		_, err := scheduleNewStep(s.SchedulerClient, s.Ledger, input)
+		return err
+	}, assignment, "start", startAt, s.Time)
+	if err != nil {
+		return fmt.Errorf("could not schedule start reminders %w", err)
+	}
+
 	err = s.Updater.UpdateAssignmentWithDetails(int(assignment.ID), schedulerID, githubRepoURL)
 	if err != nil {
 		return fmt.Errorf("could not update assignment with schedule details %w", err)
 	}
 
+	err = s.sendCalendar(assignment, startAt, false)
+	if err != nil {
+		s.Logger.Error("could not send scheduled email to candidate", "assignment_id", assignment.ID, "error", err)
+	}
+
 	return nil
 }
@@ -2,13 +2,18 @@ package assignment_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 
+	"github.com/testrelay/testrelay/backend/internal/core"
 	"github.com/testrelay/testrelay/backend/internal/core/assignment"
 	"github.com/testrelay/testrelay/backend/internal/core/assignment/mocks"
 	coreMocks "github.com/testrelay/testrelay/backend/internal/core/mocks"
+	intTime "github.com/testrelay/testrelay/backend/internal/time"
 )
 
 func TestScheduler(t *testing.T) {
@@ -18,23 +23,194 @@ func TestScheduler(t *testing.T) {
 		su := mocks.NewMockScheduleUpdater(ctrl)
 		sc := mocks.NewMockSchedulerClient(ctrl)
 		vc := coreMocks.NewMockVCSCreator(ctrl)
+		ledger := mocks.NewMockStepLedger(ctrl)
 
 		s := assignment.Scheduler{
 			Fetcher:         f,
 			SchedulerClient: sc,
 			VCSCreator:      vc,
 			Updater:         su,
+			Ledger:          ledger,
 		}
 
 		assignmentID := 123
 		schedulerID := "test-id"
 		f.EXPECT().GetAssignment(assignmentID).Return(assignment.WithTestDetails{
+			ID:                 assignmentID,
 			SchedulerID:        schedulerID,
 		}, nil)
 
 		sc.EXPECT().Stop(schedulerID).Return(nil)
+		ledger.EXPECT().GetStep(assignmentID, "start").Return(assignment.StepRecord{EventID: schedulerID}, nil)
 
 		err := s.Stop(assignmentID)
 		assert.NoError(t, err)
 	})
+	t.Run("Stop should stop a pending retry of the start step", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		f := mocks.NewMockFetcher(ctrl)
+		sc := mocks.NewMockSchedulerClient(ctrl)
+		ledger := mocks.NewMockStepLedger(ctrl)
+
+		s := assignment.Scheduler{
+			Fetcher:         f,
+			SchedulerClient: sc,
+			Ledger:          ledger,
+		}
+
+		f.EXPECT().GetAssignment(123).Return(assignment.WithTestDetails{
+			ID:          123,
+			SchedulerID: "event-1",
+		}, nil)
+
+		// the start step failed and RetryRunner scheduled its retry as event-2.
+		sc.EXPECT().Stop("event-1").Return(nil)
+		ledger.EXPECT().GetStep(123, "start").Return(assignment.StepRecord{EventID: "event-2", Attempts: 1}, nil)
+		sc.EXPECT().Stop("event-2").Return(nil)
+
+		err := s.Stop(123)
+		assert.NoError(t, err)
+	})
+	t.Run("Start", func(t *testing.T) {
+		t.Run("should not schedule choices when the business is unavailable", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			f := mocks.NewMockFetcher(ctrl)
+
+			s := assignment.Scheduler{
+				Fetcher: f,
+				Time:    func() time.Time { return time.Date(2021, 11, 12, 10, 0, 0, 0, time.UTC) },
+			}
+
+			f.EXPECT().GetAssignment(123).Return(assignment.WithTestDetails{
+				ID:                 123,
+				ChooseUntil:        "2021-11-20",
+				TestDayChosen:      "2021-11-13",
+				TestTimeChosen:     "10:00:00",
+				TestTimezoneChosen: "UTC",
+				Test: assignment.Test{
+					Business: assignment.Business{
+						Availability: intTime.Availability{
+							Openings: []intTime.Opening{{Weekday: time.Monday, Start: "09:00:00", End: "17:00:00"}},
+						},
+					},
+				},
+			}, nil)
+
+			err := s.Start(123)
+
+			var v intTime.ValidationError
+			require.ErrorAs(t, err, &v)
+			assert.Equal(t, intTime.CodeUnavailable, v.Code)
+		})
+
+		t.Run("should generate a template repo without giving the candidate access before init", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			f := mocks.NewMockFetcher(ctrl)
+			sc := mocks.NewMockSchedulerClient(ctrl)
+			su := mocks.NewMockScheduleUpdater(ctrl)
+			ledger := mocks.NewMockStepLedger(ctrl)
+			events := mocks.NewMockEventFetcher(ctrl)
+			mailer := coreMocks.NewMockMailer(ctrl)
+			templates := coreMocks.NewMockVCSCreator(ctrl)
+			collaborators := coreMocks.NewMockVCSCollaboratorAdder(ctrl)
+			ec := mocks.NewMockEventCreator(ctrl)
+			now := func() time.Time { return time.Date(2021, 11, 12, 10, 0, 0, 0, time.UTC) }
+
+			s := assignment.Scheduler{
+				Fetcher:         f,
+				SchedulerClient: sc,
+				VCSCreator:      coreMocks.NewMockVCSCreator(ctrl),
+				TemplateCreator: templates,
+				Updater:         su,
+				Ledger:          ledger,
+				Mailer:          mailer,
+				Events:          events,
+				Logger:          zap.NewNop().Sugar(),
+				Time:            now,
+			}
+
+			f.EXPECT().GetAssignment(123).Return(assignment.WithTestDetails{
+				ID:                 123,
+				CandidateID:        7,
+				ChooseUntil:        "2021-11-20",
+				TestDayChosen:      "2021-11-13",
+				TestTimeChosen:     "10:00:00",
+				TestTimezoneChosen: "UTC",
+				TimeLimit:          7200,
+				Candidate:          assignment.Candidate{GithubUsername: "jane"},
+				Test: assignment.Test{
+					Business:   assignment.Business{Name: "TestRelay"},
+					GithubRepo: "https://github.com/acme/test",
+					UploadMode: core.UploadModeTemplate,
+				},
+			}, nil)
+			templates.EXPECT().CreateRepo(core.CreateDetails{
+				BusinessName: "TestRelay",
+				Username:     "jane",
+				ID:           123,
+				TemplateRepo: "https://github.com/acme/test",
+			}).Return("https://github.com/testrelay/jane", nil)
+			ledger.EXPECT().GetStep(123, gomock.Any()).Return(assignment.StepRecord{}, nil).AnyTimes()
+			ledger.EXPECT().SaveStep(gomock.Any()).Return(nil).AnyTimes()
+			ledger.EXPECT().ScheduleStep(123, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			ledger.EXPECT().ClaimStep(123, gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
+			ledger.EXPECT().ReleaseStep(123, gomock.Any()).Return(nil).AnyTimes()
+
+			var start assignment.StartInput
+			sc.EXPECT().Start(gomock.Any()).DoAndReturn(func(input assignment.StartInput) (string, error) {
+				start = input
+				return "event-start", nil
+			})
+			su.EXPECT().UpdateAssignmentWithDetails(123, "event-start", "https://github.com/testrelay/jane").Return(nil)
+			events.EXPECT().Events(123).Return(nil, nil)
+			mailer.EXPECT().Send(gomock.Any(), gomock.Any()).Return(nil)
+
+			require.NoError(t, s.Start(123))
+			assert.Equal(t, "start", start.Type)
+
+			// the candidate is only added once the init step runs.
+			r := assignment.Runner{
+				CollaboratorAdder: collaborators,
+				EventCreator:      ec,
+				SchedulerClient:   sc,
+				Ledger:            ledger,
+				Logger:            zap.NewNop().Sugar(),
+				Time:              now,
+				WarningBeforeEnd:  time.Minute * 10,
+			}
+			collaborators.EXPECT().AddCollaborator("https://github.com/testrelay/jane", "jane").Return(nil)
+			ec.EXPECT().NewAssignmentEvent(7, 123, "inprogress").Return(nil)
+			sc.EXPECT().Start(gomock.Any()).Return("event-end", nil)
+
+			err := r.Run("init", assignment.RunData{Data: start.Data.(assignment.WithTestDetails)})
+			require.NoError(t, err)
+		})
+
+		t.Run("should not generate the repo from a template without a template creator", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			f := mocks.NewMockFetcher(ctrl)
+
+			s := assignment.Scheduler{
+				Fetcher:    f,
+				VCSCreator: coreMocks.NewMockVCSCreator(ctrl),
+				Time:       func() time.Time { return time.Date(2021, 11, 12, 10, 0, 0, 0, time.UTC) },
+			}
+
+			f.EXPECT().GetAssignment(123).Return(assignment.WithTestDetails{
+				ID:                 123,
+				ChooseUntil:        "2021-11-20",
+				TestDayChosen:      "2021-11-13",
+				TestTimeChosen:     "10:00:00",
+				TestTimezoneChosen: "UTC",
+				Test: assignment.Test{
+					GithubRepo: "https://github.com/acme/test",
+					UploadMode: core.UploadModeTemplate,
+				},
+			}, nil)
+
+			err := s.Start(123)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "no template creator is configured")
+		})
+	})
 }
@@ -0,0 +1,241 @@
+package assignment
+
+//go:generate mockgen -destination mocks/stage.go -package mocks . StageRecorder
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+)
+
+const stageStepPrefix = "stage:"
+
+// Stage is a sequential part of a staged test. The first stage is uploaded when the assignment starts,
+// each following stage is uploaded to its own branch of the assignment repo once the previous stage's
+// time limit has passed.
+type Stage struct {
+	Position int    `json:"position"`
+	Name     string `json:"name"`
+	// GithubRepo is the source repo for the stage. It defaults to the test's repo.
+	GithubRepo string `json:"github_repo"`
+	// GithubRef is the branch, tag or commit of the stage's repo that is uploaded. When the stage uses the
+	// test's repo it defaults to the test's ref.
+	GithubRef string `json:"github_ref"`
+	// GithubPath is the directory of the stage's repo that is uploaded, defaulting like GithubRef.
+	GithubPath string `json:"github_path"`
+	// TimeLimit is the number of seconds the candidate has to complete the stage.
+	TimeLimit int `json:"time_limit"`
+}
+
+// Step returns the name of the scheduled step that starts the stage, e.g. stage:2.
+func (s Stage) Step() string {
+	return stageStepPrefix + strconv.Itoa(s.Position)
+}
+
+// branch returns the branch of the assignment repo the stage at index i is uploaded to.
+// The first stage is uploaded to the default branch so branch returns an empty string.
+func (s Stage) branch(i int) string {
+	if i == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("stage-%d", s.Position)
+}
+
+func (s Stage) repo(t Test) string {
+	if s.GithubRepo != "" {
+		return s.GithubRepo
+	}
+
+	return t.GithubRepo
+}
+
+func (s Stage) ref(t Test) string {
+	if s.GithubRef != "" || s.GithubRepo != "" {
+		return s.GithubRef
+	}
+
+	return t.GithubRef
+}
+
+func (s Stage) path(t Test) string {
+	if s.GithubPath != "" || s.GithubRepo != "" {
+		return s.GithubPath
+	}
+
+	return t.GithubPath
+}
+
+// StageRecorder defines an interface for a type that records the state of each stage of an assignment.
+type StageRecorder interface {
+	// StartStage records the stage as started, along with the commit sha of the test repo uploaded for it.
+	StartStage(assignmentID, position int, branch, sha string, startedAt, deadline time.Time) error
+	// ExtendStage moves the deadline of a stage that has started.
+	ExtendStage(assignmentID, position int, deadline time.Time) error
+	// FinishStage sets the status of the stage to one of submitted|missed.
+	FinishStage(assignmentID, position int, status string) error
+}
+
+// timeLimit returns the number of seconds the candidate has to complete the assignment.
+// For staged tests this is the sum of the time limit of each stage.
+func (a WithTestDetails) timeLimit() int {
+	if len(a.Test.Stages) == 0 {
+		return a.TimeLimit
+	}
+
+	var total int
+	for _, s := range a.Test.Stages {
+		total += s.TimeLimit
+	}
+
+	return total
+}
+
+// stage returns the index of the stage that the step starts.
+func (t Test) stage(step string) (int, bool) {
+	for i, s := range t.Stages {
+		if s.Step() == step {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+type stageEmailData struct {
+	Assignment WithTestDetails
+	Stage      Stage
+	Branch     string
+	Deadline   string
+}
+
+// startStage uploads the stage at index i of the assignment test, records it as started, and
+// schedules the following stage. It does not email the candidate as the first stage is started by init.
+func (r Runner) startStage(assignment WithTestDetails, i int, p *stepProgress) (time.Time, error) {
+	stage := assignment.Test.Stages[i]
+	branch := stage.branch(i)
+
+	// the stage starts when the step first ran, so a resumed step keeps the same deadline.
+	now, err := p.mark(fmt.Sprintf("start_stage_%d", stage.Position))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	deadline := now.Add(time.Second * time.Duration(stage.TimeLimit))
+	err = p.do("upload", func() error {
+		res, err := r.Uploader.Upload(core.UploadDetails{
+			ID:             int64(assignment.ID),
+			VCSRepoURL:     assignment.GithubRepoURL,
+			TestVCSRepoURL: stage.repo(assignment.Test),
+			Ref:            stage.ref(assignment.Test),
+			Path:           stage.path(assignment.Test),
+			InstallationID: assignment.Test.Business.installationID(),
+			Workspace:      assignment.Test.Business.BitbucketWorkspace,
+			Branch:         branch,
+			TemplateGlob:   assignment.Test.TemplateGlob,
+			TemplateData:   assignment.templateData(deadline),
+		})
+		if err != nil {
+			return fmt.Errorf("could not upload stage %d to github %w", stage.Position, err)
+		}
+
+		// recording the stage alongside the upload keeps its commit sha in step with the code on the branch.
+		err = r.StageRecorder.StartStage(assignment.ID, stage.Position, branch, res.SHA, now, deadline)
+		if err != nil {
+			return fmt.Errorf("could not record start of stage %d %w", stage.Position, err)
+		}
+
+		return r.recordUpload(assignment.ID, res, map[string]interface{}{"stage": stage.Position})
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if i+1 < len(assignment.Test.Stages) {
+		err = r.schedule(StartInput{
+			Type:       assignment.Test.Stages[i+1].Step(),
+			ID:         int64(assignment.ID),
+			ScheduleAt: deadline.Format(time.RFC3339),
+			Data:       assignment,
+		})
+		if err != nil {
+			return time.Time{}, fmt.Errorf("could not schedule stage %d %w", assignment.Test.Stages[i+1].Position, err)
+		}
+	}
+
+	return deadline, nil
+}
+
+// finishStage checks whether the candidate submitted the stage at index i and records the result.
+func (r Runner) finishStage(assignment WithTestDetails, i int, p *stepProgress) error {
+	stage := assignment.Test.Stages[i]
+
+	return p.do(fmt.Sprintf("finish_stage_%d", stage.Position), func() error {
+		ok, err := r.StageSubmissionChecker.IsStageSubmitted(
+			assignment.GithubRepoURL,
+			assignment.vcsUsername(),
+			stage.branch(i),
+		)
+		if err != nil {
+			return fmt.Errorf("could not check stage %d is submitted %w", stage.Position, err)
+		}
+
+		status := "submitted"
+		if !ok {
+			status = "missed"
+		}
+
+		err = r.StageRecorder.FinishStage(assignment.ID, stage.Position, status)
+		if err != nil {
+			return fmt.Errorf("could not record stage %d as %s %w", stage.Position, status, err)
+		}
+
+		return nil
+	})
+}
+
+// stage runs a stage step, finishing the previous stage before uploading the next and letting the
+// candidate know where to find it.
+func (r Runner) stage(assignment WithTestDetails, step string, p *stepProgress) error {
+	i, ok := assignment.Test.stage(step)
+	if !ok || i == 0 {
+		r.Logger.Info("assignment stage no longer exists", "step", step, "assignment_id", assignment.ID)
+		return nil
+	}
+
+	err := r.finishStage(assignment, i-1, p)
+	if err != nil {
+		return err
+	}
+
+	deadline, err := r.startStage(assignment, i, p)
+	if err != nil {
+		return err
+	}
+
+	stage := assignment.Test.Stages[i]
+	return p.do("mail", func() error {
+		err := r.Mailer.Send(core.MailConfig{
+			TemplateName: "stage",
+			Subject:      fmt.Sprintf("Part %d of your %s technical test has started", stage.Position, assignment.Test.Business.Name),
+			From:         "candidates",
+			To:           assignment.CandidateEmail,
+		}, stageEmailData{
+			Assignment: assignment,
+			Stage:      stage,
+			Branch:     stage.branch(i),
+			Deadline:   readableIn(deadline, assignment.TestTimezoneChosen),
+		})
+		if err != nil {
+			return fmt.Errorf("could not send stage email to candidate %s %w", assignment.CandidateEmail, err)
+		}
+
+		return nil
+	})
+}
+
+func isStageStep(step string) bool {
+	return strings.HasPrefix(step, stageStepPrefix)
+}
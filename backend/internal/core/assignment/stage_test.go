@@ -0,0 +1,226 @@
+package assignment_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+	"github.com/testrelay/testrelay/backend/internal/core/assignment"
+	"github.com/testrelay/testrelay/backend/internal/core/assignment/mocks"
+	coreMocks "github.com/testrelay/testrelay/backend/internal/core/mocks"
+)
+
+func TestRunnerStages(t *testing.T) {
+	now := time.Date(2021, 11, 12, 10, 0, 0, 0, time.UTC)
+	logger := zap.NewNop().Sugar()
+
+	staged := assignment.WithTestDetails{
+		ID:                 12,
+		CandidateID:        7,
+		CandidateEmail:     "jane@testrelay.io",
+		GithubRepoURL:      "https://github.com/testrelay/jane.git",
+		TestTimezoneChosen: "UTC",
+		Candidate:          assignment.Candidate{GithubUsername: "jane"},
+		Test: assignment.Test{
+			Business:   assignment.Business{Name: "TestRelay", GithubInstallationID: 3},
+			GithubRepo: "https://github.com/testrelay/part-1",
+			// the ref and path only apply to stages that use the test's repo.
+			GithubRef:  "v1.0",
+			GithubPath: "exercises/build",
+			Stages: []assignment.Stage{
+				{Position: 1, Name: "Build", TimeLimit: 7200},
+				{Position: 2, Name: "Extend", GithubRepo: "https://github.com/testrelay/part-2", TimeLimit: 3600},
+			},
+		},
+	}
+
+	t.Run("init", func(t *testing.T) {
+		t.Run("should upload the first stage and schedule the next", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			ledger := mocks.NewMockStepLedger(ctrl)
+			uploader := coreMocks.NewMockVCSUploader(ctrl)
+			stages := mocks.NewMockStageRecorder(ctrl)
+			events := mocks.NewMockEventCreator(ctrl)
+			recorder := mocks.NewMockEventRecorder(ctrl)
+			sc := mocks.NewMockSchedulerClient(ctrl)
+
+			r := assignment.Runner{
+				Uploader:         uploader,
+				EventCreator:     events,
+				EventRecorder:    recorder,
+				SchedulerClient:  sc,
+				Ledger:           ledger,
+				StageRecorder:    stages,
+				Logger:           logger,
+				Time:             func() time.Time { return now },
+				WarningBeforeEnd: time.Minute * 10,
+			}
+
+			expectClaim(ledger, 12, "init")
+			ledger.EXPECT().GetStep(12, "init").Return(assignment.StepRecord{}, nil)
+			uploader.EXPECT().Upload(core.UploadDetails{
+				ID:             12,
+				VCSRepoURL:     "https://github.com/testrelay/jane.git",
+				TestVCSRepoURL: "https://github.com/testrelay/part-1",
+				Ref:            "v1.0",
+				Path:           "exercises/build",
+				InstallationID: 3,
+				TemplateData: core.TemplateData{
+					CandidateEmail: "jane@testrelay.io",
+					BusinessName:   "TestRelay",
+					Deadline:       "Fri 12 November 12:00 UTC",
+					Timezone:       "UTC",
+				},
+			}).Return(core.UploadResult{SHA: "a1b2c3"}, nil)
+			stages.EXPECT().StartStage(12, 1, "", "a1b2c3", now, now.Add(time.Hour*2)).Return(nil)
+			recorder.EXPECT().RecordAssignmentEvent(0, 12, "uploaded", map[string]interface{}{
+				"commit_sha": "a1b2c3",
+				"excluded":   []string{},
+				"stage":      1,
+			}).Return(nil)
+
+			ledger.EXPECT().GetStep(12, "stage:2").Return(assignment.StepRecord{}, nil)
+			sc.EXPECT().Start(assignment.StartInput{
+				Type:       "stage:2",
+				ID:         12,
+				ScheduleAt: "2021-11-12T12:00:00Z",
+				Data:       staged,
+			}).Return("event-1", nil)
+
+			events.EXPECT().NewAssignmentEvent(7, 12, "inprogress").Return(nil)
+
+			// the deadline is the sum of each stage time limit.
+			ledger.EXPECT().GetStep(12, "end").Return(assignment.StepRecord{}, nil)
+			sc.EXPECT().Start(assignment.StartInput{
+				Type:       "end",
+				ID:         12,
+				ScheduleAt: "2021-11-12T12:50:00Z",
+				Data:       staged,
+			}).Return("event-2", nil)
+			ledger.EXPECT().ScheduleStep(12, "stage:2", "event-1", gomock.Any()).Return(nil)
+			ledger.EXPECT().ScheduleStep(12, "end", "event-2", gomock.Any()).Return(nil)
+			ledger.EXPECT().SaveStep(gomock.Any()).Return(nil).Times(5)
+
+			err := r.Run("init", assignment.RunData{Data: staged})
+			require.NoError(t, err)
+		})
+	})
+
+	t.Run("stage", func(t *testing.T) {
+		t.Run("should finish the previous stage and upload the next to its own branch", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			ledger := mocks.NewMockStepLedger(ctrl)
+			uploader := coreMocks.NewMockVCSUploader(ctrl)
+			checker := coreMocks.NewMockVCSStageSubmissionChecker(ctrl)
+			stages := mocks.NewMockStageRecorder(ctrl)
+			recorder := mocks.NewMockEventRecorder(ctrl)
+			mailer := coreMocks.NewMockMailer(ctrl)
+
+			later := now.Add(time.Hour * 2)
+			r := assignment.Runner{
+				Uploader:               uploader,
+				Mailer:                 mailer,
+				Ledger:                 ledger,
+				StageRecorder:          stages,
+				EventRecorder:          recorder,
+				StageSubmissionChecker: checker,
+				Logger:                 logger,
+				Time:                   func() time.Time { return later },
+			}
+
+			expectClaim(ledger, 12, "stage:2")
+			ledger.EXPECT().GetStep(12, "stage:2").Return(assignment.StepRecord{EventID: "event-1"}, nil)
+			checker.EXPECT().IsStageSubmitted("https://github.com/testrelay/jane.git", "jane", "").Return(true, nil)
+			stages.EXPECT().FinishStage(12, 1, "submitted").Return(nil)
+			uploader.EXPECT().Upload(core.UploadDetails{
+				ID:             12,
+				VCSRepoURL:     "https://github.com/testrelay/jane.git",
+				TestVCSRepoURL: "https://github.com/testrelay/part-2",
+				InstallationID: 3,
+				Branch:         "stage-2",
+				TemplateData: core.TemplateData{
+					CandidateEmail: "jane@testrelay.io",
+					BusinessName:   "TestRelay",
+					Deadline:       "Fri 12 November 13:00 UTC",
+					Timezone:       "UTC",
+				},
+			}).Return(core.UploadResult{SHA: "d4e5f6", Excluded: []string{"hidden_test.go"}}, nil)
+			stages.EXPECT().StartStage(12, 2, "stage-2", "d4e5f6", later, later.Add(time.Hour)).Return(nil)
+			recorder.EXPECT().RecordAssignmentEvent(0, 12, "uploaded", map[string]interface{}{
+				"commit_sha": "d4e5f6",
+				"excluded":   []string{"hidden_test.go"},
+				"stage":      2,
+			}).Return(nil)
+			mailer.EXPECT().Send(core.MailConfig{
+				TemplateName: "stage",
+				Subject:      "Part 2 of your TestRelay technical test has started",
+				From:         "candidates",
+				To:           "jane@testrelay.io",
+			}, gomock.Any()).Return(nil)
+			ledger.EXPECT().SaveStep(gomock.Any()).Return(nil).Times(5)
+
+			err := r.Run("stage:2", assignment.RunData{Data: staged, EventID: "event-1"})
+			require.NoError(t, err)
+		})
+
+		t.Run("should keep the deadline of the stage when the step resumes", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			ledger := mocks.NewMockStepLedger(ctrl)
+			uploader := coreMocks.NewMockVCSUploader(ctrl)
+			stages := mocks.NewMockStageRecorder(ctrl)
+			recorder := mocks.NewMockEventRecorder(ctrl)
+			mailer := coreMocks.NewMockMailer(ctrl)
+
+			startedAt := now.Add(time.Hour * 2)
+			r := assignment.Runner{
+				Uploader:      uploader,
+				Mailer:        mailer,
+				Ledger:        ledger,
+				StageRecorder: stages,
+				EventRecorder: recorder,
+				Logger:        logger,
+				Time:          func() time.Time { return startedAt.Add(time.Minute * 5) },
+			}
+
+			// the upload failed on the first run, which is retried 5 minutes later.
+			expectClaim(ledger, 12, "stage:2")
+			ledger.EXPECT().GetStep(12, "stage:2").Return(assignment.StepRecord{
+				EventID: "event-1",
+				Actions: map[string]time.Time{"finish_stage_1": startedAt, "start_stage_2": startedAt},
+			}, nil)
+			uploader.EXPECT().Upload(gomock.Any()).DoAndReturn(func(details core.UploadDetails) (core.UploadResult, error) {
+				assert.Equal(t, "Fri 12 November 13:00 UTC", details.TemplateData.Deadline)
+				return core.UploadResult{SHA: "d4e5f6"}, nil
+			})
+			stages.EXPECT().StartStage(12, 2, "stage-2", "d4e5f6", startedAt, startedAt.Add(time.Hour)).Return(nil)
+			recorder.EXPECT().RecordAssignmentEvent(0, 12, "uploaded", gomock.Any()).Return(nil)
+			mailer.EXPECT().Send(gomock.Any(), gomock.Any()).Return(nil)
+			ledger.EXPECT().SaveStep(gomock.Any()).Return(nil).Times(3)
+
+			err := r.Run("stage:2", assignment.RunData{Data: staged, EventID: "event-1"})
+			require.NoError(t, err)
+		})
+
+		t.Run("should skip stages that no longer exist", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			ledger := mocks.NewMockStepLedger(ctrl)
+
+			r := assignment.Runner{Ledger: ledger, Logger: logger, Time: func() time.Time { return now }}
+
+			expectClaim(ledger, 12, "stage:3")
+			ledger.EXPECT().GetStep(12, "stage:3").Return(assignment.StepRecord{}, nil)
+			ledger.EXPECT().SaveStep(gomock.Any()).DoAndReturn(func(record assignment.StepRecord) error {
+				assert.True(t, record.Completed)
+				return nil
+			})
+
+			err := r.Run("stage:3", assignment.RunData{Data: staged})
+			require.NoError(t, err)
+		})
+	})
+}
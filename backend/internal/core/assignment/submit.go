@@ -0,0 +1,162 @@
+package assignment
+
+//go:generate mockgen -destination mocks/submit.go -package mocks . RepoFinder
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnknownRepo  = errors.New("repo does not belong to an assignment")
+	ErrNotCandidate = errors.New("user is not the assignment candidate")
+	ErrEarlyStage   = errors.New("assignment has stages that have not started yet")
+	ErrNotSubmitted = errors.New("candidate has not submitted their work")
+)
+
+// RepoFinder defines an interface for a type that finds the assignment that owns a vcs repo.
+// AssignmentIDByRepoURL must return 0 and no error if no assignment uses the repo.
+type RepoFinder interface {
+	AssignmentIDByRepoURL(repoURL string) (int, error)
+}
+
+// Submitter finishes assignments early once the candidate has submitted their work. Submissions are either
+// pushed by a github pull request webhook through Submit, or checked on the candidate's request through
+// SubmitAssignment, which works for any vcs provider.
+type Submitter struct {
+	Finder  RepoFinder
+	Fetcher Fetcher
+	Runner  RetryRunner
+}
+
+// Submit finishes the in progress assignment for the repo at repoURL on behalf of username, the github login
+// of the user that opened a pull request on the repo. Only github repos send webhooks, so other providers
+// submit through SubmitAssignment. See finish for how the assignment is finished.
+// Submit errors with ErrUnknownRepo, ErrNotCandidate, ErrNotInProgress or ErrEarlyStage if the submission does not
+// belong to an in progress assignment for the candidate.
+func (s Submitter) Submit(repoURL, username string) error {
+	id, err := s.Finder.AssignmentIDByRepoURL(repoURL)
+	if err != nil {
+		return fmt.Errorf("could not find assignment for repo %s %w", repoURL, err)
+	}
+
+	if id == 0 {
+		return ErrUnknownRepo
+	}
+
+	assignment, err := s.Fetcher.GetAssignment(id)
+	if err != nil {
+		return fmt.Errorf("could not fetch assignment id %d %w", id, err)
+	}
+
+	if assignment.Candidate.GithubUsername != username {
+		return ErrNotCandidate
+	}
+
+	err = s.submittable(assignment)
+	if err != nil {
+		return err
+	}
+
+	return s.finish(assignment)
+}
+
+// SubmitAssignment finishes the in progress assignment on behalf of its candidate once the candidate has submitted
+// their work, which is checked through the vcs provider of the assignment repo, e.g. a pull request on github or a
+// submission branch on a local repo. See finish for how the assignment is finished.
+// SubmitAssignment errors with ErrNotCandidate, ErrNotInProgress, ErrEarlyStage or ErrNotSubmitted if the
+// assignment cannot be submitted yet.
+func (s Submitter) SubmitAssignment(assignmentID, userID int) error {
+	assignment, err := s.Fetcher.GetAssignment(assignmentID)
+	if err != nil {
+		return fmt.Errorf("could not fetch assignment id %d %w", assignmentID, err)
+	}
+
+	if assignment.CandidateID != userID {
+		return ErrNotCandidate
+	}
+
+	err = s.submittable(assignment)
+	if err != nil {
+		return err
+	}
+
+	ok, err := s.Runner.Runner.SubmissionChecker.IsSubmitted(assignment.GithubRepoURL, assignment.vcsUsername())
+	if err != nil {
+		return fmt.Errorf("could not check if assignment %d is submitted %w", assignmentID, err)
+	}
+
+	if !ok {
+		return ErrNotSubmitted
+	}
+
+	return s.finish(assignment)
+}
+
+// submittable errors with ErrNotInProgress or ErrEarlyStage if the assignment cannot be submitted early.
+func (s Submitter) submittable(assignment WithTestDetails) error {
+	if assignment.Status != "inprogress" {
+		return ErrNotInProgress
+	}
+
+	// a staged assignment can only be submitted early once its last stage has started.
+	if n := len(assignment.Test.Stages); n > 1 {
+		last, err := s.Runner.Runner.Ledger.GetStep(assignment.ID, assignment.Test.Stages[n-1].Step())
+		if err != nil {
+			return fmt.Errorf("could not get last stage step from ledger %w", err)
+		}
+
+		if !last.Completed {
+			return ErrEarlyStage
+		}
+	}
+
+	return nil
+}
+
+// finish stops any pending end, end reminder or cleanup events and runs the cleanup step straight away, revoking
+// the candidate's access and adding reviewers to the repo. A cleanup that fails with a transient error is retried
+// by the Runner.
+func (s Submitter) finish(assignment WithTestDetails) error {
+	id := assignment.ID
+	end, err := s.Runner.Runner.Ledger.GetStep(id, "end")
+	if err != nil {
+		return fmt.Errorf("could not get end step from ledger %w", err)
+	}
+
+	// the end step is marked as completed so that an event that could not be stopped is skipped when it runs.
+	if !end.Completed {
+		if end.Pending() {
+			err = s.Runner.Runner.SchedulerClient.Stop(end.EventID)
+			if err != nil {
+				return fmt.Errorf("could not stop pending end step %w", err)
+			}
+		}
+
+		end.AssignmentID = id
+		end.Step = "end"
+		end.Completed = true
+		err = s.Runner.Runner.Ledger.SaveStep(end)
+		if err != nil {
+			return fmt.Errorf("could not mark end step as completed %w", err)
+		}
+	}
+
+	err = stopReminders(s.Runner.Runner.SchedulerClient, s.Runner.Runner.Ledger, assignment, "end")
+	if err != nil {
+		return fmt.Errorf("could not stop pending end reminders %w", err)
+	}
+
+	cleanup, err := s.Runner.Runner.Ledger.GetStep(id, "cleanup")
+	if err != nil {
+		return fmt.Errorf("could not get cleanup step from ledger %w", err)
+	}
+
+	if cleanup.Pending() {
+		err = s.Runner.Runner.SchedulerClient.Stop(cleanup.EventID)
+		if err != nil {
+			return fmt.Errorf("could not stop pending cleanup step %w", err)
+		}
+	}
+
+	return s.Runner.Run("cleanup", RunData{Data: assignment})
+}
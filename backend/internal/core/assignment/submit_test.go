@@ -0,0 +1,292 @@
+package assignment_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+	"github.com/testrelay/testrelay/backend/internal/core/assignment"
+	"github.com/testrelay/testrelay/backend/internal/core/assignment/mocks"
+	coreMocks "github.com/testrelay/testrelay/backend/internal/core/mocks"
+)
+
+func TestSubmitter(t *testing.T) {
+	now := time.Date(2021, 11, 12, 10, 0, 0, 0, time.UTC)
+	repoURL := "https://github.com/testrelay/repo.git"
+
+	t.Run("Submit", func(t *testing.T) {
+		t.Run("should stop the pending end step and cleanup straight away", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			finder := mocks.NewMockRepoFinder(ctrl)
+			fetcher := mocks.NewMockFetcher(ctrl)
+			sc := mocks.NewMockSchedulerClient(ctrl)
+			ledger := mocks.NewMockStepLedger(ctrl)
+			reviewers := mocks.NewMockReviewerCollector(ctrl)
+			events := mocks.NewMockEventCreator(ctrl)
+			cleaner := coreMocks.NewMockVCSCleaner(ctrl)
+			checker := coreMocks.NewMockVCSSubmissionChecker(ctrl)
+			mailer := coreMocks.NewMockMailer(ctrl)
+
+			s := assignment.Submitter{
+				Finder:  finder,
+				Fetcher: fetcher,
+				Runner: assignment.RetryRunner{
+					Runner: assignment.Runner{
+						Cleaner:           cleaner,
+						SubmissionChecker: checker,
+						ReviewerCollector: reviewers,
+						EventCreator:      events,
+						Mailer:            mailer,
+						Logger:            zap.NewNop().Sugar(),
+						SchedulerClient:   sc,
+						Ledger:            ledger,
+						Time:              func() time.Time { return now },
+					},
+				},
+			}
+
+			a := assignment.WithTestDetails{
+				ID:            12,
+				Status:        "inprogress",
+				CandidateID:   3,
+				GithubRepoURL: repoURL,
+				Candidate:     assignment.Candidate{GithubUsername: "jane"},
+			}
+			finder.EXPECT().AssignmentIDByRepoURL(repoURL).Return(12, nil)
+			fetcher.EXPECT().GetAssignment(12).Return(a, nil)
+
+			ledger.EXPECT().GetStep(12, "end").Return(assignment.StepRecord{
+				AssignmentID: 12,
+				Step:         "end",
+				EventID:      "event-1",
+			}, nil)
+			sc.EXPECT().Stop("event-1").Return(nil)
+			ledger.EXPECT().SaveStep(assignment.StepRecord{
+				AssignmentID: 12,
+				Step:         "end",
+				EventID:      "event-1",
+				Completed:    true,
+			}).Return(nil)
+
+			ledger.EXPECT().GetStep(12, "cleanup").Return(assignment.StepRecord{}, nil).Times(2)
+			expectClaim(ledger, 12, "cleanup")
+
+			reviewers.EXPECT().Reviewers(12).Return([]string{"bob"}, nil)
+			cleaner.EXPECT().Cleanup(core.CleanDetails{
+				ID:                 12,
+				VCSRepoURL:         repoURL,
+				CandidateUsername:  "jane",
+				ReviewersUsernames: []string{"bob"},
+			}).Return(nil)
+			checker.EXPECT().IsSubmitted(repoURL, "jane").Return(true, nil)
+			events.EXPECT().NewAssignmentEvent(3, 12, "submitted").Return(nil)
+			mailer.EXPECT().Send(gomock.Any(), a).Return(nil).Times(2)
+
+			var saved assignment.StepRecord
+			ledger.EXPECT().SaveStep(gomock.Any()).DoAndReturn(func(r assignment.StepRecord) error {
+				saved = r
+				return nil
+			}).Times(6)
+
+			err := s.Submit(repoURL, "jane")
+			require.NoError(t, err)
+
+			assert.Equal(t, "cleanup", saved.Step)
+			assert.True(t, saved.Completed)
+		})
+
+		t.Run("should stop pending end reminders", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			finder := mocks.NewMockRepoFinder(ctrl)
+			fetcher := mocks.NewMockFetcher(ctrl)
+			sc := mocks.NewMockSchedulerClient(ctrl)
+			ledger := mocks.NewMockStepLedger(ctrl)
+
+			s := assignment.Submitter{
+				Finder:  finder,
+				Fetcher: fetcher,
+				Runner: assignment.RetryRunner{
+					Runner: assignment.Runner{
+						Logger:          zap.NewNop().Sugar(),
+						SchedulerClient: sc,
+						Ledger:          ledger,
+						Time:            func() time.Time { return now },
+					},
+				},
+			}
+
+			finder.EXPECT().AssignmentIDByRepoURL(repoURL).Return(12, nil)
+			fetcher.EXPECT().GetAssignment(12).Return(assignment.WithTestDetails{
+				ID:        12,
+				Status:    "inprogress",
+				Candidate: assignment.Candidate{GithubUsername: "jane"},
+				Test: assignment.Test{Reminders: []assignment.Reminder{
+					{Before: "start", Minutes: 60},
+					{Before: "end", Minutes: 30},
+				}},
+			}, nil)
+
+			ledger.EXPECT().GetStep(12, "end").Return(assignment.StepRecord{EventID: "event-1", Completed: true}, nil)
+			ledger.EXPECT().GetStep(12, "reminder:end:30").Return(assignment.StepRecord{
+				AssignmentID: 12,
+				Step:         "reminder:end:30",
+				EventID:      "event-2",
+			}, nil)
+			sc.EXPECT().Stop("event-2").Return(nil)
+			ledger.EXPECT().SaveStep(assignment.StepRecord{
+				AssignmentID: 12,
+				Step:         "reminder:end:30",
+				EventID:      "event-2",
+				Completed:    true,
+			}).Return(nil)
+
+			// cleanup has already run, e.g. the submission was received twice.
+			ledger.EXPECT().GetStep(12, "cleanup").Return(assignment.StepRecord{EventID: "event-3", Completed: true}, nil)
+			ledger.EXPECT().ClaimStep(12, "cleanup", gomock.Any()).Return(false, nil)
+
+			err := s.Submit(repoURL, "jane")
+			require.NoError(t, err)
+		})
+
+		t.Run("should ignore pull requests from other users", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			finder := mocks.NewMockRepoFinder(ctrl)
+			fetcher := mocks.NewMockFetcher(ctrl)
+
+			s := assignment.Submitter{Finder: finder, Fetcher: fetcher}
+
+			finder.EXPECT().AssignmentIDByRepoURL(repoURL).Return(12, nil)
+			fetcher.EXPECT().GetAssignment(12).Return(assignment.WithTestDetails{
+				ID:        12,
+				Status:    "inprogress",
+				Candidate: assignment.Candidate{GithubUsername: "jane"},
+			}, nil)
+
+			err := s.Submit(repoURL, "bob")
+			assert.ErrorIs(t, err, assignment.ErrNotCandidate)
+		})
+
+		t.Run("should not submit staged assignments before the last stage has started", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			finder := mocks.NewMockRepoFinder(ctrl)
+			fetcher := mocks.NewMockFetcher(ctrl)
+			ledger := mocks.NewMockStepLedger(ctrl)
+
+			s := assignment.Submitter{Finder: finder, Fetcher: fetcher, Runner: assignment.RetryRunner{Runner: assignment.Runner{Ledger: ledger}}}
+
+			finder.EXPECT().AssignmentIDByRepoURL(repoURL).Return(12, nil)
+			fetcher.EXPECT().GetAssignment(12).Return(assignment.WithTestDetails{
+				ID:        12,
+				Status:    "inprogress",
+				Candidate: assignment.Candidate{GithubUsername: "jane"},
+				Test: assignment.Test{Stages: []assignment.Stage{
+					{Position: 1, TimeLimit: 3600},
+					{Position: 2, TimeLimit: 3600},
+				}},
+			}, nil)
+			ledger.EXPECT().GetStep(12, "stage:2").Return(assignment.StepRecord{EventID: "event-1"}, nil)
+
+			err := s.Submit(repoURL, "jane")
+			assert.ErrorIs(t, err, assignment.ErrEarlyStage)
+		})
+
+		t.Run("should error for repos without an assignment", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			finder := mocks.NewMockRepoFinder(ctrl)
+
+			s := assignment.Submitter{Finder: finder}
+
+			finder.EXPECT().AssignmentIDByRepoURL(repoURL).Return(0, nil)
+
+			err := s.Submit(repoURL, "jane")
+			assert.ErrorIs(t, err, assignment.ErrUnknownRepo)
+		})
+	})
+
+	t.Run("SubmitAssignment", func(t *testing.T) {
+		a := assignment.WithTestDetails{
+			ID:            12,
+			Status:        "inprogress",
+			CandidateID:   3,
+			GithubRepoURL: "https://gitlab.com/testrelay/jane-test.git",
+			Candidate:     assignment.Candidate{GithubUsername: "jane-gh", GitlabUsername: "jane"},
+			Test:          assignment.Test{Business: assignment.Business{VCSProvider: core.VCSProviderGitlab}},
+		}
+
+		t.Run("should check the submission through the provider and finish the assignment", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			fetcher := mocks.NewMockFetcher(ctrl)
+			sc := mocks.NewMockSchedulerClient(ctrl)
+			ledger := mocks.NewMockStepLedger(ctrl)
+			checker := coreMocks.NewMockVCSSubmissionChecker(ctrl)
+
+			s := assignment.Submitter{
+				Fetcher: fetcher,
+				Runner: assignment.RetryRunner{
+					Runner: assignment.Runner{
+						SubmissionChecker: checker,
+						Logger:            zap.NewNop().Sugar(),
+						SchedulerClient:   sc,
+						Ledger:            ledger,
+						Time:              func() time.Time { return now },
+					},
+				},
+			}
+
+			fetcher.EXPECT().GetAssignment(12).Return(a, nil)
+			checker.EXPECT().IsSubmitted(a.GithubRepoURL, "jane").Return(true, nil)
+			ledger.EXPECT().GetStep(12, "end").Return(assignment.StepRecord{
+				AssignmentID: 12,
+				Step:         "end",
+				EventID:      "event-1",
+			}, nil)
+			sc.EXPECT().Stop("event-1").Return(nil)
+			ledger.EXPECT().SaveStep(assignment.StepRecord{
+				AssignmentID: 12,
+				Step:         "end",
+				EventID:      "event-1",
+				Completed:    true,
+			}).Return(nil)
+			// the cleanup step has already completed, e.g. from an earlier submission that timed out.
+			ledger.EXPECT().GetStep(12, "cleanup").Return(assignment.StepRecord{Completed: true}, nil).Times(2)
+			expectClaim(ledger, 12, "cleanup")
+
+			err := s.SubmitAssignment(12, 3)
+			require.NoError(t, err)
+		})
+
+		t.Run("should not finish an assignment that has not been submitted", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			fetcher := mocks.NewMockFetcher(ctrl)
+			checker := coreMocks.NewMockVCSSubmissionChecker(ctrl)
+
+			s := assignment.Submitter{
+				Fetcher: fetcher,
+				Runner:  assignment.RetryRunner{Runner: assignment.Runner{SubmissionChecker: checker}},
+			}
+
+			fetcher.EXPECT().GetAssignment(12).Return(a, nil)
+			checker.EXPECT().IsSubmitted(a.GithubRepoURL, "jane").Return(false, nil)
+
+			err := s.SubmitAssignment(12, 3)
+			assert.ErrorIs(t, err, assignment.ErrNotSubmitted)
+		})
+
+		t.Run("should only let the candidate submit", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			fetcher := mocks.NewMockFetcher(ctrl)
+
+			s := assignment.Submitter{Fetcher: fetcher}
+
+			fetcher.EXPECT().GetAssignment(12).Return(a, nil)
+
+			err := s.SubmitAssignment(12, 4)
+			assert.ErrorIs(t, err, assignment.ErrNotCandidate)
+		})
+	})
+}
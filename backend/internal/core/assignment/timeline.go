@@ -0,0 +1,148 @@
+package assignment
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	intTime "github.com/testrelay/testrelay/backend/internal/time"
+)
+
+var ErrNoTimeLimit = errors.New("a time limit is required to simulate an assignment")
+
+// TimelineEntry is a step in the simulated timeline of an assignment.
+type TimelineEntry struct {
+	Step string
+	// Emails are the templates of the emails sent by the step. Emails that depend on the outcome of
+	// the step are given as each possible template, e.g. submitted|missed.
+	Emails []string
+	At     time.Time
+	// Skipped is true for reminders that would be sent before the step that schedules them runs,
+	// which are never scheduled.
+	Skipped bool
+}
+
+// SimulationInput holds the assignment to simulate. Any of the chosen day, time, timezone and time limit
+// that are set override those of the assignment with AssignmentID. Without an AssignmentID they describe
+// a hypothetical assignment without any reminders or stages.
+type SimulationInput struct {
+	AssignmentID int
+	DayChosen    string
+	TimeChosen   string
+	Timezone     string
+	// TimeLimit is the number of seconds the candidate has to complete the assignment.
+	TimeLimit int
+}
+
+// Simulator computes when each step of an assignment runs, using the same delays as the Runner.
+type Simulator struct {
+	Fetcher          Fetcher
+	StartDelay       time.Duration
+	WarningBeforeEnd time.Duration
+	Time             Time
+}
+
+// Simulate returns the timeline of the assignment in the input, in the order the steps run and in the
+// candidate's timezone, as if it were scheduled now. The schedule entry is the scheduled email, with its calendar
+// invite, that is sent at that point. Simulate never schedules any steps. Invalid choices are returned as an
+// intTime.ValidationError, see intTime.Parse.
+func (s Simulator) Simulate(input SimulationInput) ([]TimelineEntry, error) {
+	var assignment WithTestDetails
+	if input.AssignmentID != 0 {
+		var err error
+		assignment, err = s.Fetcher.GetAssignment(input.AssignmentID)
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch assignment id %d %w", input.AssignmentID, err)
+		}
+	}
+
+	if input.DayChosen != "" {
+		assignment.TestDayChosen = input.DayChosen
+	}
+	if input.TimeChosen != "" {
+		assignment.TestTimeChosen = input.TimeChosen
+	}
+	if input.Timezone != "" {
+		assignment.TestTimezoneChosen = input.Timezone
+	}
+	if input.TimeLimit != 0 {
+		assignment.TimeLimit = input.TimeLimit
+		assignment.Test.Stages = nil
+	}
+
+	if assignment.timeLimit() <= 0 {
+		return nil, ErrNoTimeLimit
+	}
+
+	t, err := intTime.Parse(intTime.AssignmentChoices{
+		DayChosen:  assignment.TestDayChosen,
+		TimeChosen: assignment.TestTimeChosen,
+		Timezone:   assignment.TestTimezoneChosen,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	startAt, _ := time.Parse(time.RFC3339, t.StartAssignmentAt)
+	notifyAt, _ := time.Parse(time.RFC3339, t.SendNotificationAt)
+	now := s.Time()
+
+	var timeline []TimelineEntry
+	initAt := startAt
+	if assignment.Mode != ModeOnDemand {
+		// scheduling the assignment emails the candidate the scheduled confirmation with its invite.ics.
+		timeline = append(timeline, TimelineEntry{Step: "schedule", Emails: []string{"scheduled"}, At: now})
+		timeline = append(timeline, reminderEntries(assignment, "start", startAt, now)...)
+		timeline = append(timeline, TimelineEntry{Step: "start", Emails: []string{"warning"}, At: notifyAt})
+		initAt = notifyAt.Add(s.StartDelay)
+	}
+
+	timeline = append(timeline, TimelineEntry{Step: "init", At: initAt})
+
+	stageAt := initAt
+	for i, stage := range assignment.Test.Stages {
+		if i > 0 {
+			timeline = append(timeline, TimelineEntry{Step: stage.Step(), Emails: []string{"stage"}, At: stageAt})
+		}
+
+		stageAt = stageAt.Add(time.Second * time.Duration(stage.TimeLimit))
+	}
+
+	deadline := initAt.Add(time.Second * time.Duration(assignment.timeLimit()))
+	timeline = append(timeline, reminderEntries(assignment, "end", deadline, initAt)...)
+
+	endAt := deadline.Add(-s.WarningBeforeEnd)
+	timeline = append(timeline,
+		TimelineEntry{Step: "end", Emails: []string{"end"}, At: endAt},
+		TimelineEntry{Step: "cleanup", Emails: []string{"submitted|missed", "submitted-recruiter|missed-recruiter"}, At: endAt.Add(s.WarningBeforeEnd)},
+	)
+
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].At.Before(timeline[j].At)
+	})
+
+	loc, _ := time.LoadLocation(assignment.TestTimezoneChosen)
+	for i := range timeline {
+		timeline[i].At = timeline[i].At.In(loc)
+	}
+
+	return timeline, nil
+}
+
+// reminderEntries returns an entry for each reminder sent before the point at, marking reminders that
+// would be sent before scheduledAt as skipped like scheduleReminders.
+func reminderEntries(assignment WithTestDetails, before string, at, scheduledAt time.Time) []TimelineEntry {
+	var entries []TimelineEntry
+	for _, r := range assignment.Test.reminders(before) {
+		sendAt := at.Add(-time.Minute * time.Duration(r.Minutes))
+		entries = append(entries, TimelineEntry{
+			Step:    r.Step(),
+			Emails:  []string{r.templateName()},
+			At:      sendAt,
+			Skipped: !sendAt.After(scheduledAt),
+		})
+	}
+
+	return entries
+}
@@ -0,0 +1,150 @@
+package assignment_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/testrelay/testrelay/backend/internal/core/assignment"
+	"github.com/testrelay/testrelay/backend/internal/core/assignment/mocks"
+	intTime "github.com/testrelay/testrelay/backend/internal/time"
+)
+
+func TestSimulator(t *testing.T) {
+	now := time.Date(2021, 11, 12, 8, 0, 0, 0, time.UTC)
+	london, err := time.LoadLocation("Europe/London")
+	require.NoError(t, err)
+
+	at := func(hour, min int) time.Time {
+		return time.Date(2021, 11, 12, hour, min, 0, 0, london)
+	}
+
+	t.Run("should simulate every step of an assignment", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		f := mocks.NewMockFetcher(ctrl)
+
+		s := assignment.Simulator{
+			Fetcher:          f,
+			StartDelay:       time.Minute * 5,
+			WarningBeforeEnd: time.Minute * 10,
+			Time:             func() time.Time { return now },
+		}
+
+		f.EXPECT().GetAssignment(12).Return(assignment.WithTestDetails{
+			ID:                 12,
+			TestDayChosen:      "2021-11-12",
+			TestTimeChosen:     "10:00:00",
+			TestTimezoneChosen: "Europe/London",
+			Test: assignment.Test{
+				Reminders: []assignment.Reminder{
+					{Before: "start", Minutes: 1440},
+					{Before: "start", Minutes: 60},
+					{Before: "end", Minutes: 30, Template: "custom-end"},
+				},
+				Stages: []assignment.Stage{
+					{Position: 1, TimeLimit: 3600},
+					{Position: 2, TimeLimit: 1800},
+				},
+			},
+		}, nil)
+
+		timeline, err := s.Simulate(assignment.SimulationInput{AssignmentID: 12})
+		require.NoError(t, err)
+
+		assert.Equal(t, []assignment.TimelineEntry{
+			{Step: "reminder:start:1440", Emails: []string{"reminder-start"}, At: time.Date(2021, 11, 11, 10, 0, 0, 0, london), Skipped: true},
+			{Step: "schedule", Emails: []string{"scheduled"}, At: at(8, 0)},
+			{Step: "reminder:start:60", Emails: []string{"reminder-start"}, At: at(9, 0)},
+			{Step: "start", Emails: []string{"warning"}, At: at(9, 55)},
+			{Step: "init", At: at(10, 0)},
+			{Step: "stage:2", Emails: []string{"stage"}, At: at(11, 0)},
+			{Step: "reminder:end:30", Emails: []string{"custom-end"}, At: at(11, 0)},
+			{Step: "end", Emails: []string{"end"}, At: at(11, 20)},
+			{Step: "cleanup", Emails: []string{"submitted|missed", "submitted-recruiter|missed-recruiter"}, At: at(11, 30)},
+		}, timeline)
+	})
+
+	t.Run("should simulate a hypothetical assignment", func(t *testing.T) {
+		s := assignment.Simulator{
+			StartDelay:       time.Minute * 5,
+			WarningBeforeEnd: time.Minute * 10,
+			Time:             func() time.Time { return now },
+		}
+
+		timeline, err := s.Simulate(assignment.SimulationInput{
+			DayChosen:  "2021-11-12",
+			TimeChosen: "10:00:00",
+			Timezone:   "Europe/London",
+			TimeLimit:  7200,
+		})
+		require.NoError(t, err)
+
+		steps := make([]string, len(timeline))
+		for i, e := range timeline {
+			steps[i] = e.Step
+		}
+		assert.Equal(t, []string{"schedule", "start", "init", "end", "cleanup"}, steps)
+		assert.Equal(t, at(12, 0), timeline[4].At)
+	})
+
+	t.Run("should start on demand assignments without a warning", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		f := mocks.NewMockFetcher(ctrl)
+
+		s := assignment.Simulator{Fetcher: f, WarningBeforeEnd: time.Minute * 10, Time: func() time.Time { return now }}
+
+		f.EXPECT().GetAssignment(12).Return(assignment.WithTestDetails{
+			ID:        12,
+			Mode:      assignment.ModeOnDemand,
+			TimeLimit: 3600,
+		}, nil)
+
+		timeline, err := s.Simulate(assignment.SimulationInput{
+			AssignmentID: 12,
+			DayChosen:    "2021-11-12",
+			TimeChosen:   "10:00:00",
+			Timezone:     "Europe/London",
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "init", timeline[0].Step)
+		assert.Equal(t, at(10, 0), timeline[0].At)
+	})
+
+	t.Run("should send the scheduled email with its invite when the assignment is scheduled", func(t *testing.T) {
+		s := assignment.Simulator{
+			StartDelay:       time.Minute * 5,
+			WarningBeforeEnd: time.Minute * 10,
+			Time:             func() time.Time { return now },
+		}
+
+		timeline, err := s.Simulate(assignment.SimulationInput{
+			DayChosen:  "2021-11-15",
+			TimeChosen: "10:00:00",
+			Timezone:   "Europe/London",
+			TimeLimit:  7200,
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, assignment.TimelineEntry{
+			Step:   "schedule",
+			Emails: []string{"scheduled"},
+			At:     now.In(london),
+		}, timeline[0])
+	})
+
+	t.Run("should return errors for invalid input", func(t *testing.T) {
+		s := assignment.Simulator{Time: func() time.Time { return now }}
+
+		_, err := s.Simulate(assignment.SimulationInput{DayChosen: "2021-11-12", TimeChosen: "10:00:00", Timezone: "UTC"})
+		assert.ErrorIs(t, err, assignment.ErrNoTimeLimit)
+
+		_, err = s.Simulate(assignment.SimulationInput{DayChosen: "2021-11-12", TimeChosen: "10am", Timezone: "UTC", TimeLimit: 60})
+		var v intTime.ValidationError
+		require.ErrorAs(t, err, &v)
+		assert.Equal(t, intTime.CodeInvalidTime, v.Code)
+	})
+}
@@ -25,8 +25,18 @@ func (a Assigner) Assign(r RawReviewer) error {
 		return fmt.Errorf("could not fetch reviewer id: %d err %w", r.ID, err)
 	}
 
-	if rd.Assignment.GithubRepoUrl != "" && rd.User.GithubUsername != "" {
-		err := a.VCSClient.AddCollaborator(rd.Assignment.GithubRepoUrl, rd.User.GithubUsername)
+	username := rd.User.GithubUsername
+	switch rd.Assignment.VCSProvider {
+	case core.VCSProviderGitlab:
+		username = rd.User.GitlabUsername
+	case core.VCSProviderBitbucket:
+		username = rd.User.BitbucketAccountID
+	case core.VCSProviderLocal:
+		username = rd.User.Email
+	}
+
+	if rd.Assignment.GithubRepoUrl != "" && username != "" {
+		err := a.VCSClient.AddCollaborator(rd.Assignment.GithubRepoUrl, username)
 		if err != nil {
 			// return as nothing to do here
 			if errors.Is(err, vcs.ErrorAlreadyCollaborator) {
@@ -35,7 +45,7 @@ func (a Assigner) Assign(r RawReviewer) error {
 
 			return fmt.Errorf(
 				"could not vcs collaborator: %s to repo: %s %w",
-				rd.User.GithubUsername,
+				username,
 				rd.Assignment.GithubRepoUrl,
 				err,
 			)
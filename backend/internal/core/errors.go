@@ -0,0 +1,33 @@
+package core
+
+import "errors"
+
+// TransientError wraps an error that is expected to succeed if retried later,
+// e.g. a vcs rate limit, a 5xx response or a dropped connection.
+// Errors that are not transient are treated as permanent.
+type TransientError struct {
+	Err error
+}
+
+func (e TransientError) Error() string {
+	return e.Err.Error()
+}
+
+func (e TransientError) Unwrap() error {
+	return e.Err
+}
+
+// NewTransientError marks err as transient. It returns nil if err is nil.
+func NewTransientError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return TransientError{Err: err}
+}
+
+// IsTransient returns whether err, or any error it wraps, is a TransientError.
+func IsTransient(err error) bool {
+	var t TransientError
+	return errors.As(err, &t)
+}
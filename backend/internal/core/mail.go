@@ -1,12 +1,37 @@
 package core
 
 //go:generate mockgen -destination mocks/mailer.go -package mocks . Mailer
+import "time"
 
 type MailConfig struct {
 	TemplateName string
 	Subject      string
 	From         string
 	To           string
+	Attachments  []Attachment
+	// Event is attached to the mail as an iCalendar file when set.
+	Event *CalendarEvent
+}
+
+// Attachment is a file attached to a mail.
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// CalendarEvent is a calendar event sent to the recipient of a mail. Updates to an event must use
+// the same UID with a higher Sequence.
+type CalendarEvent struct {
+	UID       string
+	Sequence  int
+	Cancelled bool
+	Summary   string
+	// Description and URL are shown to the recipient in their calendar.
+	Description string
+	URL         string
+	Start       time.Time
+	End         time.Time
 }
 
 type SMTPConfig struct {
@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/testrelay/testrelay/backend/internal/core (interfaces: VCSCollaboratorAdder,VCSUploader,VCSCleaner,VCSSubmissionChecker,VCSCreator)
+// Source: github.com/testrelay/testrelay/backend/internal/core (interfaces: VCSCollaboratorAdder,VCSUploader,VCSCleaner,VCSSubmissionChecker,VCSStageSubmissionChecker,VCSCreator,VCSValidator)
 
 // Package mocks is a generated GoMock package.
 package mocks
@@ -72,11 +72,12 @@ func (m *MockVCSUploader) EXPECT() *MockVCSUploaderMockRecorder {
 }
 
 // Upload mocks base method.
-func (m *MockVCSUploader) Upload(arg0 core.UploadDetails) error {
+func (m *MockVCSUploader) Upload(arg0 core.UploadDetails) (core.UploadResult, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Upload", arg0)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(core.UploadResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // Upload indicates an expected call of Upload.
@@ -160,6 +161,44 @@ func (mr *MockVCSSubmissionCheckerMockRecorder) IsSubmitted(arg0, arg1 interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsSubmitted", reflect.TypeOf((*MockVCSSubmissionChecker)(nil).IsSubmitted), arg0, arg1)
 }
 
+// MockVCSStageSubmissionChecker is a mock of VCSStageSubmissionChecker interface.
+type MockVCSStageSubmissionChecker struct {
+	ctrl     *gomock.Controller
+	recorder *MockVCSStageSubmissionCheckerMockRecorder
+}
+
+// MockVCSStageSubmissionCheckerMockRecorder is the mock recorder for MockVCSStageSubmissionChecker.
+type MockVCSStageSubmissionCheckerMockRecorder struct {
+	mock *MockVCSStageSubmissionChecker
+}
+
+// NewMockVCSStageSubmissionChecker creates a new mock instance.
+func NewMockVCSStageSubmissionChecker(ctrl *gomock.Controller) *MockVCSStageSubmissionChecker {
+	mock := &MockVCSStageSubmissionChecker{ctrl: ctrl}
+	mock.recorder = &MockVCSStageSubmissionCheckerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockVCSStageSubmissionChecker) EXPECT() *MockVCSStageSubmissionCheckerMockRecorder {
+	return m.recorder
+}
+
+// IsStageSubmitted mocks base method.
+func (m *MockVCSStageSubmissionChecker) IsStageSubmitted(arg0, arg1, arg2 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsStageSubmitted", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsStageSubmitted indicates an expected call of IsStageSubmitted.
+func (mr *MockVCSStageSubmissionCheckerMockRecorder) IsStageSubmitted(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsStageSubmitted", reflect.TypeOf((*MockVCSStageSubmissionChecker)(nil).IsStageSubmitted), arg0, arg1, arg2)
+}
+
 // MockVCSCreator is a mock of VCSCreator interface.
 type MockVCSCreator struct {
 	ctrl     *gomock.Controller
@@ -184,16 +223,54 @@ func (m *MockVCSCreator) EXPECT() *MockVCSCreatorMockRecorder {
 }
 
 // CreateRepo mocks base method.
-func (m *MockVCSCreator) CreateRepo(arg0, arg1 string, arg2 int) (string, error) {
+func (m *MockVCSCreator) CreateRepo(arg0 core.CreateDetails) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateRepo", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "CreateRepo", arg0)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateRepo indicates an expected call of CreateRepo.
-func (mr *MockVCSCreatorMockRecorder) CreateRepo(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockVCSCreatorMockRecorder) CreateRepo(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRepo", reflect.TypeOf((*MockVCSCreator)(nil).CreateRepo), arg0)
+}
+
+// MockVCSValidator is a mock of VCSValidator interface.
+type MockVCSValidator struct {
+	ctrl     *gomock.Controller
+	recorder *MockVCSValidatorMockRecorder
+}
+
+// MockVCSValidatorMockRecorder is the mock recorder for MockVCSValidator.
+type MockVCSValidatorMockRecorder struct {
+	mock *MockVCSValidator
+}
+
+// NewMockVCSValidator creates a new mock instance.
+func NewMockVCSValidator(ctrl *gomock.Controller) *MockVCSValidator {
+	mock := &MockVCSValidator{ctrl: ctrl}
+	mock.recorder = &MockVCSValidatorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockVCSValidator) EXPECT() *MockVCSValidatorMockRecorder {
+	return m.recorder
+}
+
+// Validate mocks base method.
+func (m *MockVCSValidator) Validate(arg0 core.UploadDetails) (core.ValidateResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Validate", arg0)
+	ret0, _ := ret[0].(core.ValidateResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Validate indicates an expected call of Validate.
+func (mr *MockVCSValidatorMockRecorder) Validate(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRepo", reflect.TypeOf((*MockVCSCreator)(nil).CreateRepo), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Validate", reflect.TypeOf((*MockVCSValidator)(nil).Validate), arg0)
 }
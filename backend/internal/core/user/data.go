@@ -20,6 +20,9 @@ type U struct {
 type Short struct {
 	Email          string
 	GithubUsername string
+	GitlabUsername string
+	// BitbucketAccountID identifies the user on bitbucket.
+	BitbucketAccountID string
 }
 
 type AuthClaims struct {
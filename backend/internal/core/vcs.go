@@ -1,12 +1,75 @@
 package core
 
-//go:generate mockgen -destination mocks/vcs.go -package mocks . VCSCollaboratorAdder,VCSUploader,VCSCleaner,VCSSubmissionChecker,VCSCreator
+//go:generate mockgen -destination mocks/vcs.go -package mocks . VCSCollaboratorAdder,VCSUploader,VCSCleaner,VCSSubmissionChecker,VCSStageSubmissionChecker,VCSCreator,VCSValidator
+
+// VCS providers that a business can choose to host assignment repos.
+const (
+	VCSProviderGithub    = "github"
+	VCSProviderGitlab    = "gitlab"
+	VCSProviderBitbucket = "bitbucket"
+	// VCSProviderLocal hosts repos as bare git repositories on the backend, identifying users by email.
+	VCSProviderLocal = "local"
+)
+
+// Upload modes that a test can use to fill assignment repos.
+const (
+	// UploadModePush pushes the test repo into an empty assignment repo as a single start test commit.
+	UploadModePush = "push"
+	// UploadModeTemplate generates the assignment repo from the test repo, which must be a github template
+	// repository, keeping the repo's settings rather than flattening it into a single commit.
+	UploadModeTemplate = "template"
+)
+
+type CreateDetails struct {
+	// Provider is one of github|gitlab|bitbucket|local. It defaults to github.
+	Provider     string
+	BusinessName string
+	Username     string
+	ID           int
+	// Org is the github org that the repo is created in, through the github app installation InstallationID.
+	// The repo is created under the interviewer account if it is empty.
+	Org            string
+	InstallationID int64
+	// TemplateRepo is the github template repository that the repo is generated from, see UploadModeTemplate.
+	TemplateRepo string
+	// TemplateOwner is the org that a repo generated from TemplateRepo is created in, through InstallationID.
+	// It defaults to Org.
+	TemplateOwner string
+}
 
 type UploadDetails struct {
 	ID             int64
 	VCSRepoURL     string
 	TestVCSRepoURL string
+	// Ref is the branch, tag or commit of TestVCSRepoURL to upload. It defaults to the default branch.
+	Ref string
+	// Path is the directory of TestVCSRepoURL that is uploaded as the root of VCSRepoURL, e.g. a single exercise
+	// of a monorepo. It defaults to the root of TestVCSRepoURL.
+	Path string
+	// InstallationID is the github app installation, or the gitlab group, that has access to TestVCSRepoURL.
 	InstallationID int64
+	// Workspace is the bitbucket workspace that owns TestVCSRepoURL.
+	Workspace string
+	// Provider hosts TestVCSRepoURL, one of github|gitlab|bitbucket|local. It defaults to github.
+	Provider string
+	// Branch is the branch of VCSRepoURL to upload to. It defaults to the default branch.
+	Branch string
+	// TemplateGlob is a gitignore style pattern matching the text files of the test repo that are rendered
+	// with text/template, using TemplateData, before they are committed. No files are rendered if it is empty.
+	TemplateGlob string
+	TemplateData TemplateData
+}
+
+// TemplateData holds the values that test files can reference, e.g. {{ .CandidateName }}.
+type TemplateData struct {
+	CandidateName  string
+	CandidateEmail string
+	BusinessName   string
+	TestName       string
+	// Deadline is when the uploaded assignment, or stage, ends, formatted in the candidate's timezone.
+	Deadline string
+	// Timezone is the candidate's chosen timezone, e.g. Europe/London.
+	Timezone string
 }
 
 type CleanDetails struct {
@@ -20,8 +83,32 @@ type VCSCollaboratorAdder interface {
 	AddCollaborator(repo string, username string) error
 }
 
+// UploadResult describes the test code that was uploaded into an assignment repo.
+type UploadResult struct {
+	// SHA is the commit of the test repo that was uploaded.
+	SHA string
+	// Excluded holds the paths of the files in the test repo that were left out of the candidate's commit
+	// by its .testrelayignore file. They remain in the test repo at SHA.
+	Excluded []string
+}
+
+// VCSUploader uploads the test code into an assignment repo.
 type VCSUploader interface {
-	Upload(data UploadDetails) error
+	Upload(data UploadDetails) (UploadResult, error)
+}
+
+// ValidateResult describes the files of a test repo that would be committed to an assignment repo.
+type ValidateResult struct {
+	UploadResult
+	// Files is the number of files that would be committed and Size is their total size in bytes.
+	Files int
+	Size  int64
+}
+
+// VCSValidator checks that a test repo can be uploaded, downloading and unpacking it as VCSUploader would
+// without pushing it anywhere. The VCSRepoURL and Branch of data are ignored.
+type VCSValidator interface {
+	Validate(data UploadDetails) (ValidateResult, error)
 }
 
 type VCSCleaner interface {
@@ -32,8 +119,14 @@ type VCSSubmissionChecker interface {
 	IsSubmitted(vcsURL, username string) (bool, error)
 }
 
+// VCSStageSubmissionChecker checks whether the candidate has submitted a single stage of a staged test,
+// by opening a pull request into the branch the stage was uploaded to.
+type VCSStageSubmissionChecker interface {
+	IsStageSubmitted(vcsURL, username, branch string) (bool, error)
+}
+
 type VCSCreator interface {
-	CreateRepo(businessName, username string, id int) (string, error)
+	CreateRepo(details CreateDetails) (string, error)
 }
 
 type Repo struct {
@@ -41,6 +134,22 @@ type Repo struct {
 	FullName string `json:"full_name"`
 }
 
+// RepoDir is a directory within a test repo.
+type RepoDir struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
 type RepoCollector interface {
 	CollectRepos(installationID int64) ([]Repo, error)
+	// CollectDirs lists the directories at path in the repo with fullName, so that a test can use a subtree
+	// of a monorepo. An empty path lists the top level directories.
+	CollectDirs(installationID int64, fullName, path string) ([]RepoDir, error)
+}
+
+// WorkspaceCollector is a RepoCollector for bitbucket, whose repos are grouped by workspace slug rather than by
+// installation.
+type WorkspaceCollector interface {
+	CollectRepos(workspace string) ([]Repo, error)
+	CollectDirs(workspace, fullName, path string) ([]RepoDir, error)
 }
@@ -1,13 +1,15 @@
 package http
 
-//go:generate mockgen -destination mocks/assignments.go -package mocks . AssignmentScheduler
+//go:generate mockgen -destination mocks/assignments.go -package mocks . AssignmentScheduler,StepRunner,AssignmentExpirer,AssignmentReconciler,DeadLetterReplayer
 import (
 	"encoding/json"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/gorilla/mux"
 	"go.uber.org/zap"
 
 	"github.com/testrelay/testrelay/backend/internal/core/assignment"
@@ -76,12 +78,35 @@ type AssignmentScheduler interface {
 	Stop(assignmentID int) error
 }
 
+// StepRunner defines an interface for a type that runs a single assignment step. See assignment.RetryRunner.
+type StepRunner interface {
+	Run(step string, data assignment.RunData) error
+}
+
+// AssignmentExpirer defines an interface for a type that expires assignments the candidate never scheduled.
+type AssignmentExpirer interface {
+	Sweep() (int, error)
+}
+
+// AssignmentReconciler defines an interface for a type that repairs assignments with missing steps.
+type AssignmentReconciler interface {
+	Reconcile(apply bool) ([]assignment.Repair, error)
+}
+
+// DeadLetterReplayer defines an interface for a type that re-runs a dead lettered assignment step.
+type DeadLetterReplayer interface {
+	Replay(id int) error
+}
+
 // AssignmentHandler implements a number of http.Handlers that are used in the base http server.
 type AssignmentHandler struct {
-	Inviter   assignment.Inviter
-	Logger    *zap.SugaredLogger
-	Scheduler AssignmentScheduler
-	Runner    assignment.Runner
+	Inviter    assignment.Inviter
+	Logger     *zap.SugaredLogger
+	Scheduler  AssignmentScheduler
+	Runner     StepRunner
+	Expirer    AssignmentExpirer
+	Reconciler AssignmentReconciler
+	Replayer   DeadLetterReplayer
 }
 
 // EventHandler defines a http.HandlerFunc that handles inbound hasura events.
@@ -193,7 +218,7 @@ func (a AssignmentHandler) ProcessHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = a.Runner.Run(data.Payload.Step, assignment.RunData{Data: data.Payload.Data})
+	err = a.Runner.Run(data.Payload.Step, assignment.RunData{Data: data.Payload.Data, EventID: data.Id})
 	if err != nil {
 		a.Logger.Error(
 			"run step errored",
@@ -207,3 +232,69 @@ func (a AssignmentHandler) ProcessHandler(w http.ResponseWriter, r *http.Request
 
 	httputil.Success(w)
 }
+
+// ExpireHandler defines a http.HandlerFunc that handles the inbound hasura cron trigger
+// that periodically expires assignments that were never scheduled.
+func (a AssignmentHandler) ExpireHandler(w http.ResponseWriter, r *http.Request) {
+	n, err := a.Expirer.Sweep()
+	if err != nil {
+		a.Logger.Error("could not expire assignments", "error", err)
+
+		httputil.BadRequest(w)
+		return
+	}
+
+	a.Logger.Info("expired assignments", "count", n)
+	httputil.Success(w)
+}
+
+type reconcileRequest struct {
+	Apply bool `json:"apply"`
+}
+
+type reconcileResponse struct {
+	Repairs []assignment.Repair `json:"repairs"`
+}
+
+// ReconcileHandler defines a http.HandlerFunc that reports assignments with missing steps.
+// Missing steps are only run if the request body sets apply to true, otherwise the request is a dry run.
+func (a AssignmentHandler) ReconcileHandler(w http.ResponseWriter, r *http.Request) {
+	var req reconcileRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && err != io.EOF {
+		a.Logger.Error("could not decode reconcile request", "error", err)
+
+		httputil.BadRequest(w)
+		return
+	}
+
+	repairs, err := a.Reconciler.Reconcile(req.Apply)
+	if err != nil {
+		a.Logger.Error("could not reconcile assignments", "error", err)
+
+		httputil.BadRequest(w)
+		return
+	}
+
+	a.Logger.Info("reconciled assignments", "apply", req.Apply, "repairs", len(repairs))
+	json.NewEncoder(w).Encode(reconcileResponse{Repairs: repairs})
+}
+
+// ReplayHandler defines a http.HandlerFunc that re-runs the dead lettered step with the id in the path.
+func (a AssignmentHandler) ReplayHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		httputil.BadRequest(w)
+		return
+	}
+
+	err = a.Replayer.Replay(id)
+	if err != nil {
+		a.Logger.Error("could not replay dead letter", "dead_letter_id", id, "error", err)
+
+		httputil.BadRequest(w)
+		return
+	}
+
+	httputil.Success(w)
+}
@@ -2,15 +2,19 @@ package http_test
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	http2 "net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 
+	"github.com/testrelay/testrelay/backend/internal/core/assignment"
 	"github.com/testrelay/testrelay/backend/internal/events/http"
 	"github.com/testrelay/testrelay/backend/internal/events/http/mocks"
 )
@@ -81,4 +85,123 @@ func TestAssignmentHandler(t *testing.T) {
 			})
 		})
 	})
+	t.Run("ExpireHandler", func(t *testing.T) {
+		t.Run("should sweep expired assignments", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			e := mocks.NewMockAssignmentExpirer(ctrl)
+
+			h := http.AssignmentHandler{
+				Logger:  logger,
+				Expirer: e,
+			}
+
+			e.EXPECT().Sweep().Return(2, nil)
+
+			w := httptest.NewRecorder()
+			h.ExpireHandler(w, httptest.NewRequest("POST", "/", bytes.NewBufferString(`{}`)))
+
+			assert.Equal(t, http2.StatusOK, w.Code)
+		})
+
+		t.Run("should error if the sweep fails", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			e := mocks.NewMockAssignmentExpirer(ctrl)
+
+			h := http.AssignmentHandler{
+				Logger:  logger,
+				Expirer: e,
+			}
+
+			e.EXPECT().Sweep().Return(0, errors.New("hasura down"))
+
+			w := httptest.NewRecorder()
+			h.ExpireHandler(w, httptest.NewRequest("POST", "/", bytes.NewBufferString(`{}`)))
+
+			assert.Equal(t, http2.StatusBadRequest, w.Code)
+		})
+	})
+	t.Run("ReconcileHandler", func(t *testing.T) {
+		t.Run("should apply repairs and respond with the report", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			rec := mocks.NewMockAssignmentReconciler(ctrl)
+
+			h := http.AssignmentHandler{
+				Logger:     logger,
+				Reconciler: rec,
+			}
+
+			due := time.Date(2021, 11, 12, 10, 0, 0, 0, time.UTC)
+			rec.EXPECT().Reconcile(true).Return([]assignment.Repair{
+				{AssignmentID: 12, Status: "scheduled", Step: "init", Due: due, Applied: true},
+			}, nil)
+
+			w := httptest.NewRecorder()
+			h.ReconcileHandler(w, httptest.NewRequest("POST", "/", bytes.NewBufferString(`{"apply": true}`)))
+
+			assert.Equal(t, http2.StatusOK, w.Code)
+			assert.JSONEq(t, `{"repairs": [{
+				"assignment_id": 12,
+				"status": "scheduled",
+				"step": "init",
+				"due": "2021-11-12T10:00:00Z",
+				"applied": true
+			}]}`, w.Body.String())
+		})
+
+		t.Run("should default to a dry run", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			rec := mocks.NewMockAssignmentReconciler(ctrl)
+
+			h := http.AssignmentHandler{
+				Logger:     logger,
+				Reconciler: rec,
+			}
+
+			rec.EXPECT().Reconcile(false).Return([]assignment.Repair{}, nil)
+
+			w := httptest.NewRecorder()
+			h.ReconcileHandler(w, httptest.NewRequest("POST", "/", nil))
+
+			assert.Equal(t, http2.StatusOK, w.Code)
+			assert.JSONEq(t, `{"repairs": []}`, w.Body.String())
+		})
+	})
+
+	t.Run("ReplayHandler", func(t *testing.T) {
+		t.Run("should replay the dead letter in the path", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			rep := mocks.NewMockDeadLetterReplayer(ctrl)
+
+			h := http.AssignmentHandler{
+				Logger:   logger,
+				Replayer: rep,
+			}
+
+			rep.EXPECT().Replay(4).Return(nil)
+
+			w := httptest.NewRecorder()
+			req := mux.SetURLVars(httptest.NewRequest("POST", "/", nil), map[string]string{"id": "4"})
+			h.ReplayHandler(w, req)
+
+			assert.Equal(t, http2.StatusOK, w.Code)
+		})
+
+		t.Run("should error if the replay fails", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			rep := mocks.NewMockDeadLetterReplayer(ctrl)
+
+			h := http.AssignmentHandler{
+				Logger:   logger,
+				Replayer: rep,
+			}
+
+			rep.EXPECT().Replay(4).Return(assignment.ErrAlreadyReplayed)
+
+			w := httptest.NewRecorder()
+			req := mux.SetURLVars(httptest.NewRequest("POST", "/", nil), map[string]string{"id": "4"})
+			h.ReplayHandler(w, req)
+
+			assert.Equal(t, http2.StatusBadRequest, w.Code)
+		})
+	})
 }
@@ -0,0 +1,88 @@
+package http
+
+//go:generate mockgen -destination mocks/github.go -package mocks . Submitter
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/go-github/v39/github"
+	"go.uber.org/zap"
+
+	"github.com/testrelay/testrelay/backend/internal/core/assignment"
+	"github.com/testrelay/testrelay/backend/internal/httputil"
+)
+
+// Submitter defines an interface for a type that finishes an assignment once the candidate has submitted.
+type Submitter interface {
+	Submit(repoURL, username string) error
+}
+
+// GithubHandler handles inbound github webhooks for assignment repos.
+type GithubHandler struct {
+	Secret    string
+	Submitter Submitter
+	Logger    *zap.SugaredLogger
+}
+
+// WebhookHandler defines a http.HandlerFunc that handles github webhook deliveries.
+// Deliveries must be signed with the Secret. A candidate opening a pull request on their
+// assignment repo submits the assignment early, see assignment.Submitter.
+func (g GithubHandler) WebhookHandler(w http.ResponseWriter, r *http.Request) {
+	payload, err := github.ValidatePayload(r, []byte(g.Secret))
+	if err != nil {
+		g.Logger.Error("could not validate github webhook payload", "error", err)
+
+		httputil.Unauthorized(w)
+		return
+	}
+
+	event, err := github.ParseWebHook(github.WebHookType(r), payload)
+	if err != nil {
+		g.Logger.Error(
+			"could not parse github webhook",
+			"type", github.WebHookType(r),
+			"error", err,
+		)
+
+		httputil.BadRequest(w)
+		return
+	}
+
+	e, ok := event.(*github.PullRequestEvent)
+	if !ok || (e.GetAction() != "opened" && e.GetAction() != "reopened") {
+		httputil.Success(w)
+		return
+	}
+
+	repoURL := e.GetRepo().GetCloneURL()
+	username := e.GetPullRequest().GetUser().GetLogin()
+	err = g.Submitter.Submit(repoURL, username)
+	if err != nil {
+		if errors.Is(err, assignment.ErrUnknownRepo) ||
+			errors.Is(err, assignment.ErrNotCandidate) ||
+			errors.Is(err, assignment.ErrNotInProgress) ||
+			errors.Is(err, assignment.ErrEarlyStage) {
+			g.Logger.Info(
+				"ignoring pull request",
+				"repo", repoURL,
+				"username", username,
+				"reason", err,
+			)
+
+			httputil.Success(w)
+			return
+		}
+
+		g.Logger.Error(
+			"could not submit assignment",
+			"repo", repoURL,
+			"username", username,
+			"error", err,
+		)
+
+		httputil.BadRequest(w)
+		return
+	}
+
+	httputil.Success(w)
+}
@@ -0,0 +1,95 @@
+package http_test
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	http2 "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/testrelay/testrelay/backend/internal/core/assignment"
+	"github.com/testrelay/testrelay/backend/internal/events/http"
+	"github.com/testrelay/testrelay/backend/internal/events/http/mocks"
+)
+
+func TestGithubHandler(t *testing.T) {
+	logger := zap.NewNop().Sugar()
+	secret := "webhooksecret"
+
+	newRequest := func(event, body, secret string) *http2.Request {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(body))
+
+		req := httptest.NewRequest(http2.MethodPost, "/github/webhook", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-GitHub-Event", event)
+		req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		return req
+	}
+
+	opened := `{
+    "action": "opened",
+    "pull_request": {"user": {"login": "jane"}},
+    "repository": {"clone_url": "https://github.com/testrelay/repo.git"}
+}`
+
+	t.Run("WebhookHandler", func(t *testing.T) {
+		t.Run("should submit the assignment when a pull request is opened", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			s := mocks.NewMockSubmitter(ctrl)
+
+			h := http.GithubHandler{Secret: secret, Submitter: s, Logger: logger}
+
+			s.EXPECT().Submit("https://github.com/testrelay/repo.git", "jane").Return(nil)
+
+			w := httptest.NewRecorder()
+			h.WebhookHandler(w, newRequest("pull_request", opened, secret))
+
+			assert.Equal(t, http2.StatusOK, w.Code)
+		})
+
+		t.Run("should ignore pull requests that do not belong to an in progress assignment", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			s := mocks.NewMockSubmitter(ctrl)
+
+			h := http.GithubHandler{Secret: secret, Submitter: s, Logger: logger}
+
+			s.EXPECT().Submit("https://github.com/testrelay/repo.git", "jane").Return(assignment.ErrNotInProgress)
+
+			w := httptest.NewRecorder()
+			h.WebhookHandler(w, newRequest("pull_request", opened, secret))
+
+			assert.Equal(t, http2.StatusOK, w.Code)
+		})
+
+		t.Run("should ignore other events", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			s := mocks.NewMockSubmitter(ctrl)
+
+			h := http.GithubHandler{Secret: secret, Submitter: s, Logger: logger}
+
+			w := httptest.NewRecorder()
+			h.WebhookHandler(w, newRequest("pull_request", `{"action": "closed"}`, secret))
+
+			assert.Equal(t, http2.StatusOK, w.Code)
+		})
+
+		t.Run("should reject payloads with an invalid signature", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			s := mocks.NewMockSubmitter(ctrl)
+
+			h := http.GithubHandler{Secret: secret, Submitter: s, Logger: logger}
+
+			w := httptest.NewRecorder()
+			h.WebhookHandler(w, newRequest("pull_request", opened, "wrongsecret"))
+
+			assert.Equal(t, http2.StatusUnauthorized, w.Code)
+		})
+	})
+}
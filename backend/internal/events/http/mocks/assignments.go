@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/testrelay/testrelay/backend/internal/events/http (interfaces: AssignmentScheduler)
+// Source: github.com/testrelay/testrelay/backend/internal/events/http (interfaces: AssignmentScheduler,StepRunner,AssignmentExpirer,AssignmentReconciler,DeadLetterReplayer)
 
 // Package mocks is a generated GoMock package.
 package mocks
@@ -8,6 +8,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	assignment "github.com/testrelay/testrelay/backend/internal/core/assignment"
 )
 
 // MockAssignmentScheduler is a mock of AssignmentScheduler interface.
@@ -60,3 +61,153 @@ func (mr *MockAssignmentSchedulerMockRecorder) Stop(arg0 interface{}) *gomock.Ca
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stop", reflect.TypeOf((*MockAssignmentScheduler)(nil).Stop), arg0)
 }
+
+// MockStepRunner is a mock of StepRunner interface.
+type MockStepRunner struct {
+	ctrl     *gomock.Controller
+	recorder *MockStepRunnerMockRecorder
+}
+
+// MockStepRunnerMockRecorder is the mock recorder for MockStepRunner.
+type MockStepRunnerMockRecorder struct {
+	mock *MockStepRunner
+}
+
+// NewMockStepRunner creates a new mock instance.
+func NewMockStepRunner(ctrl *gomock.Controller) *MockStepRunner {
+	mock := &MockStepRunner{ctrl: ctrl}
+	mock.recorder = &MockStepRunnerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStepRunner) EXPECT() *MockStepRunnerMockRecorder {
+	return m.recorder
+}
+
+// Run mocks base method.
+func (m *MockStepRunner) Run(arg0 string, arg1 assignment.RunData) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Run", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Run indicates an expected call of Run.
+func (mr *MockStepRunnerMockRecorder) Run(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Run", reflect.TypeOf((*MockStepRunner)(nil).Run), arg0, arg1)
+}
+
+// MockAssignmentExpirer is a mock of AssignmentExpirer interface.
+type MockAssignmentExpirer struct {
+	ctrl     *gomock.Controller
+	recorder *MockAssignmentExpirerMockRecorder
+}
+
+// MockAssignmentExpirerMockRecorder is the mock recorder for MockAssignmentExpirer.
+type MockAssignmentExpirerMockRecorder struct {
+	mock *MockAssignmentExpirer
+}
+
+// NewMockAssignmentExpirer creates a new mock instance.
+func NewMockAssignmentExpirer(ctrl *gomock.Controller) *MockAssignmentExpirer {
+	mock := &MockAssignmentExpirer{ctrl: ctrl}
+	mock.recorder = &MockAssignmentExpirerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAssignmentExpirer) EXPECT() *MockAssignmentExpirerMockRecorder {
+	return m.recorder
+}
+
+// Sweep mocks base method.
+func (m *MockAssignmentExpirer) Sweep() (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Sweep")
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Sweep indicates an expected call of Sweep.
+func (mr *MockAssignmentExpirerMockRecorder) Sweep() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Sweep", reflect.TypeOf((*MockAssignmentExpirer)(nil).Sweep))
+}
+
+// MockAssignmentReconciler is a mock of AssignmentReconciler interface.
+type MockAssignmentReconciler struct {
+	ctrl     *gomock.Controller
+	recorder *MockAssignmentReconcilerMockRecorder
+}
+
+// MockAssignmentReconcilerMockRecorder is the mock recorder for MockAssignmentReconciler.
+type MockAssignmentReconcilerMockRecorder struct {
+	mock *MockAssignmentReconciler
+}
+
+// NewMockAssignmentReconciler creates a new mock instance.
+func NewMockAssignmentReconciler(ctrl *gomock.Controller) *MockAssignmentReconciler {
+	mock := &MockAssignmentReconciler{ctrl: ctrl}
+	mock.recorder = &MockAssignmentReconcilerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAssignmentReconciler) EXPECT() *MockAssignmentReconcilerMockRecorder {
+	return m.recorder
+}
+
+// Reconcile mocks base method.
+func (m *MockAssignmentReconciler) Reconcile(arg0 bool) ([]assignment.Repair, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reconcile", arg0)
+	ret0, _ := ret[0].([]assignment.Repair)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reconcile indicates an expected call of Reconcile.
+func (mr *MockAssignmentReconcilerMockRecorder) Reconcile(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reconcile", reflect.TypeOf((*MockAssignmentReconciler)(nil).Reconcile), arg0)
+}
+
+// MockDeadLetterReplayer is a mock of DeadLetterReplayer interface.
+type MockDeadLetterReplayer struct {
+	ctrl     *gomock.Controller
+	recorder *MockDeadLetterReplayerMockRecorder
+}
+
+// MockDeadLetterReplayerMockRecorder is the mock recorder for MockDeadLetterReplayer.
+type MockDeadLetterReplayerMockRecorder struct {
+	mock *MockDeadLetterReplayer
+}
+
+// NewMockDeadLetterReplayer creates a new mock instance.
+func NewMockDeadLetterReplayer(ctrl *gomock.Controller) *MockDeadLetterReplayer {
+	mock := &MockDeadLetterReplayer{ctrl: ctrl}
+	mock.recorder = &MockDeadLetterReplayerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDeadLetterReplayer) EXPECT() *MockDeadLetterReplayerMockRecorder {
+	return m.recorder
+}
+
+// Replay mocks base method.
+func (m *MockDeadLetterReplayer) Replay(arg0 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Replay", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Replay indicates an expected call of Replay.
+func (mr *MockDeadLetterReplayerMockRecorder) Replay(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Replay", reflect.TypeOf((*MockDeadLetterReplayer)(nil).Replay), arg0)
+}
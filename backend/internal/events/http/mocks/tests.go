@@ -0,0 +1,50 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/testrelay/testrelay/backend/internal/events/http (interfaces: TestChecker)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	assignment "github.com/testrelay/testrelay/backend/internal/core/assignment"
+)
+
+// MockTestChecker is a mock of TestChecker interface.
+type MockTestChecker struct {
+	ctrl     *gomock.Controller
+	recorder *MockTestCheckerMockRecorder
+}
+
+// MockTestCheckerMockRecorder is the mock recorder for MockTestChecker.
+type MockTestCheckerMockRecorder struct {
+	mock *MockTestChecker
+}
+
+// NewMockTestChecker creates a new mock instance.
+func NewMockTestChecker(ctrl *gomock.Controller) *MockTestChecker {
+	mock := &MockTestChecker{ctrl: ctrl}
+	mock.recorder = &MockTestCheckerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTestChecker) EXPECT() *MockTestCheckerMockRecorder {
+	return m.recorder
+}
+
+// Check mocks base method.
+func (m *MockTestChecker) Check(arg0 int) (assignment.ValidationReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Check", arg0)
+	ret0, _ := ret[0].(assignment.ValidationReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Check indicates an expected call of Check.
+func (mr *MockTestCheckerMockRecorder) Check(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Check", reflect.TypeOf((*MockTestChecker)(nil).Check), arg0)
+}
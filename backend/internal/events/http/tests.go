@@ -0,0 +1,88 @@
+package http
+
+//go:generate mockgen -destination mocks/tests.go -package mocks . TestChecker
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/testrelay/testrelay/backend/internal/core/assignment"
+	"github.com/testrelay/testrelay/backend/internal/httputil"
+)
+
+// TestChecker defines an interface for a type that validates the source repos of a test. See assignment.Preflight.
+type TestChecker interface {
+	Check(testID int) (assignment.ValidationReport, error)
+}
+
+// TestHandler handles inbound hasura events for tests and their stages.
+type TestHandler struct {
+	Checker TestChecker
+	Logger  *zap.SugaredLogger
+}
+
+type testRow struct {
+	ID     int `json:"id"`
+	TestID int `json:"test_id"`
+}
+
+// EventsHandler defines a http.HandlerFunc that checks a test whenever it, or one of its stages, is created or
+// changed. A failed check is stored on the test rather than failing the request.
+func (t TestHandler) EventsHandler(w http.ResponseWriter, r *http.Request) {
+	var data HasuraEvent
+	err := json.NewDecoder(r.Body).Decode(&data)
+	if err != nil {
+		body, _ := ioutil.ReadAll(r.Body)
+		t.Logger.Error(
+			"could not decode test event data",
+			"error", err,
+			"body", body,
+		)
+
+		httputil.BadRequest(w)
+		return
+	}
+
+	row := data.Event.Data.New
+	if data.Event.Op == "DELETE" {
+		row = data.Event.Data.Old
+	}
+
+	var body testRow
+	err = json.Unmarshal(row, &body)
+	if err != nil {
+		t.Logger.Error(
+			"could not unmarshall test event data",
+			"error", err,
+			"body", string(row),
+		)
+
+		httputil.BadRequest(w)
+		return
+	}
+
+	testID := body.ID
+	if data.Table.Name == "test_stages" {
+		testID = body.TestID
+	}
+
+	report, err := t.Checker.Check(testID)
+	if err != nil {
+		t.Logger.Error(
+			"could not check test",
+			"test_id", testID,
+			"error", err,
+		)
+
+		httputil.BadRequest(w)
+		return
+	}
+
+	if !report.Passed {
+		t.Logger.Info("test failed preflight checks", "test_id", testID)
+	}
+
+	httputil.Success(w)
+}
@@ -0,0 +1,89 @@
+package http_test
+
+import (
+	"bytes"
+	"errors"
+	http2 "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/testrelay/testrelay/backend/internal/core/assignment"
+	"github.com/testrelay/testrelay/backend/internal/events/http"
+	"github.com/testrelay/testrelay/backend/internal/events/http/mocks"
+)
+
+func TestTestHandler(t *testing.T) {
+	logger := zap.NewNop().Sugar()
+
+	t.Run("EventsHandler", func(t *testing.T) {
+		t.Run("should check a test that was changed", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			checker := mocks.NewMockTestChecker(ctrl)
+			h := http.TestHandler{Checker: checker, Logger: logger}
+
+			checker.EXPECT().Check(12).Return(assignment.ValidationReport{Passed: false}, nil)
+
+			body := bytes.NewBufferString(`{
+    "event": {
+        "op": "UPDATE",
+        "data": {
+            "old": {"id": 12, "github_repo": "https://github.com/acme/old"},
+            "new": {"id": 12, "github_repo": "https://github.com/acme/new"}
+        }
+    },
+    "table": {"schema": "public", "name": "tests"}
+}`)
+
+			w := httptest.NewRecorder()
+			h.EventsHandler(w, httptest.NewRequest("POST", "/", body))
+
+			assert.Equal(t, http2.StatusOK, w.Code)
+		})
+
+		t.Run("should check the test of a deleted stage", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			checker := mocks.NewMockTestChecker(ctrl)
+			h := http.TestHandler{Checker: checker, Logger: logger}
+
+			checker.EXPECT().Check(12).Return(assignment.ValidationReport{Passed: true}, nil)
+
+			body := bytes.NewBufferString(`{
+    "event": {
+        "op": "DELETE",
+        "data": {
+            "old": {"id": 3, "test_id": 12, "position": 2},
+            "new": null
+        }
+    },
+    "table": {"schema": "public", "name": "test_stages"}
+}`)
+
+			w := httptest.NewRecorder()
+			h.EventsHandler(w, httptest.NewRequest("POST", "/", body))
+
+			assert.Equal(t, http2.StatusOK, w.Code)
+		})
+
+		t.Run("should return bad request when the check errors", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			checker := mocks.NewMockTestChecker(ctrl)
+			h := http.TestHandler{Checker: checker, Logger: logger}
+
+			checker.EXPECT().Check(12).Return(assignment.ValidationReport{}, errors.New("could not fetch test"))
+
+			body := bytes.NewBufferString(`{
+    "event": {"op": "INSERT", "data": {"old": null, "new": {"id": 12}}},
+    "table": {"schema": "public", "name": "tests"}
+}`)
+
+			w := httptest.NewRecorder()
+			h.EventsHandler(w, httptest.NewRequest("POST", "/", body))
+
+			assert.Equal(t, http2.StatusBadRequest, w.Code)
+		})
+	})
+}
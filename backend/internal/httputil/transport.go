@@ -5,11 +5,17 @@ import "net/http"
 // BearerTransport modifies the request to include a access token
 type BearerTransport struct {
 	Token string
+	// Role is sent as the X-Hasura-Role header, choosing which of the token's roles the request runs as. The
+	// default role of the token is used if it is empty.
+	Role string
 }
 
 // RoundTrip implements the roundtripper interface adding a bearer token to the request.
 func (t *BearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	req.Header.Add("Authorization", "Bearer "+t.Token)
+	if t.Role != "" {
+		req.Header.Set("X-Hasura-Role", t.Role)
+	}
 
 	return http.DefaultTransport.RoundTrip(req)
 }
@@ -0,0 +1,94 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+)
+
+const icsTimeLayout = "20060102T150405Z"
+
+// ICS returns the RFC 5545 iCalendar file for the event, organised by organizer and sent to attendee.
+// Cancelled events use METHOD:CANCEL so that calendars remove the event, other events use METHOD:REQUEST.
+func ICS(e core.CalendarEvent, organizer, attendee string, stamp time.Time) []byte {
+	method, status := "REQUEST", "CONFIRMED"
+	if e.Cancelled {
+		method, status = "CANCEL", "CANCELLED"
+	}
+
+	var b bytes.Buffer
+	for _, line := range []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//TestRelay//TestRelay//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:" + method,
+		"BEGIN:VEVENT",
+		"UID:" + escapeText(e.UID),
+		fmt.Sprintf("SEQUENCE:%d", e.Sequence),
+		"DTSTAMP:" + stamp.UTC().Format(icsTimeLayout),
+		"DTSTART:" + e.Start.UTC().Format(icsTimeLayout),
+		"DTEND:" + e.End.UTC().Format(icsTimeLayout),
+		"SUMMARY:" + escapeText(e.Summary),
+		"DESCRIPTION:" + escapeText(e.Description),
+		"URL:" + e.URL,
+		"ORGANIZER:mailto:" + organizer,
+		"ATTENDEE;ROLE=REQ-PARTICIPANT;RSVP=FALSE:mailto:" + attendee,
+		"STATUS:" + status,
+		"END:VEVENT",
+		"END:VCALENDAR",
+	} {
+		b.WriteString(fold(line))
+	}
+
+	return b.Bytes()
+}
+
+// icsAttachment returns the event as an attachment, with a content type that lets mail clients
+// show it as an invite.
+func icsAttachment(e core.CalendarEvent, organizer, attendee string, stamp time.Time) core.Attachment {
+	method := "REQUEST"
+	if e.Cancelled {
+		method = "CANCEL"
+	}
+
+	return core.Attachment{
+		Name:        "invite.ics",
+		ContentType: "text/calendar; charset=utf-8; method=" + method,
+		Data:        ICS(e, organizer, attendee, stamp),
+	}
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// fold splits a content line into lines of at most 75 octets, each continuation line starting with a
+// space, and terminates it with CRLF. Lines are never split within a multi-byte character.
+func fold(line string) string {
+	var b strings.Builder
+	limit := 75
+	for len(line) > limit {
+		i := limit
+		for i > 0 && !isRuneStart(line[i]) {
+			i--
+		}
+
+		b.WriteString(line[:i] + "\r\n ")
+		line = line[i:]
+		// continuation lines start with a space, which counts towards the limit.
+		limit = 74
+	}
+
+	b.WriteString(line + "\r\n")
+	return b.String()
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
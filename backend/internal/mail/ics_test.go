@@ -0,0 +1,75 @@
+package mail_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+	"github.com/testrelay/testrelay/backend/internal/mail"
+)
+
+func TestICS(t *testing.T) {
+	event := core.CalendarEvent{
+		UID:         "testrelay-assignment-12",
+		Sequence:    1,
+		Summary:     "TestRelay, Inc technical test",
+		Description: "Your test instructions will be uploaded to https://github.com/testrelay/a-very-long-repository-name.git",
+		URL:         "https://github.com/testrelay/a-very-long-repository-name.git",
+		Start:       time.Date(2021, 11, 12, 10, 0, 0, 0, time.UTC),
+		End:         time.Date(2021, 11, 12, 12, 0, 0, 0, time.UTC),
+	}
+	stamp := time.Date(2021, 11, 11, 8, 0, 0, 0, time.UTC)
+
+	t.Run("should render a request for the event", func(t *testing.T) {
+		ics := mail.ICS(event, "candidates@testrelay.io", "jane@testrelay.io", stamp)
+
+		assert.Equal(t, strings.Join([]string{
+			"BEGIN:VCALENDAR",
+			"VERSION:2.0",
+			"PRODID:-//TestRelay//TestRelay//EN",
+			"CALSCALE:GREGORIAN",
+			"METHOD:REQUEST",
+			"BEGIN:VEVENT",
+			"UID:testrelay-assignment-12",
+			"SEQUENCE:1",
+			"DTSTAMP:20211111T080000Z",
+			"DTSTART:20211112T100000Z",
+			"DTEND:20211112T120000Z",
+			`SUMMARY:TestRelay\, Inc technical test`,
+			"DESCRIPTION:Your test instructions will be uploaded to https://github.com/t",
+			" estrelay/a-very-long-repository-name.git",
+			"URL:https://github.com/testrelay/a-very-long-repository-name.git",
+			"ORGANIZER:mailto:candidates@testrelay.io",
+			"ATTENDEE;ROLE=REQ-PARTICIPANT;RSVP=FALSE:mailto:jane@testrelay.io",
+			"STATUS:CONFIRMED",
+			"END:VEVENT",
+			"END:VCALENDAR",
+			"",
+		}, "\r\n"), string(ics))
+	})
+
+	t.Run("should render a cancellation for cancelled events", func(t *testing.T) {
+		cancelled := event
+		cancelled.Cancelled = true
+
+		ics := string(mail.ICS(cancelled, "candidates@testrelay.io", "jane@testrelay.io", stamp))
+
+		assert.Contains(t, ics, "\r\nMETHOD:CANCEL\r\n")
+		assert.Contains(t, ics, "\r\nSTATUS:CANCELLED\r\n")
+	})
+
+	t.Run("should fold long lines without splitting characters", func(t *testing.T) {
+		long := event
+		long.Summary = strings.Repeat("é", 60)
+
+		ics := string(mail.ICS(long, "candidates@testrelay.io", "jane@testrelay.io", stamp))
+
+		for _, line := range strings.Split(ics, "\r\n") {
+			assert.LessOrEqual(t, len(line), 75)
+		}
+		assert.Contains(t, strings.ReplaceAll(ics, "\r\n ", ""), "SUMMARY:"+long.Summary+"\r\n")
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"embed"
 	"fmt"
 	"html/template"
+	"time"
 
 	mail "github.com/xhit/go-simple-mail/v2"
 
@@ -41,7 +42,8 @@ func NewSMTPMailer(config core.SMTPConfig) SMTPMailer {
 
 // Send implements the mailer.Send interface, connecting to a smtp server to
 // send a message with the provided data. It first finds a template with the provided
-// config and then passes it the data interface.
+// config and then passes it the data interface. Any config.Event is attached as an
+// invite.ics file, after config.Attachments.
 func (s SMTPMailer) Send(config core.MailConfig, data interface{}) error {
 	html, err := buildTemplate(config.TemplateName, data)
 	if err != nil {
@@ -59,12 +61,29 @@ func (s SMTPMailer) Send(config core.MailConfig, data interface{}) error {
 		SetSubject(config.Subject).
 		SetBody(mail.TextHTML, html)
 
+	for _, a := range config.Attachments {
+		email.Attach(&mail.File{Name: a.Name, MimeType: a.ContentType, Data: a.Data})
+	}
+
+	if config.Event != nil {
+		a := icsAttachment(*config.Event, from, config.To, time.Now())
+		email.Attach(&mail.File{Name: a.Name, MimeType: a.ContentType, Data: a.Data})
+	}
+
+	if email.Error != nil {
+		return fmt.Errorf("could not build email %w", email.Error)
+	}
+
 	conn, err := s.server.Connect()
 	if err != nil {
-		return fmt.Errorf("failed to get smtp server connection %w", err)
+		return core.NewTransientError(fmt.Errorf("failed to get smtp server connection %w", err))
+	}
+
+	if err := email.Send(conn); err != nil {
+		return core.NewTransientError(fmt.Errorf("could not send email %w", err))
 	}
 
-	return email.Send(conn)
+	return nil
 }
 
 func buildTemplate(name string, data interface{}) (string, error) {
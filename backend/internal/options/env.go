@@ -23,14 +23,40 @@ type Config struct {
 	HasuraURL   string
 	HasuraToken string
 
+	// SchedulerDriver is one of hasura|postgres. See the scheduler package for implementations.
+	SchedulerDriver       string
+	SchedulerPollInterval int64
+	DatabaseURL           string
+
 	GithubInterviewerAccessToken string
 	GithubInterviewerUsername    string
 	GithubInterviewerEmail       string
+	// GithubWebhookSecret signs pull request webhooks for assignment repos. Early submission is disabled if blank.
+	GithubWebhookSecret string
 
 	GithubPrivateKeyLocation string
 	GithubPrivateKey         string
 	GithubAppID              int64
 
+	// GitlabURL is the gitlab instance that businesses can choose instead of github. Gitlab is disabled if blank.
+	GitlabURL         string
+	GitlabAccessToken string
+	GitlabUsername    string
+	GitlabEmail       string
+	// GitlabNamespaceID is the group that assignment projects are created in.
+	GitlabNamespaceID int64
+
+	// BitbucketWorkspace is the workspace that assignment repos are created in. Bitbucket is disabled if blank.
+	BitbucketWorkspace   string
+	BitbucketUsername    string
+	BitbucketAppPassword string
+	BitbucketEmail       string
+
+	// LocalGitRoot is the directory that local assignment repos are stored in. Local repos are disabled if blank.
+	LocalGitRoot string
+	// LocalGitSecret derives the http passwords of local repo users.
+	LocalGitSecret string
+
 	GoogleServiceAccountLocation string
 	GoogleServiceAccount         string
 	FirebaseProjectID            string
@@ -52,17 +78,54 @@ func ConfigFromEnv() (Config, error) {
 		MailFromDomain:               envOrDefaultString("MAIL_FROM_DOMAIN", "@testrelay.io"),
 		HasuraURL:                    envOrDefaultString("HASURA_URL", "hasura"),
 		HasuraToken:                  e.envOrError("HASURA_TOKEN"),
+		SchedulerDriver:              envOrDefaultString("SCHEDULER_DRIVER", "hasura"),
+		SchedulerPollInterval:        envOrDefaultInt("SCHEDULER_POLL_INTERVAL", 5),
+		DatabaseURL:                  os.Getenv("DATABASE_URL"),
 		GithubInterviewerAccessToken: e.envOrError("GITHUB_ACCESS_TOKEN"),
 		GithubInterviewerUsername:    envOrDefaultString("GITHUB_USERNAME", "testrelay-interviewer"),
 		GithubInterviewerEmail:       e.envOrError("GITHUB_EMAIL"),
+		GithubWebhookSecret:          os.Getenv("GITHUB_WEBHOOK_SECRET"),
 		GithubPrivateKeyLocation:     envOrDefaultString("GITHUB_PRIVATE_KEY_LOCATION", "github-private-key.pem"),
 		GithubPrivateKey:             os.Getenv("GITHUB_PRIVATE_KEY"),
 		GithubAppID:                  e.envOrErrorInt("GITHUB_APP_ID"),
+		GitlabURL:                    os.Getenv("GITLAB_URL"),
+		GitlabAccessToken:            os.Getenv("GITLAB_ACCESS_TOKEN"),
+		GitlabUsername:               envOrDefaultString("GITLAB_USERNAME", "testrelay-interviewer"),
+		GitlabEmail:                  os.Getenv("GITLAB_EMAIL"),
+		GitlabNamespaceID:            envOrDefaultInt("GITLAB_NAMESPACE_ID", 0),
+		BitbucketWorkspace:           os.Getenv("BITBUCKET_WORKSPACE"),
+		BitbucketUsername:            envOrDefaultString("BITBUCKET_USERNAME", "testrelay-interviewer"),
+		BitbucketAppPassword:         os.Getenv("BITBUCKET_APP_PASSWORD"),
+		BitbucketEmail:               os.Getenv("BITBUCKET_EMAIL"),
+		LocalGitRoot:                 os.Getenv("LOCAL_GIT_ROOT"),
+		LocalGitSecret:               os.Getenv("LOCAL_GIT_SECRET"),
 		GoogleServiceAccountLocation: envOrDefaultString("GOOGLE_SERVICE_ACC_LOCATION", "service-acc.json"),
 		GoogleServiceAccount:         os.Getenv("GOOGLE_SERVICE_ACC"),
 		FirebaseProjectID:            e.envOrError("FIREBASE_PROJECT_ID"),
 	}
 
+	switch c.SchedulerDriver {
+	case "hasura":
+	case "postgres":
+		if c.DatabaseURL == "" {
+			e = append(e, errors.New("DATABASE_URL must be set when using the postgres scheduler driver"))
+		}
+	default:
+		e = append(e, fmt.Errorf("SCHEDULER_DRIVER %s is not one of hasura|postgres", c.SchedulerDriver))
+	}
+
+	if c.GitlabURL != "" && (c.GitlabAccessToken == "" || c.GitlabEmail == "") {
+		e = append(e, errors.New("GITLAB_ACCESS_TOKEN and GITLAB_EMAIL must be set when GITLAB_URL is set"))
+	}
+
+	if c.BitbucketWorkspace != "" && (c.BitbucketAppPassword == "" || c.BitbucketEmail == "") {
+		e = append(e, errors.New("BITBUCKET_APP_PASSWORD and BITBUCKET_EMAIL must be set when BITBUCKET_WORKSPACE is set"))
+	}
+
+	if c.LocalGitRoot != "" && c.LocalGitSecret == "" {
+		e = append(e, errors.New("LOCAL_GIT_SECRET must be set when LOCAL_GIT_ROOT is set"))
+	}
+
 	if c.GoogleServiceAccount != "" {
 		err := os.WriteFile(c.GoogleServiceAccountLocation, []byte(c.GoogleServiceAccount), os.ModePerm)
 		if err != nil {
@@ -0,0 +1,254 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"github.com/testrelay/testrelay/backend/internal/core/assignment"
+)
+
+const (
+	jobPending = "pending"
+	jobDone    = "done"
+	jobFailed  = "failed"
+)
+
+// PostgresAssignmentScheduler is a postgres implementation of the assignment.SchedulerClient.
+// Rather than handing scheduled steps off to a 3rd party it stores them in the scheduled_jobs
+// table. Jobs are picked up and executed by a PostgresWorker running inside the server process,
+// meaning pending jobs survive restarts and can be inspected directly in the database.
+type PostgresAssignmentScheduler struct {
+	db *sqlx.DB
+}
+
+// NewPostgresAssignmentScheduler returns a PostgresAssignmentScheduler using the given db connection.
+// The db must have the scheduled_jobs table migrated, see the hasura migrations directory.
+func NewPostgresAssignmentScheduler(db *sqlx.DB) PostgresAssignmentScheduler {
+	return PostgresAssignmentScheduler{db: db}
+}
+
+// Start inserts a pending job that will be run once input.ScheduleAt has passed.
+// It returns the id of the job which can be used to cancel the job using Stop.
+func (p PostgresAssignmentScheduler) Start(input assignment.StartInput) (string, error) {
+	scheduleAt, err := time.Parse(time.RFC3339, input.ScheduleAt)
+	if err != nil {
+		return "", fmt.Errorf("could not parse schedule_at %s %w", input.ScheduleAt, err)
+	}
+
+	payload, err := json.Marshal(input.Data)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal schedule job data %w", err)
+	}
+
+	var id string
+	err = p.db.Get(
+		&id,
+		`INSERT INTO scheduled_jobs (assignment_id, step, payload, schedule_at) VALUES ($1, $2, $3, $4) RETURNING id`,
+		input.ID,
+		input.Type,
+		payload,
+		scheduleAt,
+	)
+	if err != nil {
+		return "", fmt.Errorf("could not insert scheduled job %w", err)
+	}
+
+	return id, nil
+}
+
+// Stop removes a pending job so that it is never run. Jobs that have already been run,
+// or do not exist, are left untouched and no error is returned.
+func (p PostgresAssignmentScheduler) Stop(id string) error {
+	_, err := p.db.Exec(`DELETE FROM scheduled_jobs WHERE id = $1 AND status = $2`, id, jobPending)
+	if err != nil {
+		return fmt.Errorf("could not delete scheduled job %s %w", id, err)
+	}
+
+	return nil
+}
+
+// StepRunner defines a type that runs a single assignment step. See assignment.Runner.
+type StepRunner interface {
+	Run(step string, data assignment.RunData) error
+}
+
+// PostgresWorker polls the scheduled_jobs table for jobs that are due and passes them to the Runner.
+//
+// Jobs are claimed using a lease. Whilst a job is being run its lease is renewed every half Lease so
+// that other workers skip it, however long the step takes. If the process dies mid run the lease expires
+// and the job is picked up again on the next poll.
+//
+// A job is run once. A job whose step errors is marked as failed and never run again, so retries depend on the
+// Runner, e.g. assignment.RetryRunner reschedules steps that fail with a transient error as a new job.
+type PostgresWorker struct {
+	DB     *sqlx.DB
+	Runner StepRunner
+	Logger *zap.SugaredLogger
+
+	// Interval is the duration between polls of the scheduled_jobs table.
+	Interval time.Duration
+	// Lease is the duration a claimed job is hidden from other workers.
+	Lease time.Duration
+}
+
+type scheduledJob struct {
+	ID           string          `db:"id"`
+	AssignmentID int             `db:"assignment_id"`
+	Step         string          `db:"step"`
+	Payload      json.RawMessage `db:"payload"`
+}
+
+// Run polls for due jobs until the ctx is cancelled.
+func (w PostgresWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.ProcessDue(); err != nil {
+			w.Logger.Error("could not process scheduled jobs", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessDue runs all jobs that are due at the time of calling, returning the number of jobs run.
+// A job that errors is marked as permanently failed and does not stop the remaining jobs from running.
+func (w PostgresWorker) ProcessDue() (int, error) {
+	var processed int
+	for {
+		job, err := w.claim()
+		if errors.Is(err, sql.ErrNoRows) {
+			return processed, nil
+		}
+		if err != nil {
+			return processed, err
+		}
+
+		processed++
+		err = w.process(job)
+		if err != nil {
+			w.Logger.Error(
+				"scheduled job errored",
+				"job_id", job.ID,
+				"step", job.Step,
+				"assignment_id", job.AssignmentID,
+				"error", err,
+			)
+		}
+	}
+}
+
+func (w PostgresWorker) claim() (scheduledJob, error) {
+	var job scheduledJob
+	err := w.DB.Get(&job, `
+UPDATE scheduled_jobs SET locked_until = now() + $1 * interval '1 second'
+WHERE id = (
+	SELECT id FROM scheduled_jobs
+	WHERE status = $2 AND schedule_at <= now() AND (locked_until IS NULL OR locked_until < now())
+	ORDER BY schedule_at
+	LIMIT 1
+	FOR UPDATE SKIP LOCKED
+)
+RETURNING id, assignment_id, step, payload`,
+		w.Lease.Seconds(),
+		jobPending,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return job, err
+		}
+
+		return job, fmt.Errorf("could not claim scheduled job %w", err)
+	}
+
+	return job, nil
+}
+
+func (w PostgresWorker) process(job scheduledJob) error {
+	var data assignment.WithTestDetails
+	err := json.Unmarshal(job.Payload, &data)
+	if err != nil {
+		return w.fail(job, fmt.Errorf("could not decode job payload %w", err))
+	}
+
+	stop := w.heartbeat(job)
+	err = w.Runner.Run(job.Step, assignment.RunData{Data: data, EventID: job.ID})
+	stop()
+	if err != nil {
+		return w.fail(job, err)
+	}
+
+	_, err = w.DB.Exec(
+		`UPDATE scheduled_jobs SET status = $1, completed_at = now(), locked_until = NULL WHERE id = $2`,
+		jobDone,
+		job.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("could not mark job %s as done %w", job.ID, err)
+	}
+
+	return nil
+}
+
+// heartbeat renews the lease of the job every half Lease until the returned func is called.
+// The returned func waits for any renewal in flight, so the lease is not renewed after the job is marked.
+func (w PostgresWorker) heartbeat(job scheduledJob) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(w.Lease / 2)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			_, err := w.DB.Exec(
+				`UPDATE scheduled_jobs SET locked_until = now() + $1 * interval '1 second' WHERE id = $2 AND status = $3`,
+				w.Lease.Seconds(),
+				job.ID,
+				jobPending,
+			)
+			if err != nil {
+				w.Logger.Error("could not renew lease of scheduled job", "job_id", job.ID, "error", err)
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+func (w PostgresWorker) fail(job scheduledJob, runErr error) error {
+	_, err := w.DB.Exec(
+		`UPDATE scheduled_jobs SET status = $1, last_error = $2, locked_until = NULL WHERE id = $3`,
+		jobFailed,
+		runErr.Error(),
+		job.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("could not mark job %s as failed %s %w", job.ID, runErr, err)
+	}
+
+	return runErr
+}
@@ -0,0 +1,157 @@
+//go:build integration
+// +build integration
+
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/testrelay/testrelay/backend/internal/core/assignment"
+	. "github.com/testrelay/testrelay/backend/internal/scheduler"
+)
+
+type stepRunnerFunc func(step string, data assignment.RunData) error
+
+func (f stepRunnerFunc) Run(step string, data assignment.RunData) error {
+	return f(step, data)
+}
+
+func TestPostgresAssignmentScheduler(t *testing.T) {
+	type scheduledJob struct {
+		ID         string     `db:"id"`
+		Step       string     `db:"step"`
+		Status     string     `db:"status"`
+		Payload    string     `db:"payload"`
+		ScheduleAt *time.Time `db:"schedule_at"`
+	}
+
+	db, err := sqlx.Connect("postgres", "user=postgres dbname=postgres password=postgrespassword sslmode=disable")
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := NewPostgresAssignmentScheduler(db)
+
+	t.Run("Start", func(t *testing.T) {
+		now := time.Now().Add(time.Hour).Truncate(time.Second)
+		id, err := s.Start(assignment.StartInput{
+			Type:       "init",
+			ID:         33,
+			ScheduleAt: now.Format(time.RFC3339),
+			Data:       assignment.WithTestDetails{ID: 33, CandidateName: "testname"},
+		})
+		require.NoError(t, err)
+		defer db.Exec("DELETE FROM scheduled_jobs WHERE id = $1", id)
+
+		var job scheduledJob
+		err = db.Get(&job, "SELECT id, step, status, payload, schedule_at FROM scheduled_jobs WHERE id = $1", id)
+		require.NoError(t, err)
+
+		assert.Equal(t, "init", job.Step)
+		assert.Equal(t, "pending", job.Status)
+		assert.Contains(t, job.Payload, `"candidate_name": "testname"`)
+		assert.True(t, now.Equal(*job.ScheduleAt))
+	})
+
+	t.Run("Stop", func(t *testing.T) {
+		id, err := s.Start(assignment.StartInput{
+			Type:       "init",
+			ID:         33,
+			ScheduleAt: time.Now().Add(time.Hour).Format(time.RFC3339),
+			Data:       assignment.WithTestDetails{ID: 33},
+		})
+		require.NoError(t, err)
+
+		err = s.Stop(id)
+		assert.NoError(t, err)
+
+		var count int
+		err = db.Get(&count, "SELECT count(id) FROM scheduled_jobs WHERE id = $1", id)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, count)
+	})
+
+	t.Run("PostgresWorker.ProcessDue", func(t *testing.T) {
+		due, err := s.Start(assignment.StartInput{
+			Type:       "end",
+			ID:         34,
+			ScheduleAt: time.Now().Add(-time.Minute).Format(time.RFC3339),
+			Data:       assignment.WithTestDetails{ID: 34},
+		})
+		require.NoError(t, err)
+		defer db.Exec("DELETE FROM scheduled_jobs WHERE id = $1", due)
+
+		future, err := s.Start(assignment.StartInput{
+			Type:       "cleanup",
+			ID:         34,
+			ScheduleAt: time.Now().Add(time.Hour).Format(time.RFC3339),
+			Data:       assignment.WithTestDetails{ID: 34},
+		})
+		require.NoError(t, err)
+		defer db.Exec("DELETE FROM scheduled_jobs WHERE id = $1", future)
+
+		var ran []string
+		w := PostgresWorker{
+			DB: db,
+			Runner: stepRunnerFunc(func(step string, data assignment.RunData) error {
+				assert.Equal(t, 34, data.Data.ID)
+				ran = append(ran, step)
+				return nil
+			}),
+			Logger: zap.NewNop().Sugar(),
+			Lease:  time.Minute,
+		}
+
+		n, err := w.ProcessDue()
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.Equal(t, []string{"end"}, ran)
+
+		var status string
+		err = db.Get(&status, "SELECT status FROM scheduled_jobs WHERE id = $1", due)
+		require.NoError(t, err)
+		assert.Equal(t, "done", status)
+
+		err = db.Get(&status, "SELECT status FROM scheduled_jobs WHERE id = $1", future)
+		require.NoError(t, err)
+		assert.Equal(t, "pending", status)
+	})
+
+	t.Run("PostgresWorker.ProcessDue should renew the lease of long running jobs", func(t *testing.T) {
+		due, err := s.Start(assignment.StartInput{
+			Type:       "end",
+			ID:         35,
+			ScheduleAt: time.Now().Add(-time.Minute).Format(time.RFC3339),
+			Data:       assignment.WithTestDetails{ID: 35},
+		})
+		require.NoError(t, err)
+		defer db.Exec("DELETE FROM scheduled_jobs WHERE id = $1", due)
+
+		w := PostgresWorker{
+			DB: db,
+			Runner: stepRunnerFunc(func(step string, data assignment.RunData) error {
+				// without a renewal the lease would have expired by now.
+				time.Sleep(time.Millisecond * 1500)
+
+				var leased bool
+				err := db.Get(&leased, "SELECT locked_until > now() FROM scheduled_jobs WHERE id = $1", due)
+				require.NoError(t, err)
+				assert.True(t, leased)
+
+				return nil
+			}),
+			Logger: zap.NewNop().Sugar(),
+			Lease:  time.Second,
+		}
+
+		n, err := w.ProcessDue()
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+	})
+}
@@ -2,17 +2,22 @@ package graphql
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/hasura/go-graphql-client"
 
+	"github.com/testrelay/testrelay/backend/internal/core"
 	"github.com/testrelay/testrelay/backend/internal/core/assignment"
 	"github.com/testrelay/testrelay/backend/internal/core/assignmentuser"
 	"github.com/testrelay/testrelay/backend/internal/core/business"
 	"github.com/testrelay/testrelay/backend/internal/core/user"
 	"github.com/testrelay/testrelay/backend/internal/httputil"
+	intTime "github.com/testrelay/testrelay/backend/internal/time"
 )
 
 type AssignmentUsers struct {
@@ -23,11 +28,18 @@ type AssignmentUsers struct {
 type ShortAssignment struct {
 	CandidateName graphql.String `graphql:"candidate_name" json:"candidate_name"`
 	GithubRepoUrl graphql.String `graphql:"github_repo_url" json:"github_repo_url"`
+	Test          struct {
+		Business struct {
+			VCSProvider graphql.String `graphql:"vcs_provider" json:"vcs_provider"`
+		} `graphql:"business" json:"business"`
+	} `graphql:"test" json:"test"`
 }
 
 type User struct {
-	Email          graphql.String `graphql:"email" json:"email"`
-	GithubUsername graphql.String `graphql:"github_username" json:"github_username"`
+	Email              graphql.String `graphql:"email" json:"email"`
+	GithubUsername     graphql.String `graphql:"github_username" json:"github_username"`
+	GitlabUsername     graphql.String `graphql:"gitlab_username" json:"gitlab_username"`
+	BitbucketAccountID graphql.String `graphql:"bitbucket_account_id" json:"bitbucket_account_id"`
 }
 
 type Assignment struct {
@@ -46,20 +58,54 @@ type Assignment struct {
 	CandidateEmail     graphql.String `graphql:"candidate_email" json:"candidate_email"`
 	TestTimezoneChosen graphql.String `graphql:"test_timezone_chosen" json:"test_timezone_chosen"`
 	SchedulerID        graphql.String `graphql:"step_arn" json:"step_arn"`
+	Mode               graphql.String `graphql:"mode" json:"mode"`
 	Candidate          Candidate      `graphql:"candidate" json:"candidate"`
 	Recruiter          Recruiter      `graphql:"recruiter" json:"recruiter"`
 	Test               Test           `graphql:"test" json:"test"`
 }
 
 type Test struct {
-	Business   Business       `graphql:"business" json:"business"`
-	Name       string         `graphql:"name" json:"name"`
+	Business      Business        `graphql:"business" json:"business"`
+	Name          string          `graphql:"name" json:"name"`
+	GithubRepo    graphql.String  `graphql:"github_repo" json:"github_repo"`
+	GithubRef     graphql.String  `graphql:"github_ref" json:"github_ref"`
+	GithubPath    graphql.String  `graphql:"github_path" json:"github_path"`
+	Reminders     json.RawMessage `graphql:"reminders" json:"reminders"`
+	Stages        []TestStage     `graphql:"stages(order_by: {position: asc})" json:"stages"`
+	TemplateGlob  graphql.String  `graphql:"template_glob" json:"template_glob"`
+	UploadMode    graphql.String  `graphql:"upload_mode" json:"upload_mode"`
+	TemplateOwner graphql.String  `graphql:"template_owner" json:"template_owner"`
+}
+
+type TestStage struct {
+	Position   graphql.Int    `graphql:"position" json:"position"`
+	Name       graphql.String `graphql:"name" json:"name"`
 	GithubRepo graphql.String `graphql:"github_repo" json:"github_repo"`
+	GithubRef  graphql.String `graphql:"github_ref" json:"github_ref"`
+	GithubPath graphql.String `graphql:"github_path" json:"github_path"`
+	TimeLimit  graphql.Int    `graphql:"time_limit" json:"time_limit"`
 }
 
 type Business struct {
-	Name                 graphql.String `graphql:"name" json:"name"`
-	GithubInstallationID graphql.String `graphql:"github_installation_id" json:"github_installation_id"`
+	Name                 graphql.String         `graphql:"name" json:"name"`
+	GithubInstallationID graphql.String         `graphql:"github_installation_id" json:"github_installation_id"`
+	GithubOrg            graphql.String         `graphql:"github_org" json:"github_org"`
+	VCSProvider          graphql.String         `graphql:"vcs_provider" json:"vcs_provider"`
+	GitlabGroupID        graphql.String         `graphql:"gitlab_group_id" json:"gitlab_group_id"`
+	BitbucketWorkspace   graphql.String         `graphql:"bitbucket_workspace" json:"bitbucket_workspace"`
+	Timezone             graphql.String         `graphql:"timezone" json:"timezone"`
+	Availability         []BusinessAvailability `graphql:"availability" json:"availability"`
+	Blackouts            []BusinessBlackout     `graphql:"blackouts" json:"blackouts"`
+}
+
+type BusinessAvailability struct {
+	Weekday   graphql.Int    `graphql:"weekday" json:"weekday"`
+	StartTime graphql.String `graphql:"start_time" json:"start_time"`
+	EndTime   graphql.String `graphql:"end_time" json:"end_time"`
+}
+
+type BusinessBlackout struct {
+	Day graphql.String `graphql:"day" json:"day"`
 }
 
 type Recruiter struct {
@@ -67,9 +113,11 @@ type Recruiter struct {
 }
 
 type Candidate struct {
-	Email             graphql.String `graphql:"email" json:"email"`
-	GithubUsername    graphql.String `graphql:"github_username" json:"github_username"`
-	GithubAccessToken graphql.String `graphql:"github_access_token" json:"github_access_token"`
+	Email              graphql.String `graphql:"email" json:"email"`
+	GithubUsername     graphql.String `graphql:"github_username" json:"github_username"`
+	GitlabUsername     graphql.String `graphql:"gitlab_username" json:"gitlab_username"`
+	BitbucketAccountID graphql.String `graphql:"bitbucket_account_id" json:"bitbucket_account_id"`
+	GithubAccessToken  graphql.String `graphql:"github_access_token" json:"github_access_token"`
 }
 
 type assignmentQ struct {
@@ -99,12 +147,37 @@ func NewHasuraClient(url string, token string) *HasuraClient {
 		client: graphql.NewClient(
 			url,
 			&http.Client{
-				Transport: &httputil.KeyTransport{Key: "x-hasura-admin-secret", Value: token},
+				Transport: transientTransport{
+					next: &httputil.KeyTransport{Key: "x-hasura-admin-secret", Value: token},
+				},
 			},
 		),
 	}
 }
 
+// transientTransport marks requests to hasura that are worth retrying as transient, so that a brief outage of
+// hasura or its database is retried rather than failing an assignment step for good. Dropped connections, rate
+// limits and 5xx responses are transient, graphql errors such as a failed permission check are left permanent.
+type transientTransport struct {
+	next http.RoundTripper
+}
+
+func (t transientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, core.NewTransientError(err)
+	}
+
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= http.StatusInternalServerError {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		res.Body.Close()
+
+		return nil, core.NewTransientError(fmt.Errorf("hasura responded with %s %q", res.Status, body))
+	}
+
+	return res, nil
+}
+
 // GetBusiness returns a short business from the given businessID.
 func (h HasuraClient) GetBusiness(businessID int64) (business.Short, error) {
 	var q getBusinessQuery
@@ -165,12 +238,15 @@ func (h HasuraClient) GetReviewer(id int) (assignmentuser.ReviewerDetail, error)
 
 	return assignmentuser.ReviewerDetail{
 		User: user.Short{
-			Email:          string(q.AssignmentUsersByPK.User.Email),
-			GithubUsername: string(q.AssignmentUsersByPK.User.GithubUsername),
+			Email:              string(q.AssignmentUsersByPK.User.Email),
+			GithubUsername:     string(q.AssignmentUsersByPK.User.GithubUsername),
+			GitlabUsername:     string(q.AssignmentUsersByPK.User.GitlabUsername),
+			BitbucketAccountID: string(q.AssignmentUsersByPK.User.BitbucketAccountID),
 		},
 		Assignment: assignment.Short{
 			CandidateName: string(q.AssignmentUsersByPK.Assignment.CandidateName),
 			GithubRepoUrl: string(q.AssignmentUsersByPK.Assignment.GithubRepoUrl),
+			VCSProvider:   string(q.AssignmentUsersByPK.Assignment.Test.Business.VCSProvider),
 		},
 	}, nil
 }
@@ -184,42 +260,190 @@ func (h HasuraClient) GetAssignment(id int) (assignment.WithTestDetails, error)
 		return assignment.WithTestDetails{}, fmt.Errorf("could not fetch graphql assignment %w", err)
 	}
 
-	installationID, _ := strconv.ParseInt(string(q.AssignmentsByPK.Test.Business.GithubInstallationID), 10, 64)
+	return toAssignment(q.AssignmentsByPK)
+}
+
+// UnscheduledBefore returns the sent or viewed assignments with a choose_until before the given date.
+func (h HasuraClient) UnscheduledBefore(before time.Time) ([]assignment.WithTestDetails, error) {
+	var q unscheduledAssignmentsQuery
+	err := h.client.Query(context.Background(), &q, map[string]interface{}{
+		"date": date(before.Format("2006-01-02")),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch unscheduled assignments %w", err)
+	}
+
+	assignments := make([]assignment.WithTestDetails, len(q.Assignments))
+	for i, a := range q.Assignments {
+		assignments[i], err = toAssignment(a)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return assignments, nil
+}
+
+// InFlight returns all scheduled or inprogress assignments.
+func (h HasuraClient) InFlight() ([]assignment.WithTestDetails, error) {
+	var q inFlightAssignmentsQuery
+	err := h.client.Query(context.Background(), &q, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch in flight assignments %w", err)
+	}
+
+	assignments := make([]assignment.WithTestDetails, len(q.Assignments))
+	for i, a := range q.Assignments {
+		assignments[i], err = toAssignment(a)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return assignments, nil
+}
+
+// Events returns the events for the assignment, oldest first.
+func (h HasuraClient) Events(assignmentID int) ([]assignment.Event, error) {
+	var q assignmentEventsQuery
+	err := h.client.Query(context.Background(), &q, map[string]interface{}{
+		"assignment_id": graphql.Int(assignmentID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch assignment events %w", err)
+	}
+
+	events := make([]assignment.Event, len(q.AssignmentEvents))
+	for i, e := range q.AssignmentEvents {
+		createdAt, err := time.Parse(time.RFC3339, string(e.CreatedAt))
+		if err != nil {
+			return nil, fmt.Errorf("could not parse event created_at %s %w", e.CreatedAt, err)
+		}
+
+		events[i] = assignment.Event{Type: string(e.EventType), CreatedAt: createdAt}
+	}
+
+	return events, nil
+}
+
+func toAssignment(a Assignment) (assignment.WithTestDetails, error) {
+	test, err := toTest(a.Test)
+	if err != nil {
+		return assignment.WithTestDetails{}, err
+	}
+
 	return assignment.WithTestDetails{
-		Status:             string(q.AssignmentsByPK.Status),
-		TestTimeChosen:     string(q.AssignmentsByPK.TestTimeChosen),
-		ChooseUntil:        string(q.AssignmentsByPK.ChooseUntil),
-		TestDayChosen:      string(q.AssignmentsByPK.TestDayChosen),
-		TestID:             int(q.AssignmentsByPK.TestId),
-		TimeLimit:          int(q.AssignmentsByPK.TimeLimit),
-		CandidateID:        int(q.AssignmentsByPK.CandidateId),
-		ID:                 int(q.AssignmentsByPK.ID),
-		CandidateName:      string(q.AssignmentsByPK.CandidateName),
-		RecruiterID:        int(q.AssignmentsByPK.RecruiterId),
-		InviteCode:         string(q.AssignmentsByPK.InviteCode),
-		GithubRepoURL:      string(q.AssignmentsByPK.GithubRepoUrl),
-		CandidateEmail:     string(q.AssignmentsByPK.CandidateEmail),
-		TestTimezoneChosen: string(q.AssignmentsByPK.TestTimezoneChosen),
-		SchedulerID:        string(q.AssignmentsByPK.SchedulerID),
+		Status:             string(a.Status),
+		TestTimeChosen:     string(a.TestTimeChosen),
+		ChooseUntil:        string(a.ChooseUntil),
+		TestDayChosen:      string(a.TestDayChosen),
+		TestID:             int(a.TestId),
+		TimeLimit:          int(a.TimeLimit),
+		CandidateID:        int(a.CandidateId),
+		ID:                 int(a.ID),
+		CandidateName:      string(a.CandidateName),
+		RecruiterID:        int(a.RecruiterId),
+		InviteCode:         string(a.InviteCode),
+		GithubRepoURL:      string(a.GithubRepoUrl),
+		CandidateEmail:     string(a.CandidateEmail),
+		TestTimezoneChosen: string(a.TestTimezoneChosen),
+		SchedulerID:        string(a.SchedulerID),
+		Mode:               string(a.Mode),
 		Candidate: assignment.Candidate{
-			Email:             string(q.AssignmentsByPK.Candidate.Email),
-			GithubUsername:    string(q.AssignmentsByPK.Candidate.GithubUsername),
-			GithubAccessToken: string(q.AssignmentsByPK.Candidate.GithubAccessToken),
+			Email:              string(a.Candidate.Email),
+			GithubUsername:     string(a.Candidate.GithubUsername),
+			GitlabUsername:     string(a.Candidate.GitlabUsername),
+			BitbucketAccountID: string(a.Candidate.BitbucketAccountID),
+			GithubAccessToken:  string(a.Candidate.GithubAccessToken),
 		},
 		Recruiter: assignment.Recruiter{
-			Email: string(q.AssignmentsByPK.Recruiter.Email),
+			Email: string(a.Recruiter.Email),
 		},
-		Test: assignment.Test{
-			Business: assignment.Business{
-				Name:                 string(q.AssignmentsByPK.Test.Business.Name),
-				GithubInstallationID: installationID,
-			},
-			Name:       string(q.AssignmentsByPK.Test.Name),
-			GithubRepo: string(q.AssignmentsByPK.Test.GithubRepo),
+		Test: test,
+	}, nil
+}
+
+func toTest(t Test) (assignment.Test, error) {
+	installationID, _ := strconv.ParseInt(string(t.Business.GithubInstallationID), 10, 64)
+	groupID, _ := strconv.ParseInt(string(t.Business.GitlabGroupID), 10, 64)
+
+	var reminders []assignment.Reminder
+	if len(t.Reminders) > 0 {
+		err := json.Unmarshal(t.Reminders, &reminders)
+		if err != nil {
+			return assignment.Test{}, fmt.Errorf("could not decode test reminders %s %w", t.Reminders, err)
+		}
+	}
+
+	var stages []assignment.Stage
+	for _, s := range t.Stages {
+		stages = append(stages, assignment.Stage{
+			Position:   int(s.Position),
+			Name:       string(s.Name),
+			GithubRepo: string(s.GithubRepo),
+			GithubRef:  string(s.GithubRef),
+			GithubPath: string(s.GithubPath),
+			TimeLimit:  int(s.TimeLimit),
+		})
+	}
+
+	return assignment.Test{
+		Business: assignment.Business{
+			Name:                 string(t.Business.Name),
+			GithubInstallationID: installationID,
+			GithubOrg:            string(t.Business.GithubOrg),
+			VCSProvider:          string(t.Business.VCSProvider),
+			GitlabGroupID:        groupID,
+			BitbucketWorkspace:   string(t.Business.BitbucketWorkspace),
+			Availability:         toAvailability(t.Business),
 		},
+		Name:          string(t.Name),
+		GithubRepo:    string(t.GithubRepo),
+		GithubRef:     string(t.GithubRef),
+		GithubPath:    string(t.GithubPath),
+		Reminders:     reminders,
+		Stages:        stages,
+		TemplateGlob:  string(t.TemplateGlob),
+		UploadMode:    string(t.UploadMode),
+		TemplateOwner: string(t.TemplateOwner),
 	}, nil
 }
 
+func toAvailability(b Business) intTime.Availability {
+	availability := intTime.Availability{Timezone: string(b.Timezone)}
+	for _, o := range b.Availability {
+		availability.Openings = append(availability.Openings, intTime.Opening{
+			Weekday: time.Weekday(o.Weekday),
+			Start:   string(o.StartTime),
+			End:     string(o.EndTime),
+		})
+	}
+
+	for _, d := range b.Blackouts {
+		availability.Blackouts = append(availability.Blackouts, string(d.Day))
+	}
+
+	return availability
+}
+
+// AssignmentIDByRepoURL returns the id of the assignment using the vcs repo at repoURL.
+// It returns 0 if no assignment uses the repo.
+func (h HasuraClient) AssignmentIDByRepoURL(repoURL string) (int, error) {
+	var q assignmentByRepoQuery
+	err := h.client.Query(context.Background(), &q, map[string]interface{}{
+		"url": graphql.String(repoURL),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("could not query assignment by repo url %s %w", repoURL, err)
+	}
+
+	if len(q.Assignments) == 0 {
+		return 0, nil
+	}
+
+	return int(q.Assignments[0].ID), nil
+}
+
 func newInt(i int) *int {
 	return &i
 }
@@ -243,6 +467,33 @@ func (h HasuraClient) UpdateAssignmentWithDetails(id int, arn string, url string
 	return nil
 }
 
+// UpdateAssignmentStarted moves an on demand assignment that is sent or viewed to scheduled, setting the chosen
+// day, time and timezone to startedAt in the timezone. It returns false if no assignment was updated.
+func (h HasuraClient) UpdateAssignmentStarted(id int, startedAt time.Time, timezone string) (bool, error) {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return false, fmt.Errorf("could not load timezone %s %w", timezone, err)
+	}
+
+	startedAt = startedAt.In(loc)
+
+	var mu startAssignmentMutation
+	err = h.client.Mutate(context.Background(), &mu, map[string]interface{}{
+		"id": graphql.Int(id),
+		"set": assignments_set_input{
+			"status":               "scheduled",
+			"test_day_chosen":      startedAt.Format("2006-01-02"),
+			"test_time_chosen":     startedAt.Format("15:04:05"),
+			"test_timezone_chosen": timezone,
+		},
+	})
+	if err != nil {
+		return false, fmt.Errorf("could not update assignment %d with start details %w", id, err)
+	}
+
+	return mu.UpdateAssignments.AffectedRows > 0, nil
+}
+
 func (h HasuraClient) UpdateAssignmentToSent(a assignment.SentDetails) error {
 	var q UserQuery
 	err := h.client.Query(context.Background(), &q, map[string]interface{}{
@@ -281,7 +532,16 @@ func (h HasuraClient) Reviewers(id int) ([]string, error) {
 
 	reviewers := make([]string, len(q.AssignmentUsers.Reviewers))
 	for i, reviewer := range q.AssignmentUsers.Reviewers {
-		reviewers[i] = reviewer.User.GithubUsername
+		switch q.AssignmentUsers.Test.Business.VCSProvider {
+		case core.VCSProviderGitlab:
+			reviewers[i] = reviewer.User.GitlabUsername
+		case core.VCSProviderBitbucket:
+			reviewers[i] = reviewer.User.BitbucketAccountID
+		case core.VCSProviderLocal:
+			reviewers[i] = reviewer.User.Email
+		default:
+			reviewers[i] = reviewer.User.GithubUsername
+		}
 	}
 
 	return reviewers, err
@@ -310,3 +570,310 @@ func (h HasuraClient) NewAssignmentEvent(userID int, assignmentID int, status st
 		"status":  newStatus(status),
 	})
 }
+
+// RecordAssignmentEvent inserts an assignment event with the given meta, leaving the status of the
+// assignment untouched. A userID of 0 records the event without a user.
+func (h HasuraClient) RecordAssignmentEvent(userID int, assignmentID int, eventType string, meta map[string]interface{}) error {
+	var uid *graphql.Int
+	if userID != 0 {
+		i := graphql.Int(userID)
+		uid = &i
+	}
+
+	var mu recordAssignmentEventMutation
+	err := h.client.Mutate(context.Background(), &mu, map[string]interface{}{
+		"event_type":    *newStatus(eventType),
+		"user_id":       uid,
+		"assignment_id": graphql.Int(assignmentID),
+		"meta":          jsonb(meta),
+	})
+	if err != nil {
+		return fmt.Errorf("could not record assignment event %s for %d %w", eventType, assignmentID, err)
+	}
+
+	return nil
+}
+
+// GetStep returns the step ledger entry for the assignment step. If no entry exists
+// a zero assignment.StepRecord is returned.
+func (h HasuraClient) GetStep(assignmentID int, step string) (assignment.StepRecord, error) {
+	var q assignmentStepQuery
+	err := h.client.Query(context.Background(), &q, map[string]interface{}{
+		"assignment_id": graphql.Int(assignmentID),
+		"step":          graphql.String(step),
+	})
+	if err != nil {
+		return assignment.StepRecord{}, fmt.Errorf("could not fetch assignment step %d %s %w", assignmentID, step, err)
+	}
+
+	if len(q.AssignmentSteps) == 0 {
+		return assignment.StepRecord{}, nil
+	}
+
+	s := q.AssignmentSteps[0]
+	record := assignment.StepRecord{
+		AssignmentID: assignmentID,
+		Step:         step,
+		EventID:      string(s.EventID),
+		Completed:    s.CompletedAt != "",
+		Attempts:     int(s.Attempts),
+	}
+
+	if s.ScheduleAt != "" {
+		record.ScheduleAt, err = time.Parse(time.RFC3339, string(s.ScheduleAt))
+		if err != nil {
+			return assignment.StepRecord{}, fmt.Errorf("could not parse step schedule_at %s %w", s.ScheduleAt, err)
+		}
+	}
+
+	if len(s.Actions) > 0 {
+		err = json.Unmarshal(s.Actions, &record.Actions)
+		if err != nil {
+			return assignment.StepRecord{}, fmt.Errorf("could not decode step actions %s %w", s.Actions, err)
+		}
+	}
+
+	return record, nil
+}
+
+// SaveStep upserts the step ledger entry for the record's assignment step.
+func (h HasuraClient) SaveStep(record assignment.StepRecord) error {
+	actions := make(jsonb, len(record.Actions))
+	for action, at := range record.Actions {
+		actions[action] = at.Format(time.RFC3339)
+	}
+
+	var completedAt *timestamptz
+	if record.Completed {
+		completedAt = newTimestamp(time.Now())
+	}
+
+	var mu upsertAssignmentStepMutation
+	err := h.client.Mutate(context.Background(), &mu, map[string]interface{}{
+		"assignment_id": graphql.Int(record.AssignmentID),
+		"step":          graphql.String(record.Step),
+		"event_id":      graphql.String(record.EventID),
+		"schedule_at":   newTimestamp(record.ScheduleAt),
+		"actions":       actions,
+		"completed_at":  completedAt,
+		"attempts":      graphql.Int(record.Attempts),
+	})
+	if err != nil {
+		return fmt.Errorf("could not save assignment step %d %s %w", record.AssignmentID, record.Step, err)
+	}
+
+	return nil
+}
+
+// ScheduleStep upserts the scheduled event of the assignment step, keeping the actions, attempts and completion
+// already recorded for the step.
+func (h HasuraClient) ScheduleStep(assignmentID int, step string, eventID string, scheduleAt time.Time) error {
+	var mu scheduleAssignmentStepMutation
+	err := h.client.Mutate(context.Background(), &mu, map[string]interface{}{
+		"assignment_id": graphql.Int(assignmentID),
+		"step":          graphql.String(step),
+		"event_id":      graphql.String(eventID),
+		"schedule_at":   newTimestamp(scheduleAt),
+	})
+	if err != nil {
+		return fmt.Errorf("could not save assignment step schedule %d %s %w", assignmentID, step, err)
+	}
+
+	return nil
+}
+
+// ClaimStep claims the assignment step until the given time. The claim is made in a single upsert that only
+// updates a step that has not completed and has no unexpired claim, so only one caller can win it.
+func (h HasuraClient) ClaimStep(assignmentID int, step string, until time.Time) (bool, error) {
+	var mu claimAssignmentStepMutation
+	err := h.client.Mutate(context.Background(), &mu, map[string]interface{}{
+		"assignment_id": graphql.Int(assignmentID),
+		"step":          graphql.String(step),
+		"locked_until":  newTimestamp(until),
+		"now":           newTimestamp(time.Now()),
+	})
+	if err != nil {
+		return false, fmt.Errorf("could not claim assignment step %d %s %w", assignmentID, step, err)
+	}
+
+	return mu.InsertAssignmentSteps.AffectedRows > 0, nil
+}
+
+// ReleaseStep clears the claim on the assignment step.
+func (h HasuraClient) ReleaseStep(assignmentID int, step string) error {
+	var mu releaseAssignmentStepMutation
+	err := h.client.Mutate(context.Background(), &mu, map[string]interface{}{
+		"assignment_id": graphql.Int(assignmentID),
+		"step":          graphql.String(step),
+	})
+	if err != nil {
+		return fmt.Errorf("could not release assignment step %d %s %w", assignmentID, step, err)
+	}
+
+	return nil
+}
+
+// AddDeadLetter inserts a dead letter for a step that could not be run.
+func (h HasuraClient) AddDeadLetter(letter assignment.DeadLetter) error {
+	b, err := json.Marshal(letter.Data)
+	if err != nil {
+		return fmt.Errorf("could not encode dead letter payload %w", err)
+	}
+
+	var payload jsonb
+	err = json.Unmarshal(b, &payload)
+	if err != nil {
+		return fmt.Errorf("could not decode dead letter payload %w", err)
+	}
+
+	var mu insertDeadLetterMutation
+	err = h.client.Mutate(context.Background(), &mu, map[string]interface{}{
+		"assignment_id": graphql.Int(letter.AssignmentID),
+		"step":          graphql.String(letter.Step),
+		"payload":       payload,
+		"error":         graphql.String(letter.Error),
+		"attempts":      graphql.Int(letter.Attempts),
+	})
+	if err != nil {
+		return fmt.Errorf("could not insert dead letter for step %s of assignment %d %w", letter.Step, letter.AssignmentID, err)
+	}
+
+	return nil
+}
+
+// GetDeadLetter returns the dead letter with the given id.
+func (h HasuraClient) GetDeadLetter(id int) (assignment.DeadLetter, error) {
+	var q deadLetterQuery
+	err := h.client.Query(context.Background(), &q, map[string]interface{}{
+		"id": graphql.Int(id),
+	})
+	if err != nil {
+		return assignment.DeadLetter{}, fmt.Errorf("could not fetch dead letter %d %w", id, err)
+	}
+
+	d := q.DeadLetter
+	if d.ID == 0 {
+		return assignment.DeadLetter{}, fmt.Errorf("dead letter %d does not exist", id)
+	}
+
+	letter := assignment.DeadLetter{
+		ID:           int(d.ID),
+		AssignmentID: int(d.AssignmentID),
+		Step:         string(d.Step),
+		Error:        string(d.Error),
+		Attempts:     int(d.Attempts),
+		Replayed:     d.ReplayedAt != "",
+	}
+
+	err = json.Unmarshal(d.Payload, &letter.Data)
+	if err != nil {
+		return assignment.DeadLetter{}, fmt.Errorf("could not decode dead letter payload %s %w", d.Payload, err)
+	}
+
+	return letter, nil
+}
+
+// MarkReplayed records that the dead letter with the given id has been replayed.
+func (h HasuraClient) MarkReplayed(id int) error {
+	var mu markDeadLetterReplayedMutation
+	err := h.client.Mutate(context.Background(), &mu, map[string]interface{}{
+		"id":          graphql.Int(id),
+		"replayed_at": newTimestamp(time.Now()),
+	})
+	if err != nil {
+		return fmt.Errorf("could not mark dead letter %d as replayed %w", id, err)
+	}
+
+	return nil
+}
+
+// StartStage records that the stage at position has started for the assignment, along with the commit sha
+// of the test repo that was uploaded for it.
+func (h HasuraClient) StartStage(assignmentID, position int, branch, sha string, startedAt, deadline time.Time) error {
+	var mu upsertAssignmentStageMutation
+	err := h.client.Mutate(context.Background(), &mu, map[string]interface{}{
+		"assignment_id": graphql.Int(assignmentID),
+		"position":      graphql.Int(position),
+		"branch":        graphql.String(branch),
+		"commit_sha":    graphql.String(sha),
+		"started_at":    newTimestamp(startedAt),
+		"deadline":      newTimestamp(deadline),
+	})
+	if err != nil {
+		return fmt.Errorf("could not start stage %d for assignment %d %w", position, assignmentID, err)
+	}
+
+	return nil
+}
+
+// RecordTestCommit sets the commit sha of the test repo that was uploaded to the assignment.
+func (h HasuraClient) RecordTestCommit(assignmentID int, sha string) error {
+	var mu updateAssignmentMutation
+	err := h.client.Mutate(context.Background(), &mu, map[string]interface{}{
+		"id":  graphql.Int(assignmentID),
+		"set": assignments_set_input{"test_commit_sha": sha},
+	})
+	if err != nil {
+		return fmt.Errorf("could not record test commit %s for assignment %d %w", sha, assignmentID, err)
+	}
+
+	return nil
+}
+
+// GetTest returns the test with id along with its business and stages.
+func (h HasuraClient) GetTest(id int) (assignment.Test, error) {
+	var q testQuery
+	err := h.client.Query(context.Background(), &q, map[string]interface{}{
+		"id": graphql.Int(id),
+	})
+	if err != nil {
+		return assignment.Test{}, fmt.Errorf("could not fetch graphql test %w", err)
+	}
+
+	return toTest(q.TestsByPK)
+}
+
+// RecordTestValidation stores the preflight report on the test.
+func (h HasuraClient) RecordTestValidation(testID int, report assignment.ValidationReport) error {
+	var mu updateTestMutation
+	err := h.client.Mutate(context.Background(), &mu, map[string]interface{}{
+		"id":  graphql.Int(testID),
+		"set": tests_set_input{"validation": report},
+	})
+	if err != nil {
+		return fmt.Errorf("could not record validation for test %d %w", testID, err)
+	}
+
+	return nil
+}
+
+// ExtendStage sets the deadline of the stage at position for the assignment.
+func (h HasuraClient) ExtendStage(assignmentID, position int, deadline time.Time) error {
+	var mu extendAssignmentStageMutation
+	err := h.client.Mutate(context.Background(), &mu, map[string]interface{}{
+		"assignment_id": graphql.Int(assignmentID),
+		"position":      graphql.Int(position),
+		"deadline":      newTimestamp(deadline),
+	})
+	if err != nil {
+		return fmt.Errorf("could not extend stage %d for assignment %d %w", position, assignmentID, err)
+	}
+
+	return nil
+}
+
+// FinishStage sets the status of the stage at position for the assignment.
+func (h HasuraClient) FinishStage(assignmentID, position int, status string) error {
+	var mu finishAssignmentStageMutation
+	err := h.client.Mutate(context.Background(), &mu, map[string]interface{}{
+		"assignment_id": graphql.Int(assignmentID),
+		"position":      graphql.Int(position),
+		"status":        graphql.String(status),
+		"finished_at":   newTimestamp(time.Now()),
+	})
+	if err != nil {
+		return fmt.Errorf("could not finish stage %d for assignment %d %w", position, assignmentID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package graphql_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+	"github.com/testrelay/testrelay/backend/internal/store/graphql"
+)
+
+func TestHasuraClientTransport(t *testing.T) {
+	t.Run("should mark hasura outages as transient", func(t *testing.T) {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer s.Close()
+
+		_, err := graphql.NewHasuraClient(s.URL, "secret").GetStep(12, "init")
+		require.Error(t, err)
+		assert.True(t, core.IsTransient(err))
+	})
+
+	t.Run("should mark dropped connections as transient", func(t *testing.T) {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		s.Close()
+
+		_, err := graphql.NewHasuraClient(s.URL, "secret").GetStep(12, "init")
+		require.Error(t, err)
+		assert.True(t, core.IsTransient(err))
+	})
+
+	t.Run("should leave graphql errors permanent", func(t *testing.T) {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"errors":[{"message":"field \"assignment_steps\" not found in type: 'query_root'"}]}`))
+		}))
+		defer s.Close()
+
+		_, err := graphql.NewHasuraClient(s.URL, "secret").GetStep(12, "init")
+		require.Error(t, err)
+		assert.False(t, core.IsTransient(err))
+	})
+}
+
+func TestHasuraClientSteps(t *testing.T) {
+	// newServer returns a server that responds with data and records the query of each request.
+	newServer := func(t *testing.T, data map[string]interface{}, queries *[]string) *httptest.Server {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body struct {
+				Query string `json:"query"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			*queries = append(*queries, body.Query)
+
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+		}))
+		t.Cleanup(s.Close)
+
+		return s
+	}
+
+	claimed := func(rows int) map[string]interface{} {
+		return map[string]interface{}{"insert_assignment_steps": map[string]interface{}{"affected_rows": rows}}
+	}
+
+	t.Run("ClaimStep should claim steps that are not completed or claimed", func(t *testing.T) {
+		var queries []string
+		s := newServer(t, claimed(1), &queries)
+
+		ok, err := graphql.NewHasuraClient(s.URL, "secret").ClaimStep(12, "init", time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		require.Len(t, queries, 1)
+		assert.Contains(t, queries[0], "update_columns: [locked_until], where: {completed_at: {_is_null: true}")
+	})
+
+	t.Run("ClaimStep should not claim a step when no row is updated", func(t *testing.T) {
+		var queries []string
+		s := newServer(t, claimed(0), &queries)
+
+		ok, err := graphql.NewHasuraClient(s.URL, "secret").ClaimStep(12, "init", time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("ScheduleStep should only update the scheduled event of the step", func(t *testing.T) {
+		var queries []string
+		s := newServer(t, map[string]interface{}{"insert_assignment_steps_one": map[string]interface{}{"id": 1}}, &queries)
+
+		err := graphql.NewHasuraClient(s.URL, "secret").ScheduleStep(12, "end", "event-1", time.Now())
+		require.NoError(t, err)
+
+		require.Len(t, queries, 1)
+		assert.Contains(t, queries[0], "update_columns: [event_id, schedule_at]")
+	})
+}
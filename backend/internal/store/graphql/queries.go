@@ -1,6 +1,9 @@
 package graphql
 
 import (
+	"encoding/json"
+	"time"
+
 	"github.com/hasura/go-graphql-client"
 )
 
@@ -45,7 +48,6 @@ type getBusinessQuery struct {
 	} `graphql:"businesses_by_pk(id: $id)"`
 }
 
-
 type linkUserMutation struct {
 	InsertBusinessUsersOne struct {
 		ID graphql.Int `graphql:"id"`
@@ -64,6 +66,28 @@ type UpdateAssignmentMutation struct {
 	} `graphql:"insert_business_users_one(object: {business_id: $business_id, user_id: $candidate_id, user_type: $user_type},on_conflict: {constraint: business_users_business_id_user_id_user_type_key})"`
 }
 
+type updateAssignmentMutation struct {
+	UpdateAssignmentsByPK struct {
+		ID graphql.Int `graphql:"id"`
+	} `graphql:"update_assignments_by_pk(pk_columns: {id: $id}, _set: $set)"`
+}
+
+type startAssignmentMutation struct {
+	UpdateAssignments struct {
+		AffectedRows graphql.Int `graphql:"affected_rows"`
+	} `graphql:"update_assignments(where: {id: {_eq: $id}, status: {_in: [sent, viewed]}}, _set: $set)"`
+}
+
+type testQuery struct {
+	TestsByPK Test `graphql:"tests_by_pk(id: $id)"`
+}
+
+type updateTestMutation struct {
+	UpdateTestsByPK struct {
+		ID graphql.Int `graphql:"id"`
+	} `graphql:"update_tests_by_pk(pk_columns: {id: $id}, _set: $set)"`
+}
+
 type InsertAssignmentEvent struct {
 	UpdateAssignmentsByPK struct {
 		ID graphql.Int `graphql:"id"`
@@ -73,18 +97,148 @@ type InsertAssignmentEvent struct {
 	} `graphql:"insert_assignment_events(objects: {event_type: $status, user_id: $user_id, assignment_id: $id})"`
 }
 
+type unscheduledAssignmentsQuery struct {
+	Assignments []Assignment `graphql:"assignments(where: {status: {_in: [sent, viewed]}, choose_until: {_lt: $date}})"`
+}
+
+type inFlightAssignmentsQuery struct {
+	Assignments []Assignment `graphql:"assignments(where: {status: {_in: [scheduled, inprogress]}})"`
+}
+
+type assignmentEventsQuery struct {
+	AssignmentEvents []struct {
+		EventType graphql.String `graphql:"event_type"`
+		CreatedAt graphql.String `graphql:"created_at"`
+	} `graphql:"assignment_events(where: {assignment_id: {_eq: $assignment_id}}, order_by: {created_at: asc})"`
+}
+
+type assignmentByRepoQuery struct {
+	Assignments []struct {
+		ID graphql.Int `graphql:"id"`
+	} `graphql:"assignments(where: {github_repo_url: {_eq: $url}}, limit: 1)"`
+}
+
+type recordAssignmentEventMutation struct {
+	InsertAssignmentEventsOne struct {
+		ID graphql.Int `graphql:"id"`
+	} `graphql:"insert_assignment_events_one(object: {event_type: $event_type, user_id: $user_id, assignment_id: $assignment_id, meta: $meta})"`
+}
+
 type AssignmentReviewers struct {
 	AssignmentUsers struct {
 		Reviewers []Reviewer `graphql:"reviewers"`
+		Test      struct {
+			Business struct {
+				VCSProvider string `graphql:"vcs_provider"`
+			} `graphql:"business"`
+		} `graphql:"test"`
 	} `graphql:"assignments_by_pk(id: $id)"`
 }
 
 type Reviewer struct {
 	User struct {
-		GithubUsername string `graphql:"github_username" json:"github_username"`
+		Email              string `graphql:"email" json:"email"`
+		GithubUsername     string `graphql:"github_username" json:"github_username"`
+		GitlabUsername     string `graphql:"gitlab_username" json:"gitlab_username"`
+		BitbucketAccountID string `graphql:"bitbucket_account_id" json:"bitbucket_account_id"`
 	} `graphql:"user" json:"user"`
 }
 
+type assignmentStepQuery struct {
+	AssignmentSteps []struct {
+		EventID     graphql.String  `graphql:"event_id"`
+		ScheduleAt  graphql.String  `graphql:"schedule_at"`
+		Actions     json.RawMessage `graphql:"actions"`
+		CompletedAt graphql.String  `graphql:"completed_at"`
+		Attempts    graphql.Int     `graphql:"attempts"`
+	} `graphql:"assignment_steps(where: {assignment_id: {_eq: $assignment_id}, step: {_eq: $step}})"`
+}
+
+type upsertAssignmentStepMutation struct {
+	InsertAssignmentStepsOne struct {
+		ID graphql.Int `graphql:"id"`
+	} `graphql:"insert_assignment_steps_one(object: {assignment_id: $assignment_id, step: $step, event_id: $event_id, schedule_at: $schedule_at, actions: $actions, completed_at: $completed_at, attempts: $attempts}, on_conflict: {constraint: assignment_steps_assignment_id_step_key, update_columns: [event_id, schedule_at, actions, completed_at, attempts]})"`
+}
+
+type scheduleAssignmentStepMutation struct {
+	InsertAssignmentStepsOne struct {
+		ID graphql.Int `graphql:"id"`
+	} `graphql:"insert_assignment_steps_one(object: {assignment_id: $assignment_id, step: $step, event_id: $event_id, schedule_at: $schedule_at}, on_conflict: {constraint: assignment_steps_assignment_id_step_key, update_columns: [event_id, schedule_at]})"`
+}
+
+type claimAssignmentStepMutation struct {
+	InsertAssignmentSteps struct {
+		AffectedRows graphql.Int `graphql:"affected_rows"`
+	} `graphql:"insert_assignment_steps(objects: [{assignment_id: $assignment_id, step: $step, locked_until: $locked_until}], on_conflict: {constraint: assignment_steps_assignment_id_step_key, update_columns: [locked_until], where: {completed_at: {_is_null: true}, _or: [{locked_until: {_is_null: true}}, {locked_until: {_lt: $now}}]}})"`
+}
+
+type releaseAssignmentStepMutation struct {
+	UpdateAssignmentSteps struct {
+		AffectedRows graphql.Int `graphql:"affected_rows"`
+	} `graphql:"update_assignment_steps(where: {assignment_id: {_eq: $assignment_id}, step: {_eq: $step}}, _set: {locked_until: null})"`
+}
+
+type insertDeadLetterMutation struct {
+	InsertDeadLettersOne struct {
+		ID graphql.Int `graphql:"id"`
+	} `graphql:"insert_dead_letters_one(object: {assignment_id: $assignment_id, step: $step, payload: $payload, error: $error, attempts: $attempts})"`
+}
+
+type deadLetterQuery struct {
+	DeadLetter struct {
+		ID           graphql.Int     `graphql:"id"`
+		AssignmentID graphql.Int     `graphql:"assignment_id"`
+		Step         graphql.String  `graphql:"step"`
+		Payload      json.RawMessage `graphql:"payload"`
+		Error        graphql.String  `graphql:"error"`
+		Attempts     graphql.Int     `graphql:"attempts"`
+		ReplayedAt   graphql.String  `graphql:"replayed_at"`
+	} `graphql:"dead_letters_by_pk(id: $id)"`
+}
+
+type markDeadLetterReplayedMutation struct {
+	UpdateDeadLettersByPk struct {
+		ID graphql.Int `graphql:"id"`
+	} `graphql:"update_dead_letters_by_pk(pk_columns: {id: $id}, _set: {replayed_at: $replayed_at})"`
+}
+
+type upsertAssignmentStageMutation struct {
+	InsertAssignmentStagesOne struct {
+		ID graphql.Int `graphql:"id"`
+	} `graphql:"insert_assignment_stages_one(object: {assignment_id: $assignment_id, position: $position, branch: $branch, commit_sha: $commit_sha, started_at: $started_at, deadline: $deadline}, on_conflict: {constraint: assignment_stages_assignment_id_position_key, update_columns: [branch, commit_sha, started_at, deadline]})"`
+}
+
+type finishAssignmentStageMutation struct {
+	UpdateAssignmentStages struct {
+		AffectedRows graphql.Int `graphql:"affected_rows"`
+	} `graphql:"update_assignment_stages(where: {assignment_id: {_eq: $assignment_id}, position: {_eq: $position}}, _set: {status: $status, finished_at: $finished_at})"`
+}
+
+type extendAssignmentStageMutation struct {
+	UpdateAssignmentStages struct {
+		AffectedRows graphql.Int `graphql:"affected_rows"`
+	} `graphql:"update_assignment_stages(where: {assignment_id: {_eq: $assignment_id}, position: {_eq: $position}}, _set: {deadline: $deadline})"`
+}
+
+type timestamptz string
+
+func newTimestamp(t time.Time) *timestamptz {
+	if t.IsZero() {
+		return nil
+	}
+
+	ts := timestamptz(t.Format(time.RFC3339))
+	return &ts
+}
+
+type jsonb map[string]interface{}
+
+type date string
+
+type assignments_set_input map[string]interface{}
+
+type tests_set_input map[string]interface{}
+
 type assignment_status_enum string
 
 func newStatus(s string) *assignment_status_enum {
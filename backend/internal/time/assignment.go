@@ -5,6 +5,44 @@ import (
 	"time"
 )
 
+const (
+	dayLayout  = "2006-01-02"
+	timeLayout = "15:04:05"
+)
+
+// Validation error codes returned in ValidationError.Code.
+const (
+	CodeInvalidDay       = "invalid_day"
+	CodeInvalidTime      = "invalid_time"
+	CodeInvalidTimezone  = "invalid_timezone"
+	CodeNonexistentTime  = "nonexistent_time"
+	CodeAmbiguousTime    = "ambiguous_time"
+	CodeInPast           = "in_past"
+	CodeAfterChooseUntil = "after_choose_until"
+	CodeBlackout         = "blackout"
+	CodeUnavailable      = "unavailable"
+)
+
+// ValidationError is returned when the candidate's choice of day, time or timezone cannot be scheduled.
+// Field is the input that is invalid, one of day|time|timezone, and Code describes why.
+type ValidationError struct {
+	Field   string
+	Code    string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return e.Message
+}
+
+// Extensions returns the field and code of the error, so that they are included in graphql error responses.
+func (e ValidationError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"field": e.Field,
+		"code":  e.Code,
+	}
+}
+
 type AssignmentChoices struct {
 	DayChosen  string
 	TimeChosen string
@@ -16,27 +54,144 @@ type ScheduleOutput struct {
 	SendNotificationAt string
 }
 
+// Parse interprets the chosen day and time as a wall clock time in the chosen IANA timezone.
+// It returns a ValidationError if any of the choices are malformed, or if the wall clock time does not
+// exist or happens twice in the timezone because of a daylight saving change.
 func Parse(input AssignmentChoices) (*ScheduleOutput, error) {
-	t, err := time.Parse("2006-01-02 15:04:05",
-		fmt.Sprintf("%s %s", input.DayChosen, input.TimeChosen),
-	)
+	t, err := parseInLocation(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return newScheduleOutput(t), nil
+}
+
+// Window holds the bounds that a candidate's choice must fall within.
+type Window struct {
+	Now time.Time
+	// ChooseUntil is the last day, in the format 2006-01-02, that the candidate can choose.
+	ChooseUntil string
+	// Availability is when the business is available for the test to start.
+	Availability Availability
+}
+
+// Validate parses the choices like Parse, also returning a ValidationError if the chosen time is
+// before w.Now, the chosen day is after w.ChooseUntil or the business is unavailable at the chosen time.
+func Validate(input AssignmentChoices, w Window) (*ScheduleOutput, error) {
+	t, err := parseInLocation(input)
 	if err != nil {
-		return nil, fmt.Errorf(
-			"could not parse time input given, day: %s time: %s err: %w",
-			input.DayChosen,
-			input.TimeChosen,
-			err,
-		)
+		return nil, err
+	}
+
+	if !t.After(w.Now) {
+		return nil, ValidationError{
+			Field:   "time",
+			Code:    CodeInPast,
+			Message: fmt.Sprintf("%s %s has already passed", input.DayChosen, input.TimeChosen),
+		}
 	}
 
-	loc, err := time.LoadLocation(string(input.Timezone))
+	chooseUntil, err := time.Parse(dayLayout, w.ChooseUntil)
 	if err != nil {
-		return nil, fmt.Errorf("could not load location %s err: %w", input.Timezone, err)
+		return nil, fmt.Errorf("could not parse choose until %s %w", w.ChooseUntil, err)
 	}
-	t = t.In(loc)
 
+	day, _ := time.Parse(dayLayout, input.DayChosen)
+	if day.After(chooseUntil) {
+		return nil, ValidationError{
+			Field:   "day",
+			Code:    CodeAfterChooseUntil,
+			Message: fmt.Sprintf("the test must be taken on or before %s", w.ChooseUntil),
+		}
+	}
+
+	err = w.Availability.validate(t, input)
+	if err != nil {
+		return nil, err
+	}
+
+	return newScheduleOutput(t), nil
+}
+
+func newScheduleOutput(t time.Time) *ScheduleOutput {
 	return &ScheduleOutput{
 		StartAssignmentAt:  t.Format(time.RFC3339),
 		SendNotificationAt: t.Add(-(time.Minute * 5)).Format(time.RFC3339),
-	}, nil
+	}
+}
+
+func parseInLocation(input AssignmentChoices) (time.Time, error) {
+	_, err := time.Parse(dayLayout, input.DayChosen)
+	if err != nil {
+		return time.Time{}, ValidationError{
+			Field:   "day",
+			Code:    CodeInvalidDay,
+			Message: fmt.Sprintf("%q is not a valid day, expected YYYY-MM-DD", input.DayChosen),
+		}
+	}
+
+	_, err = time.Parse(timeLayout, input.TimeChosen)
+	if err != nil {
+		return time.Time{}, ValidationError{
+			Field:   "time",
+			Code:    CodeInvalidTime,
+			Message: fmt.Sprintf("%q is not a valid time, expected HH:MM:SS", input.TimeChosen),
+		}
+	}
+
+	// LoadLocation treats an empty name as UTC, which would hide a missing timezone.
+	loc, err := time.LoadLocation(input.Timezone)
+	if input.Timezone == "" || err != nil {
+		return time.Time{}, ValidationError{
+			Field:   "timezone",
+			Code:    CodeInvalidTimezone,
+			Message: fmt.Sprintf("%q is not a valid IANA timezone", input.Timezone),
+		}
+	}
+
+	chosen := input.DayChosen + " " + input.TimeChosen
+	t, err := time.ParseInLocation(dayLayout+" "+timeLayout, chosen, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not parse time input given, day: %s time: %s err: %w", input.DayChosen, input.TimeChosen, err)
+	}
+
+	// ParseInLocation moves times in the gap when the clocks go forward, so they no longer show the chosen time.
+	if t.Format(dayLayout+" "+timeLayout) != chosen {
+		return time.Time{}, ValidationError{
+			Field:   "time",
+			Code:    CodeNonexistentTime,
+			Message: fmt.Sprintf("%s does not exist in %s because the clocks go forward", chosen, input.Timezone),
+		}
+	}
+
+	if isAmbiguous(t) {
+		return time.Time{}, ValidationError{
+			Field:   "time",
+			Code:    CodeAmbiguousTime,
+			Message: fmt.Sprintf("%s happens twice in %s because the clocks go back", chosen, input.Timezone),
+		}
+	}
+
+	return t, nil
+}
+
+// isAmbiguous returns whether the wall clock time of t happens twice in its location,
+// i.e. in the overlap when the clocks go back.
+func isAmbiguous(t time.Time) bool {
+	_, offset := t.Zone()
+
+	// the offsets either side of t cover any transition that could affect it.
+	for _, near := range []time.Time{t.Add(-time.Hour * 24), t.Add(time.Hour * 24)} {
+		_, other := near.Zone()
+		if other == offset {
+			continue
+		}
+
+		u := t.Add(time.Second * time.Duration(offset-other))
+		if _, actual := u.Zone(); actual == other && u.Format(dayLayout+timeLayout) == t.Format(dayLayout+timeLayout) {
+			return true
+		}
+	}
+
+	return false
 }
@@ -0,0 +1,192 @@
+package time_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	intTime "github.com/testrelay/testrelay/backend/internal/time"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("should interpret the choice in the candidate's timezone", func(t *testing.T) {
+		out, err := intTime.Parse(intTime.AssignmentChoices{
+			DayChosen:  "2021-07-12",
+			TimeChosen: "10:00:00",
+			Timezone:   "Europe/London",
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, &intTime.ScheduleOutput{
+			StartAssignmentAt:  "2021-07-12T10:00:00+01:00",
+			SendNotificationAt: "2021-07-12T09:55:00+01:00",
+		}, out)
+	})
+
+	t.Run("should return validation errors for invalid choices", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			input    intTime.AssignmentChoices
+			expected intTime.ValidationError
+		}{
+			{
+				name:  "malformed day",
+				input: intTime.AssignmentChoices{DayChosen: "12/07/2021", TimeChosen: "10:00:00", Timezone: "UTC"},
+				expected: intTime.ValidationError{
+					Field:   "day",
+					Code:    intTime.CodeInvalidDay,
+					Message: `"12/07/2021" is not a valid day, expected YYYY-MM-DD`,
+				},
+			},
+			{
+				name:  "malformed time",
+				input: intTime.AssignmentChoices{DayChosen: "2021-07-12", TimeChosen: "10am", Timezone: "UTC"},
+				expected: intTime.ValidationError{
+					Field:   "time",
+					Code:    intTime.CodeInvalidTime,
+					Message: `"10am" is not a valid time, expected HH:MM:SS`,
+				},
+			},
+			{
+				name:  "unknown timezone",
+				input: intTime.AssignmentChoices{DayChosen: "2021-07-12", TimeChosen: "10:00:00", Timezone: "Europe/Nowhere"},
+				expected: intTime.ValidationError{
+					Field:   "timezone",
+					Code:    intTime.CodeInvalidTimezone,
+					Message: `"Europe/Nowhere" is not a valid IANA timezone`,
+				},
+			},
+			{
+				name:  "clocks go forward",
+				input: intTime.AssignmentChoices{DayChosen: "2021-03-28", TimeChosen: "01:30:00", Timezone: "Europe/London"},
+				expected: intTime.ValidationError{
+					Field:   "time",
+					Code:    intTime.CodeNonexistentTime,
+					Message: "2021-03-28 01:30:00 does not exist in Europe/London because the clocks go forward",
+				},
+			},
+			{
+				name:  "clocks go back",
+				input: intTime.AssignmentChoices{DayChosen: "2021-11-07", TimeChosen: "01:30:00", Timezone: "America/New_York"},
+				expected: intTime.ValidationError{
+					Field:   "time",
+					Code:    intTime.CodeAmbiguousTime,
+					Message: "2021-11-07 01:30:00 happens twice in America/New_York because the clocks go back",
+				},
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := intTime.Parse(tt.input)
+				assert.Equal(t, tt.expected, err)
+			})
+		}
+	})
+
+	t.Run("should accept times either side of a clock change", func(t *testing.T) {
+		out, err := intTime.Parse(intTime.AssignmentChoices{
+			DayChosen:  "2021-11-07",
+			TimeChosen: "02:30:00",
+			Timezone:   "America/New_York",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "2021-11-07T02:30:00-05:00", out.StartAssignmentAt)
+	})
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Date(2021, 11, 12, 10, 0, 0, 0, time.UTC)
+	window := intTime.Window{Now: now, ChooseUntil: "2021-11-20"}
+
+	t.Run("should return the start time of a valid choice", func(t *testing.T) {
+		out, err := intTime.Validate(intTime.AssignmentChoices{
+			DayChosen:  "2021-11-20",
+			TimeChosen: "09:00:00",
+			Timezone:   "Asia/Tokyo",
+		}, window)
+		require.NoError(t, err)
+		assert.Equal(t, "2021-11-20T09:00:00+09:00", out.StartAssignmentAt)
+	})
+
+	t.Run("should reject choices in the past", func(t *testing.T) {
+		// 10:30 in Paris is 09:30 UTC, half an hour before now.
+		_, err := intTime.Validate(intTime.AssignmentChoices{
+			DayChosen:  "2021-11-12",
+			TimeChosen: "10:30:00",
+			Timezone:   "Europe/Paris",
+		}, window)
+
+		var v intTime.ValidationError
+		require.ErrorAs(t, err, &v)
+		assert.Equal(t, intTime.CodeInPast, v.Code)
+	})
+
+	t.Run("should reject choices after choose until", func(t *testing.T) {
+		_, err := intTime.Validate(intTime.AssignmentChoices{
+			DayChosen:  "2021-11-21",
+			TimeChosen: "09:00:00",
+			Timezone:   "UTC",
+		}, window)
+
+		var v intTime.ValidationError
+		require.ErrorAs(t, err, &v)
+		assert.Equal(t, intTime.ValidationError{
+			Field:   "day",
+			Code:    intTime.CodeAfterChooseUntil,
+			Message: "the test must be taken on or before 2021-11-20",
+		}, v)
+	})
+
+	t.Run("should reject choices when the business is unavailable", func(t *testing.T) {
+		w := window
+		w.Availability = intTime.Availability{
+			Timezone:  "Europe/London",
+			Openings:  weekdays("09:00:00", "17:00:00"),
+			Blackouts: []string{"2021-11-15"},
+		}
+
+		tests := []struct {
+			name     string
+			input    intTime.AssignmentChoices
+			expected intTime.ValidationError
+		}{
+			{
+				name:  "outside of openings",
+				input: intTime.AssignmentChoices{DayChosen: "2021-11-16", TimeChosen: "03:30:00", Timezone: "America/New_York"},
+				expected: intTime.ValidationError{
+					Field:   "time",
+					Code:    intTime.CodeUnavailable,
+					Message: "the business is unavailable at 2021-11-16 03:30:00",
+				},
+			},
+			{
+				name:  "blackout",
+				input: intTime.AssignmentChoices{DayChosen: "2021-11-15", TimeChosen: "10:00:00", Timezone: "Europe/London"},
+				expected: intTime.ValidationError{
+					Field:   "day",
+					Code:    intTime.CodeBlackout,
+					Message: "the business is unavailable on 2021-11-15",
+				},
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := intTime.Validate(tt.input, w)
+				assert.Equal(t, tt.expected, err)
+			})
+		}
+
+		// 04:30 in New York is 09:30 in London.
+		out, err := intTime.Validate(intTime.AssignmentChoices{
+			DayChosen:  "2021-11-16",
+			TimeChosen: "04:30:00",
+			Timezone:   "America/New_York",
+		}, w)
+		require.NoError(t, err)
+		assert.Equal(t, "2021-11-16T04:30:00-05:00", out.StartAssignmentAt)
+	})
+}
@@ -0,0 +1,179 @@
+package time
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Opening is a weekly window of time that a business is available for candidates to start a test.
+type Opening struct {
+	Weekday time.Weekday `json:"weekday"`
+	// Start and End are wall clock times in the format 15:04:05. End is exclusive, an End of
+	// 00:00:00 is midnight at the end of the day.
+	Start string `json:"start_time"`
+	End   string `json:"end_time"`
+}
+
+// Availability holds when a business is available for candidates to start a test. A business
+// without any openings is available at any time on days that are not blacked out.
+type Availability struct {
+	// Timezone is the IANA timezone that openings and blackouts are given in. It defaults to UTC.
+	Timezone string    `json:"timezone"`
+	Openings []Opening `json:"openings"`
+	// Blackouts are days, in the format 2006-01-02, that the business is unavailable e.g. holidays.
+	Blackouts []string `json:"blackouts"`
+}
+
+// Slot is a period of time that a candidate can start a test.
+type Slot struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Slots returns the periods between from and to that the business is available, in order and in the
+// location of from. Openings that touch or overlap are returned as a single slot.
+func (a Availability) Slots(from, to time.Time) ([]Slot, error) {
+	loc, err := a.location()
+	if err != nil {
+		return nil, err
+	}
+
+	blackouts := make(map[string]bool, len(a.Blackouts))
+	for _, b := range a.Blackouts {
+		blackouts[b] = true
+	}
+
+	var slots []Slot
+	start := from.In(loc)
+	for day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc); day.Before(to); day = day.AddDate(0, 0, 1) {
+		if blackouts[day.Format(dayLayout)] {
+			continue
+		}
+
+		openings, err := a.openingsOn(day)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, o := range openings {
+			if o.Start.Before(from) {
+				o.Start = from
+			}
+			if o.End.After(to) {
+				o.End = to
+			}
+			if !o.Start.Before(o.End) {
+				continue
+			}
+
+			slots = append(slots, o)
+		}
+	}
+
+	sort.Slice(slots, func(i, j int) bool {
+		return slots[i].Start.Before(slots[j].Start)
+	})
+
+	var merged []Slot
+	for _, s := range slots {
+		s.Start, s.End = s.Start.In(from.Location()), s.End.In(from.Location())
+
+		last := len(merged) - 1
+		if last >= 0 && !s.Start.After(merged[last].End) {
+			if s.End.After(merged[last].End) {
+				merged[last].End = s.End
+			}
+			continue
+		}
+
+		merged = append(merged, s)
+	}
+
+	return merged, nil
+}
+
+// validate returns a ValidationError if t is on a blackout day or outside of the business's openings.
+func (a Availability) validate(t time.Time, input AssignmentChoices) error {
+	loc, err := a.location()
+	if err != nil {
+		return err
+	}
+
+	for _, b := range a.Blackouts {
+		if t.In(loc).Format(dayLayout) == b {
+			return ValidationError{
+				Field:   "day",
+				Code:    CodeBlackout,
+				Message: fmt.Sprintf("the business is unavailable on %s", input.DayChosen),
+			}
+		}
+	}
+
+	slots, err := a.Slots(t, t.Add(time.Second))
+	if err != nil {
+		return err
+	}
+
+	if len(slots) == 0 {
+		return ValidationError{
+			Field:   "time",
+			Code:    CodeUnavailable,
+			Message: fmt.Sprintf("the business is unavailable at %s %s", input.DayChosen, input.TimeChosen),
+		}
+	}
+
+	return nil
+}
+
+// openingsOn returns the openings of the business on the given day as slots. It returns the whole day
+// if the business has no openings.
+func (a Availability) openingsOn(day time.Time) ([]Slot, error) {
+	if len(a.Openings) == 0 {
+		return []Slot{{Start: day, End: day.AddDate(0, 0, 1)}}, nil
+	}
+
+	var slots []Slot
+	for _, o := range a.Openings {
+		if o.Weekday != day.Weekday() {
+			continue
+		}
+
+		start, err := onDay(day, o.Start)
+		if err != nil {
+			return nil, err
+		}
+
+		end, err := onDay(day, o.End)
+		if err != nil {
+			return nil, err
+		}
+
+		if !end.After(start) {
+			end = day.AddDate(0, 0, 1)
+		}
+
+		slots = append(slots, Slot{Start: start, End: end})
+	}
+
+	return slots, nil
+}
+
+func (a Availability) location() (*time.Location, error) {
+	loc, err := time.LoadLocation(a.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("could not load availability timezone %s %w", a.Timezone, err)
+	}
+
+	return loc, nil
+}
+
+// onDay returns the wall clock time clock, in the format 15:04:05, on day.
+func onDay(day time.Time, clock string) (time.Time, error) {
+	c, err := time.Parse(timeLayout, clock)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not parse availability time %s %w", clock, err)
+	}
+
+	return time.Date(day.Year(), day.Month(), day.Day(), c.Hour(), c.Minute(), c.Second(), 0, day.Location()), nil
+}
@@ -0,0 +1,86 @@
+package time_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	intTime "github.com/testrelay/testrelay/backend/internal/time"
+)
+
+func weekdays(start, end string) []intTime.Opening {
+	var openings []intTime.Opening
+	for d := time.Monday; d <= time.Friday; d++ {
+		openings = append(openings, intTime.Opening{Weekday: d, Start: start, End: end})
+	}
+
+	return openings
+}
+
+func TestAvailabilitySlots(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	// friday 12th november, 10:00 in London.
+	now := time.Date(2021, 11, 12, 10, 0, 0, 0, time.UTC)
+
+	t.Run("should return openings outside of blackouts in the location of from", func(t *testing.T) {
+		a := intTime.Availability{
+			Timezone:  "Europe/London",
+			Openings:  weekdays("09:00:00", "17:00:00"),
+			Blackouts: []string{"2021-11-15"},
+		}
+
+		slots, err := a.Slots(now.In(newYork), time.Date(2021, 11, 17, 0, 0, 0, 0, newYork))
+		require.NoError(t, err)
+
+		assert.Equal(t, []intTime.Slot{
+			{
+				Start: time.Date(2021, 11, 12, 5, 0, 0, 0, newYork),
+				End:   time.Date(2021, 11, 12, 12, 0, 0, 0, newYork),
+			},
+			{
+				Start: time.Date(2021, 11, 16, 4, 0, 0, 0, newYork),
+				End:   time.Date(2021, 11, 16, 12, 0, 0, 0, newYork),
+			},
+		}, slots)
+	})
+
+	t.Run("should merge openings that touch", func(t *testing.T) {
+		a := intTime.Availability{
+			Timezone: "UTC",
+			Openings: []intTime.Opening{
+				{Weekday: time.Friday, Start: "12:00:00", End: "00:00:00"},
+				{Weekday: time.Friday, Start: "09:00:00", End: "12:00:00"},
+				{Weekday: time.Saturday, Start: "00:00:00", End: "02:00:00"},
+			},
+		}
+
+		slots, err := a.Slots(time.Date(2021, 11, 12, 0, 0, 0, 0, time.UTC), time.Date(2021, 11, 14, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+
+		assert.Equal(t, []intTime.Slot{{
+			Start: time.Date(2021, 11, 12, 9, 0, 0, 0, time.UTC),
+			End:   time.Date(2021, 11, 13, 2, 0, 0, 0, time.UTC),
+		}}, slots)
+	})
+
+	t.Run("should be available all day without any openings", func(t *testing.T) {
+		a := intTime.Availability{Blackouts: []string{"2021-11-13"}}
+
+		slots, err := a.Slots(now, time.Date(2021, 11, 15, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+
+		assert.Equal(t, []intTime.Slot{
+			{Start: now, End: time.Date(2021, 11, 13, 0, 0, 0, 0, time.UTC)},
+			{Start: time.Date(2021, 11, 14, 0, 0, 0, 0, time.UTC), End: time.Date(2021, 11, 15, 0, 0, 0, 0, time.UTC)},
+		}, slots)
+	})
+
+	t.Run("should error for an unknown timezone", func(t *testing.T) {
+		_, err := intTime.Availability{Timezone: "Europe/Nowhere"}.Slots(now, now.Add(time.Hour))
+		assert.Error(t, err)
+	})
+}
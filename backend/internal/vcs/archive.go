@@ -0,0 +1,170 @@
+package vcs
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+)
+
+// maxUnpackedSize bounds the total size of the files unpacked from a test repo archive, as uploads are held in
+// memory rather than written to disk.
+const maxUnpackedSize = 512 << 20
+
+// maxArchiveSize bounds the size of a downloaded test repo archive, which is held in memory until it has been
+// unpacked. Each upload reads at most this many bytes from the provider.
+const maxArchiveSize = 256 << 20
+
+// readArchive copies the archive in r into buf, reading at most limit+1 bytes and failing if the archive is
+// larger than limit.
+func readArchive(buf *bytes.Buffer, r io.Reader, limit int64) error {
+	n, err := buf.ReadFrom(io.LimitReader(r, limit+1))
+	if err != nil {
+		return err
+	}
+
+	if n > limit {
+		return fmt.Errorf("archive is larger than %d bytes", limit)
+	}
+
+	return nil
+}
+
+// extractArchive unpacks the zip archive in buf into fs. The archive must hold a single top level directory,
+// as returned by github, gitlab and bitbucket, whose contents, or the contents of data.Path within it, become the
+// root of fs. File modes and symlinks are kept. Files matched by the .testrelayignore at that root are then
+// removed, see removeIgnored, and files matched by data.TemplateGlob are rendered, see renderTemplates. It returns
+// the paths of the files that were removed.
+func extractArchive(buf *bytes.Buffer, data core.UploadDetails, fs billy.Filesystem) ([]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		return nil, fmt.Errorf("could not read archive of test repo %s %w", data.TestVCSRepoURL, err)
+	}
+
+	var top string
+	for _, f := range zr.File {
+		if i := strings.Index(f.Name, "/"); i > 0 {
+			top = f.Name[:i]
+			break
+		}
+	}
+
+	if top == "" {
+		return nil, fmt.Errorf("test repo %s is empty", data.TestVCSRepoURL)
+	}
+
+	// only the subtree at data.Path becomes the root of the repo, rooting the path so it cannot escape the archive.
+	root := path.Join(top, path.Clean("/"+data.Path))
+
+	var found bool
+	remaining := int64(maxUnpackedSize)
+	for _, f := range zr.File {
+		name := path.Clean(f.Name)
+		if path.IsAbs(f.Name) || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("%s: illegal file path", f.Name)
+		}
+
+		if name != root && !strings.HasPrefix(name, root+"/") {
+			continue
+		}
+
+		rel := strings.TrimPrefix(strings.TrimPrefix(name, root), "/")
+		if rel == "" {
+			found = found || f.FileInfo().IsDir()
+			continue
+		}
+		found = true
+
+		n, err := unpack(fs, f, rel, remaining)
+		if err != nil {
+			return nil, fmt.Errorf("could not unpack %s %w", rel, err)
+		}
+
+		remaining -= n
+		if remaining < 0 {
+			return nil, fmt.Errorf("test repo %s unpacks to more than %d bytes", data.TestVCSRepoURL, maxUnpackedSize)
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("path %s is not a directory of test repo %s", data.Path, data.TestVCSRepoURL)
+	}
+
+	excluded, err := removeIgnored(fs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = renderTemplates(fs, data.TemplateGlob, data.TemplateData)
+	if err != nil {
+		return nil, err
+	}
+
+	return excluded, nil
+}
+
+// unpack writes the zip entry f to name in fs, reading at most limit+1 bytes so that the caller can tell when
+// the limit is passed. It returns the number of bytes read.
+func unpack(fs billy.Filesystem, f *zip.File, name string, limit int64) (int64, error) {
+	if f.FileInfo().IsDir() {
+		return 0, fs.MkdirAll(name, os.ModePerm)
+	}
+
+	err := fs.MkdirAll(path.Dir(name), os.ModePerm)
+	if err != nil {
+		return 0, err
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+
+	if f.Mode()&os.ModeSymlink != 0 {
+		target, err := io.ReadAll(io.LimitReader(rc, limit+1))
+		if err != nil {
+			return 0, err
+		}
+
+		return int64(len(target)), fs.Symlink(string(target), name)
+	}
+
+	out, err := fs.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode().Perm())
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+
+	return io.Copy(out, io.LimitReader(rc, limit+1))
+}
+
+// walk calls fn with the slash separated path and info of every file below dir in fs, recursing into directories.
+func walk(fs billy.Filesystem, dir string, fn func(p string, info os.FileInfo) error) error {
+	infos, err := fs.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, info := range infos {
+		p := path.Join(dir, info.Name())
+		if info.IsDir() {
+			err = walk(fs, p, fn)
+		} else {
+			err = fn(p, info)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,193 @@
+package vcs
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+)
+
+type archiveEntry struct {
+	name, content string
+	mode          os.FileMode
+}
+
+// newArchive returns a zip of entries within a single top level directory, like the archives returned by
+// hosted providers.
+func newArchive(t *testing.T, entries ...archiveEntry) *bytes.Buffer {
+	buf := bytes.NewBuffer([]byte{})
+	zw := zip.NewWriter(buf)
+	for _, e := range entries {
+		h := &zip.FileHeader{Name: "test-main-abc123/" + e.name, Method: zip.Deflate}
+		h.SetMode(e.mode)
+		w, err := zw.CreateHeader(h)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(e.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	return buf
+}
+
+// archiveOf returns a zip of the regular files, see newArchive.
+func archiveOf(t *testing.T, files map[string]string) *bytes.Buffer {
+	var entries []archiveEntry
+	for name, content := range files {
+		entries = append(entries, archiveEntry{name: name, content: content, mode: 0644})
+	}
+
+	return newArchive(t, entries...)
+}
+
+// newMemFS returns an in memory filesystem holding files.
+func newMemFS(t *testing.T, files map[string]string) billy.Filesystem {
+	fs := memfs.New()
+	for name, content := range files {
+		require.NoError(t, util.WriteFile(fs, name, []byte(content), 0644))
+	}
+
+	return fs
+}
+
+// listFiles returns the sorted paths of the files in fs.
+func listFiles(t *testing.T, fs billy.Filesystem) []string {
+	var files []string
+	require.NoError(t, walk(fs, "", func(p string, info os.FileInfo) error {
+		files = append(files, p)
+		return nil
+	}))
+	sort.Strings(files)
+
+	return files
+}
+
+func readFile(t *testing.T, fs billy.Filesystem, name string) string {
+	b, err := util.ReadFile(fs, name)
+	require.NoError(t, err)
+
+	return string(b)
+}
+
+func TestExtractArchive(t *testing.T) {
+	data := core.UploadDetails{TestVCSRepoURL: "https://gitlab.com/testrelay/backend-test.git"}
+
+	t.Run("should unpack the top level directory to the root", func(t *testing.T) {
+		fs := memfs.New()
+		excluded, err := extractArchive(archiveOf(t, map[string]string{
+			"README.md":   "# Backend test",
+			"src/main.go": "package main",
+		}), data, fs)
+		require.NoError(t, err)
+
+		assert.Empty(t, excluded)
+		assert.Equal(t, []string{"README.md", "src/main.go"}, listFiles(t, fs))
+		assert.Equal(t, "package main", readFile(t, fs, "src/main.go"))
+	})
+
+	t.Run("should unpack only the subtree at the given path", func(t *testing.T) {
+		d := data
+		d.Path = "exercises/api/"
+
+		fs := memfs.New()
+		excluded, err := extractArchive(archiveOf(t, map[string]string{
+			"README.md":                      "# All exercises",
+			"exercises/api/README.md":        "# API exercise",
+			"exercises/api/.testrelayignore": "solution.go",
+			"exercises/api/solution.go":      "package api",
+			"exercises/api/src/main.go":      "package main",
+			"exercises/frontend/README.md":   "# Frontend exercise",
+		}), d, fs)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{".testrelayignore", "solution.go"}, excluded)
+		assert.Equal(t, []string{"README.md", "src/main.go"}, listFiles(t, fs))
+		assert.Equal(t, "# API exercise", readFile(t, fs, "README.md"))
+	})
+
+	t.Run("should error for paths that are not a directory of the archive", func(t *testing.T) {
+		d := data
+		d.Path = "exercises/missing"
+
+		_, err := extractArchive(archiveOf(t, map[string]string{"exercises/api/README.md": "# API exercise"}), d, memfs.New())
+		assert.EqualError(t, err, "path exercises/missing is not a directory of test repo "+data.TestVCSRepoURL)
+	})
+
+	t.Run("should keep file modes and symlinks", func(t *testing.T) {
+		fs := memfs.New()
+		_, err := extractArchive(newArchive(t,
+			archiveEntry{"README.md", "# Backend test", 0644},
+			archiveEntry{"run.sh", "#!/bin/sh", 0755},
+			archiveEntry{"docs", "README.md", 0777 | os.ModeSymlink},
+		), data, fs)
+		require.NoError(t, err)
+
+		info, err := fs.Lstat("README.md")
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0644), info.Mode())
+
+		info, err = fs.Lstat("run.sh")
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0755), info.Mode())
+
+		target, err := fs.Readlink("docs")
+		require.NoError(t, err)
+		assert.Equal(t, "README.md", target)
+	})
+
+	t.Run("should reject paths outside of the archive", func(t *testing.T) {
+		_, err := extractArchive(archiveOf(t, map[string]string{"../../etc/passwd": "root"}), data, memfs.New())
+		assert.EqualError(t, err, "test-main-abc123/../../etc/passwd: illegal file path")
+	})
+
+	t.Run("should error for empty archives", func(t *testing.T) {
+		_, err := extractArchive(newArchive(t), data, memfs.New())
+		assert.EqualError(t, err, "test repo "+data.TestVCSRepoURL+" is empty")
+	})
+
+	t.Run("should remove ignored files and render templates", func(t *testing.T) {
+		d := data
+		d.TemplateGlob = "*.md"
+		d.TemplateData = core.TemplateData{CandidateName: "Jane"}
+
+		fs := memfs.New()
+		excluded, err := extractArchive(archiveOf(t, map[string]string{
+			".testrelayignore": "solution.md",
+			"README.md":        "# Hi {{ .CandidateName }}",
+			"solution.md":      "{{ .Salary }}",
+		}), d, fs)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{".testrelayignore", "solution.md"}, excluded)
+		assert.Equal(t, "# Hi Jane", readFile(t, fs, "README.md"))
+	})
+}
+
+func TestReadArchive(t *testing.T) {
+	t.Run("should read archives up to the limit", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		err := readArchive(buf, strings.NewReader("12345"), 5)
+		require.NoError(t, err)
+
+		assert.Equal(t, "12345", buf.String())
+	})
+
+	t.Run("should fail archives over the limit", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		err := readArchive(buf, strings.NewReader("123456789"), 5)
+		require.Error(t, err)
+
+		assert.Contains(t, err.Error(), "archive is larger than 5 bytes")
+		assert.Equal(t, 6, buf.Len())
+	})
+}
@@ -0,0 +1,396 @@
+package vcs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+	gitHttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+)
+
+// BitbucketError is returned when the bitbucket api responds with a non 2xx status.
+type BitbucketError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *BitbucketError) Error() string {
+	return fmt.Sprintf("bitbucket responded with %d %s", e.StatusCode, e.Message)
+}
+
+// BitbucketConfig represents fields required to manage assignment repos in a bitbucket cloud workspace.
+type BitbucketConfig struct {
+	// URL is the web url of bitbucket, used for clone urls and archive downloads. It defaults to https://bitbucket.org.
+	URL string
+	// APIURL defaults to https://api.bitbucket.org/2.0.
+	APIURL string
+
+	// Username and AppPassword authenticate with both the api and git. The app password needs repository admin
+	// and pull request read permissions.
+	Username    string
+	AppPassword string
+	Email       string
+	// Workspace is the workspace that assignment repos are created in.
+	Workspace string
+}
+
+// BitbucketClient handles communicating with the bitbucket cloud api to orchestrate repository management.
+// Users are identified by their account id, as bitbucket no longer accepts usernames in its api. The
+// configured user must have read access to each business's workspace to upload their test repos.
+type BitbucketClient struct {
+	client *http.Client
+	conf   BitbucketConfig
+}
+
+// NewBitbucketClient returns a BitbucketClient for conf, setting the default bitbucket cloud urls.
+func NewBitbucketClient(conf BitbucketConfig) *BitbucketClient {
+	if conf.URL == "" {
+		conf.URL = "https://bitbucket.org"
+	}
+	if conf.APIURL == "" {
+		conf.APIURL = "https://api.bitbucket.org/2.0"
+	}
+
+	conf.URL = strings.TrimSuffix(conf.URL, "/")
+	conf.APIURL = strings.TrimSuffix(conf.APIURL, "/")
+
+	return &BitbucketClient{
+		client: &http.Client{Timeout: time.Minute},
+		conf:   conf,
+	}
+}
+
+type bitbucketRepo struct {
+	FullName   string `json:"full_name"`
+	MainBranch struct {
+		Name string `json:"name"`
+	} `json:"mainbranch"`
+}
+
+// CreateRepo creates a private repo in the configured workspace, giving the candidate write access.
+// It returns the https clone url of the repo.
+func (c BitbucketClient) CreateRepo(details core.CreateDetails) (string, error) {
+	// account ids are not valid in repo slugs so unlike makeRepoName the candidate is left out.
+	slug := strings.ToLower(fmt.Sprintf("%s-test-%d", space.ReplaceAllString(details.BusinessName, "-"), details.ID))
+
+	var repo bitbucketRepo
+	err := c.do(http.MethodPost, c.conf.APIURL+"/repositories/"+c.conf.Workspace+"/"+slug, map[string]interface{}{
+		"scm":         "git",
+		"is_private":  true,
+		"fork_policy": "no_forks",
+		"description": "code assignment for " + details.BusinessName,
+	}, &repo)
+	if err != nil {
+		return "", fmt.Errorf("could not create repo %w", err)
+	}
+
+	err = c.grantWrite(repo.FullName, details.Username)
+	if err != nil {
+		return "", err
+	}
+
+	return c.conf.URL + "/" + repo.FullName + ".git", nil
+}
+
+// AddCollaborator gives the account write access to the repo. It returns ErrorAlreadyCollaborator
+// if the account already has an explicit permission on the repo.
+func (c BitbucketClient) AddCollaborator(repo string, accountID string) error {
+	fullName := c.fullName(repo)
+	err := c.do(http.MethodGet, c.permissionURL(fullName, accountID), nil, nil)
+	if err == nil {
+		return ErrorAlreadyCollaborator
+	}
+
+	var bErr *BitbucketError
+	if !errors.As(err, &bErr) || bErr.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("could not get permission of %s on repo %s %w", accountID, repo, err)
+	}
+
+	return c.grantWrite(fullName, accountID)
+}
+
+func (c BitbucketClient) grantWrite(fullName, accountID string) error {
+	err := c.do(http.MethodPut, c.permissionURL(fullName, accountID), map[string]string{"permission": "write"}, nil)
+	if err != nil {
+		return fmt.Errorf("could not give %s write access to repo %s %w", accountID, fullName, err)
+	}
+
+	return nil
+}
+
+// Upload uploads data.Ref of the test repo, or its main branch if data.Ref is empty, into the assignment repo,
+// returning the commit sha it resolved to. The test repo must be in data.Workspace, so that a business can only
+// use its own test repos. See pushArchive for how the test code is committed.
+func (c BitbucketClient) Upload(data core.UploadDetails) (core.UploadResult, error) {
+	buf, sha, err := c.download(data)
+	if err != nil {
+		return core.UploadResult{}, err
+	}
+
+	excluded, err := pushArchive(buf, data, object.Signature{
+		Name:  c.conf.Username,
+		Email: c.conf.Email,
+		When:  time.Now(),
+	}, &gitHttp.BasicAuth{
+		Username: c.conf.Username,
+		Password: c.conf.AppPassword,
+	})
+	if err != nil {
+		return core.UploadResult{}, classifyAPI(err)
+	}
+
+	return core.UploadResult{SHA: sha, Excluded: excluded}, nil
+}
+
+// Validate downloads data.Ref of the test repo and unpacks it as Upload would, without pushing it to an
+// assignment repo.
+func (c BitbucketClient) Validate(data core.UploadDetails) (core.ValidateResult, error) {
+	buf, sha, err := c.download(data)
+	if err != nil {
+		return core.ValidateResult{}, err
+	}
+
+	res, err := validateArchive(buf, data)
+	if err != nil {
+		return core.ValidateResult{}, err
+	}
+
+	res.SHA = sha
+	return res, nil
+}
+
+// download returns a zip archive of data.Ref of the test repo, or its main branch if data.Ref is empty, and
+// the commit sha it resolved to.
+func (c BitbucketClient) download(data core.UploadDetails) (*bytes.Buffer, string, error) {
+	testName := c.fullName(data.TestVCSRepoURL)
+	if data.Workspace == "" || !strings.HasPrefix(testName, data.Workspace+"/") {
+		return nil, "", fmt.Errorf("test repo %s is not in workspace %s", data.TestVCSRepoURL, data.Workspace)
+	}
+
+	ref := data.Ref
+	if ref == "" {
+		var test bitbucketRepo
+		err := c.do(http.MethodGet, c.conf.APIURL+"/repositories/"+testName, nil, &test)
+		if err != nil {
+			return nil, "", fmt.Errorf("could not get test repo %s %w", testName, err)
+		}
+
+		ref = test.MainBranch.Name
+	}
+
+	var commit struct {
+		Hash string `json:"hash"`
+	}
+	err := c.do(http.MethodGet, c.conf.APIURL+"/repositories/"+testName+"/commit/"+url.PathEscape(ref), nil, &commit)
+	if err != nil {
+		return nil, "", fmt.Errorf("could not resolve ref %s of test repo %s %w", ref, testName, err)
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	err = c.do(http.MethodGet, c.conf.URL+"/"+testName+"/get/"+commit.Hash+".zip", nil, buf)
+	if err != nil {
+		return nil, "", fmt.Errorf("could not download repo contents %w", err)
+	}
+
+	return buf, commit.Hash, nil
+}
+
+// IsSubmitted returns whether the candidate has opened a pull request in the repo.
+func (c BitbucketClient) IsSubmitted(vcsURL, accountID string) (bool, error) {
+	return c.IsStageSubmitted(vcsURL, accountID, "")
+}
+
+// IsStageSubmitted returns whether the candidate has opened a pull request into the branch of the repo.
+// An empty branch matches pull requests into any branch.
+func (c BitbucketClient) IsStageSubmitted(vcsURL, accountID, branch string) (bool, error) {
+	filter := fmt.Sprintf("author.account_id=%q", accountID)
+	if branch != "" {
+		filter += fmt.Sprintf(" AND destination.branch.name=%q", branch)
+	}
+
+	q := url.Values{
+		"state": {"OPEN", "MERGED", "DECLINED", "SUPERSEDED"},
+		"q":     {filter},
+	}
+
+	var prs struct {
+		Values []struct {
+			Author struct {
+				AccountID string `json:"account_id"`
+			} `json:"author"`
+		} `json:"values"`
+	}
+	err := c.do(http.MethodGet, c.conf.APIURL+"/repositories/"+c.fullName(vcsURL)+"/pullrequests?"+q.Encode(), nil, &prs)
+	if err != nil {
+		return false, fmt.Errorf("could not list pull requests %w", err)
+	}
+
+	for _, pr := range prs.Values {
+		if pr.Author.AccountID == accountID {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Cleanup removes the candidate's access to the repo and gives each reviewer write access.
+func (c BitbucketClient) Cleanup(details core.CleanDetails) error {
+	fullName := c.fullName(details.VCSRepoURL)
+	err := c.do(http.MethodDelete, c.permissionURL(fullName, details.CandidateUsername), nil, nil)
+	var bErr *BitbucketError
+	if err != nil && !(errors.As(err, &bErr) && bErr.StatusCode == http.StatusNotFound) {
+		return fmt.Errorf("could not remove permission from test repo %s %w", details.VCSRepoURL, err)
+	}
+
+	for _, reviewer := range details.ReviewersUsernames {
+		err := c.AddCollaborator(details.VCSRepoURL, reviewer)
+		if err != nil && !errors.Is(err, ErrorAlreadyCollaborator) {
+			return fmt.Errorf("could not add %s to repo %w", reviewer, err)
+		}
+	}
+
+	return nil
+}
+
+// CollectRepos returns the repos in the bitbucket workspace. Bitbucket repos only have uuids, so the ID of each
+// repo is 0.
+func (c BitbucketClient) CollectRepos(workspace string) ([]core.Repo, error) {
+	repos := []core.Repo{}
+	u := c.conf.APIURL + "/repositories/" + url.PathEscape(workspace) + "?pagelen=100"
+	for u != "" {
+		var page struct {
+			Values []bitbucketRepo `json:"values"`
+			Next   string          `json:"next"`
+		}
+		err := c.do(http.MethodGet, u, nil, &page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list repos %w", err)
+		}
+
+		for _, r := range page.Values {
+			repos = append(repos, core.Repo{FullName: r.FullName})
+		}
+
+		u = page.Next
+	}
+
+	return repos, nil
+}
+
+// CollectDirs lists the directories at path in the repo with fullName, on its main branch. The repo must be
+// within the workspace.
+func (c BitbucketClient) CollectDirs(workspace, fullName, path string) ([]core.RepoDir, error) {
+	if workspace == "" || !strings.HasPrefix(fullName, workspace+"/") {
+		return nil, fmt.Errorf("repo %s is not in workspace %s", fullName, workspace)
+	}
+
+	var repo bitbucketRepo
+	err := c.do(http.MethodGet, c.conf.APIURL+"/repositories/"+fullName, nil, &repo)
+	if err != nil {
+		return nil, fmt.Errorf("could not get repo %s %w", fullName, err)
+	}
+
+	dirs := []core.RepoDir{}
+	u := c.conf.APIURL + "/repositories/" + fullName + "/src/" + url.PathEscape(repo.MainBranch.Name) + "/"
+	if p := strings.Trim(path, "/"); p != "" {
+		u += p + "/"
+	}
+	u += "?pagelen=100"
+
+	for u != "" {
+		var page struct {
+			Values []struct {
+				Path string `json:"path"`
+				Type string `json:"type"`
+			} `json:"values"`
+			Next string `json:"next"`
+		}
+		err := c.do(http.MethodGet, u, nil, &page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list src of %s/%s %w", fullName, path, err)
+		}
+
+		for _, v := range page.Values {
+			if v.Type == "commit_directory" {
+				dirs = append(dirs, core.RepoDir{Name: v.Path[strings.LastIndex(v.Path, "/")+1:], Path: v.Path})
+			}
+		}
+
+		u = page.Next
+	}
+
+	return dirs, nil
+}
+
+// owns returns whether the repo url is hosted on bitbucket.
+func (c BitbucketClient) owns(repoURL string) bool {
+	return strings.HasPrefix(repoURL, c.conf.URL+"/")
+}
+
+// fullName returns the workspace/slug name of the repo at repoURL.
+func (c BitbucketClient) fullName(repoURL string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(repoURL, c.conf.URL+"/"), ".git")
+}
+
+func (c BitbucketClient) permissionURL(fullName, accountID string) string {
+	return c.conf.APIURL + "/repositories/" + fullName + "/permissions-config/users/" + url.PathEscape(accountID)
+}
+
+// do sends a request to bitbucket with body encoded as json. A successful response is decoded into out,
+// or copied into out, up to maxArchiveSize bytes, if it is a *bytes.Buffer. Errors are classified with classifyAPI.
+func (c BitbucketClient) do(method, u string, body, out interface{}) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("could not encode request body %w", err)
+		}
+
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, u, r)
+	if err != nil {
+		return fmt.Errorf("could not create request %w", err)
+	}
+
+	req.SetBasicAuth(c.conf.Username, c.conf.AppPassword)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return classifyAPI(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return classifyAPI(&BitbucketError{StatusCode: res.StatusCode, Message: strings.TrimSpace(string(msg))})
+	}
+
+	switch o := out.(type) {
+	case nil:
+		return nil
+	case *bytes.Buffer:
+		err = readArchive(o, res.Body, maxArchiveSize)
+	default:
+		err = json.NewDecoder(res.Body).Decode(out)
+	}
+	if err != nil {
+		return fmt.Errorf("could not read %s %s response %w", method, u, err)
+	}
+
+	return nil
+}
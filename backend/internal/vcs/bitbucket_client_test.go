@@ -0,0 +1,463 @@
+package vcs_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+	"github.com/testrelay/testrelay/backend/internal/vcs"
+)
+
+type fakeBitbucketRepo struct {
+	private     bool
+	mainBranch  string
+	permissions map[string]string
+	prs         []map[string]interface{}
+	// refs maps each branch, tag or commit to the hash it resolves to, archives each hash to its zip.
+	refs     map[string]string
+	archives map[string][]byte
+	// files lists the paths of the files on the main branch.
+	files []string
+}
+
+func (r *fakeBitbucketRepo) commit(ref, hash string, archive []byte) {
+	r.refs[ref] = hash
+	r.refs[hash] = hash
+	r.archives[hash] = archive
+}
+
+// fakeBitbucket implements the parts of the bitbucket cloud api used by the BitbucketClient, keeping state
+// in memory. It serves both the api, under /2.0, and the archive downloads of the web url.
+type fakeBitbucket struct {
+	t     *testing.T
+	mu    sync.Mutex
+	repos map[string]*fakeBitbucketRepo
+	fail  int
+}
+
+func newFakeBitbucket(t *testing.T) (*fakeBitbucket, *httptest.Server) {
+	f := &fakeBitbucket{t: t, repos: map[string]*fakeBitbucketRepo{}}
+	s := httptest.NewServer(f)
+	t.Cleanup(s.Close)
+
+	return f, s
+}
+
+func (f *fakeBitbucket) addRepo(fullName string) *fakeBitbucketRepo {
+	r := &fakeBitbucketRepo{
+		mainBranch:  "main",
+		permissions: map[string]string{},
+		refs:        map[string]string{},
+		archives:    map[string][]byte{},
+	}
+	f.repos[fullName] = r
+
+	return r
+}
+
+func (f *fakeBitbucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.fail != 0 {
+		w.WriteHeader(f.fail)
+		return
+	}
+
+	if u, p, ok := r.BasicAuth(); !ok || u != "testrelay" || p != "app-password" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if !strings.HasPrefix(r.URL.Path, "/2.0/") {
+		// web archive downloads, e.g. /workspace/slug/get/a1b2c3.zip
+		parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/get/", 2)
+		repo, ok := f.repos[parts[0]]
+		if !ok || len(parts) != 2 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		archive, ok := repo.archives[strings.TrimSuffix(parts[1], ".zip")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		_, _ = w.Write(archive)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), "/2.0/repositories/"), "/")
+	if len(parts) == 1 && r.Method == http.MethodGet {
+		f.serveWorkspace(w, r, parts[0])
+		return
+	}
+
+	fullName := parts[0] + "/" + parts[1]
+	rest := strings.Join(parts[2:], "/")
+
+	if r.Method == http.MethodPost && rest == "" {
+		var body map[string]interface{}
+		require.NoError(f.t, json.NewDecoder(r.Body).Decode(&body))
+		repo := f.addRepo(fullName)
+		repo.private = body["is_private"] == true
+		f.json(w, map[string]interface{}{"full_name": fullName})
+		return
+	}
+
+	repo, ok := f.repos[fullName]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && rest == "":
+		f.json(w, map[string]interface{}{"full_name": fullName, "mainbranch": map[string]string{"name": repo.mainBranch}})
+	case r.Method == http.MethodGet && strings.HasPrefix(rest, "commit/"):
+		ref, _ := url.PathUnescape(strings.TrimPrefix(rest, "commit/"))
+		hash, ok := repo.refs[ref]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		f.json(w, map[string]string{"hash": hash})
+	case strings.HasPrefix(rest, "permissions-config/users/"):
+		account, _ := url.PathUnescape(strings.TrimPrefix(rest, "permissions-config/users/"))
+		f.servePermission(w, r, repo, account)
+	case r.Method == http.MethodGet && rest == "pullrequests":
+		f.servePullRequests(w, r, repo)
+	case r.Method == http.MethodGet && strings.HasPrefix(rest, "src/"+repo.mainBranch+"/"):
+		f.serveSrc(w, repo, strings.Trim(strings.TrimPrefix(rest, "src/"+repo.mainBranch), "/"))
+	default:
+		f.t.Errorf("unexpected bitbucket request %s %s", r.Method, r.URL)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeBitbucket) servePermission(w http.ResponseWriter, r *http.Request, repo *fakeBitbucketRepo, account string) {
+	switch r.Method {
+	case http.MethodPut:
+		var body map[string]string
+		require.NoError(f.t, json.NewDecoder(r.Body).Decode(&body))
+		repo.permissions[account] = body["permission"]
+		f.json(w, body)
+	case http.MethodGet, http.MethodDelete:
+		permission, ok := repo.permissions[account]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodDelete {
+			delete(repo.permissions, account)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		f.json(w, map[string]string{"permission": permission})
+	}
+}
+
+// serveWorkspace lists the repos in the workspace two at a time, so that clients must follow the next link.
+func (f *fakeBitbucket) serveWorkspace(w http.ResponseWriter, r *http.Request, workspace string) {
+	var names []string
+	for name := range f.repos {
+		if strings.HasPrefix(name, workspace+"/") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page == 0 {
+		page = 1
+	}
+
+	res := map[string]interface{}{}
+	var values []map[string]interface{}
+	for i := (page - 1) * 2; i < len(names) && i < page*2; i++ {
+		values = append(values, map[string]interface{}{"full_name": names[i]})
+	}
+	res["values"] = values
+
+	if page*2 < len(names) {
+		q := r.URL.Query()
+		q.Set("page", strconv.Itoa(page+1))
+		res["next"] = "http://" + r.Host + r.URL.Path + "?" + q.Encode()
+	}
+
+	f.json(w, res)
+}
+
+// serveSrc lists the files and directories directly below dir on the main branch.
+func (f *fakeBitbucket) serveSrc(w http.ResponseWriter, repo *fakeBitbucketRepo, dir string) {
+	seen := map[string]bool{}
+	var values []map[string]string
+	for _, file := range repo.files {
+		rel := file
+		if dir != "" {
+			if !strings.HasPrefix(file, dir+"/") {
+				continue
+			}
+			rel = strings.TrimPrefix(file, dir+"/")
+		}
+
+		p, typ := path.Join(dir, rel), "commit_file"
+		if i := strings.Index(rel, "/"); i >= 0 {
+			p, typ = path.Join(dir, rel[:i]), "commit_directory"
+		}
+
+		if !seen[p] {
+			seen[p] = true
+			values = append(values, map[string]string{"path": p, "type": typ})
+		}
+	}
+
+	f.json(w, map[string]interface{}{"values": values})
+}
+
+// servePullRequests supports the author.account_id and destination.branch.name filters of the q parameter.
+func (f *fakeBitbucket) servePullRequests(w http.ResponseWriter, r *http.Request, repo *fakeBitbucketRepo) {
+	assert.ElementsMatch(f.t, []string{"OPEN", "MERGED", "DECLINED", "SUPERSEDED"}, r.URL.Query()["state"])
+
+	filters := map[string]string{}
+	for _, cond := range strings.Split(r.URL.Query().Get("q"), " AND ") {
+		kv := strings.SplitN(cond, "=", 2)
+		filters[kv[0]] = strings.Trim(kv[1], `"`)
+	}
+
+	var values []map[string]interface{}
+	for _, pr := range repo.prs {
+		author := pr["author"].(map[string]interface{})["account_id"]
+		branch, hasBranch := filters["destination.branch.name"]
+		if author == filters["author.account_id"] && (!hasBranch || branch == pr["branch"]) {
+			values = append(values, pr)
+		}
+	}
+
+	f.json(w, map[string]interface{}{"values": values})
+}
+
+func (f *fakeBitbucket) json(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	require.NoError(f.t, json.NewEncoder(w).Encode(v))
+}
+
+func pullRequest(accountID, branch string) map[string]interface{} {
+	return map[string]interface{}{
+		"author": map[string]interface{}{"account_id": accountID},
+		"branch": branch,
+	}
+}
+
+func TestBitbucketClient(t *testing.T) {
+	const jane = "557058:jane"
+
+	newClient := func(s *httptest.Server) *vcs.BitbucketClient {
+		return vcs.NewBitbucketClient(vcs.BitbucketConfig{
+			URL:         s.URL,
+			APIURL:      s.URL + "/2.0",
+			Username:    "testrelay",
+			AppPassword: "app-password",
+			Email:       "interviewer@testrelay.io",
+			Workspace:   "testrelay",
+		})
+	}
+
+	t.Run("CreateRepo", func(t *testing.T) {
+		f, s := newFakeBitbucket(t)
+
+		repo, err := newClient(s).CreateRepo(core.CreateDetails{
+			Provider:     core.VCSProviderBitbucket,
+			BusinessName: "TestRelay",
+			Username:     jane,
+			ID:           12,
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, s.URL+"/testrelay/testrelay-test-12.git", repo)
+		r := f.repos["testrelay/testrelay-test-12"]
+		require.NotNil(t, r)
+		assert.True(t, r.private)
+		assert.Equal(t, map[string]string{jane: "write"}, r.permissions)
+	})
+
+	t.Run("AddCollaborator", func(t *testing.T) {
+		f, s := newFakeBitbucket(t)
+		r := f.addRepo("testrelay/test-12")
+		c := newClient(s)
+
+		require.NoError(t, c.AddCollaborator(s.URL+"/testrelay/test-12.git", "bob"))
+		assert.Equal(t, map[string]string{"bob": "write"}, r.permissions)
+
+		err := c.AddCollaborator(s.URL+"/testrelay/test-12.git", "bob")
+		assert.ErrorIs(t, err, vcs.ErrorAlreadyCollaborator)
+	})
+
+	t.Run("IsSubmitted", func(t *testing.T) {
+		f, s := newFakeBitbucket(t)
+		r := f.addRepo("testrelay/test-12")
+		c := newClient(s)
+		repo := s.URL + "/testrelay/test-12.git"
+
+		submitted, err := c.IsSubmitted(repo, jane)
+		require.NoError(t, err)
+		assert.False(t, submitted)
+
+		r.prs = append(r.prs, pullRequest("bob", "main"), pullRequest(jane, "stage-2"))
+
+		submitted, err = c.IsSubmitted(repo, jane)
+		require.NoError(t, err)
+		assert.True(t, submitted)
+
+		submitted, err = c.IsStageSubmitted(repo, jane, "stage-2")
+		require.NoError(t, err)
+		assert.True(t, submitted)
+
+		submitted, err = c.IsStageSubmitted(repo, jane, "stage-3")
+		require.NoError(t, err)
+		assert.False(t, submitted)
+	})
+
+	t.Run("Cleanup", func(t *testing.T) {
+		f, s := newFakeBitbucket(t)
+		r := f.addRepo("testrelay/test-12")
+		r.permissions = map[string]string{jane: "write", "alice": "write"}
+
+		err := newClient(s).Cleanup(core.CleanDetails{
+			VCSRepoURL:         s.URL + "/testrelay/test-12.git",
+			CandidateUsername:  jane,
+			ReviewersUsernames: []string{"bob", "alice"},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]string{"bob": "write", "alice": "write"}, r.permissions)
+	})
+
+	t.Run("Upload", func(t *testing.T) {
+		upload := func(t *testing.T, s *httptest.Server, ref string) (core.UploadResult, string) {
+			dir := t.TempDir()
+			_, err := git.PlainInit(dir, true)
+			require.NoError(t, err)
+
+			res, err := newClient(s).Upload(core.UploadDetails{
+				ID:             12,
+				VCSRepoURL:     dir,
+				TestVCSRepoURL: s.URL + "/acme/backend-test.git",
+				Ref:            ref,
+				Workspace:      "acme",
+			})
+			require.NoError(t, err)
+
+			r, err := git.PlainOpen(dir)
+			require.NoError(t, err)
+			head, err := r.Head()
+			require.NoError(t, err)
+			commit, err := r.CommitObject(head.Hash())
+			require.NoError(t, err)
+			file, err := commit.File("README.md")
+			require.NoError(t, err)
+			content, err := file.Contents()
+			require.NoError(t, err)
+
+			return res, content
+		}
+
+		t.Run("should push the main branch of the test repo to the assignment repo", func(t *testing.T) {
+			f, s := newFakeBitbucket(t)
+			test := f.addRepo("acme/backend-test")
+			test.mainBranch = "develop"
+			test.commit("develop", "a1b2c3", zipArchive(t, map[string]string{"README.md": "# Backend test"}))
+
+			res, content := upload(t, s, "")
+			assert.Equal(t, "a1b2c3", res.SHA)
+			assert.Equal(t, "# Backend test", content)
+		})
+
+		t.Run("should push the given ref of the test repo", func(t *testing.T) {
+			f, s := newFakeBitbucket(t)
+			test := f.addRepo("acme/backend-test")
+			test.commit("main", "a1b2c3", zipArchive(t, map[string]string{"README.md": "# Work in progress"}))
+			test.commit("v1.0", "d4e5f6", zipArchive(t, map[string]string{"README.md": "# Backend test"}))
+
+			res, content := upload(t, s, "v1.0")
+			assert.Equal(t, "d4e5f6", res.SHA)
+			assert.Equal(t, "# Backend test", content)
+		})
+
+		t.Run("should reject test repos outside of the business workspace", func(t *testing.T) {
+			f, s := newFakeBitbucket(t)
+			f.addRepo("acme/backend-test")
+
+			_, err := newClient(s).Upload(core.UploadDetails{
+				ID:             12,
+				VCSRepoURL:     t.TempDir(),
+				TestVCSRepoURL: s.URL + "/acme/backend-test.git",
+				Workspace:      "other",
+			})
+			assert.EqualError(t, err, "test repo "+s.URL+"/acme/backend-test.git is not in workspace other")
+		})
+	})
+
+	t.Run("CollectRepos", func(t *testing.T) {
+		f, s := newFakeBitbucket(t)
+		f.addRepo("acme/backend-test")
+		f.addRepo("acme/frontend-test")
+		f.addRepo("acme/mobile-test")
+		f.addRepo("other/backend-test")
+
+		repos, err := newClient(s).CollectRepos("acme")
+		require.NoError(t, err)
+
+		assert.Equal(t, []core.Repo{
+			{FullName: "acme/backend-test"},
+			{FullName: "acme/frontend-test"},
+			{FullName: "acme/mobile-test"},
+		}, repos)
+	})
+
+	t.Run("CollectDirs", func(t *testing.T) {
+		f, s := newFakeBitbucket(t)
+		f.addRepo("acme/interview-exercises").files = []string{
+			"README.md",
+			"exercises/api/README.md",
+			"exercises/api/src/main.go",
+			"exercises/frontend/index.js",
+		}
+		f.addRepo("other/exercises")
+
+		dirs, err := newClient(s).CollectDirs("acme", "acme/interview-exercises", "")
+		require.NoError(t, err)
+		assert.Equal(t, []core.RepoDir{{Name: "exercises", Path: "exercises"}}, dirs)
+
+		dirs, err = newClient(s).CollectDirs("acme", "acme/interview-exercises", "exercises")
+		require.NoError(t, err)
+		assert.Equal(t, []core.RepoDir{
+			{Name: "api", Path: "exercises/api"},
+			{Name: "frontend", Path: "exercises/frontend"},
+		}, dirs)
+
+		_, err = newClient(s).CollectDirs("acme", "other/exercises", "")
+		assert.EqualError(t, err, "repo other/exercises is not in workspace acme")
+	})
+
+	t.Run("should return transient errors for rate limits", func(t *testing.T) {
+		f, s := newFakeBitbucket(t)
+		f.fail = http.StatusTooManyRequests
+
+		_, err := newClient(s).IsSubmitted(s.URL+"/testrelay/test-12.git", jane)
+		assert.True(t, core.IsTransient(err))
+	})
+}
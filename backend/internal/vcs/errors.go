@@ -0,0 +1,78 @@
+package vcs
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	gitHttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/google/go-github/v39/github"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+)
+
+// classify marks github errors that are worth retrying as transient. Rate limits, 5xx responses
+// and network errors, including those of a git push, are transient, anything else, e.g. a missing repo,
+// is left as a permanent error.
+func classify(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var rateLimit *github.RateLimitError
+	var abuseLimit *github.AbuseRateLimitError
+	var res *github.ErrorResponse
+	switch {
+	case errors.As(err, &rateLimit), errors.As(err, &abuseLimit), transientGit(err):
+		return core.NewTransientError(err)
+	case errors.As(err, &res) && res.Response != nil && res.Response.StatusCode >= http.StatusInternalServerError:
+		return core.NewTransientError(err)
+	}
+
+	return err
+}
+
+// classifyAPI marks gitlab and bitbucket errors that are worth retrying as transient. Rate limits, 5xx responses
+// and network errors, including those of a git push, are transient, anything else is left as a permanent error.
+func classifyAPI(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var gitlabErr *GitlabError
+	var bitbucketErr *BitbucketError
+	switch {
+	case transientGit(err):
+		return core.NewTransientError(err)
+	case errors.As(err, &gitlabErr) && retryable(gitlabErr.StatusCode):
+		return core.NewTransientError(err)
+	case errors.As(err, &bitbucketErr) && retryable(bitbucketErr.StatusCode):
+		return core.NewTransientError(err)
+	}
+
+	return err
+}
+
+func retryable(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+// transientGit reports whether err is a network error, a dropped connection or a retryable http response of
+// a git push. go-git wraps http responses in a plumbing.UnexpectedError that does not unwrap, so it is
+// unpacked here.
+func transientGit(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var unexpected *plumbing.UnexpectedError
+	if errors.As(err, &unexpected) {
+		err = unexpected.Err
+	}
+
+	var gitErr *gitHttp.Err
+	return errors.As(err, &gitErr) && gitErr.Response != nil && retryable(gitErr.Response.StatusCode)
+}
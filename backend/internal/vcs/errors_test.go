@@ -0,0 +1,44 @@
+package vcs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	gitHttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+)
+
+func TestClassify(t *testing.T) {
+	push := func(statusCode int) error {
+		return fmt.Errorf("could not push to remote %w", gitHttp.NewErr(&http.Response{
+			StatusCode: statusCode,
+			Request:    &http.Request{},
+		}))
+	}
+
+	tests := []struct {
+		name      string
+		err       error
+		transient bool
+	}{
+		{name: "push 5xx", err: push(http.StatusBadGateway), transient: true},
+		{name: "push rate limit", err: push(http.StatusTooManyRequests), transient: true},
+		{name: "push 4xx", err: push(http.StatusUnprocessableEntity), transient: false},
+		{name: "push missing repo", err: fmt.Errorf("could not push to remote %w", transport.ErrRepositoryNotFound), transient: false},
+		{name: "dropped connection", err: fmt.Errorf("could not push to remote %w", io.ErrUnexpectedEOF), transient: true},
+		{name: "other", err: errors.New("could not commit changes"), transient: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.transient, core.IsTransient(classify(tt.err)))
+			assert.Equal(t, tt.transient, core.IsTransient(classifyAPI(tt.err)))
+		})
+	}
+}
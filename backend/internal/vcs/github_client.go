@@ -1,26 +1,23 @@
 package vcs
 
 import (
-	"archive/zip"
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
-	"io"
-	"io/ioutil"
 	"log"
 	"math/rand"
 	"os"
-	"path"
-	"path/filepath"
 	"regexp"
 	"strings"
-	"syscall"
 	"time"
 
+	"github.com/go-git/go-billy/v5/memfs"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing/object"
-	gitHttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/memory"
 	"github.com/google/go-github/v39/github"
 	"golang.org/x/oauth2"
 
@@ -39,11 +36,13 @@ var (
 // These repositories are fully maintained by testrelay and thus use a single github user to perform actions.
 //
 // Installation interactions are scoped to reading the contents for business test repositories that have been
-// given access through an app installation.
+// given access through an app installation. Businesses that set an org have their assignment repos created in
+// it and managed through the installation instead of the access token, see repoClientFor.
 type GithubClient struct {
-	client          *github.Client
-	intervConf      GithubInterviewerConfig
-	newInstallation InstallationFunc
+	client              *github.Client
+	intervConf          GithubInterviewerConfig
+	newInstallation     InstallationFunc
+	newRepoInstallation repoInstallationFunc
 }
 
 // GithubInterviewerConfig represents fields required to generate repos using a personal access token.
@@ -51,6 +50,10 @@ type GithubInterviewerConfig struct {
 	AccessToken string
 	Username    string
 	Email       string
+
+	// WebhookURL is the url that pull request events for created repos are sent to. If blank no webhook is added.
+	WebhookURL    string
+	WebhookSecret string
 }
 
 // NewGithubClient returns a GithubClient with all the underlying github api client initialized.
@@ -67,31 +70,55 @@ func NewGithubClient(intervConf GithubInterviewerConfig, appPrivKeyLoc string, a
 		&oauth2.Token{AccessToken: intervConf.AccessToken},
 	)
 
+	newRepoInstallation, err := newGithubAppRepoInstallationFunc(appID, b)
+	if err != nil {
+		return nil, err
+	}
+
 	tc := oauth2.NewClient(context.Background(), ts)
 	return &GithubClient{
-		client:          github.NewClient(tc),
-		newInstallation: NewGithubAppInstallationFunc(appID, b),
-		intervConf:      intervConf,
+		client:              github.NewClient(tc),
+		newInstallation:     NewGithubAppInstallationFunc(appID, b),
+		newRepoInstallation: newRepoInstallation,
+		intervConf:          intervConf,
 	}, nil
 }
 
-func (c GithubClient) CreateRepo(bName, username string, id int) (string, error) {
-	name := makeRepoName(bName, username, id)
+// CreateRepo creates a private repo for the assignment under the interviewer account, or in details.Org through
+// the github app installation details.InstallationID, giving the candidate access to it.
+func (c GithubClient) CreateRepo(details core.CreateDetails) (string, error) {
+	rc, err := c.creator(details.InstallationID, details.Org)
+	if err != nil {
+		return "", err
+	}
+
+	name := makeRepoName(details.BusinessName, details.Username, details.ID)
 	r := &github.Repository{
 		Name:         github.String(name),
 		Private:      github.Bool(true),
-		Description:  github.String(username + " code assignment for " + bName),
+		Description:  github.String(details.Username + " code assignment for " + details.BusinessName),
 		MasterBranch: github.String("master"),
 	}
 
-	repo, _, err := c.client.Repositories.Create(context.Background(), "", r)
+	repo, _, err := rc.client.Repositories.Create(context.Background(), details.Org, r)
 	if err != nil {
-		return "", fmt.Errorf("could not create repo %w", err)
+		return "", fmt.Errorf("could not create repo %w", classify(err))
 	}
 
+	return c.finishRepo(rc, repo, details.Username)
+}
+
+// finishRepo gives the candidate with username access to a newly created assignment repo and adds the
+// pull request webhook through rc, returning the clone url of the repo.
+func (c GithubClient) finishRepo(rc repoClient, repo *github.Repository, username string) (string, error) {
 	login := repo.GetOwner().GetLogin()
 	repoName := repo.GetName()
-	err = c.addCollaborator(login, repoName, username)
+	err := addCollaborator(rc, login, repoName, username)
+	if err != nil {
+		return "", err
+	}
+
+	err = c.addWebhook(rc, login, repoName)
 	if err != nil {
 		return "", err
 	}
@@ -99,6 +126,27 @@ func (c GithubClient) CreateRepo(bName, username string, id int) (string, error)
 	return repo.GetCloneURL(), nil
 }
 
+func (c GithubClient) addWebhook(rc repoClient, owner, repo string) error {
+	if c.intervConf.WebhookURL == "" {
+		return nil
+	}
+
+	_, _, err := rc.client.Repositories.CreateHook(context.Background(), owner, repo, &github.Hook{
+		Config: map[string]interface{}{
+			"url":          c.intervConf.WebhookURL,
+			"content_type": "json",
+			"secret":       c.intervConf.WebhookSecret,
+		},
+		Events: []string{"pull_request"},
+		Active: github.Bool(true),
+	})
+	if err != nil {
+		return fmt.Errorf("could not add webhook to repo %s %s %w", owner, repo, err)
+	}
+
+	return nil
+}
+
 var (
 	repl  = regexp.MustCompile("https://github.com/")
 	grepl = regexp.MustCompile("\\.git")
@@ -114,10 +162,14 @@ func (c GithubClient) AddCollaborator(repo string, username string) error {
 	owner := pieces[0]
 	name := pieces[1]
 
+	rc, err := c.repoClientFor(owner)
+	if err != nil {
+		return err
+	}
+
 	var colabs []*github.User
-	var err error
 	for i := 0; i < 3; i++ {
-		colabs, _, err = c.client.Repositories.ListCollaborators(context.Background(), owner, name, nil)
+		colabs, _, err = rc.client.Repositories.ListCollaborators(context.Background(), owner, name, nil)
 		if err == nil {
 			break
 		}
@@ -130,7 +182,7 @@ func (c GithubClient) AddCollaborator(repo string, username string) error {
 		return fmt.Errorf("could not list colaborators for repo %s %s %w", owner, name, err)
 	}
 
-	invites, _, err := c.client.Repositories.ListInvitations(context.Background(), owner, name, nil)
+	invites, _, err := rc.client.Repositories.ListInvitations(context.Background(), owner, name, nil)
 	if err != nil {
 		return fmt.Errorf("could not list invites for repo %s %s %w", owner, name, err)
 	}
@@ -147,7 +199,7 @@ func (c GithubClient) AddCollaborator(repo string, username string) error {
 		return ErrorAlreadyCollaborator
 	}
 
-	_, _, err = c.client.Repositories.AddCollaborator(context.Background(), owner, name, username, nil)
+	_, _, err = rc.client.Repositories.AddCollaborator(context.Background(), owner, name, username, nil)
 	if err != nil {
 		return fmt.Errorf("could not add %s to generated repository %s %w", username, repo, err)
 	}
@@ -155,11 +207,11 @@ func (c GithubClient) AddCollaborator(repo string, username string) error {
 	return nil
 }
 
-func (c GithubClient) addCollaborator(login string, repoName string, username string) error {
+func addCollaborator(rc repoClient, login string, repoName string, username string) error {
 	var i int
 	var err error
 	for i < 3 {
-		_, _, err = c.client.Repositories.AddCollaborator(context.Background(), login, repoName, username, nil)
+		_, _, err = rc.client.Repositories.AddCollaborator(context.Background(), login, repoName, username, nil)
 		if err == nil {
 			return nil
 		}
@@ -172,7 +224,7 @@ func (c GithubClient) addCollaborator(login string, repoName string, username st
 	return fmt.Errorf("could not add %s to generated repository %s %w", username, repoName, err)
 }
 
-var space = regexp.MustCompile("/s+")
+var space = regexp.MustCompile(`\s+`)
 
 func makeRepoName(bName, username string, id int) string {
 	rand.Seed(time.Now().UnixNano())
@@ -196,139 +248,149 @@ func randSeq(n int) string {
 }
 
 // Upload uploads the test code into the assignment repository.
-// It first clones the test github repo specified for the assignment into the tmp directory.
-// After cloning, it bundles all the test files into a single commit. Signing it with a start test message.
+// It first downloads data.Ref of the test github repo specified for the assignment into memory.
+// After unpacking, it bundles all the test files into a single commit. Signing it with a start test message.
 // The Upload method expects that the repository provided in data have the correct permissions to be
 // able to init a test. This means that the test repository needs to have access given to the github app
 // as part of an installation. The assignment repository needs to be also created by the user whom
-// c.accessToken stems from.
+// c.accessToken stems from, or in an org that has the github app installed.
 //
-// Upload returns an error if there is any problem in execution of the upload. It cleans the temp directory
-// of the cloned repository.
-func (c GithubClient) Upload(data core.UploadDetails) error {
-	i, err := c.newInstallation(data.InstallationID)
+// Upload returns the commit sha of the test repo that data.Ref resolved to, or an error if there is any problem
+// in execution of the upload. Nothing is written to disk, see pushArchive.
+func (c GithubClient) Upload(data core.UploadDetails) (core.UploadResult, error) {
+	owner, _ := getRepoName(data.VCSRepoURL)
+	rc, err := c.repoClientFor(owner)
 	if err != nil {
-		return fmt.Errorf("failed to generate installation with id %d %w", data.InstallationID, err)
+		return core.UploadResult{}, err
 	}
 
-	buf, err := i.DownloadRepo(context.Background(), data.TestVCSRepoURL)
+	buf, sha, err := c.download(data)
 	if err != nil {
-		return fmt.Errorf("could not download repo contents %w", err)
+		return core.UploadResult{}, err
 	}
 
-	zipPath := os.TempDir()
-	clonePath := path.Join(zipPath, fmt.Sprintf("%d_%d", data.ID, time.Now().Unix()))
-	err = os.MkdirAll(clonePath, os.ModePerm)
+	excluded, err := pushArchive(buf, data, object.Signature{
+		Name:  c.intervConf.Username,
+		Email: c.intervConf.Email,
+		When:  time.Now(),
+	}, rc.auth)
 	if err != nil {
-		return fmt.Errorf("could not create repo clone dir %s %w", clonePath, err)
+		return core.UploadResult{}, classify(err)
 	}
 
-	defer func() {
-		err := removeContents(clonePath)
-		if err != nil {
-			log.Printf("could not remove clone path dir %s\n", err)
-		}
-	}()
+	return core.UploadResult{SHA: sha, Excluded: excluded}, nil
+}
 
-	zipFile := path.Join(zipPath, fmt.Sprintf("%d.zip", time.Now().Unix()))
-	out, err := os.Create(zipFile)
+// Validate downloads data.Ref of the test github repo through the installation and unpacks it as Upload would,
+// without pushing it to an assignment repo.
+func (c GithubClient) Validate(data core.UploadDetails) (core.ValidateResult, error) {
+	buf, sha, err := c.download(data)
 	if err != nil {
-		return fmt.Errorf("could not create zipFile %s %w", zipFile, err)
+		return core.ValidateResult{}, err
 	}
-	defer out.Close()
 
-	// Write the body to file
-	_, err = buf.WriteTo(out)
+	res, err := validateArchive(buf, data)
 	if err != nil {
-		return fmt.Errorf("failed to write to zipFile %w", err)
+		return core.ValidateResult{}, err
 	}
 
-	r, err := git.PlainInit(clonePath, false)
-	if err != nil {
-		return fmt.Errorf("could not plain init dir %s %w", clonePath, err)
-	}
+	res.SHA = sha
+	return res, nil
+}
 
-	_, err = r.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{data.VCSRepoURL}})
+// download returns a zip archive of data.Ref of the test repo and the commit sha it resolved to.
+func (c GithubClient) download(data core.UploadDetails) (*bytes.Buffer, string, error) {
+	i, err := c.newInstallation(data.InstallationID)
 	if err != nil {
-		return fmt.Errorf("could not create remote %s %w", data.VCSRepoURL, err)
+		return nil, "", fmt.Errorf("failed to generate installation with id %d %w", data.InstallationID, err)
 	}
 
-	w, err := r.Worktree()
+	buf, sha, err := i.DownloadRepo(context.Background(), data.TestVCSRepoURL, data.Ref)
 	if err != nil {
-		return fmt.Errorf("failed to init worktree %w", err)
+		return nil, "", fmt.Errorf("could not download repo contents %w", classify(err))
 	}
 
-	_, err = unzip(out.Name(), clonePath)
-	if err != nil {
-		return fmt.Errorf("could not untip to %s %w", clonePath, err)
-	}
+	return buf, sha, nil
+}
 
-	files, err := ioutil.ReadDir(clonePath)
+// pushArchive commits the contents of the zip archive in buf to data.VCSRepoURL as a single start test
+// commit, force pushing it to data.Branch or the default branch. The archive is unpacked by extractArchive
+// into an in memory worktree, so nothing is written to disk. It returns the paths of the files that were left out.
+func pushArchive(buf *bytes.Buffer, data core.UploadDetails, author object.Signature, auth transport.AuthMethod) ([]string, error) {
+	fs := memfs.New()
+	r, err := git.Init(memory.NewStorage(), fs)
 	if err != nil {
-		return fmt.Errorf("could not read clone dir files %w", err)
+		return nil, fmt.Errorf("could not init repo %w", err)
 	}
 
-	// copy the top level directory as the zipFile creates a throwaway dir
-	var dirname string
-	for _, file := range files {
-		if file.IsDir() && file.Name() != ".git" {
-			dirname = file.Name()
-			break
-		}
+	_, err = r.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{data.VCSRepoURL}})
+	if err != nil {
+		return nil, fmt.Errorf("could not create remote %s %w", data.VCSRepoURL, err)
 	}
 
-	abs := path.Join(clonePath, dirname)
-	err = copyDirectory(abs, clonePath)
+	w, err := r.Worktree()
 	if err != nil {
-		return fmt.Errorf("could not copy ziped dir %s to %s %w", abs, clonePath, err)
+		return nil, fmt.Errorf("failed to init worktree %w", err)
 	}
 
-	err = removeContents(abs)
+	excluded, err := extractArchive(buf, data, fs)
 	if err != nil {
-		return fmt.Errorf("could not remove dir %s %w", abs, err)
+		return nil, err
 	}
 
 	_, err = w.Add(".")
 	if err != nil {
-		return fmt.Errorf("could not add all files %w", err)
+		return nil, fmt.Errorf("could not add all files %w", err)
 	}
 
 	commit, err := w.Commit("start test", &git.CommitOptions{
-		Author: &object.Signature{
-			Name:  c.intervConf.Username,
-			Email: c.intervConf.Email,
-			When:  time.Now(),
-		},
+		Author: &author,
 	})
 	if err != nil {
-		return fmt.Errorf("could not commit changes %w", err)
+		return nil, fmt.Errorf("could not commit changes %w", err)
 	}
 
 	_, err = r.CommitObject(commit)
 	if err != nil {
-		return fmt.Errorf("failed to get commit obj %w", err)
+		return nil, fmt.Errorf("failed to get commit obj %w", err)
 	}
 
-	err = r.Push(&git.PushOptions{
+	opts := &git.PushOptions{
 		RemoteName: "origin",
-		Auth: &gitHttp.BasicAuth{
-			Username: c.intervConf.Username,
-			Password: c.intervConf.AccessToken,
-		},
-		Force: true,
-	})
+		Auth:       auth,
+		Force:      true,
+	}
+
+	// stages after the first are pushed to their own branch so the candidate's work is left untouched.
+	if data.Branch != "" {
+		head, err := r.Head()
+		if err != nil {
+			return nil, fmt.Errorf("could not get head ref %w", err)
+		}
+
+		opts.RefSpecs = []config.RefSpec{
+			config.RefSpec(fmt.Sprintf("%s:refs/heads/%s", head.Name(), data.Branch)),
+		}
+	}
+
+	err = r.Push(opts)
 	if err != nil {
-		return fmt.Errorf("could not push to remote %w", err)
+		return nil, fmt.Errorf("could not push to remote %w", err)
 	}
 
-	return nil
+	return excluded, nil
 }
 
 func (c GithubClient) IsSubmitted(vcsURL, username string) (bool, error) {
 	owner, name := getRepoName(vcsURL)
-	prs, _, err := c.client.PullRequests.List(context.Background(), owner, name, nil)
+	rc, err := c.repoClientFor(owner)
 	if err != nil {
-		return false, fmt.Errorf("could not list prs %w", err)
+		return false, err
+	}
+
+	prs, _, err := rc.client.PullRequests.List(context.Background(), owner, name, nil)
+	if err != nil {
+		return false, fmt.Errorf("could not list prs %w", classify(err))
 	}
 
 	for _, pr := range prs {
@@ -340,214 +402,73 @@ func (c GithubClient) IsSubmitted(vcsURL, username string) (bool, error) {
 	return false, nil
 }
 
-func (c GithubClient) Cleanup(details core.CleanDetails) error {
-	owner, name := getRepoName(details.VCSRepoURL)
-	_, err := c.client.Repositories.RemoveCollaborator(context.Background(), owner, name, details.CandidateUsername)
-	if err != nil {
-		return fmt.Errorf("could not remove collaborator from test repo %s %s %w", owner, name, err)
+// IsStageSubmitted returns whether the candidate has opened a pull request into the branch of the repo.
+// An empty branch matches pull requests into any branch.
+func (c GithubClient) IsStageSubmitted(vcsURL, username, branch string) (bool, error) {
+	owner, name := getRepoName(vcsURL)
+	opts := &github.PullRequestListOptions{State: "all"}
+	if branch != "" {
+		opts.Base = branch
 	}
 
-	invites, _, err := c.client.Repositories.ListInvitations(context.Background(), owner, name, nil)
+	rc, err := c.repoClientFor(owner)
 	if err != nil {
-		return fmt.Errorf("could not list invitations for repo %s %s %s", owner, name, err)
+		return false, err
 	}
 
-	for _, invite := range invites {
-		if invite.GetInvitee().GetLogin() == details.CandidateUsername {
-			_, err := c.client.Repositories.DeleteInvitation(context.Background(), owner, name, invite.GetID())
-			if err != nil {
-				return fmt.Errorf("could not remove collaborator invitation from test repo %s %s %w", owner, name, err)
-			}
-		}
+	prs, _, err := rc.client.PullRequests.List(context.Background(), owner, name, opts)
+	if err != nil {
+		return false, fmt.Errorf("could not list prs %w", classify(err))
 	}
 
-	for _, reviewer := range details.ReviewersUsernames {
-		err := c.addCollaborator(owner, name, reviewer)
-		if err != nil {
-			return fmt.Errorf("could not add %s to repo %w", reviewer, err)
+	for _, pr := range prs {
+		if pr.GetUser().GetLogin() == username {
+			return true, nil
 		}
 	}
 
-	return nil
-}
-
-func getRepoName(githubRepoURL string) (string, string) {
-	pieces := strings.Split(githubRepoURL, "/")
-	end := pieces[len(pieces)-1]
-
-	return pieces[len(pieces)-2], strings.Replace(end, ".git", "", 1)
+	return false, nil
 }
 
-func removeContents(dir string) error {
-	d, err := os.Open(dir)
+func (c GithubClient) Cleanup(details core.CleanDetails) error {
+	owner, name := getRepoName(details.VCSRepoURL)
+	rc, err := c.repoClientFor(owner)
 	if err != nil {
 		return err
 	}
-	defer d.Close()
-	names, err := d.Readdirnames(-1)
+
+	_, err = rc.client.Repositories.RemoveCollaborator(context.Background(), owner, name, details.CandidateUsername)
 	if err != nil {
-		return err
+		return fmt.Errorf("could not remove collaborator from test repo %s %s %w", owner, name, classify(err))
 	}
-	for _, name := range names {
-		err = os.RemoveAll(filepath.Join(dir, name))
-		if err != nil {
-			return err
-		}
-	}
-	return os.Remove(dir)
-}
 
-func copyDirectory(scrDir, dest string) error {
-	entries, err := ioutil.ReadDir(scrDir)
+	invites, _, err := rc.client.Repositories.ListInvitations(context.Background(), owner, name, nil)
 	if err != nil {
-		return err
+		return fmt.Errorf("could not list invitations for repo %s %s %w", owner, name, classify(err))
 	}
-	for _, entry := range entries {
-		sourcePath := filepath.Join(scrDir, entry.Name())
-		destPath := filepath.Join(dest, entry.Name())
-
-		fileInfo, err := os.Stat(sourcePath)
-		if err != nil {
-			return err
-		}
-
-		stat, ok := fileInfo.Sys().(*syscall.Stat_t)
-		if !ok {
-			return fmt.Errorf("failed to get raw syscall.Stat_t data for '%s'", sourcePath)
-		}
 
-		switch fileInfo.Mode() & os.ModeType {
-		case os.ModeDir:
-			if err := createIfNotExists(destPath, 0755); err != nil {
-				return err
-			}
-			if err := copyDirectory(sourcePath, destPath); err != nil {
-				return err
-			}
-		case os.ModeSymlink:
-			if err := copySymLink(sourcePath, destPath); err != nil {
-				return err
-			}
-		default:
-			if err := copy(sourcePath, destPath); err != nil {
-				return err
-			}
-		}
-
-		if err := os.Lchown(destPath, int(stat.Uid), int(stat.Gid)); err != nil {
-			return err
-		}
-
-		isSymlink := entry.Mode()&os.ModeSymlink != 0
-		if !isSymlink {
-			if err := os.Chmod(destPath, entry.Mode()); err != nil {
-				return err
+	for _, invite := range invites {
+		if invite.GetInvitee().GetLogin() == details.CandidateUsername {
+			_, err := rc.client.Repositories.DeleteInvitation(context.Background(), owner, name, invite.GetID())
+			if err != nil {
+				return fmt.Errorf("could not remove collaborator invitation from test repo %s %s %w", owner, name, classify(err))
 			}
 		}
 	}
-	return nil
-}
-
-func copy(srcFile, dstFile string) error {
-	out, err := os.Create(dstFile)
-	if err != nil {
-		return err
-	}
-
-	defer out.Close()
-
-	in, err := os.Open(srcFile)
-	defer in.Close()
-	if err != nil {
-		return err
-	}
-
-	_, err = io.Copy(out, in)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func exists(filePath string) bool {
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return false
-	}
 
-	return true
-}
-
-func createIfNotExists(dir string, perm os.FileMode) error {
-	if exists(dir) {
-		return nil
-	}
-
-	if err := os.MkdirAll(dir, perm); err != nil {
-		return fmt.Errorf("failed to create directory: '%s', error: '%s'", dir, err.Error())
+	for _, reviewer := range details.ReviewersUsernames {
+		err := addCollaborator(rc, owner, name, reviewer)
+		if err != nil {
+			return fmt.Errorf("could not add %s to repo %w", reviewer, classify(err))
+		}
 	}
 
 	return nil
 }
 
-func copySymLink(source, dest string) error {
-	link, err := os.Readlink(source)
-	if err != nil {
-		return err
-	}
-	return os.Symlink(link, dest)
-}
-
-func unzip(src string, dest string) ([]string, error) {
-	var filenames []string
-
-	r, err := zip.OpenReader(src)
-	if err != nil {
-		return filenames, err
-	}
-	defer r.Close()
-
-	for _, f := range r.File {
-
-		// Store filename/path for returning and using later on
-		fpath := filepath.Join(dest, f.Name)
-
-		if !strings.HasPrefix(fpath, filepath.Clean(dest)+string(os.PathSeparator)) {
-			return filenames, fmt.Errorf("%s: illegal file path", fpath)
-		}
-
-		filenames = append(filenames, fpath)
-
-		if f.FileInfo().IsDir() {
-			// Make Folder
-			os.MkdirAll(fpath, os.ModePerm)
-			continue
-		}
-
-		// Make File
-		if err = os.MkdirAll(filepath.Dir(fpath), os.ModePerm); err != nil {
-			return filenames, err
-		}
-
-		outFile, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
-		if err != nil {
-			return filenames, err
-		}
-
-		rc, err := f.Open()
-		if err != nil {
-			return filenames, err
-		}
-
-		_, err = io.Copy(outFile, rc)
-
-		// Close the file without defer to close before next iteration of loop
-		outFile.Close()
-		rc.Close()
+func getRepoName(githubRepoURL string) (string, string) {
+	pieces := strings.Split(githubRepoURL, "/")
+	end := pieces[len(pieces)-1]
 
-		if err != nil {
-			return filenames, err
-		}
-	}
-	return filenames, nil
+	return pieces[len(pieces)-2], strings.Replace(end, ".git", "", 1)
 }
@@ -57,13 +57,14 @@ func TestGithubClient(t *testing.T) {
 		}()
 
 		installationID, _ := strconv.ParseInt(os.Getenv("TEST_GITHUB_INSTALLATION"), 10, 64)
-		err = githubClient.Upload(core.UploadDetails{
+		res, err := githubClient.Upload(core.UploadDetails{
 			ID:             unix,
 			VCSRepoURL:     repo.GetCloneURL(),
 			TestVCSRepoURL: os.Getenv("TEST_GITHUB_REPO_URL"),
 			InstallationID: installationID,
 		})
 		require.NoError(t, err)
+		assert.Len(t, res.SHA, 40)
 
 		owner := repo.GetOwner().GetLogin()
 		repoName := repo.GetName()
@@ -89,4 +90,72 @@ func TestGithubClient(t *testing.T) {
 		assert.Contains(t, filenames, "test/index.txt")
 		assert.Contains(t, filenames, "echo.txt")
 	})
+
+	t.Run("CreateRepo in org", func(t *testing.T) {
+		org := os.Getenv("TEST_GITHUB_ORG")
+		if org == "" {
+			t.Skip("TEST_GITHUB_ORG is not set")
+		}
+
+		unix := time.Now().Unix()
+		installationID, _ := strconv.ParseInt(os.Getenv("TEST_GITHUB_INSTALLATION"), 10, 64)
+		url, err := githubClient.CreateRepo(core.CreateDetails{
+			BusinessName:   "e2e",
+			Username:       os.Getenv("TEST_GITHUB_CANDIDATE"),
+			ID:             int(unix),
+			Org:            org,
+			InstallationID: installationID,
+		})
+		require.NoError(t, err)
+		assert.Contains(t, url, "github.com/"+org+"/")
+		defer func() {
+			owner, name := org, fmt.Sprintf("%s-e2e-test-%d", os.Getenv("TEST_GITHUB_CANDIDATE"), unix)
+			if _, err := rawClient.Repositories.Delete(context.Background(), owner, name); err != nil {
+				t.Logf("could not delete repo %s/%s %s", owner, name, err)
+			}
+		}()
+
+		res, err := githubClient.Upload(core.UploadDetails{
+			ID:             unix,
+			VCSRepoURL:     url,
+			TestVCSRepoURL: os.Getenv("TEST_GITHUB_REPO_URL"),
+			InstallationID: installationID,
+		})
+		require.NoError(t, err)
+		assert.Len(t, res.SHA, 40)
+
+		require.NoError(t, githubClient.Cleanup(core.CleanDetails{
+			VCSRepoURL:        url,
+			CandidateUsername: os.Getenv("TEST_GITHUB_CANDIDATE"),
+		}))
+	})
+
+	t.Run("GithubTemplateCreator", func(t *testing.T) {
+		templateURL := os.Getenv("TEST_GITHUB_TEMPLATE_REPO_URL")
+		if templateURL == "" {
+			t.Skip("TEST_GITHUB_TEMPLATE_REPO_URL is not set")
+		}
+
+		unix := time.Now().Unix()
+		url, err := vcs.GithubTemplateCreator{Github: githubClient}.CreateRepo(core.CreateDetails{
+			BusinessName: "e2e",
+			Username:     os.Getenv("TEST_GITHUB_CANDIDATE"),
+			ID:           int(unix),
+			TemplateRepo: templateURL,
+		})
+		require.NoError(t, err)
+
+		owner := os.Getenv("GITHUB_USERNAME")
+		name := fmt.Sprintf("%s-e2e-test-%d", os.Getenv("TEST_GITHUB_CANDIDATE"), unix)
+		defer func() {
+			_, err := rawClient.Repositories.Delete(context.Background(), owner, name)
+			assert.NoError(t, err)
+		}()
+
+		repo, _, err := rawClient.Repositories.Get(context.Background(), owner, name)
+		require.NoError(t, err)
+		assert.Equal(t, url, repo.GetCloneURL())
+		assert.True(t, repo.GetPrivate())
+		assert.Equal(t, templateURL, repo.GetTemplateRepository().GetHTMLURL())
+	})
 }
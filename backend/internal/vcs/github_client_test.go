@@ -0,0 +1,25 @@
+package vcs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeRepoName(t *testing.T) {
+	tests := []struct {
+		name     string
+		business string
+		expected string
+	}{
+		{name: "single word", business: "TestRelay", expected: "jane-testrelay-test-12"},
+		{name: "spaces", business: "Test Relay", expected: "jane-test-relay-test-12"},
+		{name: "repeated whitespace", business: "Test \t Relay  Ltd", expected: "jane-test-relay-ltd-test-12"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, makeRepoName(tt.business, "Jane", 12))
+		})
+	}
+}
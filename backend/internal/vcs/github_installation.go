@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/bradleyfalzon/ghinstallation"
 	"github.com/google/go-github/v39/github"
@@ -58,6 +59,33 @@ func (g GithubRepoCollector) CollectRepos(installationID int64) ([]core.Repo, er
 	return qrepos, nil
 }
 
+// CollectDirs lists the directories at path in the github repo with fullName, on the default branch.
+func (g GithubRepoCollector) CollectDirs(installationID int64, fullName, path string) ([]core.RepoDir, error) {
+	c, err := g.newInstallation(installationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate installation with id %d %w", installationID, err)
+	}
+
+	if !strings.Contains(fullName, "/") {
+		return nil, fmt.Errorf("invalid repo name %s", fullName)
+	}
+
+	owner, repo := getRepoName(fullName)
+	_, contents, _, err := c.GetContents(context.Background(), owner, repo, path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list contents of %s/%s %w", fullName, path, classify(err))
+	}
+
+	dirs := []core.RepoDir{}
+	for _, content := range contents {
+		if content.GetType() == "dir" {
+			dirs = append(dirs, core.RepoDir{Name: content.GetName(), Path: content.GetPath()})
+		}
+	}
+
+	return dirs, nil
+}
+
 // InstallationFunc represents a function that returns a new client for the given installationID.
 // In most cases this represents a given github APP installation.
 type InstallationFunc func(installationID int64) (GithubInstallationClient, error)
@@ -94,7 +122,8 @@ func NewGithubAppInstallationFunc(appID int64, pk []byte) InstallationFunc {
 // InstallationClient defines an interface around a vcs installation that is scoped for read-only access to repos.
 type InstallationClient interface {
 	ListRepos(ctx context.Context, opts *github.ListOptions) (*github.ListRepositories, *github.Response, error)
-	DownloadRepo(ctx context.Context, url string) (*bytes.Buffer, error)
+	DownloadRepo(ctx context.Context, url, ref string) (*bytes.Buffer, string, error)
+	GetContents(ctx context.Context, owner, repo, path string, opts *github.RepositoryContentGetOptions) (*github.RepositoryContent, []*github.RepositoryContent, *github.Response, error)
 }
 
 // GithubInstallationClient wraps an InstallationClient interface with a hard type. This is done for extra interface/
@@ -108,23 +137,40 @@ type GithubInstallationWrapper struct {
 	client *github.Client
 }
 
-// DownloadRepo downloads a zip of the given repo the provided url and returns it as a bytes.Buffer.
-func (g GithubInstallationWrapper) DownloadRepo(ctx context.Context, url string) (*bytes.Buffer, error) {
+// DownloadRepo downloads a zip of the given repo the provided url at ref and returns it as a bytes.Buffer, along
+// with the commit sha that ref resolved to. An empty ref downloads the default branch. Archives larger than
+// maxArchiveSize are rejected.
+func (g GithubInstallationWrapper) DownloadRepo(ctx context.Context, url, ref string) (*bytes.Buffer, string, error) {
 	owner, repo := getRepoName(url)
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	// resolve the ref first so that the archive and the returned sha match even if the ref moves.
+	sha, _, err := g.client.Repositories.GetCommitSHA1(ctx, owner, repo, ref, "")
+	if err != nil {
+		return nil, "", fmt.Errorf("could not resolve ref %s of repo %s/%s %w", ref, owner, repo, classify(err))
+	}
 
-	u, _, err := g.client.Repositories.GetArchiveLink(ctx, owner, repo, github.Zipball, nil, true)
+	u, _, err := g.client.Repositories.GetArchiveLink(ctx, owner, repo, github.Zipball, &github.RepositoryContentGetOptions{Ref: sha}, true)
 	if err != nil {
-		return nil, fmt.Errorf("could not get archive link for repo %s/%s %w", owner, repo, err)
+		return nil, "", fmt.Errorf("could not get archive link for repo %s/%s %w", owner, repo, classify(err))
 	}
 
 	req, _ := g.client.NewRequest("GET", u.String(), nil)
+	res, err := g.client.BareDo(ctx, req)
+	if err != nil {
+		return nil, "", fmt.Errorf("problem downloading zipFile for repo %s/%s %w", owner, repo, classify(err))
+	}
+	defer res.Body.Close()
+
 	buf := bytes.NewBuffer([]byte{})
-	_, err = g.client.Do(context.Background(), req, buf)
+	err = readArchive(buf, res.Body, maxArchiveSize)
 	if err != nil {
-		return nil, fmt.Errorf("problem downloading zipFile for repo %s/%s %w", owner, repo, err)
+		return nil, "", fmt.Errorf("problem downloading zipFile for repo %s/%s %w", owner, repo, classify(err))
 	}
 
-	return buf, nil
+	return buf, sha, nil
 }
 
 // ListRepos is a slim wrapper around the Apps.ListRepos. In future this could be adapted with more
@@ -132,3 +178,9 @@ func (g GithubInstallationWrapper) DownloadRepo(ctx context.Context, url string)
 func (g GithubInstallationWrapper) ListRepos(ctx context.Context, opts *github.ListOptions) (*github.ListRepositories, *github.Response, error) {
 	return g.client.Apps.ListRepos(ctx, opts)
 }
+
+// GetContents is a slim wrapper around the Repositories.GetContents, returning either the file or the directory
+// listing at path.
+func (g GithubInstallationWrapper) GetContents(ctx context.Context, owner, repo, path string, opts *github.RepositoryContentGetOptions) (*github.RepositoryContent, []*github.RepositoryContent, *github.Response, error) {
+	return g.client.Repositories.GetContents(ctx, owner, repo, path, opts)
+}
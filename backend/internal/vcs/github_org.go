@@ -0,0 +1,159 @@
+package vcs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bradleyfalzon/ghinstallation"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	gitHttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/google/go-github/v39/github"
+	"golang.org/x/oauth2"
+)
+
+// repoClient manages assignment repos, either through the interviewer's access token for repos owned by the
+// interviewer account, or through a github app installation for repos owned by a business org.
+type repoClient struct {
+	client *github.Client
+	// auth pushes to the repos the client manages.
+	auth transport.AuthMethod
+}
+
+// repoInstallationFunc represents a function that returns a repoClient for the github app installation with
+// installationID, or for the installation on org if installationID is 0.
+type repoInstallationFunc func(installationID int64, org string) (repoClient, error)
+
+// tokenExpiryMargin is how long before its access token expires that a cached repoClient is replaced, so that
+// a client is never handed out with a token that expires mid operation.
+const tokenExpiryMargin = 5 * time.Minute
+
+// newGithubAppRepoInstallationFunc returns a repoInstallationFunc for a given github app. Installations are looked
+// up by org using the app's own jwt, and their clients push with a short lived installation access token.
+//
+// pk must be a valid primary key for the appID given.
+func newGithubAppRepoInstallationFunc(appID int64, pk []byte) (repoInstallationFunc, error) {
+	atr, err := ghinstallation.NewAppsTransport(http.DefaultTransport, appID, pk)
+	if err != nil {
+		return nil, fmt.Errorf("could not init github app transport %w", err)
+	}
+
+	return newRepoInstallations(github.NewClient(&http.Client{Transport: atr})).get, nil
+}
+
+// repoInstallations mints repoClients for the installations of a github app, caching each client by installation,
+// or by org when looked up by org, until shortly before its access token expires.
+type repoInstallations struct {
+	// app calls the github api as the app itself.
+	app *github.Client
+
+	mu      *sync.Mutex
+	clients map[string]cachedRepoClient
+}
+
+type cachedRepoClient struct {
+	repoClient
+	expiresAt time.Time
+}
+
+func newRepoInstallations(app *github.Client) repoInstallations {
+	return repoInstallations{
+		app:     app,
+		mu:      &sync.Mutex{},
+		clients: make(map[string]cachedRepoClient),
+	}
+}
+
+// get implements repoInstallationFunc.
+func (r repoInstallations) get(installationID int64, org string) (repoClient, error) {
+	key := "org:" + strings.ToLower(org)
+	if installationID != 0 {
+		key = strconv.FormatInt(installationID, 10)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if c, ok := r.clients[key]; ok && time.Now().Add(tokenExpiryMargin).Before(c.expiresAt) {
+		return c.repoClient, nil
+	}
+
+	if installationID == 0 {
+		i, _, err := r.app.Apps.FindOrganizationInstallation(context.Background(), org)
+		if err != nil {
+			return repoClient{}, fmt.Errorf("could not find app installation on org %s %w", org, classify(err))
+		}
+
+		installationID = i.GetID()
+	}
+
+	token, _, err := r.app.Apps.CreateInstallationToken(context.Background(), installationID, nil)
+	if err != nil {
+		return repoClient{}, fmt.Errorf("could not get access token for installation %d %w", installationID, classify(err))
+	}
+
+	client := github.NewClient(oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(
+		&oauth2.Token{AccessToken: token.GetToken()},
+	)))
+	client.BaseURL = r.app.BaseURL
+
+	c := cachedRepoClient{
+		repoClient: repoClient{
+			client: client,
+			auth:   &gitHttp.BasicAuth{Username: "x-access-token", Password: token.GetToken()},
+		},
+		expiresAt: token.GetExpiresAt(),
+	}
+	r.clients[key] = c
+
+	return c.repoClient, nil
+}
+
+// interviewer returns the repoClient that manages repos owned by the interviewer account.
+func (c GithubClient) interviewer() repoClient {
+	return repoClient{
+		client: c.client,
+		auth: &gitHttp.BasicAuth{
+			Username: c.intervConf.Username,
+			Password: c.intervConf.AccessToken,
+		},
+	}
+}
+
+// creator returns the repoClient that creates a repo in org through the installation with installationID, or
+// the interviewer client if org is empty.
+func (c GithubClient) creator(installationID int64, org string) (repoClient, error) {
+	if org == "" {
+		return c.interviewer(), nil
+	}
+
+	if installationID == 0 {
+		return repoClient{}, fmt.Errorf("no github installation is set to create repos in org %s", org)
+	}
+
+	rc, err := c.newRepoInstallation(installationID, org)
+	if err != nil {
+		return repoClient{}, fmt.Errorf("failed to generate installation with id %d %w", installationID, err)
+	}
+
+	return rc, nil
+}
+
+// repoClientFor returns the repoClient that manages an assignment repo owned by owner. Repos outside the
+// interviewer account were created in a business org, so are managed through the app installation on that org.
+func (c GithubClient) repoClientFor(owner string) (repoClient, error) {
+	if owner == "" || strings.EqualFold(owner, c.intervConf.Username) {
+		return c.interviewer(), nil
+	}
+
+	rc, err := c.newRepoInstallation(0, owner)
+	if err != nil {
+		return repoClient{}, fmt.Errorf("failed to generate installation for org %s %w", owner, err)
+	}
+
+	return rc, nil
+}
@@ -0,0 +1,219 @@
+package vcs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gitHttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/google/go-github/v39/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+)
+
+// fakeGithubApp serves the github api endpoints used to find app installations and mint their access tokens, and
+// to generate repos from templates.
+type fakeGithubApp struct {
+	mu            sync.Mutex
+	installations map[string]int64
+	// tokenTTL is how long minted access tokens are valid for.
+	tokenTTL time.Duration
+	lookups  int
+	minted   map[int64]int
+	// generated holds the authorization header of each request to generate a repo.
+	generated []string
+}
+
+func newFakeGithubApp(t *testing.T) (*fakeGithubApp, *httptest.Server) {
+	f := &fakeGithubApp{
+		installations: map[string]int64{"acme": 7},
+		tokenTTL:      time.Hour,
+		minted:        make(map[int64]int),
+	}
+
+	s := httptest.NewServer(f)
+	t.Cleanup(s.Close)
+
+	return f, s
+}
+
+func (f *fakeGithubApp) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var id int64
+	var owner, repo string
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/orgs/"):
+		f.lookups++
+		id, ok := f.installations[strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/orgs/"), "/installation")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "Not Found"}`))
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": id})
+	case r.Method == http.MethodPost && scan(r.URL.Path, "/app/installations/%d/access_tokens", &id):
+		f.minted[id]++
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"token":      fmt.Sprintf("token-%d-%d", id, f.minted[id]),
+			"expires_at": time.Now().Add(f.tokenTTL).UTC().Format(time.RFC3339),
+		})
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/generate"):
+		f.generated = append(f.generated, r.Header.Get("Authorization"))
+
+		var req github.TemplateRepoRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		owner, repo = req.GetOwner(), req.GetName()
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"name":      repo,
+			"owner":     map[string]interface{}{"login": owner},
+			"clone_url": "https://github.com/" + owner + "/" + repo + ".git",
+		})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func scan(path, format string, id *int64) bool {
+	n, err := fmt.Sscanf(path, format, id)
+	return err == nil && n == 1
+}
+
+func newTestGithubClient(t *testing.T, s *httptest.Server) GithubClient {
+	u, err := url.Parse(s.URL + "/")
+	require.NoError(t, err)
+
+	app := github.NewClient(nil)
+	app.BaseURL = u
+
+	return GithubClient{
+		client:              github.NewClient(nil),
+		intervConf:          GithubInterviewerConfig{Username: "interviewer", AccessToken: "personal-token"},
+		newRepoInstallation: newRepoInstallations(app).get,
+	}
+}
+
+func password(t *testing.T, rc repoClient) string {
+	auth, ok := rc.auth.(*gitHttp.BasicAuth)
+	require.True(t, ok)
+
+	return auth.Password
+}
+
+func TestGithubClientRepoClients(t *testing.T) {
+	t.Run("repoClientFor should use the personal token for repos owned by the interviewer", func(t *testing.T) {
+		f, s := newFakeGithubApp(t)
+		c := newTestGithubClient(t, s)
+
+		for _, owner := range []string{"", "interviewer", "Interviewer"} {
+			rc, err := c.repoClientFor(owner)
+			require.NoError(t, err)
+			assert.Equal(t, "personal-token", password(t, rc))
+		}
+
+		assert.Equal(t, 0, f.lookups)
+		assert.Empty(t, f.minted)
+	})
+
+	t.Run("repoClientFor should use a cached installation token for repos owned by an org", func(t *testing.T) {
+		f, s := newFakeGithubApp(t)
+		c := newTestGithubClient(t, s)
+
+		for i := 0; i < 3; i++ {
+			rc, err := c.repoClientFor("acme")
+			require.NoError(t, err)
+			assert.Equal(t, "token-7-1", password(t, rc))
+		}
+
+		assert.Equal(t, 1, f.lookups)
+		assert.Equal(t, map[int64]int{7: 1}, f.minted)
+	})
+
+	t.Run("repoClientFor should error for orgs without an installation", func(t *testing.T) {
+		_, s := newFakeGithubApp(t)
+		c := newTestGithubClient(t, s)
+
+		_, err := c.repoClientFor("umbrella")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "could not find app installation on org umbrella")
+	})
+
+	t.Run("creator should use the personal token when there is no org", func(t *testing.T) {
+		f, s := newFakeGithubApp(t)
+		c := newTestGithubClient(t, s)
+
+		rc, err := c.creator(9, "")
+		require.NoError(t, err)
+		assert.Equal(t, "personal-token", password(t, rc))
+		assert.Empty(t, f.minted)
+	})
+
+	t.Run("creator should error when no installation is set for the org", func(t *testing.T) {
+		_, s := newFakeGithubApp(t)
+		c := newTestGithubClient(t, s)
+
+		_, err := c.creator(0, "acme")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no github installation is set to create repos in org acme")
+	})
+
+	t.Run("creator should use a cached token for the installation", func(t *testing.T) {
+		f, s := newFakeGithubApp(t)
+		c := newTestGithubClient(t, s)
+
+		for i := 0; i < 2; i++ {
+			rc, err := c.creator(9, "acme")
+			require.NoError(t, err)
+			assert.Equal(t, "token-9-1", password(t, rc))
+		}
+
+		assert.Equal(t, 0, f.lookups)
+		assert.Equal(t, map[int64]int{9: 1}, f.minted)
+	})
+
+	t.Run("should mint a new token once the cached token is about to expire", func(t *testing.T) {
+		f, s := newFakeGithubApp(t)
+		f.tokenTTL = tokenExpiryMargin - time.Minute
+		c := newTestGithubClient(t, s)
+
+		rc, err := c.creator(9, "acme")
+		require.NoError(t, err)
+		assert.Equal(t, "token-9-1", password(t, rc))
+
+		rc, err = c.creator(9, "acme")
+		require.NoError(t, err)
+		assert.Equal(t, "token-9-2", password(t, rc))
+	})
+
+	t.Run("template creator should generate org repos with a cached installation token", func(t *testing.T) {
+		f, s := newFakeGithubApp(t)
+		c := newTestGithubClient(t, s)
+		creator := GithubTemplateCreator{Github: &c}
+
+		for _, id := range []int{12, 13} {
+			repo, err := creator.CreateRepo(core.CreateDetails{
+				ID:             id,
+				Username:       "jane",
+				BusinessName:   "TestRelay",
+				TemplateRepo:   "https://github.com/acme/backend-test",
+				Org:            "acme",
+				InstallationID: 9,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf("https://github.com/acme/%s.git", makeRepoName("TestRelay", "jane", id)), repo)
+		}
+
+		assert.Equal(t, []string{"Bearer token-9-1", "Bearer token-9-1"}, f.generated)
+		assert.Equal(t, map[int64]int{9: 1}, f.minted)
+	})
+}
@@ -0,0 +1,55 @@
+package vcs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/v39/github"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+)
+
+// GithubTemplateCreator creates assignment repos by generating them from a github template repository, so that
+// the repo holds the test as soon as it is created, see core.UploadModeTemplate. It implements core.VCSCreator.
+type GithubTemplateCreator struct {
+	Github *GithubClient
+}
+
+// CreateRepo generates a private repo from details.TemplateRepo under the interviewer account, or in the
+// details.TemplateOwner org, falling back to details.Org, through the github app installation
+// details.InstallationID. The app must have administration write access to the org to create repos in it. The
+// webhook is added as GithubClient.CreateRepo does, but the candidate is not added as a collaborator. The repo
+// already holds the test, so the candidate is only given access once the assignment starts, see assignment.Runner.
+func (c GithubTemplateCreator) CreateRepo(details core.CreateDetails) (string, error) {
+	org := details.TemplateOwner
+	if org == "" {
+		org = details.Org
+	}
+
+	rc, err := c.Github.creator(details.InstallationID, org)
+	if err != nil {
+		return "", err
+	}
+
+	owner, name := getRepoName(details.TemplateRepo)
+	r := &github.TemplateRepoRequest{
+		Name:        github.String(makeRepoName(details.BusinessName, details.Username, details.ID)),
+		Description: github.String(details.Username + " code assignment for " + details.BusinessName),
+		Private:     github.Bool(true),
+	}
+	if org != "" {
+		r.Owner = github.String(org)
+	}
+
+	repo, _, err := rc.client.Repositories.CreateFromTemplate(context.Background(), owner, name, r)
+	if err != nil {
+		return "", fmt.Errorf("could not generate repo from template %s/%s %w", owner, name, classify(err))
+	}
+
+	err = c.Github.addWebhook(rc, repo.GetOwner().GetLogin(), repo.GetName())
+	if err != nil {
+		return "", err
+	}
+
+	return repo.GetCloneURL(), nil
+}
@@ -0,0 +1,426 @@
+package vcs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+	gitHttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+)
+
+// developerAccess is the gitlab access level that lets members push to a project and open merge requests.
+const developerAccess = 30
+
+// GitlabError is returned when the gitlab api responds with a non 2xx status.
+type GitlabError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *GitlabError) Error() string {
+	return fmt.Sprintf("gitlab responded with %d %s", e.StatusCode, e.Message)
+}
+
+// GitlabConfig represents fields required to manage assignment projects on a gitlab instance.
+type GitlabConfig struct {
+	// URL is the base url of the gitlab instance, e.g. https://gitlab.com.
+	URL         string
+	AccessToken string
+	Username    string
+	Email       string
+	// NamespaceID is the group that assignment projects are created in. It defaults to the user's namespace.
+	NamespaceID int64
+}
+
+// GitlabClient handles communicating with the gitlab api to orchestrate gitlab project management.
+// All interactions use a single personal access token. Assignment projects are created and maintained
+// by the token's user, who must also be a member of each business's group to read their test projects.
+type GitlabClient struct {
+	client *http.Client
+	conf   GitlabConfig
+}
+
+// NewGitlabClient returns a GitlabClient for the gitlab instance at conf.URL.
+func NewGitlabClient(conf GitlabConfig) *GitlabClient {
+	conf.URL = strings.TrimSuffix(conf.URL, "/")
+
+	return &GitlabClient{
+		client: &http.Client{Timeout: time.Minute},
+		conf:   conf,
+	}
+}
+
+type gitlabProject struct {
+	ID                int64  `json:"id"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	HTTPURLToRepo     string `json:"http_url_to_repo"`
+}
+
+type gitlabUser struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+// CreateRepo creates a private project for the assignment, adding the candidate as a developer.
+// It returns the http clone url of the project.
+func (c GitlabClient) CreateRepo(details core.CreateDetails) (string, error) {
+	name := makeRepoName(details.BusinessName, details.Username, details.ID)
+	body := map[string]interface{}{
+		"name":        name,
+		"path":        name,
+		"description": details.Username + " code assignment for " + details.BusinessName,
+		"visibility":  "private",
+	}
+	if c.conf.NamespaceID != 0 {
+		body["namespace_id"] = c.conf.NamespaceID
+	}
+
+	var project gitlabProject
+	err := c.do(http.MethodPost, "/projects", body, &project)
+	if err != nil {
+		return "", fmt.Errorf("could not create project %w", err)
+	}
+
+	u, err := c.user(details.Username)
+	if err != nil {
+		return "", err
+	}
+
+	err = c.addMember(strconv.FormatInt(project.ID, 10), u)
+	if err != nil {
+		return "", err
+	}
+
+	return project.HTTPURLToRepo, nil
+}
+
+// AddCollaborator adds the user as a developer of the project at repo. It returns ErrorAlreadyCollaborator
+// if the user is already a member.
+func (c GitlabClient) AddCollaborator(repo string, username string) error {
+	project := c.projectPath(repo)
+	u, err := c.user(username)
+	if err != nil {
+		return err
+	}
+
+	err = c.do(http.MethodGet, fmt.Sprintf("/projects/%s/members/%d", project, u.ID), nil, nil)
+	if err == nil {
+		return ErrorAlreadyCollaborator
+	}
+
+	var gErr *GitlabError
+	if !errors.As(err, &gErr) || gErr.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("could not get member %s of project %s %w", username, repo, err)
+	}
+
+	return c.addMember(project, u)
+}
+
+func (c GitlabClient) addMember(projectID string, u gitlabUser) error {
+	err := c.do(http.MethodPost, "/projects/"+projectID+"/members", map[string]interface{}{
+		"user_id":      u.ID,
+		"access_level": developerAccess,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("could not add %s to project %s %w", u.Username, projectID, err)
+	}
+
+	return nil
+}
+
+func (c GitlabClient) user(username string) (gitlabUser, error) {
+	var users []gitlabUser
+	err := c.do(http.MethodGet, "/users?username="+url.QueryEscape(username), nil, &users)
+	if err != nil {
+		return gitlabUser{}, fmt.Errorf("could not find gitlab user %s %w", username, err)
+	}
+
+	if len(users) == 0 {
+		return gitlabUser{}, fmt.Errorf("gitlab user %s does not exist", username)
+	}
+
+	return users[0], nil
+}
+
+// Upload uploads data.Ref of the test project into the assignment project, returning the commit sha it resolved
+// to. The test project must be within the gitlab group given by data.InstallationID, so that a business can only
+// use its own test projects. See pushArchive for how the test code is committed.
+func (c GitlabClient) Upload(data core.UploadDetails) (core.UploadResult, error) {
+	buf, sha, err := c.download(data)
+	if err != nil {
+		return core.UploadResult{}, err
+	}
+
+	excluded, err := pushArchive(buf, data, object.Signature{
+		Name:  c.conf.Username,
+		Email: c.conf.Email,
+		When:  time.Now(),
+	}, &gitHttp.BasicAuth{
+		Username: c.conf.Username,
+		Password: c.conf.AccessToken,
+	})
+	if err != nil {
+		return core.UploadResult{}, classifyAPI(err)
+	}
+
+	return core.UploadResult{SHA: sha, Excluded: excluded}, nil
+}
+
+// Validate downloads data.Ref of the test project and unpacks it as Upload would, without pushing it to an
+// assignment project.
+func (c GitlabClient) Validate(data core.UploadDetails) (core.ValidateResult, error) {
+	buf, sha, err := c.download(data)
+	if err != nil {
+		return core.ValidateResult{}, err
+	}
+
+	res, err := validateArchive(buf, data)
+	if err != nil {
+		return core.ValidateResult{}, err
+	}
+
+	res.SHA = sha
+	return res, nil
+}
+
+// download returns a zip archive of data.Ref of the test project and the commit sha it resolved to.
+func (c GitlabClient) download(data core.UploadDetails) (*bytes.Buffer, string, error) {
+	testPath, err := c.groupProject(data.InstallationID, strings.TrimSuffix(strings.TrimPrefix(data.TestVCSRepoURL, c.conf.URL+"/"), ".git"))
+	if err != nil {
+		return nil, "", err
+	}
+
+	sha, err := c.resolveRef(testPath, data.Ref)
+	if err != nil {
+		return nil, "", err
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	err = c.do(http.MethodGet, "/projects/"+testPath+"/repository/archive.zip?sha="+sha, nil, buf)
+	if err != nil {
+		return nil, "", fmt.Errorf("could not download project contents %w", err)
+	}
+
+	return buf, sha, nil
+}
+
+// groupProject returns the url encoded path of the project with fullName, erroring if it is not within the group.
+func (c GitlabClient) groupProject(groupID int64, fullName string) (string, error) {
+	var group struct {
+		FullPath string `json:"full_path"`
+	}
+	err := c.do(http.MethodGet, fmt.Sprintf("/groups/%d", groupID), nil, &group)
+	if err != nil {
+		return "", fmt.Errorf("could not get group %d %w", groupID, err)
+	}
+
+	if !strings.HasPrefix(fullName, group.FullPath+"/") {
+		return "", fmt.Errorf("test project %s is not in group %s", fullName, group.FullPath)
+	}
+
+	return url.PathEscape(fullName), nil
+}
+
+// resolveRef returns the commit sha of the branch, tag or commit ref of the project, or of its default branch
+// if ref is empty.
+func (c GitlabClient) resolveRef(projectPath, ref string) (string, error) {
+	if ref == "" {
+		var project struct {
+			DefaultBranch string `json:"default_branch"`
+		}
+		err := c.do(http.MethodGet, "/projects/"+projectPath, nil, &project)
+		if err != nil {
+			return "", fmt.Errorf("could not get project %s %w", projectPath, err)
+		}
+
+		ref = project.DefaultBranch
+	}
+
+	var commit struct {
+		ID string `json:"id"`
+	}
+	err := c.do(http.MethodGet, "/projects/"+projectPath+"/repository/commits/"+url.PathEscape(ref), nil, &commit)
+	if err != nil {
+		return "", fmt.Errorf("could not resolve ref %s of project %s %w", ref, projectPath, err)
+	}
+
+	return commit.ID, nil
+}
+
+// IsSubmitted returns whether the candidate has opened a merge request in the project.
+func (c GitlabClient) IsSubmitted(vcsURL, username string) (bool, error) {
+	return c.IsStageSubmitted(vcsURL, username, "")
+}
+
+// IsStageSubmitted returns whether the candidate has opened a merge request into the branch of the project.
+// An empty branch matches merge requests into any branch.
+func (c GitlabClient) IsStageSubmitted(vcsURL, username, branch string) (bool, error) {
+	q := url.Values{"state": {"all"}, "author_username": {username}}
+	if branch != "" {
+		q.Set("target_branch", branch)
+	}
+
+	var mrs []struct {
+		Author gitlabUser `json:"author"`
+	}
+	err := c.do(http.MethodGet, "/projects/"+c.projectPath(vcsURL)+"/merge_requests?"+q.Encode(), nil, &mrs)
+	if err != nil {
+		return false, fmt.Errorf("could not list merge requests %w", err)
+	}
+
+	for _, mr := range mrs {
+		if mr.Author.Username == username {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Cleanup removes the candidate from the project and adds each reviewer as a developer.
+func (c GitlabClient) Cleanup(details core.CleanDetails) error {
+	project := c.projectPath(details.VCSRepoURL)
+	u, err := c.user(details.CandidateUsername)
+	if err != nil {
+		return err
+	}
+
+	err = c.do(http.MethodDelete, fmt.Sprintf("/projects/%s/members/%d", project, u.ID), nil, nil)
+	var gErr *GitlabError
+	if err != nil && !(errors.As(err, &gErr) && gErr.StatusCode == http.StatusNotFound) {
+		return fmt.Errorf("could not remove member from test project %s %w", details.VCSRepoURL, err)
+	}
+
+	for _, reviewer := range details.ReviewersUsernames {
+		err := c.AddCollaborator(details.VCSRepoURL, reviewer)
+		if err != nil && !errors.Is(err, ErrorAlreadyCollaborator) {
+			return fmt.Errorf("could not add %s to project %w", reviewer, err)
+		}
+	}
+
+	return nil
+}
+
+// CollectRepos returns the projects in the gitlab group with the given id, including its subgroups.
+func (c GitlabClient) CollectRepos(groupID int64) ([]core.Repo, error) {
+	var repos []core.Repo
+	for page := 1; ; page++ {
+		var projects []gitlabProject
+		err := c.do(http.MethodGet, fmt.Sprintf("/groups/%d/projects?include_subgroups=true&per_page=100&page=%d", groupID, page), nil, &projects)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list projects %w", err)
+		}
+
+		for _, p := range projects {
+			repos = append(repos, core.Repo{ID: p.ID, FullName: p.PathWithNamespace})
+		}
+
+		if len(projects) < 100 {
+			return repos, nil
+		}
+	}
+}
+
+// CollectDirs lists the directories at path in the project with fullName, on the default branch. The project
+// must be within the group.
+func (c GitlabClient) CollectDirs(groupID int64, fullName, path string) ([]core.RepoDir, error) {
+	project, err := c.groupProject(groupID, fullName)
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := []core.RepoDir{}
+	for page := 1; ; page++ {
+		q := url.Values{"path": {path}, "per_page": {"100"}, "page": {strconv.Itoa(page)}}
+
+		var tree []struct {
+			Name string `json:"name"`
+			Type string `json:"type"`
+			Path string `json:"path"`
+		}
+		err := c.do(http.MethodGet, "/projects/"+project+"/repository/tree?"+q.Encode(), nil, &tree)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tree of %s/%s %w", fullName, path, err)
+		}
+
+		for _, t := range tree {
+			if t.Type == "tree" {
+				dirs = append(dirs, core.RepoDir{Name: t.Name, Path: t.Path})
+			}
+		}
+
+		if len(tree) < 100 {
+			return dirs, nil
+		}
+	}
+}
+
+// owns returns whether the repo url is hosted on the gitlab instance.
+func (c GitlabClient) owns(repoURL string) bool {
+	return strings.HasPrefix(repoURL, c.conf.URL+"/")
+}
+
+// projectPath returns the url encoded path of the project at repoURL, which the gitlab api accepts
+// in place of a project id.
+func (c GitlabClient) projectPath(repoURL string) string {
+	return url.PathEscape(strings.TrimSuffix(strings.TrimPrefix(repoURL, c.conf.URL+"/"), ".git"))
+}
+
+// do sends a request to the gitlab api with body encoded as json. A successful response is decoded into out,
+// or copied into out, up to maxArchiveSize bytes, if it is a *bytes.Buffer. Errors are classified with classifyAPI.
+func (c GitlabClient) do(method, path string, body, out interface{}) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("could not encode request body %w", err)
+		}
+
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, c.conf.URL+"/api/v4"+path, r)
+	if err != nil {
+		return fmt.Errorf("could not create request %w", err)
+	}
+
+	req.Header.Set("PRIVATE-TOKEN", c.conf.AccessToken)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return classifyAPI(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return classifyAPI(&GitlabError{StatusCode: res.StatusCode, Message: strings.TrimSpace(string(msg))})
+	}
+
+	switch o := out.(type) {
+	case nil:
+		return nil
+	case *bytes.Buffer:
+		err = readArchive(o, res.Body, maxArchiveSize)
+	default:
+		err = json.NewDecoder(res.Body).Decode(out)
+	}
+	if err != nil {
+		return fmt.Errorf("could not read %s %s response %w", method, path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,550 @@
+package vcs_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+	"github.com/testrelay/testrelay/backend/internal/vcs"
+)
+
+type fakeProject struct {
+	id        int64
+	path      string
+	namespace int64
+	private   bool
+	members   map[int64]int
+	mrs       []map[string]interface{}
+	// defaultBranch is the branch that the test code is uploaded from when no ref is given.
+	defaultBranch string
+	// refs maps each branch, tag or commit to the sha it resolves to, archives each sha to its zip.
+	refs     map[string]string
+	archives map[string][]byte
+	// files holds the path of every file on the default branch, from which repository trees are listed.
+	files []string
+}
+
+func (p *fakeProject) commit(ref, sha string, archive []byte) {
+	p.refs[ref] = sha
+	p.refs[sha] = sha
+	p.archives[sha] = archive
+}
+
+// fakeGitlab implements the parts of the gitlab v4 api used by the GitlabClient, keeping state in memory.
+type fakeGitlab struct {
+	t        *testing.T
+	mu       sync.Mutex
+	token    string
+	users    map[string]int64
+	groups   map[int64]string
+	projects map[string]*fakeProject
+	// cloneURL returns the http_url_to_repo of newly created projects.
+	cloneURL func(path string) string
+	// fail responds to every request with the status if set.
+	fail int
+}
+
+func newFakeGitlab(t *testing.T) (*fakeGitlab, *httptest.Server) {
+	f := &fakeGitlab{
+		t:        t,
+		token:    "gitlab-token",
+		users:    map[string]int64{"jane": 1, "bob": 2, "alice": 3, "testrelay": 4},
+		groups:   map[int64]string{10: "testrelay", 11: "other"},
+		projects: map[string]*fakeProject{},
+	}
+	s := httptest.NewServer(f)
+	t.Cleanup(s.Close)
+
+	f.cloneURL = func(path string) string {
+		return s.URL + "/" + path + ".git"
+	}
+
+	return f, s
+}
+
+func (f *fakeGitlab) addProject(path string, namespace int64) *fakeProject {
+	p := &fakeProject{
+		id:            int64(len(f.projects) + 100),
+		path:          path,
+		namespace:     namespace,
+		members:       map[int64]int{},
+		defaultBranch: "main",
+		refs:          map[string]string{},
+		archives:      map[string][]byte{},
+	}
+	f.projects[path] = p
+
+	return p
+}
+
+func (f *fakeGitlab) project(escaped string) *fakeProject {
+	path, _ := url.PathUnescape(escaped)
+	if p, ok := f.projects[path]; ok {
+		return p
+	}
+
+	for _, p := range f.projects {
+		if strconv.FormatInt(p.id, 10) == path {
+			return p
+		}
+	}
+
+	return nil
+}
+
+func (f *fakeGitlab) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.fail != 0 {
+		w.WriteHeader(f.fail)
+		return
+	}
+
+	if r.Header.Get("PRIVATE-TOKEN") != f.token {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), "/api/v4/"), "/")
+	route := r.Method + " " + parts[0]
+	if len(parts) > 2 {
+		route += "/:id/" + strings.Join(parts[2:], "/")
+	}
+
+	var body map[string]interface{}
+	if r.Body != nil {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+	}
+
+	switch {
+	case route == "GET users":
+		var users []map[string]interface{}
+		if id, ok := f.users[r.URL.Query().Get("username")]; ok {
+			users = append(users, map[string]interface{}{"id": id, "username": r.URL.Query().Get("username")})
+		}
+		f.json(w, users)
+	case route == "POST projects":
+		ns, _ := body["namespace_id"].(float64)
+		path := f.groups[int64(ns)] + "/" + body["path"].(string)
+		p := f.addProject(path, int64(ns))
+		p.private = body["visibility"] == "private"
+		f.json(w, map[string]interface{}{"id": p.id, "path_with_namespace": path, "http_url_to_repo": f.cloneURL(path)})
+	case parts[0] == "groups" && len(parts) == 2 && r.Method == http.MethodGet:
+		id, _ := strconv.ParseInt(parts[1], 10, 64)
+		path, ok := f.groups[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		f.json(w, map[string]interface{}{"id": id, "full_path": path})
+	case route == "GET groups/:id/projects":
+		id, _ := strconv.ParseInt(parts[1], 10, 64)
+		var projects []map[string]interface{}
+		for _, p := range f.projects {
+			if p.namespace == id {
+				projects = append(projects, map[string]interface{}{"id": p.id, "path_with_namespace": p.path})
+			}
+		}
+		f.json(w, projects)
+	case route == "GET projects" && len(parts) == 2:
+		p := f.project(parts[1])
+		if p == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		f.json(w, map[string]interface{}{"id": p.id, "path_with_namespace": p.path, "default_branch": p.defaultBranch})
+	case parts[0] == "projects" && len(parts) > 2:
+		p := f.project(parts[1])
+		if p == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		f.serveProject(w, r, p, route, parts, body)
+	default:
+		f.t.Errorf("unexpected gitlab request %s %s", r.Method, r.URL)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeGitlab) serveProject(w http.ResponseWriter, r *http.Request, p *fakeProject, route string, parts []string, body map[string]interface{}) {
+	switch route {
+	case "POST projects/:id/members":
+		p.members[int64(body["user_id"].(float64))] = int(body["access_level"].(float64))
+		w.WriteHeader(http.StatusCreated)
+	case "GET projects/:id/members/" + parts[len(parts)-1], "DELETE projects/:id/members/" + parts[len(parts)-1]:
+		id, _ := strconv.ParseInt(parts[len(parts)-1], 10, 64)
+		if _, ok := p.members[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodDelete {
+			delete(p.members, id)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		f.json(w, map[string]interface{}{"id": id})
+	case "GET projects/:id/merge_requests":
+		q := r.URL.Query()
+		var mrs []map[string]interface{}
+		for _, mr := range p.mrs {
+			author := mr["author"].(map[string]interface{})["username"]
+			if author == q.Get("author_username") && (q.Get("target_branch") == "" || q.Get("target_branch") == mr["target_branch"]) {
+				mrs = append(mrs, mr)
+			}
+		}
+		f.json(w, mrs)
+	case "GET projects/:id/repository/commits/" + parts[len(parts)-1]:
+		ref, _ := url.PathUnescape(parts[len(parts)-1])
+		sha, ok := p.refs[ref]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		f.json(w, map[string]interface{}{"id": sha})
+	case "GET projects/:id/repository/tree":
+		f.json(w, p.tree(r.URL.Query().Get("path")))
+	case "GET projects/:id/repository/archive.zip":
+		archive, ok := p.archives[r.URL.Query().Get("sha")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(archive)
+	default:
+		f.t.Errorf("unexpected gitlab request %s %s", r.Method, r.URL)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// tree returns the direct children of dir, listing directories as trees and files as blobs.
+func (p *fakeProject) tree(dir string) []map[string]string {
+	prefix := ""
+	if dir != "" {
+		prefix = dir + "/"
+	}
+
+	seen := map[string]bool{}
+	var entries []map[string]string
+	for _, file := range p.files {
+		if !strings.HasPrefix(file, prefix) {
+			continue
+		}
+
+		parts := strings.SplitN(strings.TrimPrefix(file, prefix), "/", 2)
+		if seen[parts[0]] {
+			continue
+		}
+		seen[parts[0]] = true
+
+		typ := "blob"
+		if len(parts) == 2 {
+			typ = "tree"
+		}
+		entries = append(entries, map[string]string{"name": parts[0], "type": typ, "path": prefix + parts[0]})
+	}
+
+	return entries
+}
+
+func (f *fakeGitlab) json(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	require.NoError(f.t, json.NewEncoder(w).Encode(v))
+}
+
+func mergeRequest(author, branch string) map[string]interface{} {
+	return map[string]interface{}{
+		"author":        map[string]interface{}{"username": author},
+		"target_branch": branch,
+	}
+}
+
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	buf := bytes.NewBuffer([]byte{})
+	zw := zip.NewWriter(buf)
+	for name, content := range files {
+		w, err := zw.Create("test-main-abc123/" + name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	return buf.Bytes()
+}
+
+func TestGitlabClient(t *testing.T) {
+	newClient := func(s *httptest.Server) *vcs.GitlabClient {
+		return vcs.NewGitlabClient(vcs.GitlabConfig{
+			URL:         s.URL + "/",
+			AccessToken: "gitlab-token",
+			Username:    "testrelay",
+			Email:       "interviewer@testrelay.io",
+			NamespaceID: 10,
+		})
+	}
+
+	t.Run("CreateRepo", func(t *testing.T) {
+		t.Run("should create a private project with the candidate as a developer", func(t *testing.T) {
+			f, s := newFakeGitlab(t)
+
+			repo, err := newClient(s).CreateRepo(core.CreateDetails{
+				Provider:     core.VCSProviderGitlab,
+				BusinessName: "TestRelay",
+				Username:     "jane",
+				ID:           12,
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, s.URL+"/testrelay/jane-testrelay-test-12.git", repo)
+			p := f.projects["testrelay/jane-testrelay-test-12"]
+			require.NotNil(t, p)
+			assert.True(t, p.private)
+			assert.Equal(t, map[int64]int{1: 30}, p.members)
+		})
+
+		t.Run("should error if the candidate does not exist", func(t *testing.T) {
+			_, s := newFakeGitlab(t)
+
+			_, err := newClient(s).CreateRepo(core.CreateDetails{BusinessName: "TestRelay", Username: "nobody", ID: 12})
+			assert.EqualError(t, err, "gitlab user nobody does not exist")
+		})
+	})
+
+	t.Run("AddCollaborator", func(t *testing.T) {
+		t.Run("should add the user as a developer once", func(t *testing.T) {
+			f, s := newFakeGitlab(t)
+			p := f.addProject("testrelay/jane-test", 10)
+			c := newClient(s)
+
+			require.NoError(t, c.AddCollaborator(s.URL+"/testrelay/jane-test.git", "bob"))
+			assert.Equal(t, map[int64]int{2: 30}, p.members)
+
+			err := c.AddCollaborator(s.URL+"/testrelay/jane-test.git", "bob")
+			assert.ErrorIs(t, err, vcs.ErrorAlreadyCollaborator)
+		})
+	})
+
+	t.Run("IsSubmitted", func(t *testing.T) {
+		f, s := newFakeGitlab(t)
+		p := f.addProject("testrelay/jane-test", 10)
+		c := newClient(s)
+		repo := s.URL + "/testrelay/jane-test.git"
+
+		submitted, err := c.IsSubmitted(repo, "jane")
+		require.NoError(t, err)
+		assert.False(t, submitted)
+
+		p.mrs = append(p.mrs, mergeRequest("bob", "main"), mergeRequest("jane", "stage-2"))
+
+		submitted, err = c.IsSubmitted(repo, "jane")
+		require.NoError(t, err)
+		assert.True(t, submitted)
+
+		submitted, err = c.IsStageSubmitted(repo, "jane", "stage-2")
+		require.NoError(t, err)
+		assert.True(t, submitted)
+
+		submitted, err = c.IsStageSubmitted(repo, "jane", "stage-3")
+		require.NoError(t, err)
+		assert.False(t, submitted)
+	})
+
+	t.Run("Cleanup", func(t *testing.T) {
+		f, s := newFakeGitlab(t)
+		p := f.addProject("testrelay/jane-test", 10)
+		p.members = map[int64]int{1: 30, 3: 30}
+
+		err := newClient(s).Cleanup(core.CleanDetails{
+			VCSRepoURL:         s.URL + "/testrelay/jane-test.git",
+			CandidateUsername:  "jane",
+			ReviewersUsernames: []string{"bob", "alice"},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, map[int64]int{2: 30, 3: 30}, p.members)
+	})
+
+	t.Run("Upload", func(t *testing.T) {
+		upload := func(t *testing.T, s *httptest.Server, ref string) (core.UploadResult, *object.Commit) {
+			dir := t.TempDir()
+			_, err := git.PlainInit(dir, true)
+			require.NoError(t, err)
+
+			res, err := newClient(s).Upload(core.UploadDetails{
+				ID:             12,
+				VCSRepoURL:     dir,
+				TestVCSRepoURL: s.URL + "/testrelay/backend-test.git",
+				Ref:            ref,
+				InstallationID: 10,
+				Branch:         "stage-2",
+			})
+			require.NoError(t, err)
+
+			r, err := git.PlainOpen(dir)
+			require.NoError(t, err)
+			head, err := r.Reference(plumbing.NewBranchReferenceName("stage-2"), true)
+			require.NoError(t, err)
+			commit, err := r.CommitObject(head.Hash())
+			require.NoError(t, err)
+
+			return res, commit
+		}
+
+		t.Run("should push the default branch of the test project to the assignment repo", func(t *testing.T) {
+			f, s := newFakeGitlab(t)
+			test := f.addProject("testrelay/backend-test", 10)
+			test.commit("main", "a1b2c3", zipArchive(t, map[string]string{"README.md": "# Backend test", "src/main.go": "package main"}))
+
+			res, commit := upload(t, s, "")
+			assert.Equal(t, "a1b2c3", res.SHA)
+			assert.Empty(t, res.Excluded)
+
+			assert.Equal(t, "testrelay", commit.Author.Name)
+			file, err := commit.File("src/main.go")
+			require.NoError(t, err)
+			content, err := file.Contents()
+			require.NoError(t, err)
+			assert.Equal(t, "package main", content)
+		})
+
+		t.Run("should push the given ref of the test project", func(t *testing.T) {
+			f, s := newFakeGitlab(t)
+			test := f.addProject("testrelay/backend-test", 10)
+			test.commit("main", "a1b2c3", zipArchive(t, map[string]string{"README.md": "# Work in progress"}))
+			test.commit("v1.0", "d4e5f6", zipArchive(t, map[string]string{"README.md": "# Backend test"}))
+
+			res, commit := upload(t, s, "v1.0")
+			assert.Equal(t, "d4e5f6", res.SHA)
+
+			file, err := commit.File("README.md")
+			require.NoError(t, err)
+			content, err := file.Contents()
+			require.NoError(t, err)
+			assert.Equal(t, "# Backend test", content)
+		})
+
+		t.Run("should reject test projects outside of the business group", func(t *testing.T) {
+			f, s := newFakeGitlab(t)
+			f.addProject("testrelay/backend-test", 10)
+
+			_, err := newClient(s).Upload(core.UploadDetails{
+				ID:             12,
+				VCSRepoURL:     t.TempDir(),
+				TestVCSRepoURL: s.URL + "/testrelay/backend-test.git",
+				InstallationID: 11,
+			})
+			assert.EqualError(t, err, "test project testrelay/backend-test is not in group other")
+		})
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		t.Run("should measure the files that would be uploaded", func(t *testing.T) {
+			f, s := newFakeGitlab(t)
+			test := f.addProject("testrelay/interview-exercises", 10)
+			test.commit("main", "a1b2c3", zipArchive(t, map[string]string{
+				"README.md":                      "# All exercises",
+				"exercises/api/README.md":        "# {{ .TestName }}",
+				"exercises/api/.testrelayignore": "solution.go",
+				"exercises/api/solution.go":      "package api",
+				"exercises/api/src/main.go":      "package main",
+			}))
+
+			res, err := newClient(s).Validate(core.UploadDetails{
+				TestVCSRepoURL: s.URL + "/testrelay/interview-exercises.git",
+				Path:           "exercises/api",
+				InstallationID: 10,
+				TemplateGlob:   "*.md",
+				TemplateData:   core.TemplateData{TestName: "API"},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, core.ValidateResult{
+				UploadResult: core.UploadResult{SHA: "a1b2c3", Excluded: []string{".testrelayignore", "solution.go"}},
+				Files:        2,
+				Size:         int64(len("# API") + len("package main")),
+			}, res)
+		})
+
+		t.Run("should error for empty test projects and broken templates", func(t *testing.T) {
+			f, s := newFakeGitlab(t)
+			empty := f.addProject("testrelay/empty", 10)
+			empty.commit("main", "a1b2c3", zipArchive(t, map[string]string{}))
+			broken := f.addProject("testrelay/broken", 10)
+			broken.commit("main", "d4e5f6", zipArchive(t, map[string]string{"README.md": "{{ .Salary }}"}))
+
+			_, err := newClient(s).Validate(core.UploadDetails{
+				TestVCSRepoURL: s.URL + "/testrelay/empty.git",
+				InstallationID: 10,
+			})
+			assert.EqualError(t, err, "test repo "+s.URL+"/testrelay/empty.git is empty")
+
+			_, err = newClient(s).Validate(core.UploadDetails{
+				TestVCSRepoURL: s.URL + "/testrelay/broken.git",
+				InstallationID: 10,
+				TemplateGlob:   "*.md",
+			})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "could not render template README.md")
+		})
+	})
+
+	t.Run("CollectRepos", func(t *testing.T) {
+		f, s := newFakeGitlab(t)
+		f.addProject("testrelay/backend-test", 10)
+		f.addProject("other/frontend-test", 11)
+
+		repos, err := newClient(s).CollectRepos(10)
+		require.NoError(t, err)
+
+		assert.Equal(t, []core.Repo{{ID: 100, FullName: "testrelay/backend-test"}}, repos)
+	})
+
+	t.Run("CollectDirs", func(t *testing.T) {
+		f, s := newFakeGitlab(t)
+		test := f.addProject("testrelay/interview-exercises", 10)
+		test.files = []string{"README.md", "exercises/api/README.md", "exercises/api/src/main.go", "exercises/frontend/index.js"}
+		f.addProject("other/exercises", 11)
+
+		dirs, err := newClient(s).CollectDirs(10, "testrelay/interview-exercises", "")
+		require.NoError(t, err)
+		assert.Equal(t, []core.RepoDir{{Name: "exercises", Path: "exercises"}}, dirs)
+
+		dirs, err = newClient(s).CollectDirs(10, "testrelay/interview-exercises", "exercises")
+		require.NoError(t, err)
+		assert.Equal(t, []core.RepoDir{
+			{Name: "api", Path: "exercises/api"},
+			{Name: "frontend", Path: "exercises/frontend"},
+		}, dirs)
+
+		_, err = newClient(s).CollectDirs(10, "other/exercises", "")
+		assert.EqualError(t, err, "test project other/exercises is not in group testrelay")
+	})
+
+	t.Run("should return transient errors for gitlab outages", func(t *testing.T) {
+		f, s := newFakeGitlab(t)
+		c := newClient(s)
+
+		f.fail = http.StatusServiceUnavailable
+		_, err := c.IsSubmitted(s.URL+"/testrelay/jane-test.git", "jane")
+		assert.True(t, core.IsTransient(err))
+
+		f.fail = http.StatusForbidden
+		_, err = c.IsSubmitted(s.URL+"/testrelay/jane-test.git", "jane")
+		require.Error(t, err)
+		assert.False(t, core.IsTransient(err))
+	})
+}
@@ -0,0 +1,70 @@
+package vcs
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// ignoreFile lists, in gitignore syntax, the files of a test repo that are left out of the candidate's commit,
+// e.g. reference solutions, hidden tests and internal notes.
+const ignoreFile = ".testrelayignore"
+
+// removeIgnored deletes the files in fs matched by the ignoreFile at its root, along with the ignoreFile
+// itself, returning the slash separated path of each deleted file.
+func removeIgnored(fs billy.Filesystem) ([]string, error) {
+	patterns, err := readIgnorePatterns(fs, ignoreFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m := gitignore.NewMatcher(patterns)
+
+	var removed []string
+	err = walk(fs, "", func(p string, info os.FileInfo) error {
+		if p != ignoreFile && !m.Match(strings.Split(p, "/"), false) {
+			return nil
+		}
+
+		removed = append(removed, p)
+		return fs.Remove(p)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not remove files matching %s %w", ignoreFile, err)
+	}
+
+	return removed, nil
+}
+
+func readIgnorePatterns(fs billy.Filesystem, name string) ([]gitignore.Pattern, error) {
+	f, err := fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []gitignore.Pattern
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimRight(s.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		patterns = append(patterns, gitignore.ParsePattern(line, nil))
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("could not read %s %w", ignoreFile, err)
+	}
+
+	return patterns, nil
+}
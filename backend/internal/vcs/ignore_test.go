@@ -0,0 +1,45 @@
+package vcs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoveIgnored(t *testing.T) {
+	t.Run("should remove files matching .testrelayignore and the file itself", func(t *testing.T) {
+		fs := newMemFS(t, map[string]string{
+			".testrelayignore":     "# reference solution\nsolutions/\n*.secret.md\n!README.secret.md\n",
+			"README.md":            "# Backend test",
+			"README.secret.md":     "# Kept",
+			"notes.secret.md":      "# Internal notes",
+			"solutions/main.go":    "package main",
+			"solutions/api/api.go": "package api",
+			"src/main.go":          "package main",
+			"src/hidden.secret.md": "# Hidden",
+		})
+
+		removed, err := removeIgnored(fs)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{
+			".testrelayignore",
+			"notes.secret.md",
+			"solutions/api/api.go",
+			"solutions/main.go",
+			"src/hidden.secret.md",
+		}, removed)
+		assert.Equal(t, []string{"README.md", "README.secret.md", "src/main.go"}, listFiles(t, fs))
+	})
+
+	t.Run("should leave every file when there is no .testrelayignore", func(t *testing.T) {
+		fs := newMemFS(t, map[string]string{"README.md": "# Backend test", "solutions/main.go": "package main"})
+
+		removed, err := removeIgnored(fs)
+		require.NoError(t, err)
+
+		assert.Empty(t, removed)
+		assert.Equal(t, []string{"README.md", "solutions/main.go"}, listFiles(t, fs))
+	})
+}
@@ -0,0 +1,511 @@
+package vcs
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cgi"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+)
+
+// accessFile is the file in each bare repo that lists the users with access to it, one per line.
+const accessFile = "testrelay-access"
+
+// submissionRef is the branch or tag a candidate pushes to submit an assignment.
+const submissionRef = "submission"
+
+var ErrRepoNotFound = errors.New("repo not found")
+
+// LocalConfig represents fields required to host assignment repos as bare repositories on disk.
+type LocalConfig struct {
+	// Root is the directory that holds the bare repos.
+	Root string
+	// URL is the base url that Handler is served at, e.g. https://backend.testrelay.io/git.
+	URL string
+	// Secret derives each user's password, see LocalGitClient.Password.
+	Secret string
+	// Username and Email are the author of uploaded test code. Username can access every repo.
+	Username string
+	Email    string
+}
+
+// LocalGitClient hosts assignment repos as bare git repositories under a single directory, serving them over
+// http with Handler. Users are identified by their email and authenticate with http basic auth, using the
+// password returned by Password. Candidates submit by pushing a submission branch or tag.
+//
+// Test repos are read from the same directory, so a LocalGitClient should only be used by a single business,
+// e.g. on premise or for local development.
+type LocalGitClient struct {
+	conf LocalConfig
+	// mu guards the access files of every repo.
+	mu sync.Mutex
+}
+
+// NewLocalGitClient returns a LocalGitClient for the repos in conf.Root.
+func NewLocalGitClient(conf LocalConfig) *LocalGitClient {
+	conf.URL = strings.TrimSuffix(conf.URL, "/")
+
+	return &LocalGitClient{conf: conf}
+}
+
+// Password returns the http password of the user, an hmac of the username keyed with the configured secret.
+func (c *LocalGitClient) Password(username string) string {
+	mac := hmac.New(sha256.New, []byte(c.conf.Secret))
+	mac.Write([]byte(username))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CreateRepo initialises an empty bare repo, giving the candidate access to it.
+// It returns the http clone url of the repo.
+func (c *LocalGitClient) CreateRepo(details core.CreateDetails) (string, error) {
+	name := makeRepoName(details.BusinessName, strings.ReplaceAll(details.Username, "@", "-at-"), details.ID) + ".git"
+
+	_, err := git.PlainInit(filepath.Join(c.conf.Root, name), true)
+	if err != nil {
+		return "", fmt.Errorf("could not init repo %s %w", name, err)
+	}
+
+	repoURL := c.conf.URL + "/" + name
+	err = c.AddCollaborator(repoURL, details.Username)
+	if err != nil {
+		return "", err
+	}
+
+	return repoURL, nil
+}
+
+// AddCollaborator gives the user read and write access to the repo. It returns ErrorAlreadyCollaborator
+// if the user already has access.
+func (c *LocalGitClient) AddCollaborator(repo string, username string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	dir, err := c.dir(repo)
+	if err != nil {
+		return err
+	}
+
+	users, err := readAccess(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, u := range users {
+		if u == username {
+			return ErrorAlreadyCollaborator
+		}
+	}
+
+	return writeAccess(dir, append(users, username))
+}
+
+// Upload commits data.Ref of the test repo, or its head if data.Ref is empty, into the assignment repo,
+// returning the commit sha it resolved to. See pushArchive for how the test code is committed.
+func (c *LocalGitClient) Upload(data core.UploadDetails) (core.UploadResult, error) {
+	testDir, err := c.dir(data.TestVCSRepoURL)
+	if err != nil {
+		return core.UploadResult{}, err
+	}
+
+	buf, sha, err := archive(testDir, data.Ref)
+	if err != nil {
+		return core.UploadResult{}, err
+	}
+
+	dir, err := c.dir(data.VCSRepoURL)
+	if err != nil {
+		return core.UploadResult{}, err
+	}
+
+	// push straight to the bare repo rather than through Handler.
+	data.VCSRepoURL = dir
+	excluded, err := pushArchive(buf, data, object.Signature{
+		Name:  c.conf.Username,
+		Email: c.conf.Email,
+		When:  time.Now(),
+	}, nil)
+	if err != nil {
+		return core.UploadResult{}, err
+	}
+
+	return core.UploadResult{SHA: sha, Excluded: excluded}, nil
+}
+
+// Validate archives data.Ref of the local test repo and unpacks it as Upload would, without pushing it to an
+// assignment repo.
+func (c *LocalGitClient) Validate(data core.UploadDetails) (core.ValidateResult, error) {
+	testDir, err := c.dir(data.TestVCSRepoURL)
+	if err != nil {
+		return core.ValidateResult{}, err
+	}
+
+	buf, sha, err := archive(testDir, data.Ref)
+	if err != nil {
+		return core.ValidateResult{}, err
+	}
+
+	res, err := validateArchive(buf, data)
+	if err != nil {
+		return core.ValidateResult{}, err
+	}
+
+	res.SHA = sha
+	return res, nil
+}
+
+// CollectRepos lists the test repos in the root, i.e. every repo without an access file, as the root also holds
+// assignment repos. Local repos are not grouped by business, so the installation id is ignored. The full name of
+// each repo is its clone url, which is what tests use as their github_repo.
+func (c *LocalGitClient) CollectRepos(int64) ([]core.Repo, error) {
+	entries, err := os.ReadDir(c.conf.Root)
+	if err != nil {
+		return nil, fmt.Errorf("could not list repos in %s %w", c.conf.Root, err)
+	}
+
+	repos := []core.Repo{}
+	for _, e := range entries {
+		dir := filepath.Join(c.conf.Root, e.Name())
+		if !e.IsDir() {
+			continue
+		}
+
+		if _, err := git.PlainOpen(dir); err != nil {
+			continue
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, accessFile)); err == nil {
+			continue
+		}
+
+		repos = append(repos, core.Repo{FullName: c.conf.URL + "/" + e.Name()})
+	}
+
+	return repos, nil
+}
+
+// CollectDirs lists the directories at path in the head of the test repo whose clone url is fullName.
+func (c *LocalGitClient) CollectDirs(_ int64, fullName, path string) ([]core.RepoDir, error) {
+	dir, err := c.dir(fullName)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not open test repo %s %w", fullName, err)
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return nil, fmt.Errorf("could not get head of test repo %s %w", fullName, err)
+	}
+
+	commit, err := r.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("could not get head commit of test repo %s %w", fullName, err)
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("could not get tree of test repo %s %w", fullName, err)
+	}
+
+	path = strings.Trim(path, "/")
+	if path != "" {
+		tree, err = tree.Tree(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not get %s of test repo %s %w", path, fullName, err)
+		}
+	}
+
+	dirs := []core.RepoDir{}
+	for _, e := range tree.Entries {
+		if e.Mode == filemode.Dir {
+			dirs = append(dirs, core.RepoDir{Name: e.Name, Path: strings.TrimPrefix(path+"/"+e.Name, "/")})
+		}
+	}
+
+	return dirs, nil
+}
+
+// archive returns a zip of the files at ref of the repo in dir, within a single top level directory like the
+// archives returned by hosted providers, and the commit sha that ref resolved to. An empty ref archives the head.
+func archive(dir, ref string) (*bytes.Buffer, string, error) {
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		return nil, "", fmt.Errorf("could not open test repo %s %w", dir, err)
+	}
+
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	hash, err := r.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return nil, "", fmt.Errorf("could not resolve ref %s of test repo %s %w", ref, dir, err)
+	}
+
+	commit, err := r.CommitObject(*hash)
+	if err != nil {
+		return nil, "", fmt.Errorf("could not get commit %s of test repo %s %w", hash, dir, err)
+	}
+
+	files, err := commit.Files()
+	if err != nil {
+		return nil, "", fmt.Errorf("could not list files of test repo %s %w", dir, err)
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	zw := zip.NewWriter(buf)
+	err = files.ForEach(func(f *object.File) error {
+		w, err := zw.Create("test/" + f.Name)
+		if err != nil {
+			return err
+		}
+
+		rd, err := f.Reader()
+		if err != nil {
+			return err
+		}
+		defer rd.Close()
+
+		_, err = io.Copy(w, rd)
+		return err
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("could not archive test repo %s %w", dir, err)
+	}
+
+	err = zw.Close()
+	if err != nil {
+		return nil, "", fmt.Errorf("could not archive test repo %s %w", dir, err)
+	}
+
+	return buf, hash.String(), nil
+}
+
+// IsSubmitted returns whether a submission branch or tag has been pushed to the repo.
+func (c *LocalGitClient) IsSubmitted(vcsURL, username string) (bool, error) {
+	return c.IsStageSubmitted(vcsURL, username, "")
+}
+
+// IsStageSubmitted returns whether a <branch>-submission branch or tag has been pushed to the repo. An empty
+// branch checks for the submission branch or tag.
+func (c *LocalGitClient) IsStageSubmitted(vcsURL, username, branch string) (bool, error) {
+	dir, err := c.dir(vcsURL)
+	if err != nil {
+		return false, err
+	}
+
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		return false, fmt.Errorf("could not open repo %s %w", dir, err)
+	}
+
+	name := submissionRef
+	if branch != "" {
+		name = branch + "-" + submissionRef
+	}
+
+	for _, ref := range []plumbing.ReferenceName{plumbing.NewBranchReferenceName(name), plumbing.NewTagReferenceName(name)} {
+		_, err := r.Reference(ref, false)
+		if err == nil {
+			return true, nil
+		}
+
+		if !errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return false, fmt.Errorf("could not get ref %s of %s %w", ref, dir, err)
+		}
+	}
+
+	return false, nil
+}
+
+// Cleanup removes the candidate's access to the repo and gives each reviewer access.
+func (c *LocalGitClient) Cleanup(details core.CleanDetails) error {
+	dir, err := c.dir(details.VCSRepoURL)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	users, err := readAccess(dir)
+	if err == nil {
+		var kept []string
+		for _, u := range users {
+			if u != details.CandidateUsername {
+				kept = append(kept, u)
+			}
+		}
+
+		err = writeAccess(dir, kept)
+	}
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("could not remove access to test repo %s %w", details.VCSRepoURL, err)
+	}
+
+	for _, reviewer := range details.ReviewersUsernames {
+		err := c.AddCollaborator(details.VCSRepoURL, reviewer)
+		if err != nil && !errors.Is(err, ErrorAlreadyCollaborator) {
+			return fmt.Errorf("could not add %s to repo %w", reviewer, err)
+		}
+	}
+
+	return nil
+}
+
+// Handler returns a http.Handler that serves the repos over the git smart http protocol using git http-backend,
+// which requires git to be installed. Requests are authenticated with basic auth and users can only access the
+// repos they have been given access to.
+func (c *LocalGitClient) Handler() http.Handler {
+	u, _ := url.Parse(c.conf.URL)
+	gitPath, _ := exec.LookPath("git")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || !hmac.Equal([]byte(password), []byte(c.Password(username))) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="testrelay"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		rest := strings.TrimPrefix(r.URL.Path, u.Path+"/")
+		i := strings.Index(rest, ".git/")
+		if i == -1 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		allowed, err := c.allowed(c.conf.URL+"/"+rest[:i+4], username)
+		if errors.Is(err, ErrRepoNotFound) || (err == nil && !allowed) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		h := &cgi.Handler{
+			Path: gitPath,
+			Args: []string{"http-backend"},
+			Root: u.Path,
+			Env: []string{
+				"GIT_PROJECT_ROOT=" + c.conf.Root,
+				"GIT_HTTP_EXPORT_ALL=1",
+				// receive-pack is only enabled for authenticated users.
+				"REMOTE_USER=" + username,
+			},
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func (c *LocalGitClient) allowed(repo, username string) (bool, error) {
+	if username == c.conf.Username {
+		_, err := c.dir(repo)
+		return err == nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	dir, err := c.dir(repo)
+	if err != nil {
+		return false, err
+	}
+
+	users, err := readAccess(dir)
+	if err != nil {
+		return false, err
+	}
+
+	for _, u := range users {
+		if u == username {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// owns returns whether the repo url is served by Handler.
+func (c *LocalGitClient) owns(repoURL string) bool {
+	return strings.HasPrefix(repoURL, c.conf.URL+"/")
+}
+
+// dir returns the directory of the repo at repoURL, erroring with ErrRepoNotFound if it is not
+// a directory under the configured root.
+func (c *LocalGitClient) dir(repoURL string) (string, error) {
+	name := strings.TrimPrefix(repoURL, c.conf.URL+"/")
+	dir := filepath.Join(c.conf.Root, filepath.FromSlash(name))
+
+	rel, err := filepath.Rel(c.conf.Root, dir)
+	if err != nil || !c.owns(repoURL) || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("%w %s", ErrRepoNotFound, repoURL)
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		return "", fmt.Errorf("%w %s", ErrRepoNotFound, repoURL)
+	}
+
+	return dir, nil
+}
+
+func readAccess(dir string) ([]string, error) {
+	f, err := os.Open(filepath.Join(dir, accessFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not open access file %w", err)
+	}
+	defer f.Close()
+
+	var users []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if line := strings.TrimSpace(s.Text()); line != "" {
+			users = append(users, line)
+		}
+	}
+
+	return users, s.Err()
+}
+
+func writeAccess(dir string, users []string) error {
+	var b strings.Builder
+	for _, u := range users {
+		b.WriteString(u + "\n")
+	}
+
+	err := os.WriteFile(filepath.Join(dir, accessFile), []byte(b.String()), 0600)
+	if err != nil {
+		return fmt.Errorf("could not write access file %w", err)
+	}
+
+	return nil
+}
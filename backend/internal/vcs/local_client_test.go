@@ -0,0 +1,233 @@
+package vcs_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	gitHttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+	"github.com/testrelay/testrelay/backend/internal/vcs"
+)
+
+func TestLocalGitClient(t *testing.T) {
+	const jane = "jane@testrelay.io"
+
+	setup := func(t *testing.T) (*vcs.LocalGitClient, *httptest.Server, string) {
+		root := t.TempDir()
+
+		var handler http.Handler
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler.ServeHTTP(w, r)
+		}))
+		t.Cleanup(s.Close)
+
+		c := vcs.NewLocalGitClient(vcs.LocalConfig{
+			Root:     root,
+			URL:      s.URL + "/git",
+			Secret:   "secret",
+			Username: "testrelay",
+			Email:    "interviewer@testrelay.io",
+		})
+		handler = c.Handler()
+
+		return c, s, root
+	}
+
+	// commitTest commits files to the backend-test repo in root, creating it if needed, as a business would
+	// push its test. It returns the repo and the hash of the new commit.
+	commitTest := func(t *testing.T, root string, files map[string]string) (*git.Repository, plumbing.Hash) {
+		dir := filepath.Join(root, "backend-test")
+		r, err := git.PlainInit(dir, false)
+		if errors.Is(err, git.ErrRepositoryAlreadyExists) {
+			r, err = git.PlainOpen(dir)
+		}
+		require.NoError(t, err)
+		w, err := r.Worktree()
+		require.NoError(t, err)
+
+		for name, content := range files {
+			require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), os.ModePerm))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+		}
+		_, err = w.Add(".")
+		require.NoError(t, err)
+		hash, err := w.Commit("test", &git.CommitOptions{Author: &object.Signature{Name: "acme", When: time.Now()}})
+		require.NoError(t, err)
+
+		return r, hash
+	}
+
+	t.Run("should create a repo, upload the test and detect a pushed submission", func(t *testing.T) {
+		if _, err := exec.LookPath("git"); err != nil {
+			t.Skip("git is required to serve repos over http")
+		}
+
+		c, s, root := setup(t)
+		_, hash := commitTest(t, root, map[string]string{"README.md": "# Backend test", "src/main.go": "package main"})
+
+		repo, err := c.CreateRepo(core.CreateDetails{Provider: core.VCSProviderLocal, BusinessName: "TestRelay", Username: jane, ID: 12})
+		require.NoError(t, err)
+		assert.Equal(t, s.URL+"/git/jane-at-testrelay.io-testrelay-test-12.git", repo)
+
+		res, err := c.Upload(core.UploadDetails{ID: 12, VCSRepoURL: repo, TestVCSRepoURL: s.URL + "/git/backend-test"})
+		require.NoError(t, err)
+		assert.Equal(t, hash.String(), res.SHA)
+
+		dir := t.TempDir()
+		auth := &gitHttp.BasicAuth{Username: jane, Password: c.Password(jane)}
+		r, err := git.PlainClone(dir, false, &git.CloneOptions{URL: repo, Auth: auth})
+		require.NoError(t, err)
+
+		content, err := os.ReadFile(filepath.Join(dir, "src/main.go"))
+		require.NoError(t, err)
+		assert.Equal(t, "package main", string(content))
+
+		submitted, err := c.IsSubmitted(repo, jane)
+		require.NoError(t, err)
+		assert.False(t, submitted)
+
+		head, err := r.Head()
+		require.NoError(t, err)
+		err = r.Push(&git.PushOptions{
+			Auth:     auth,
+			RefSpecs: []config.RefSpec{config.RefSpec(head.Name() + ":refs/heads/submission")},
+		})
+		require.NoError(t, err)
+
+		submitted, err = c.IsSubmitted(repo, jane)
+		require.NoError(t, err)
+		assert.True(t, submitted)
+
+		submitted, err = c.IsStageSubmitted(repo, jane, "stage-2")
+		require.NoError(t, err)
+		assert.False(t, submitted)
+	})
+
+	t.Run("should only serve repos to users with access", func(t *testing.T) {
+		if _, err := exec.LookPath("git"); err != nil {
+			t.Skip("git is required to serve repos over http")
+		}
+
+		c, s, root := setup(t)
+		commitTest(t, root, map[string]string{"README.md": "# Backend test"})
+
+		repo, err := c.CreateRepo(core.CreateDetails{BusinessName: "TestRelay", Username: jane, ID: 12})
+		require.NoError(t, err)
+		_, err = c.Upload(core.UploadDetails{ID: 12, VCSRepoURL: repo, TestVCSRepoURL: s.URL + "/git/backend-test"})
+		require.NoError(t, err)
+
+		_, err = git.PlainClone(t.TempDir(), false, &git.CloneOptions{
+			URL:  repo,
+			Auth: &gitHttp.BasicAuth{Username: jane, Password: "wrong"},
+		})
+		assert.Error(t, err)
+
+		bob := &gitHttp.BasicAuth{Username: "bob@testrelay.io", Password: c.Password("bob@testrelay.io")}
+		_, err = git.PlainClone(t.TempDir(), false, &git.CloneOptions{URL: repo, Auth: bob})
+		assert.Error(t, err)
+
+		err = c.Cleanup(core.CleanDetails{VCSRepoURL: repo, CandidateUsername: jane, ReviewersUsernames: []string{"bob@testrelay.io"}})
+		require.NoError(t, err)
+
+		_, err = git.PlainClone(t.TempDir(), false, &git.CloneOptions{URL: repo, Auth: bob})
+		assert.NoError(t, err)
+
+		_, err = git.PlainClone(t.TempDir(), false, &git.CloneOptions{
+			URL:  repo,
+			Auth: &gitHttp.BasicAuth{Username: jane, Password: c.Password(jane)},
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("should upload the given ref of the test repo", func(t *testing.T) {
+		c, s, root := setup(t)
+		r, hash := commitTest(t, root, map[string]string{"README.md": "# Backend test"})
+		_, err := r.CreateTag("v1.0", hash, nil)
+		require.NoError(t, err)
+		commitTest(t, root, map[string]string{"README.md": "# Work in progress"})
+
+		repo, err := c.CreateRepo(core.CreateDetails{BusinessName: "TestRelay", Username: jane, ID: 12})
+		require.NoError(t, err)
+
+		res, err := c.Upload(core.UploadDetails{ID: 12, VCSRepoURL: repo, TestVCSRepoURL: s.URL + "/git/backend-test", Ref: "v1.0"})
+		require.NoError(t, err)
+		assert.Equal(t, hash.String(), res.SHA)
+
+		uploaded, err := git.PlainOpen(filepath.Join(root, "jane-at-testrelay.io-testrelay-test-12.git"))
+		require.NoError(t, err)
+		head, err := uploaded.Head()
+		require.NoError(t, err)
+		commit, err := uploaded.CommitObject(head.Hash())
+		require.NoError(t, err)
+		file, err := commit.File("README.md")
+		require.NoError(t, err)
+		content, err := file.Contents()
+		require.NoError(t, err)
+		assert.Equal(t, "# Backend test", content)
+
+		_, err = c.Upload(core.UploadDetails{ID: 12, VCSRepoURL: repo, TestVCSRepoURL: s.URL + "/git/backend-test", Ref: "missing"})
+		assert.Error(t, err)
+	})
+
+	t.Run("CollectRepos should list test repos but not assignment repos", func(t *testing.T) {
+		c, s, root := setup(t)
+		commitTest(t, root, map[string]string{"README.md": "# Backend test"})
+
+		_, err := c.CreateRepo(core.CreateDetails{BusinessName: "TestRelay", Username: jane, ID: 12})
+		require.NoError(t, err)
+		require.NoError(t, os.Mkdir(filepath.Join(root, "not-a-repo"), os.ModePerm))
+
+		repos, err := c.CollectRepos(0)
+		require.NoError(t, err)
+		assert.Equal(t, []core.Repo{{FullName: s.URL + "/git/backend-test"}}, repos)
+	})
+
+	t.Run("CollectDirs", func(t *testing.T) {
+		c, s, root := setup(t)
+		commitTest(t, root, map[string]string{
+			"README.md":                   "# Exercises",
+			"exercises/api/main.go":       "package main",
+			"exercises/frontend/index.js": "",
+		})
+
+		dirs, err := c.CollectDirs(0, s.URL+"/git/backend-test", "")
+		require.NoError(t, err)
+		assert.Equal(t, []core.RepoDir{{Name: "exercises", Path: "exercises"}}, dirs)
+
+		dirs, err = c.CollectDirs(0, s.URL+"/git/backend-test", "exercises")
+		require.NoError(t, err)
+		assert.Equal(t, []core.RepoDir{
+			{Name: "api", Path: "exercises/api"},
+			{Name: "frontend", Path: "exercises/frontend"},
+		}, dirs)
+
+		_, err = c.CollectDirs(0, s.URL+"/git/../outside", "")
+		assert.ErrorIs(t, err, vcs.ErrRepoNotFound)
+	})
+
+	t.Run("AddCollaborator", func(t *testing.T) {
+		c, s, _ := setup(t)
+
+		repo, err := c.CreateRepo(core.CreateDetails{BusinessName: "TestRelay", Username: jane, ID: 12})
+		require.NoError(t, err)
+
+		err = c.AddCollaborator(repo, jane)
+		assert.ErrorIs(t, err, vcs.ErrorAlreadyCollaborator)
+
+		err = c.AddCollaborator(s.URL+"/git/../outside.git", jane)
+		assert.ErrorIs(t, err, vcs.ErrRepoNotFound)
+	})
+}
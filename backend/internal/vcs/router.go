@@ -0,0 +1,128 @@
+package vcs
+
+import (
+	"errors"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+)
+
+var ErrProviderNotConfigured = errors.New("vcs provider is not configured")
+
+type provider interface {
+	core.VCSCreator
+	core.VCSCollaboratorAdder
+	core.VCSUploader
+	core.VCSCleaner
+	core.VCSSubmissionChecker
+	core.VCSStageSubmissionChecker
+	core.VCSValidator
+}
+
+// Router implements the core vcs interfaces across github, gitlab, bitbucket and local repos. Repos are created
+// on the provider chosen by the business, every other call goes to the provider that hosts the repo url.
+type Router struct {
+	Github *GithubClient
+	// Gitlab, Bitbucket and Local are nil when they are not configured. Repos that no configured provider
+	// hosts are sent to Github.
+	Gitlab    *GitlabClient
+	Bitbucket *BitbucketClient
+	Local     *LocalGitClient
+}
+
+func (r Router) CreateRepo(details core.CreateDetails) (string, error) {
+	switch details.Provider {
+	case core.VCSProviderGitlab:
+		if r.Gitlab == nil {
+			return "", ErrProviderNotConfigured
+		}
+
+		return r.Gitlab.CreateRepo(details)
+	case core.VCSProviderBitbucket:
+		if r.Bitbucket == nil {
+			return "", ErrProviderNotConfigured
+		}
+
+		return r.Bitbucket.CreateRepo(details)
+	case core.VCSProviderLocal:
+		if r.Local == nil {
+			return "", ErrProviderNotConfigured
+		}
+
+		return r.Local.CreateRepo(details)
+	}
+
+	return r.Github.CreateRepo(details)
+}
+
+func (r Router) AddCollaborator(repo string, username string) error {
+	return r.forURL(repo).AddCollaborator(repo, username)
+}
+
+func (r Router) Upload(data core.UploadDetails) (core.UploadResult, error) {
+	return r.forURL(data.VCSRepoURL).Upload(data)
+}
+
+// Validate checks the test repo with the provider of the business that owns it. Test repos are stored as a full
+// name rather than a url, so unlike the other calls they can't be routed by their host.
+func (r Router) Validate(data core.UploadDetails) (core.ValidateResult, error) {
+	switch data.Provider {
+	case core.VCSProviderGitlab:
+		if r.Gitlab == nil {
+			return core.ValidateResult{}, ErrProviderNotConfigured
+		}
+
+		return r.Gitlab.Validate(data)
+	case core.VCSProviderBitbucket:
+		if r.Bitbucket == nil {
+			return core.ValidateResult{}, ErrProviderNotConfigured
+		}
+
+		return r.Bitbucket.Validate(data)
+	case core.VCSProviderLocal:
+		if r.Local == nil {
+			return core.ValidateResult{}, ErrProviderNotConfigured
+		}
+
+		return r.Local.Validate(data)
+	}
+
+	return r.Github.Validate(data)
+}
+
+func (r Router) Cleanup(details core.CleanDetails) error {
+	return r.forURL(details.VCSRepoURL).Cleanup(details)
+}
+
+func (r Router) IsSubmitted(vcsURL, username string) (bool, error) {
+	return r.forURL(vcsURL).IsSubmitted(vcsURL, username)
+}
+
+func (r Router) IsStageSubmitted(vcsURL, username, branch string) (bool, error) {
+	return r.forURL(vcsURL).IsStageSubmitted(vcsURL, username, branch)
+}
+
+// Password returns the password that username clones repoURL with, for repos hosted by the local provider. It
+// returns false for repos on hosted providers, which users access with their own account.
+func (r Router) Password(repoURL, username string) (string, bool) {
+	if r.Local == nil || !r.Local.owns(repoURL) {
+		return "", false
+	}
+
+	return r.Local.Password(username), true
+}
+
+func (r Router) forURL(repoURL string) provider {
+	if r.Gitlab != nil && r.Gitlab.owns(repoURL) {
+		return r.Gitlab
+	}
+
+	if r.Bitbucket != nil && r.Bitbucket.owns(repoURL) {
+		return r.Bitbucket
+	}
+
+	if r.Local != nil && r.Local.owns(repoURL) {
+		return r.Local
+	}
+
+	return r.Github
+}
@@ -0,0 +1,90 @@
+package vcs_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+	"github.com/testrelay/testrelay/backend/internal/vcs"
+)
+
+func TestRouter(t *testing.T) {
+	t.Run("should send gitlab repos to the gitlab client", func(t *testing.T) {
+		f, s := newFakeGitlab(t)
+		f.addProject("testrelay/jane-test", 10).mrs = []map[string]interface{}{mergeRequest("jane", "main")}
+
+		r := vcs.Router{Gitlab: vcs.NewGitlabClient(vcs.GitlabConfig{URL: s.URL, AccessToken: "gitlab-token", NamespaceID: 10})}
+
+		submitted, err := r.IsSubmitted(s.URL+"/testrelay/jane-test.git", "jane")
+		require.NoError(t, err)
+		assert.True(t, submitted)
+
+		repo, err := r.CreateRepo(core.CreateDetails{Provider: core.VCSProviderGitlab, BusinessName: "TestRelay", Username: "jane", ID: 12})
+		require.NoError(t, err)
+		assert.Equal(t, s.URL+"/testrelay/jane-testrelay-test-12.git", repo)
+	})
+
+	t.Run("should send bitbucket repos to the bitbucket client", func(t *testing.T) {
+		f, s := newFakeBitbucket(t)
+		f.addRepo("testrelay/test-12")
+
+		r := vcs.Router{Bitbucket: vcs.NewBitbucketClient(vcs.BitbucketConfig{
+			URL:         s.URL,
+			APIURL:      s.URL + "/2.0",
+			Username:    "testrelay",
+			AppPassword: "app-password",
+		})}
+
+		require.NoError(t, r.AddCollaborator(s.URL+"/testrelay/test-12.git", "bob"))
+		assert.Equal(t, map[string]string{"bob": "write"}, f.repos["testrelay/test-12"].permissions)
+	})
+
+	t.Run("should validate test repos stored as a full name with the business's provider", func(t *testing.T) {
+		f, s := newFakeBitbucket(t)
+		f.addRepo("acme/backend-test").commit("main", "a1b2c3", zipArchive(t, map[string]string{"README.md": "# Backend test"}))
+
+		r := vcs.Router{Bitbucket: vcs.NewBitbucketClient(vcs.BitbucketConfig{
+			URL:         s.URL,
+			APIURL:      s.URL + "/2.0",
+			Username:    "testrelay",
+			AppPassword: "app-password",
+		})}
+
+		res, err := r.Validate(core.UploadDetails{
+			TestVCSRepoURL: "acme/backend-test",
+			Workspace:      "acme",
+			Provider:       core.VCSProviderBitbucket,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "a1b2c3", res.SHA)
+		assert.Equal(t, 1, res.Files)
+	})
+
+	t.Run("should only issue passwords for local repos", func(t *testing.T) {
+		local := vcs.NewLocalGitClient(vcs.LocalConfig{Root: t.TempDir(), URL: "https://backend.testrelay.io/git", Secret: "secret"})
+		r := vcs.Router{Local: local}
+
+		password, ok := r.Password("https://backend.testrelay.io/git/testrelay-test-12.git", "jane@testrelay.io")
+		assert.True(t, ok)
+		assert.Equal(t, local.Password("jane@testrelay.io"), password)
+
+		_, ok = r.Password("https://github.com/testrelay/testrelay-test-12", "jane@testrelay.io")
+		assert.False(t, ok)
+
+		_, ok = vcs.Router{}.Password("https://backend.testrelay.io/git/testrelay-test-12.git", "jane@testrelay.io")
+		assert.False(t, ok)
+	})
+
+	t.Run("should error for providers that are not configured", func(t *testing.T) {
+		_, err := vcs.Router{}.CreateRepo(core.CreateDetails{Provider: core.VCSProviderGitlab})
+		assert.ErrorIs(t, err, vcs.ErrProviderNotConfigured)
+
+		_, err = vcs.Router{}.CreateRepo(core.CreateDetails{Provider: core.VCSProviderBitbucket})
+		assert.ErrorIs(t, err, vcs.ErrProviderNotConfigured)
+
+		_, err = vcs.Router{}.Validate(core.UploadDetails{TestVCSRepoURL: "acme/backend-test", Provider: core.VCSProviderGitlab})
+		assert.ErrorIs(t, err, vcs.ErrProviderNotConfigured)
+	})
+}
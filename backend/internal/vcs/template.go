@@ -0,0 +1,72 @@
+package vcs
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+)
+
+// binarySniffLen is how much of a file is checked for null bytes to decide whether it is binary, as git does.
+const binarySniffLen = 8000
+
+// renderTemplates executes each text file in fs matched by glob, a gitignore style pattern, as a text/template
+// with data, overwriting the file with the result. Binary files and symlinks are left untouched, as is every file
+// when glob is empty.
+func renderTemplates(fs billy.Filesystem, glob string, data core.TemplateData) error {
+	if glob == "" {
+		return nil
+	}
+
+	pattern := gitignore.ParsePattern(glob, nil)
+
+	err := walk(fs, "", func(p string, info os.FileInfo) error {
+		if !info.Mode().IsRegular() || pattern.Match(strings.Split(p, "/"), false) != gitignore.Exclude {
+			return nil
+		}
+
+		b, err := util.ReadFile(fs, p)
+		if err != nil {
+			return err
+		}
+
+		if isBinary(b) {
+			return nil
+		}
+
+		t, err := template.New(p).Option("missingkey=error").Parse(string(b))
+		if err != nil {
+			return fmt.Errorf("could not parse template %s %w", p, err)
+		}
+
+		var out bytes.Buffer
+		err = t.Execute(&out, data)
+		if err != nil {
+			return fmt.Errorf("could not render template %s %w", p, err)
+		}
+
+		return util.WriteFile(fs, p, out.Bytes(), info.Mode())
+	})
+	if err != nil {
+		return fmt.Errorf("could not render files matching %s %w", glob, err)
+	}
+
+	return nil
+}
+
+func isBinary(b []byte) bool {
+	sniff := b
+	if len(sniff) > binarySniffLen {
+		sniff = sniff[:binarySniffLen]
+	}
+
+	return bytes.IndexByte(sniff, 0) != -1 || !utf8.Valid(b)
+}
@@ -0,0 +1,54 @@
+package vcs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+)
+
+func TestRenderTemplates(t *testing.T) {
+	data := core.TemplateData{
+		CandidateName:  "Jane",
+		CandidateEmail: "jane@testrelay.io",
+		BusinessName:   "TestRelay",
+		Deadline:       "Fri 12 November 12:00 GMT",
+	}
+
+	t.Run("should render text files matching the glob", func(t *testing.T) {
+		fs := newMemFS(t, map[string]string{
+			"README.md":       "# Hi {{ .CandidateName }}\n\n{{ .BusinessName }} needs this by {{ .Deadline }}.",
+			"docs/api.md":     "Email {{ .CandidateEmail }} with questions.",
+			"docs/diagram.md": "\x00\x01{{ .Binary",
+			"config.yaml":     "name: {{ .CandidateName }}",
+		})
+
+		require.NoError(t, renderTemplates(fs, "*.md", data))
+
+		for name, want := range map[string]string{
+			"README.md":       "# Hi Jane\n\nTestRelay needs this by Fri 12 November 12:00 GMT.",
+			"docs/api.md":     "Email jane@testrelay.io with questions.",
+			"docs/diagram.md": "\x00\x01{{ .Binary",
+			"config.yaml":     "name: {{ .CandidateName }}",
+		} {
+			assert.Equal(t, want, readFile(t, fs, name), name)
+		}
+	})
+
+	t.Run("should leave every file when the glob is empty", func(t *testing.T) {
+		fs := newMemFS(t, map[string]string{"README.md": "# Hi {{ .CandidateName }}"})
+
+		require.NoError(t, renderTemplates(fs, "", data))
+		assert.Equal(t, "# Hi {{ .CandidateName }}", readFile(t, fs, "README.md"))
+	})
+
+	t.Run("should error for templates that reference unknown fields", func(t *testing.T) {
+		fs := newMemFS(t, map[string]string{"README.md": "{{ .Salary }}"})
+
+		err := renderTemplates(fs, "*.md", data)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "could not render template README.md")
+	})
+}
@@ -0,0 +1,33 @@
+package vcs
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/go-git/go-billy/v5/memfs"
+
+	"github.com/testrelay/testrelay/backend/internal/core"
+)
+
+// validateArchive unpacks the zip archive in buf in memory, as pushArchive would, and returns the number and
+// total size of the files that would be committed along with the files that would be left out.
+func validateArchive(buf *bytes.Buffer, data core.UploadDetails) (core.ValidateResult, error) {
+	fs := memfs.New()
+	excluded, err := extractArchive(buf, data, fs)
+	if err != nil {
+		return core.ValidateResult{}, err
+	}
+
+	res := core.ValidateResult{UploadResult: core.UploadResult{Excluded: excluded}}
+	err = walk(fs, "", func(p string, info os.FileInfo) error {
+		res.Files++
+		res.Size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return core.ValidateResult{}, fmt.Errorf("could not measure test repo %s %w", data.TestVCSRepoURL, err)
+	}
+
+	return res, nil
+}
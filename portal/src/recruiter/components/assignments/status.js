@@ -23,6 +23,7 @@ const AssignmentStatus = (props) => {
             colour = "bg-green-200";
             break;
         case "missed":
+        case "expired":
             colour = "bg-red-200";
             break;
         default:
@@ -158,7 +158,7 @@ const TimelineBody = (props) => {
 const TimelineItem = (props) => {
     let colour = "bg-indigo-500"
 
-    if (props.event_type === "missed") {
+    if (props.event_type === "missed" || props.event_type === "expired") {
         colour = "bg-red-500";
     }
 